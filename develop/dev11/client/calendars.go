@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"develop/dev11/internal/models"
+	"net/http"
+	"net/url"
+)
+
+// Calendars - возвращает календари пользователя по возрастанию id
+func (c *Client) Calendars(ctx context.Context, userID int) ([]*models.Calendar, error) {
+	var calendars []*models.Calendar
+	_, err := c.call(ctx, request{method: http.MethodGet, path: userPath(userID, "calendars")}, &calendars)
+	return calendars, err
+}
+
+// GetCalendar - возвращает календарь пользователя
+func (c *Client) GetCalendar(ctx context.Context, userID, calendarID int) (*models.Calendar, error) {
+	var calendar models.Calendar
+	if _, err := c.call(ctx, request{method: http.MethodGet, path: userPath(userID, "calendars", calendarID)}, &calendar); err != nil {
+		return nil, err
+	}
+	return &calendar, nil
+}
+
+// CreateCalendar - создает календарь пользователя calendar.UserID, пустые цвет и видимость заполняются сервером
+func (c *Client) CreateCalendar(ctx context.Context, calendar *models.Calendar) (*models.Calendar, error) {
+	return c.saveCalendar(ctx, http.MethodPost, userPath(calendar.UserID, "calendars"), calendar)
+}
+
+// UpdateCalendar - изменяет непустые поля календаря calendar.ID
+func (c *Client) UpdateCalendar(ctx context.Context, calendar *models.Calendar) (*models.Calendar, error) {
+	return c.saveCalendar(ctx, http.MethodPatch, userPath(calendar.UserID, "calendars", calendar.ID), calendar)
+}
+
+// DeleteCalendar - удаляет календарь без событий
+func (c *Client) DeleteCalendar(ctx context.Context, userID, calendarID int) error {
+	_, err := c.call(ctx, request{method: http.MethodDelete, path: userPath(userID, "calendars", calendarID)}, nil)
+	return err
+}
+
+// saveCalendar - отправляет непустые поля календаря и возвращает сохраненный календарь
+func (c *Client) saveCalendar(ctx context.Context, method, path string, calendar *models.Calendar) (*models.Calendar, error) {
+	form := url.Values{}
+	for key, value := range map[string]string{"name": calendar.Name, "color": calendar.Color, "visibility": calendar.Visibility} {
+		if value != "" {
+			form.Set(key, value)
+		}
+	}
+
+	var saved models.Calendar
+	if _, err := c.call(ctx, request{method: method, path: path, form: form}, &saved); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
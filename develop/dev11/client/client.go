@@ -0,0 +1,238 @@
+// Package client - типизированный клиент HTTP API календаря. Запросы и ответы описаны в спецификации /openapi.json
+package client
+
+import (
+	"context"
+	"develop/dev11/internal/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nextCursorHeader - заголовок ответа с курсором следующей страницы
+const nextCursorHeader = "X-Next-Cursor"
+
+// Client - клиент API календаря. Безопасен для одновременного использования из нескольких горутин
+type Client struct {
+	baseURL    string       // baseURL - адрес сервера без завершающего /
+	httpClient *http.Client // httpClient - клиент, через который отправляются запросы
+	token      string       // token - токен доступа, пустой - запросы без аутентификации
+}
+
+// Option - необязательная настройка Client
+type Option func(*Client)
+
+// WithHTTPClient - задает HTTP-клиент, например с таймаутом или сертификатами mTLS
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken - задает токен доступа, который передается в заголовке Authorization
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// New - конструктор для Client, baseURL - адрес сервера, например http://localhost:8080
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Error - ответ сервера с кодом ошибки
+type Error struct {
+	StatusCode int           // StatusCode - HTTP-статус ответа
+	Message    string        // Message - текст ошибки из поля error
+	RequestID  string        // RequestID - идентификатор запроса для поиска в журнале сервера
+	RetryAfter time.Duration // RetryAfter - через сколько можно повторить запрос (429 и 503)
+}
+
+// Error возвращает статус и текст ошибки сервера
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// envelope - тело ответа сервера
+type envelope struct {
+	Result    json.RawMessage `json:"result"`     // Result - результат успешного запроса
+	Conflicts []*models.Event `json:"conflicts"`  // Conflicts - события, пересекающиеся с сохраненным
+	Error     string          `json:"error"`      // Error - текст ошибки
+	RequestID string          `json:"request_id"` // RequestID - идентификатор запроса
+}
+
+// reply - заголовки и пересекающиеся события успешного ответа
+type reply struct {
+	header    http.Header
+	conflicts []*models.Event
+}
+
+// request - запрос к API
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	form        url.Values // form - тело application/x-www-form-urlencoded
+	body        io.Reader  // body - тело с типом contentType, если form не задан
+	contentType string
+	header      http.Header // header - дополнительные заголовки
+}
+
+// send - отправляет запрос и возвращает ответ с успешным статусом, ответы с ошибкой возвращаются как *Error.
+// Тело возвращенного ответа закрывает вызывающий
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	target := c.baseURL + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+
+	body, contentType := req.body, req.contentType
+	if req.form != nil {
+		body, contentType = strings.NewReader(req.form.Encode()), "application/x-www-form-urlencoded"
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, req.method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range req.header {
+		httpRequest.Header[key] = values
+	}
+	if contentType != "" {
+		httpRequest.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		httpRequest.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	response, err := c.httpClient.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		defer response.Body.Close()
+		return nil, responseError(response)
+	}
+	return response, nil
+}
+
+// call - отправляет запрос и декодирует поле result ответа в result (nil - результат не нужен).
+// Возвращает заголовки ответа, из которых читаются ETag и курсор следующей страницы, и конфликты
+func (c *Client) call(ctx context.Context, req request, result any) (reply, error) {
+	response, err := c.send(ctx, req)
+	if err != nil {
+		return reply{}, err
+	}
+	defer response.Body.Close()
+
+	var body envelope
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return reply{}, fmt.Errorf("decode %s %s response: %w", req.method, req.path, err)
+	}
+	if result != nil {
+		if err := json.Unmarshal(body.Result, result); err != nil {
+			return reply{}, fmt.Errorf("decode %s %s result: %w", req.method, req.path, err)
+		}
+	}
+	return reply{header: response.Header, conflicts: body.Conflicts}, nil
+}
+
+// responseError - собирает *Error из ответа с кодом ошибки
+func responseError(response *http.Response) error {
+	apiError := &Error{
+		StatusCode: response.StatusCode,
+		RequestID:  response.Header.Get("X-Request-ID"),
+	}
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil {
+		apiError.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	var body envelope
+	data, _ := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err := json.Unmarshal(data, &body); err == nil && body.Error != "" {
+		apiError.Message = body.Error
+		if body.RequestID != "" {
+			apiError.RequestID = body.RequestID
+		}
+	} else {
+		apiError.Message = strings.TrimSpace(string(data))
+	}
+	return apiError
+}
+
+// Token - токен доступа, выданный /login
+type Token struct {
+	Token     string    `json:"token"`      // Token - значение для заголовка Authorization: Bearer
+	TokenType string    `json:"token_type"` // TokenType - тип токена, всегда Bearer
+	UserID    int       `json:"user_id"`    // UserID - пользователь, которому выдан токен
+	ExpiresAt time.Time `json:"expires_at"` // ExpiresAt - время истечения токена
+}
+
+// Login - получает токен доступа по имени и паролю. Токен передается в New через WithToken
+func (c *Client) Login(ctx context.Context, name, password string) (Token, error) {
+	var token Token
+	_, err := c.call(ctx, request{
+		method: http.MethodPost,
+		path:   "/login",
+		form:   url.Values{"name": {name}, "password": {password}},
+	}, &token)
+	return token, err
+}
+
+// Health - проверяет, что процесс сервера жив
+func (c *Client) Health(ctx context.Context) error {
+	_, err := c.call(ctx, request{method: http.MethodGet, path: "/healthz"}, nil)
+	return err
+}
+
+// Ready - проверяет, что сервер готов принимать запросы
+func (c *Client) Ready(ctx context.Context) error {
+	_, err := c.call(ctx, request{method: http.MethodGet, path: "/readyz"}, nil)
+	return err
+}
+
+// OpenAPI - возвращает спецификацию OpenAPI сервера
+func (c *Client) OpenAPI(ctx context.Context) ([]byte, error) {
+	response, err := c.send(ctx, request{method: http.MethodGet, path: "/openapi.json"})
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	return io.ReadAll(response.Body)
+}
+
+// userPath - путь ресурса пользователя REST API
+func userPath(userID int, parts ...any) string {
+	path := "/api/v1/users/" + strconv.Itoa(userID)
+	for _, part := range parts {
+		path += fmt.Sprintf("/%v", part)
+	}
+	return path
+}
+
+// joinInts - объединяет числа через запятую
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = strconv.Itoa(value)
+	}
+	return strings.Join(parts, ",")
+}
+
+// etagVersion - возвращает версию события из заголовка ETag, 0 - заголовка нет
+func etagVersion(header http.Header) int {
+	version, _ := strconv.Atoi(strings.Trim(header.Get("ETag"), `"`))
+	return version
+}
@@ -0,0 +1,396 @@
+package client
+
+import (
+	"context"
+	"develop/dev11/internal/auth"
+	"develop/dev11/internal/data"
+	"develop/dev11/internal/handler"
+	"develop/dev11/internal/models"
+	"develop/dev11/internal/service"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// start - запускает сервер с аутентификацией, в котором есть пользователи alice (id 5) и bob (id 6)
+func start(t *testing.T) string {
+	t.Helper()
+	hash, err := auth.HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := auth.NewUsers([]*auth.User{{ID: 5, Name: "alice", PasswordHash: hash}, {ID: 6, Name: "bob", PasswordHash: hash}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := handler.New(service.New(data.New()), handler.WithAuth(auth.New("key", time.Hour, users)))
+	ts := httptest.NewServer(h.InitRouter())
+	t.Cleanup(func() {
+		h.Shutdown()
+		ts.Close()
+	})
+	return ts.URL
+}
+
+// login - возвращает клиент с токеном пользователя name
+func login(t *testing.T, baseURL, name string) *Client {
+	t.Helper()
+	token, err := New(baseURL).Login(context.Background(), name, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(baseURL, WithToken(token.Token))
+}
+
+// statusCode - возвращает HTTP-статус ошибки сервера, 0 - ошибка не от сервера
+func statusCode(err error) int {
+	var apiError *Error
+	if errors.As(err, &apiError) {
+		return apiError.StatusCode
+	}
+	return 0
+}
+
+func TestClientEvents(t *testing.T) {
+	baseURL := start(t)
+	alice := login(t, baseURL, "alice")
+	ctx := context.Background()
+
+	location, _ := time.LoadLocation("Europe/Moscow")
+	date := time.Date(2036, 5, 12, 10, 0, 0, 0, location)
+	end := date.Add(time.Hour)
+	event := models.NewEvent(5, 0, date, "Standup", "Daily")
+	event.TZ = "Europe/Moscow"
+	event.End = &end
+	event.Reminders = []models.Reminder{models.Reminder(15 * time.Minute)}
+	event.Attendees = []models.Attendee{{UserID: 6}}
+	event.Recurrence = &models.Recurrence{Freq: "DAILY", Count: 3}
+
+	saved, err := alice.CreateEvent(ctx, event, ConflictWarn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := saved.Event
+	if created.Version != 1 || !created.Date.Equal(date) || created.TZ != "Europe/Moscow" || created.Recurrence.String() != "FREQ=DAILY;COUNT=3" ||
+		len(created.Reminders) != 1 || len(created.Attendees) != 1 || !created.End.Equal(end) {
+		t.Fatalf("created event: got %+v", created)
+	}
+
+	t.Run("Conflicts", func(t *testing.T) {
+		overlapping := models.NewEvent(5, 0, date.Add(30*time.Minute), "Review", "")
+		if _, err := alice.CreateEvent(ctx, overlapping, ConflictReject); statusCode(err) != http.StatusConflict {
+			t.Errorf("reject: got %v want %v", err, http.StatusConflict)
+		}
+		saved, err := alice.CreateEvent(ctx, overlapping, ConflictWarn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(saved.Conflicts) != 1 || saved.Conflicts[0].ID != created.ID {
+			t.Errorf("conflicts: got %v want event %d", saved.Conflicts, created.ID)
+		}
+		if err := alice.DeleteEvent(ctx, 5, saved.Event.ID, 0); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Lists", func(t *testing.T) {
+		page, err := alice.EventsFor(ctx, 5, date, Week, Query{TZ: "Europe/Moscow", Q: "stand"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Events) != 3 {
+			t.Fatalf("week: got %d events want 3", len(page.Events))
+		}
+		if _, offset := page.Events[0].Date.Zone(); offset != 3*60*60 {
+			t.Errorf("week: got offset %d want Europe/Moscow", offset)
+		}
+
+		page, err = alice.EventsBetween(ctx, 5, date, date.AddDate(0, 0, 3), Query{Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Events) != 2 || page.NextCursor == "" {
+			t.Fatalf("first page: got %d events, cursor %q", len(page.Events), page.NextCursor)
+		}
+		page, err = alice.EventsBetween(ctx, 5, date, date.AddDate(0, 0, 3), Query{Limit: 2, Cursor: page.NextCursor})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Events) != 1 || page.NextCursor != "" {
+			t.Errorf("last page: got %d events, cursor %q", len(page.Events), page.NextCursor)
+		}
+
+		upcoming, err := alice.Upcoming(ctx, 5, 2, date, Query{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(upcoming) != 2 {
+			t.Errorf("upcoming: got %d want 2", len(upcoming))
+		}
+
+		busy, err := alice.FreeBusy(ctx, 5, date, date.AddDate(0, 0, 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(busy) != 1 || !busy[0].End.Equal(end) {
+			t.Errorf("free busy: got %v", busy)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		title := "Sync"
+		patched, err := alice.PatchEvent(ctx, 5, created.ID, created.Version, EventPatch{Title: &title, End: &time.Time{}}, ConflictIgnore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if patched.Event.Title != "Sync" || patched.Event.End != nil || patched.Event.Description != "Daily" || patched.Event.Version != 2 {
+			t.Errorf("patched event: got %+v", patched.Event)
+		}
+
+		// Замена устаревшей версии отклоняется
+		stale := created.Clone()
+		if _, err := alice.ReplaceEvent(ctx, stale, ConflictIgnore); statusCode(err) != http.StatusPreconditionFailed {
+			t.Errorf("stale replace: got %v want %v", err, http.StatusPreconditionFailed)
+		}
+		current, err := alice.GetEvent(ctx, 5, created.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		current.Recurrence = nil
+		replaced, err := alice.ReplaceEvent(ctx, current, ConflictIgnore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if replaced.Event.Recurrence != nil || replaced.Event.Version != 3 {
+			t.Errorf("replaced event: got %+v", replaced.Event)
+		}
+	})
+
+	t.Run("Respond", func(t *testing.T) {
+		bob := login(t, baseURL, "bob")
+		version, err := bob.RespondEvent(ctx, 6, created.ID, models.RSVPAccepted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != 4 {
+			t.Errorf("version: got %d want 4", version)
+		}
+	})
+
+	t.Run("Trash", func(t *testing.T) {
+		if err := alice.DeleteEvent(ctx, 5, created.ID, 4); err != nil {
+			t.Fatal(err)
+		}
+		trash, err := alice.Trash(ctx, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// В корзине также событие, удаленное после проверки конфликтов
+		var deleted *models.Event
+		for _, event := range trash {
+			if event.ID == created.ID {
+				deleted = event
+			}
+		}
+		if len(trash) != 2 || deleted == nil || deleted.DeletedAt == nil {
+			t.Fatalf("trash: got %d events without event %d", len(trash), created.ID)
+		}
+		version, err := alice.RestoreEvent(ctx, 5, created.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != 6 {
+			t.Errorf("restored version: got %d want 6", version)
+		}
+	})
+
+	t.Run("Changes", func(t *testing.T) {
+		history, err := alice.History(ctx, 5, created.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ops := make([]string, len(history))
+		for i, change := range history {
+			ops[i] = change.Op
+		}
+		if got := strings.Join(ops, ","); got != "create,update,update,respond,delete,restore" {
+			t.Errorf("history: got %s", got)
+		}
+
+		changes, next, err := alice.Changes(ctx, 5, 0, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != 3 || next != changes[2].Seq {
+			t.Errorf("changes: got %d, next %d", len(changes), next)
+		}
+	})
+
+	t.Run("ICS", func(t *testing.T) {
+		calendar, err := alice.ExportICS(ctx, 5, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(calendar), "SUMMARY:Sync") {
+			t.Fatalf("export: got %s", calendar)
+		}
+		result, err := alice.ImportICS(ctx, 5, strings.NewReader(string(calendar)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Повторный импорт выгрузки не создает копию события
+		if len(result.Imported) != 1 || len(result.Failed) != 0 || result.Imported[0].Status != "unchanged" {
+			t.Errorf("import: got %+v", result)
+		}
+	})
+}
+
+func TestClientStream(t *testing.T) {
+	baseURL := start(t)
+	alice := login(t, baseURL, "alice")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	saved, err := alice.CreateEvent(ctx, models.NewEvent(5, 0, time.Date(2036, 5, 12, 10, 0, 0, 0, time.UTC), "Standup", ""), ConflictIgnore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.DeleteEvent(ctx, 5, saved.Event.ID, saved.Event.Version); err != nil {
+		t.Fatal(err)
+	}
+
+	// Пропущенные после первого изменения изменения приходят сразу после подключения
+	stop := errors.New("stop")
+	var received []models.Change
+	err = alice.Stream(ctx, 5, 1, func(change models.Change) error {
+		received = append(received, change)
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("stream: got %v want %v", err, stop)
+	}
+	if len(received) != 1 || received[0].Seq != 2 || received[0].Op != models.ChangeDelete {
+		t.Errorf("received: got %+v", received)
+	}
+}
+
+func TestClientCalendars(t *testing.T) {
+	baseURL := start(t)
+	alice := login(t, baseURL, "alice")
+	ctx := context.Background()
+
+	created, err := alice.CreateCalendar(ctx, &models.Calendar{UserID: 5, Name: "Work"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == 0 || created.Color != models.DefaultCalendarColor || created.Visibility != models.CalendarVisible {
+		t.Fatalf("created calendar: got %+v", created)
+	}
+
+	updated, err := alice.UpdateCalendar(ctx, &models.Calendar{UserID: 5, ID: created.ID, Visibility: models.CalendarHidden})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "Work" || updated.Visibility != models.CalendarHidden {
+		t.Errorf("updated calendar: got %+v", updated)
+	}
+
+	got, err := alice.GetCalendar(ctx, 5, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != *updated {
+		t.Errorf("get calendar: got %+v want %+v", got, updated)
+	}
+
+	if err := alice.DeleteCalendar(ctx, 5, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.GetCalendar(ctx, 5, created.ID); statusCode(err) != http.StatusNotFound {
+		t.Errorf("deleted calendar: got %v want %v", err, http.StatusNotFound)
+	}
+	calendars, err := alice.Calendars(ctx, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calendars) != 0 {
+		t.Errorf("calendars: got %v want none", calendars)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	baseURL := start(t)
+	alice := login(t, baseURL, "alice")
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		call       func() error
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name: "Wrong Password",
+			call: func() error {
+				_, err := New(baseURL).Login(ctx, "alice", "wrong")
+				return err
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantError:  "401 Unauthorized: unauthorized: invalid user name or password",
+		},
+		{
+			name: "No Token",
+			call: func() error {
+				_, err := New(baseURL).Calendars(ctx, 5)
+				return err
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantError:  "401 Unauthorized: unauthorized: missing bearer token",
+		},
+		{
+			name: "Other User",
+			call: func() error {
+				_, err := alice.Trash(ctx, 6)
+				return err
+			},
+			wantStatus: http.StatusForbidden,
+			wantError:  "403 Forbidden: forbidden: access to user_id 6 is denied",
+		},
+		{
+			name: "Validation",
+			call: func() error {
+				_, err := alice.CreateEvent(ctx, models.NewEvent(5, 0, time.Date(2036, 5, 12, 10, 0, 0, 0, time.UTC), "", ""), ConflictIgnore)
+				return err
+			},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "400 Bad Request: bad request: empty parameter: title",
+		},
+		{
+			name: "Health",
+			call: func() error {
+				return New(baseURL).Health(ctx)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Errorf("error: got %v want nil", err)
+				}
+				return
+			}
+			var apiError *Error
+			if !errors.As(err, &apiError) {
+				t.Fatalf("error: got %v want *Error", err)
+			}
+			if apiError.StatusCode != tt.wantStatus || apiError.Error() != tt.wantError || apiError.RequestID == "" {
+				t.Errorf("error: got %d %q request %q want %d %q", apiError.StatusCode, apiError.Error(), apiError.RequestID, tt.wantStatus, tt.wantError)
+			}
+		})
+	}
+}
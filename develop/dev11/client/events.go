@@ -0,0 +1,456 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"develop/dev11/internal/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Period - период выборки экземпляров событий вокруг даты
+type Period string
+
+// Периоды выборки событий
+const (
+	Day   Period = "day"   // День, содержащий дату
+	Week  Period = "week"  // Неделя с понедельника, содержащая дату
+	Month Period = "month" // Месяц, содержащий дату
+)
+
+// ConflictMode - обработка событий, пересекающихся с сохраняемым
+type ConflictMode string
+
+// Режимы обработки конфликтов
+const (
+	ConflictIgnore ConflictMode = ""       // Пересечения не проверяются
+	ConflictWarn   ConflictMode = "warn"   // Событие сохраняется, пересекающиеся события возвращаются в Saved.Conflicts
+	ConflictReject ConflictMode = "reject" // При пересечении событие не сохраняется, сервер отвечает 409
+)
+
+// Query - фильтр и страница выборки событий. Пустые поля не передаются
+type Query struct {
+	TZ          string // TZ - часовой пояс границ периода и времени событий в ответе
+	Title       string // Title - заголовок содержит строку без учета регистра
+	Description string // Description - описание содержит строку без учета регистра
+	Q           string // Q - заголовок или описание содержит строку
+	Calendars   []int  // Calendars - календари, 0 - календарь по умолчанию
+	Limit       int    // Limit - размер страницы
+	Cursor      string // Cursor - курсор страницы из Page.NextCursor
+}
+
+// values - параметры строки запроса фильтра
+func (query Query) values() url.Values {
+	values := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	set("tz", query.TZ)
+	set("title", query.Title)
+	set("description", query.Description)
+	set("q", query.Q)
+	set("calendars", joinInts(query.Calendars))
+	set("cursor", query.Cursor)
+	if query.Limit > 0 {
+		values.Set("limit", strconv.Itoa(query.Limit))
+	}
+	return values
+}
+
+// Page - страница событий
+type Page struct {
+	Events     []*models.Event // Events - экземпляры событий по времени начала
+	NextCursor string          // NextCursor - курсор следующей страницы, пустой на последней странице
+}
+
+// Saved - сохраненное событие и события, пересекающиеся с ним
+type Saved struct {
+	Event     *models.Event   // Event - событие после сохранения с новой версией
+	Conflicts []*models.Event // Conflicts - пересекающиеся события при ConflictWarn
+}
+
+// EventPatch - изменяемые поля события, nil - поле не меняется.
+// Пустое значение необязательного поля очищает его
+type EventPatch struct {
+	Date        *time.Time         // Date - начало, окончание сдвигается вместе с ним
+	End         *time.Time         // End - окончание, нулевое время убирает длительность
+	Title       *string            // Title - заголовок
+	Description *string            // Description - описание
+	TZ          *string            // TZ - часовой пояс события
+	CalendarID  *int               // CalendarID - календарь, 0 - календарь по умолчанию
+	Reminders   *[]models.Reminder // Reminders - напоминания
+	Attendees   *[]int             // Attendees - приглашенные пользователи
+	RRule       *string            // RRule - правило повторения, пустое отменяет повторение
+	ExDates     *[]time.Time       // ExDates - исключенные экземпляры повторяющегося события
+}
+
+// values - поля тела запроса PATCH
+func (patch EventPatch) values() url.Values {
+	values := url.Values{}
+	if patch.Date != nil {
+		values.Set("date", formatTime(*patch.Date))
+	}
+	if patch.End != nil {
+		if patch.End.IsZero() {
+			values.Set("end", "")
+		} else {
+			values.Set("end", formatTime(*patch.End))
+		}
+	}
+	if patch.Title != nil {
+		values.Set("title", *patch.Title)
+	}
+	if patch.Description != nil {
+		values.Set("description", *patch.Description)
+	}
+	if patch.TZ != nil {
+		values.Set("tz", *patch.TZ)
+	}
+	if patch.CalendarID != nil {
+		values.Set("calendar_id", strconv.Itoa(*patch.CalendarID))
+	}
+	if patch.Reminders != nil {
+		values.Set("remind", joinReminders(*patch.Reminders))
+	}
+	if patch.Attendees != nil {
+		values.Set("attendees", joinInts(*patch.Attendees))
+	}
+	if patch.RRule != nil {
+		values.Set("rrule", *patch.RRule)
+	}
+	if patch.ExDates != nil {
+		values.Set("exdate", joinTimes(*patch.ExDates))
+	}
+	return values
+}
+
+// ImportResult - результат загрузки файла iCalendar
+type ImportResult struct {
+	Imported []struct {
+		Index  int    `json:"index"`  // Index - порядковый номер VEVENT в файле
+		UID    string `json:"uid"`    // UID - UID из файла
+		ID     int    `json:"id"`     // ID - id созданного или измененного события
+		Status string `json:"status"` // Status - created, updated или unchanged, если событие с UID уже было
+	} `json:"imported"`
+	Failed []struct {
+		Index int    `json:"index"` // Index - порядковый номер VEVENT в файле
+		UID   string `json:"uid"`   // UID - UID из файла
+		Error string `json:"error"` // Error - причина ошибки
+	} `json:"failed"`
+}
+
+// CreateEvent - создает событие пользователя event.UserID. Из event передаются все поля, кроме ID и Version
+func (c *Client) CreateEvent(ctx context.Context, event *models.Event, mode ConflictMode) (Saved, error) {
+	return c.saveEvent(ctx, http.MethodPost, userPath(event.UserID, "events"), eventValues(event), event.Version, mode)
+}
+
+// GetEvent - возвращает событие пользователя
+func (c *Client) GetEvent(ctx context.Context, userID, eventID int) (*models.Event, error) {
+	var event models.Event
+	if _, err := c.call(ctx, request{method: http.MethodGet, path: userPath(userID, "events", eventID)}, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// ReplaceEvent - заменяет событие целиком. event.Version - ожидаемая версия, 0 - любая версия
+func (c *Client) ReplaceEvent(ctx context.Context, event *models.Event, mode ConflictMode) (Saved, error) {
+	return c.saveEvent(ctx, http.MethodPut, userPath(event.UserID, "events", event.ID), eventValues(event), event.Version, mode)
+}
+
+// PatchEvent - изменяет переданные поля события. version - ожидаемая версия, 0 - любая версия
+func (c *Client) PatchEvent(ctx context.Context, userID, eventID, version int, patch EventPatch, mode ConflictMode) (Saved, error) {
+	return c.saveEvent(ctx, http.MethodPatch, userPath(userID, "events", eventID), patch.values(), version, mode)
+}
+
+// DeleteEvent - перемещает событие в корзину. version - ожидаемая версия, 0 - любая версия
+func (c *Client) DeleteEvent(ctx context.Context, userID, eventID, version int) error {
+	_, err := c.call(ctx, request{
+		method: http.MethodDelete,
+		path:   userPath(userID, "events", eventID),
+		header: ifMatch(version),
+	}, nil)
+	return err
+}
+
+// saveEvent - отправляет поля события и возвращает сохраненное событие
+func (c *Client) saveEvent(ctx context.Context, method, path string, form url.Values, version int, mode ConflictMode) (Saved, error) {
+	if mode != ConflictIgnore {
+		form.Set("conflict", string(mode))
+	}
+	req := request{method: method, path: path, form: form}
+	if method != http.MethodPost {
+		req.header = ifMatch(version)
+	}
+
+	var event models.Event
+	result, err := c.call(ctx, req, &event)
+	if err != nil {
+		return Saved{}, err
+	}
+	return Saved{Event: &event, Conflicts: result.conflicts}, nil
+}
+
+// EventsFor - возвращает экземпляры событий за день, неделю или месяц, содержащие date
+func (c *Client) EventsFor(ctx context.Context, userID int, date time.Time, period Period, query Query) (Page, error) {
+	values := query.values()
+	values.Set("date", date.Format(time.DateOnly))
+	values.Set("mode", string(period))
+	return c.events(ctx, request{method: http.MethodGet, path: userPath(userID, "events"), query: values})
+}
+
+// AllEvents - возвращает все события пользователя без разворачивания повторений
+func (c *Client) AllEvents(ctx context.Context, userID int, query Query) (Page, error) {
+	return c.events(ctx, request{method: http.MethodGet, path: userPath(userID, "events"), query: query.values()})
+}
+
+// EventsBetween - возвращает экземпляры событий, начинающиеся в [from, to)
+func (c *Client) EventsBetween(ctx context.Context, userID int, from, to time.Time, query Query) (Page, error) {
+	values := query.values()
+	values.Set("user_id", strconv.Itoa(userID))
+	values.Set("from", formatTime(from))
+	values.Set("to", formatTime(to))
+	return c.events(ctx, request{method: http.MethodGet, path: "/events", query: values})
+}
+
+// events - запрашивает страницу событий
+func (c *Client) events(ctx context.Context, req request) (Page, error) {
+	var page Page
+	result, err := c.call(ctx, req, &page.Events)
+	if err != nil {
+		return Page{}, err
+	}
+	page.NextCursor = result.header.Get(nextCursorHeader)
+	return page, nil
+}
+
+// Upcoming - возвращает n ближайших экземпляров событий после from (нулевое время - после текущего момента).
+// Limit и Cursor запроса не используются
+func (c *Client) Upcoming(ctx context.Context, userID, n int, from time.Time, query Query) ([]*models.Event, error) {
+	values := query.values()
+	values.Del("limit")
+	values.Del("cursor")
+	values.Set("user_id", strconv.Itoa(userID))
+	if n > 0 {
+		values.Set("n", strconv.Itoa(n))
+	}
+	if !from.IsZero() {
+		values.Set("from", formatTime(from))
+	}
+	var events []*models.Event
+	_, err := c.call(ctx, request{method: http.MethodGet, path: "/events/upcoming", query: values}, &events)
+	return events, err
+}
+
+// FreeBusy - возвращает занятые промежутки пользователя в [from, to)
+func (c *Client) FreeBusy(ctx context.Context, userID int, from, to time.Time) ([]models.Interval, error) {
+	values := url.Values{"user_id": {strconv.Itoa(userID)}, "from": {formatTime(from)}, "to": {formatTime(to)}}
+	var busy []models.Interval
+	_, err := c.call(ctx, request{method: http.MethodGet, path: "/free_busy", query: values}, &busy)
+	return busy, err
+}
+
+// Trash - возвращает события в корзине
+func (c *Client) Trash(ctx context.Context, userID int) ([]*models.Event, error) {
+	var events []*models.Event
+	_, err := c.call(ctx, request{method: http.MethodGet, path: "/trash", query: url.Values{"user_id": {strconv.Itoa(userID)}}}, &events)
+	return events, err
+}
+
+// RestoreEvent - восстанавливает событие из корзины и возвращает его новую версию
+func (c *Client) RestoreEvent(ctx context.Context, userID, eventID int) (int, error) {
+	result, err := c.call(ctx, request{
+		method: http.MethodPost,
+		path:   "/restore_event",
+		form:   url.Values{"user_id": {strconv.Itoa(userID)}, "id": {strconv.Itoa(eventID)}},
+	}, nil)
+	if err != nil {
+		return 0, err
+	}
+	return etagVersion(result.header), nil
+}
+
+// RespondEvent - сохраняет ответ приглашенного пользователя userID (models.RSVPAccepted, RSVPDeclined или RSVPTentative)
+// и возвращает новую версию события
+func (c *Client) RespondEvent(ctx context.Context, userID, eventID int, status string) (int, error) {
+	result, err := c.call(ctx, request{
+		method: http.MethodPost,
+		path:   "/respond_event",
+		form:   url.Values{"user_id": {strconv.Itoa(userID)}, "id": {strconv.Itoa(eventID)}, "status": {status}},
+	}, nil)
+	if err != nil {
+		return 0, err
+	}
+	return etagVersion(result.header), nil
+}
+
+// History - возвращает изменения события от первого к последнему
+func (c *Client) History(ctx context.Context, userID, eventID int) ([]models.Change, error) {
+	var changes []models.Change
+	_, err := c.call(ctx, request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/events/%d/history", eventID),
+		query:  url.Values{"user_id": {strconv.Itoa(userID)}},
+	}, &changes)
+	return changes, err
+}
+
+// Changes - возвращает до limit изменений событий пользователя после since и курсор следующего запроса
+func (c *Client) Changes(ctx context.Context, userID int, since int64, limit int) ([]models.Change, int64, error) {
+	values := url.Values{"user_id": {strconv.Itoa(userID)}, "since": {strconv.FormatInt(since, 10)}}
+	if limit > 0 {
+		values.Set("limit", strconv.Itoa(limit))
+	}
+	var changes []models.Change
+	result, err := c.call(ctx, request{method: http.MethodGet, path: "/changes", query: values}, &changes)
+	if err != nil {
+		return nil, 0, err
+	}
+	next, err := strconv.ParseInt(result.header.Get(nextCursorHeader), 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid %s: %w", nextCursorHeader, err)
+	}
+	return changes, next, nil
+}
+
+// Stream - читает поток изменений событий пользователя и вызывает handle для каждого изменения, пока ctx не отменен,
+// сервер не закрыл поток или handle не вернул ошибку. lastSeq - номер последнего полученного изменения,
+// пропущенные после него изменения отправляются первыми (0 - только новые изменения)
+func (c *Client) Stream(ctx context.Context, userID int, lastSeq int64, handle func(models.Change) error) error {
+	req := request{method: http.MethodGet, path: "/events/stream", query: url.Values{"user_id": {strconv.Itoa(userID)}}}
+	if lastSeq > 0 {
+		req.header = http.Header{"Last-Event-Id": {strconv.FormatInt(lastSeq, 10)}}
+	}
+	response, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	// Событие потока - строки "поле: значение" до пустой строки, изменение передается в поле data
+	var data strings.Builder
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if value, ok := strings.CutPrefix(line, "data: "); ok {
+			data.WriteString(value)
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+		var change models.Change
+		if err := json.Unmarshal([]byte(data.String()), &change); err != nil {
+			return fmt.Errorf("decode change: %w", err)
+		}
+		data.Reset()
+		if err := handle(change); err != nil {
+			return err
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return scanner.Err()
+}
+
+// ExportICS - возвращает события пользователя в формате iCalendar, пустой calendars - все календари
+func (c *Client) ExportICS(ctx context.Context, userID int, calendars []int) ([]byte, error) {
+	values := url.Values{"user_id": {strconv.Itoa(userID)}}
+	if len(calendars) > 0 {
+		values.Set("calendars", joinInts(calendars))
+	}
+	response, err := c.send(ctx, request{method: http.MethodGet, path: "/export.ics", query: values})
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	return io.ReadAll(response.Body)
+}
+
+// ImportICS - загружает события пользователя из файла iCalendar
+func (c *Client) ImportICS(ctx context.Context, userID int, calendar io.Reader) (ImportResult, error) {
+	var result ImportResult
+	_, err := c.call(ctx, request{
+		method:      http.MethodPost,
+		path:        "/import_ics",
+		query:       url.Values{"user_id": {strconv.Itoa(userID)}},
+		body:        calendar,
+		contentType: "text/calendar",
+	}, &result)
+	return result, err
+}
+
+// eventValues - поля тела запроса для создания или замены события
+func eventValues(event *models.Event) url.Values {
+	values := url.Values{
+		"date":        {formatTime(event.Date)},
+		"title":       {event.Title},
+		"description": {event.Description},
+	}
+	if event.TZ != "" {
+		values.Set("tz", event.TZ)
+	}
+	if event.CalendarID != 0 {
+		values.Set("calendar_id", strconv.Itoa(event.CalendarID))
+	}
+	if event.End != nil {
+		values.Set("end", formatTime(*event.End))
+	}
+	if len(event.Reminders) > 0 {
+		values.Set("remind", joinReminders(event.Reminders))
+	}
+	if len(event.Attendees) > 0 {
+		ids := make([]int, len(event.Attendees))
+		for i, attendee := range event.Attendees {
+			ids[i] = attendee.UserID
+		}
+		values.Set("attendees", joinInts(ids))
+	}
+	if event.Recurrence != nil {
+		values.Set("rrule", event.Recurrence.String())
+		if len(event.Recurrence.ExDates) > 0 {
+			values.Set("exdate", joinTimes(event.Recurrence.ExDates))
+		}
+	}
+	return values
+}
+
+// ifMatch - заголовок If-Match с ожидаемой версией события, 0 - любая версия
+func ifMatch(version int) http.Header {
+	if version == 0 {
+		return http.Header{"If-Match": {"*"}}
+	}
+	return http.Header{"If-Match": {`"` + strconv.Itoa(version) + `"`}}
+}
+
+// formatTime - записывает время в RFC 3339, смещение сохраняет часовой пояс момента
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
+
+// joinTimes - объединяет моменты через запятую
+func joinTimes(times []time.Time) string {
+	parts := make([]string, len(times))
+	for i, t := range times {
+		parts[i] = formatTime(t)
+	}
+	return strings.Join(parts, ",")
+}
+
+// joinReminders - объединяет напоминания через запятую
+func joinReminders(reminders []models.Reminder) string {
+	parts := make([]string, len(reminders))
+	for i, reminder := range reminders {
+		parts[i] = reminder.String()
+	}
+	return strings.Join(parts, ",")
+}
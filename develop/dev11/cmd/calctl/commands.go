@@ -0,0 +1,447 @@
+package main
+
+import (
+	"context"
+	"develop/dev11/client"
+	"develop/dev11/internal/models"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayouts - форматы дат в аргументах, даты без смещения разбираются в часовом поясе из настроек
+var dateLayouts = []string{time.RFC3339, time.DateTime, "2006-01-02 15:04", time.DateOnly}
+
+// parseTime - разбирает дату в одном из форматов dateLayouts
+func parseTime(value string, location *time.Location) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(value), location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q: use 2006-01-02, 2006-01-02 15:04 or RFC 3339", value)
+}
+
+// eventFlags - флаги полей события для create и update
+type eventFlags struct {
+	date        string
+	end         string
+	duration    time.Duration
+	title       string
+	description string
+	tz          string
+	calendar    int
+	remind      string
+	attendees   string
+	rrule       string
+	exdate      string
+	conflict    string
+}
+
+// addEventFlags - регистрирует флаги полей события
+func addEventFlags(flags *flag.FlagSet, conflict string) *eventFlags {
+	fields := &eventFlags{}
+	flags.StringVar(&fields.date, "date", "", "start: 2006-01-02 15:04 in -tz or config tz, or RFC 3339")
+	flags.StringVar(&fields.end, "end", "", "end in the -date format, use either -end or -duration")
+	flags.DurationVar(&fields.duration, "duration", 0, "duration like 1h30m")
+	flags.StringVar(&fields.title, "title", "", "title, up to 20 characters")
+	flags.StringVar(&fields.description, "description", "", "description, up to 50 characters")
+	flags.StringVar(&fields.tz, "tz", "", "IANA time zone of the event, recurrences expand in it (default config tz)")
+	flags.IntVar(&fields.calendar, "calendar", 0, "calendar id, 0 - default calendar")
+	flags.StringVar(&fields.remind, "remind", "", "reminders before the start like 15m,1h")
+	flags.StringVar(&fields.attendees, "attendees", "", "invited user ids like 6,7")
+	flags.StringVar(&fields.rrule, "rrule", "", "recurrence rule like FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10")
+	flags.StringVar(&fields.exdate, "exdate", "", "excluded occurrences in the -date format separated by commas")
+	flags.StringVar(&fields.conflict, "conflict", conflict, "overlapping events: warn, reject or empty to skip the check")
+	return fields
+}
+
+// location - возвращает часовой пояс дат события: -tz или пояс из настроек
+func (fields *eventFlags) location(s *session) (*time.Location, error) {
+	if fields.tz == "" {
+		return s.location, nil
+	}
+	return models.LoadLocation(fields.tz)
+}
+
+// conflictMode - проверяет значение -conflict
+func (fields *eventFlags) conflictMode() (client.ConflictMode, error) {
+	switch mode := client.ConflictMode(fields.conflict); mode {
+	case client.ConflictIgnore, client.ConflictWarn, client.ConflictReject:
+		return mode, nil
+	}
+	return "", fmt.Errorf("-conflict: must be warn, reject or empty")
+}
+
+// reminders - разбирает -remind
+func (fields *eventFlags) reminders() ([]models.Reminder, error) {
+	reminders := make([]models.Reminder, 0)
+	for _, part := range splitList(fields.remind) {
+		reminder, err := models.ParseReminder(part)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, reminder)
+	}
+	return reminders, nil
+}
+
+// attendeeIDs - разбирает -attendees
+func (fields *eventFlags) attendeeIDs() ([]int, error) {
+	ids := make([]int, 0)
+	for _, part := range splitList(fields.attendees) {
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("-attendees: invalid user id %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// exDates - разбирает -exdate
+func (fields *eventFlags) exDates(location *time.Location) ([]time.Time, error) {
+	exDates := make([]time.Time, 0)
+	for _, part := range splitList(fields.exdate) {
+		exDate, err := parseTime(part, location)
+		if err != nil {
+			return nil, fmt.Errorf("-exdate: %w", err)
+		}
+		exDates = append(exDates, exDate)
+	}
+	return exDates, nil
+}
+
+// splitList - разбивает значение через запятую, пустые элементы пропускаются
+func splitList(value string) []string {
+	parts := make([]string, 0)
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// runCreate создает событие и выводит его
+func runCreate(ctx context.Context, args []string, stdout io.Writer) error {
+	flags, options := newFlagSet("create")
+	fields := addEventFlags(flags, string(client.ConflictWarn))
+	if err := parseArgs(flags, args); err != nil {
+		return err
+	}
+	if fields.date == "" || fields.title == "" {
+		return errors.New("-date and -title are required")
+	}
+	if fields.end != "" && fields.duration != 0 {
+		return errors.New("use either -end or -duration")
+	}
+	s, err := options.connect(ctx)
+	if err != nil {
+		return err
+	}
+	mode, err := fields.conflictMode()
+	if err != nil {
+		return err
+	}
+
+	location, err := fields.location(s)
+	if err != nil {
+		return err
+	}
+	date, err := parseTime(fields.date, location)
+	if err != nil {
+		return fmt.Errorf("-date: %w", err)
+	}
+	event := models.NewEvent(s.userID, 0, date, fields.title, fields.description)
+	event.TZ = fields.tz
+	if event.TZ == "" {
+		event.TZ = s.tz
+	}
+	event.CalendarID = fields.calendar
+
+	switch {
+	case fields.end != "":
+		end, err := parseTime(fields.end, location)
+		if err != nil {
+			return fmt.Errorf("-end: %w", err)
+		}
+		event.End = &end
+	case fields.duration != 0:
+		end := date.Add(fields.duration)
+		event.End = &end
+	}
+	if event.Reminders, err = fields.reminders(); err != nil {
+		return err
+	}
+	ids, err := fields.attendeeIDs()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		event.Attendees = append(event.Attendees, models.Attendee{UserID: id})
+	}
+	if fields.rrule != "" {
+		exDates, err := fields.exDates(location)
+		if err != nil {
+			return err
+		}
+		if event.Recurrence, err = models.ParseRecurrence(fields.rrule, exDates, location); err != nil {
+			return err
+		}
+	}
+
+	saved, err := s.client.CreateEvent(ctx, event, mode)
+	if err != nil {
+		return err
+	}
+	warnConflicts(saved.Conflicts, s.location)
+	return renderEvents(stdout, s.output, []*models.Event{saved.Event}, s.location)
+}
+
+// runGet выводит событие
+func runGet(ctx context.Context, args []string, stdout io.Writer) error {
+	flags, options := newFlagSet("get")
+	id := flags.Int("id", -1, "event id")
+	if err := parseArgs(flags, args); err != nil {
+		return err
+	}
+	if *id < 0 {
+		return errors.New("-id is required")
+	}
+	s, err := options.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	event, err := s.client.GetEvent(ctx, s.userID, *id)
+	if err != nil {
+		return err
+	}
+	return renderEvents(stdout, s.output, []*models.Event{event}, s.location)
+}
+
+// runUpdate изменяет переданные флагами поля события и выводит его
+func runUpdate(ctx context.Context, args []string, stdout io.Writer) error {
+	flags, options := newFlagSet("update")
+	id := flags.Int("id", -1, "event id")
+	version := flags.Int("version", 0, "expected event version, 0 - overwrite any version")
+	fields := addEventFlags(flags, "")
+	if err := parseArgs(flags, args); err != nil {
+		return err
+	}
+	if *id < 0 {
+		return errors.New("-id is required")
+	}
+	if fields.end != "" && fields.duration != 0 {
+		return errors.New("use either -end or -duration")
+	}
+	s, err := options.connect(ctx)
+	if err != nil {
+		return err
+	}
+	mode, err := fields.conflictMode()
+	if err != nil {
+		return err
+	}
+
+	location, err := fields.location(s)
+	if err != nil {
+		return err
+	}
+	patch, err := fields.patch(flags, location)
+	if err != nil {
+		return err
+	}
+
+	// Окончание по длительности отсчитывается от нового начала или от текущего начала события
+	if fields.duration != 0 {
+		start := patch.Date
+		if start == nil {
+			current, err := s.client.GetEvent(ctx, s.userID, *id)
+			if err != nil {
+				return err
+			}
+			start = &current.Date
+		}
+		end := start.Add(fields.duration)
+		patch.End = &end
+	}
+
+	saved, err := s.client.PatchEvent(ctx, s.userID, *id, *version, patch, mode)
+	if err != nil {
+		return err
+	}
+	warnConflicts(saved.Conflicts, s.location)
+	return renderEvents(stdout, s.output, []*models.Event{saved.Event}, s.location)
+}
+
+// patch - собирает изменение из явно заданных флагов, пустое значение очищает необязательное поле
+func (fields *eventFlags) patch(flags *flag.FlagSet, location *time.Location) (client.EventPatch, error) {
+	var patch client.EventPatch
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+		switch f.Name {
+		case "date":
+			var date time.Time
+			if date, err = parseTime(fields.date, location); err == nil {
+				patch.Date = &date
+			}
+		case "end":
+			end := time.Time{}
+			if fields.end != "" {
+				end, err = parseTime(fields.end, location)
+			}
+			patch.End = &end
+		case "title":
+			patch.Title = &fields.title
+		case "description":
+			patch.Description = &fields.description
+		case "tz":
+			patch.TZ = &fields.tz
+		case "calendar":
+			patch.CalendarID = &fields.calendar
+		case "remind":
+			var reminders []models.Reminder
+			if reminders, err = fields.reminders(); err == nil {
+				patch.Reminders = &reminders
+			}
+		case "attendees":
+			var ids []int
+			if ids, err = fields.attendeeIDs(); err == nil {
+				patch.Attendees = &ids
+			}
+		case "rrule":
+			patch.RRule = &fields.rrule
+		case "exdate":
+			var exDates []time.Time
+			if exDates, err = fields.exDates(location); err == nil {
+				patch.ExDates = &exDates
+			}
+		}
+	})
+	if err != nil {
+		return client.EventPatch{}, fmt.Errorf("update: %w", err)
+	}
+	return patch, nil
+}
+
+// runDelete перемещает событие в корзину
+func runDelete(ctx context.Context, args []string, stdout io.Writer) error {
+	flags, options := newFlagSet("delete")
+	id := flags.Int("id", -1, "event id")
+	version := flags.Int("version", 0, "expected event version, 0 - delete any version")
+	if err := parseArgs(flags, args); err != nil {
+		return err
+	}
+	if *id < 0 {
+		return errors.New("-id is required")
+	}
+	s, err := options.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.client.DeleteEvent(ctx, s.userID, *id, *version); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "event %d moved to trash\n", *id)
+	return nil
+}
+
+// runList выводит экземпляры событий за день, неделю или месяц, содержащие -date, или за период [-from, -to)
+func runList(ctx context.Context, args []string, stdout io.Writer) error {
+	flags, options := newFlagSet("list")
+	period := flags.String("period", string(client.Day), "day, week (from Monday) or month containing -date")
+	date := flags.String("date", "", "day in the period (default today)")
+	from := flags.String("from", "", "range start instead of -period, 2006-01-02 or 2006-01-02 15:04")
+	to := flags.String("to", "", "range end (exclusive), at most 366 days after -from")
+	var query client.Query
+	flags.StringVar(&query.Q, "q", "", "title or description contains, case insensitive")
+	flags.StringVar(&query.Title, "title", "", "title contains, case insensitive")
+	flags.StringVar(&query.Description, "description", "", "description contains, case insensitive")
+	calendars := flags.String("calendars", "", "calendar ids like 0,2, hidden calendars are shown only when listed")
+	if err := parseArgs(flags, args); err != nil {
+		return err
+	}
+	s, err := options.connect(ctx)
+	if err != nil {
+		return err
+	}
+	for _, part := range splitList(*calendars) {
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return fmt.Errorf("-calendars: invalid calendar id %q", part)
+		}
+		query.Calendars = append(query.Calendars, id)
+	}
+
+	var start, end time.Time
+	if *from != "" || *to != "" {
+		if *from == "" || *to == "" {
+			return errors.New("-from and -to are used together")
+		}
+		if start, err = parseTime(*from, s.location); err != nil {
+			return fmt.Errorf("-from: %w", err)
+		}
+		if end, err = parseTime(*to, s.location); err != nil {
+			return fmt.Errorf("-to: %w", err)
+		}
+	} else {
+		day := time.Now().In(s.location)
+		if *date != "" {
+			if day, err = parseTime(*date, s.location); err != nil {
+				return fmt.Errorf("-date: %w", err)
+			}
+		}
+		if start, end, err = periodBounds(day, client.Period(*period)); err != nil {
+			return err
+		}
+	}
+
+	// Читаем все страницы периода
+	events := make([]*models.Event, 0)
+	for {
+		page, err := s.client.EventsBetween(ctx, s.userID, start, end, query)
+		if err != nil {
+			return err
+		}
+		events = append(events, page.Events...)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	return renderEvents(stdout, s.output, events, s.location)
+}
+
+// periodBounds - возвращает границы дня, недели с понедельника или месяца, содержащих day, в поясе day
+func periodBounds(day time.Time, period client.Period) (time.Time, time.Time, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	switch period {
+	case client.Day:
+		return start, start.AddDate(0, 0, 1), nil
+	case client.Week:
+		start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+		return start, start.AddDate(0, 0, 7), nil
+	case client.Month:
+		start = start.AddDate(0, 0, 1-start.Day())
+		return start, start.AddDate(0, 1, 0), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("-period: must be day, week or month")
+}
+
+// warnConflicts - сообщает о событиях, пересекающихся с сохраненным
+func warnConflicts(conflicts []*models.Event, location *time.Location) {
+	for _, conflict := range conflicts {
+		fmt.Fprintf(os.Stderr, "warning: overlaps event %d %q at %s\n", conflict.ID, conflict.Title, conflict.Date.In(location).Format("2006-01-02 15:04"))
+	}
+}
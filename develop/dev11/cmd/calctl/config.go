@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFileEnv - переменная окружения с путем к файлу настроек
+const configFileEnv = "CALCTL_CONFIG"
+
+// defaultServer - адрес сервера, если он не задан в файле настроек
+const defaultServer = "http://localhost:8080"
+
+// defaultTimeout - время ожидания ответа сервера по умолчанию
+const defaultTimeout = 30 * time.Second
+
+// Config - настройки calctl из файла YAML
+type Config struct {
+	Server   string        `yaml:"server"`   // Server - адрес сервера, например https://localhost:8443
+	UserID   int           `yaml:"user_id"`  // UserID - пользователь, чьи события показываются, 0 - пользователь токена
+	Token    string        `yaml:"token"`    // Token - токен доступа, выданный /login
+	Name     string        `yaml:"name"`     // Name - имя пользователя для получения токена, если Token не задан
+	Password string        `yaml:"password"` // Password - пароль пользователя Name
+	TZ       string        `yaml:"tz"`       // TZ - часовой пояс дат в командах и в выводе, пустой - локальный пояс
+	CAFile   string        `yaml:"ca_file"`  // CAFile - сертификат центра, которым подписан сертификат сервера
+	Timeout  time.Duration `yaml:"timeout"`  // Timeout - время ожидания ответа сервера
+}
+
+// defaultConfigPath - возвращает путь к файлу настроек по умолчанию: ~/.config/calctl/config.yaml
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "calctl", "config.yaml")
+}
+
+// loadConfig - читает настройки из файла path (пустой путь - CALCTL_CONFIG или файл по умолчанию).
+// Отсутствующий файл по умолчанию не является ошибкой, явно заданный - является
+func loadConfig(path string) (Config, error) {
+	config := Config{Server: defaultServer, Timeout: defaultTimeout}
+
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv(configFileEnv)
+		explicit = path != ""
+	}
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	if path == "" {
+		return config, nil
+	}
+
+	payload, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return config, nil
+	}
+	if err != nil {
+		return Config{}, err
+	}
+	if err := yaml.Unmarshal(payload, &config); err != nil {
+		return Config{}, fmt.Errorf("config file %s: %w", path, err)
+	}
+	// Относительный путь к сертификату отсчитывается от каталога файла настроек
+	if config.CAFile != "" && !filepath.IsAbs(config.CAFile) {
+		config.CAFile = filepath.Join(filepath.Dir(path), config.CAFile)
+	}
+	return config, nil
+}
+
+// location - возвращает часовой пояс дат в командах и в выводе
+func (config Config) location() (*time.Location, error) {
+	if config.TZ == "" {
+		return time.Local, nil
+	}
+	location, err := time.LoadLocation(config.TZ)
+	if err != nil {
+		return nil, fmt.Errorf("tz: unknown time zone %s", config.TZ)
+	}
+	return location, nil
+}
+
+// httpClient - возвращает HTTP-клиент с таймаутом и, если задан CAFile, с доверием к сертификату сервера
+func (config Config) httpClient() (*http.Client, error) {
+	httpClient := &http.Client{Timeout: config.Timeout}
+	if config.CAFile == "" {
+		return httpClient, nil
+	}
+
+	pem, err := os.ReadFile(config.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("ca_file %s: no certificates found", config.CAFile)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	httpClient.Transport = transport
+	return httpClient, nil
+}
@@ -0,0 +1,196 @@
+// Command calctl - клиент командной строки сервера календаря.
+//
+// Адрес сервера и учетные данные читаются из файла YAML (-config, CALCTL_CONFIG или ~/.config/calctl/config.yaml):
+//
+//	server: http://localhost:8080
+//	name: alice
+//	password: secret
+//	tz: Europe/Moscow
+//
+// Примеры:
+//
+//	calctl create -date "2036-05-12 10:00" -duration 30m -title Standup -rrule FREQ=WEEKLY;BYDAY=MO
+//	calctl list -period week -date 2036-05-12
+//	calctl list -from 2036-05-01 -to 2036-06-01 -o json
+//	calctl update -id 3 -title Sync
+//	calctl delete -id 3
+package main
+
+import (
+	"context"
+	"develop/dev11/client"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	// База часовых поясов встраивается в бинарник, чтобы параметр tz работал и без tzdata в системе
+	_ "time/tzdata"
+)
+
+// Форматы вывода
+const (
+	outputTable = "table" // Расписание по дням в виде таблицы
+	outputJSON  = "json"  // События в JSON в формате models.Event
+)
+
+// command - подкоманда calctl
+type command struct {
+	summary string                                                           // summary - описание для справки
+	run     func(ctx context.Context, args []string, stdout io.Writer) error // run - разбирает флаги подкоманды и выполняет ее
+}
+
+// commands - подкоманды calctl по именам
+var commands = map[string]command{
+	"create": {"create an event", runCreate},
+	"get":    {"show an event", runGet},
+	"update": {"change passed fields of an event", runUpdate},
+	"delete": {"move an event to trash", runDelete},
+	"list":   {"show events of a day, week, month or range", runList},
+}
+
+// errUsage - ошибка в аргументах, справка уже выведена, calctl завершается с кодом 2
+var errUsage = errors.New("usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := run(ctx, os.Args[1:], os.Stdout)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "calctl:", err)
+		os.Exit(1)
+	}
+}
+
+// run - выполняет подкоманду args[0] с флагами args[1:]
+func run(ctx context.Context, args []string, stdout io.Writer) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(os.Stderr)
+		return errUsage
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "calctl: unknown command %q\n\n", args[0])
+		usage(os.Stderr)
+		return errUsage
+	}
+	return cmd.run(ctx, args[1:], stdout)
+}
+
+// usage - выводит список подкоманд
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: calctl <command> [flags]\n\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w, "\nRun calctl <command> -h for command flags.")
+}
+
+// common - флаги, общие для всех подкоманд
+type common struct {
+	configPath string
+	server     string
+	userID     int
+	output     string
+}
+
+// newFlagSet - создает набор флагов подкоманды name с общими флагами
+func newFlagSet(name string) (*flag.FlagSet, *common) {
+	flags := flag.NewFlagSet("calctl "+name, flag.ContinueOnError)
+	options := &common{}
+	flags.StringVar(&options.configPath, "config", "", "path to YAML config file (default $"+configFileEnv+" or "+defaultConfigPath()+")")
+	flags.StringVar(&options.server, "server", "", "server URL, overrides server from config")
+	flags.IntVar(&options.userID, "user", 0, "user id, overrides user_id from config")
+	flags.StringVar(&options.output, "o", outputTable, "output format: table or json")
+	return flags, options
+}
+
+// session - подключение к серверу для выполнения подкоманды
+type session struct {
+	client   *client.Client
+	userID   int            // userID - пользователь, с событиями которого работает подкоманда
+	location *time.Location // location - часовой пояс дат в аргументах и в выводе
+	tz       string         // tz - имя часового пояса из настроек, с ним создаются события
+	output   string         // output - формат вывода
+}
+
+// connect - читает настройки, применяет общие флаги и при необходимости получает токен по имени и паролю
+func (options *common) connect(ctx context.Context) (*session, error) {
+	if options.output != outputTable && options.output != outputJSON {
+		return nil, fmt.Errorf("-o: must be %s or %s", outputTable, outputJSON)
+	}
+
+	config, err := loadConfig(options.configPath)
+	if err != nil {
+		return nil, err
+	}
+	if options.server != "" {
+		config.Server = options.server
+	}
+	if options.userID != 0 {
+		config.UserID = options.userID
+	}
+	location, err := config.location()
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := config.httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	token, userID := config.Token, config.UserID
+	if token == "" && config.Name != "" {
+		issued, err := client.New(config.Server, client.WithHTTPClient(httpClient)).Login(ctx, config.Name, config.Password)
+		if err != nil {
+			return nil, fmt.Errorf("login as %s: %w", config.Name, err)
+		}
+		token = issued.Token
+		if userID == 0 {
+			userID = issued.UserID
+		}
+	}
+	if userID == 0 {
+		return nil, errors.New("user is unknown: set user_id or name and password in config or pass -user")
+	}
+
+	return &session{
+		client:   client.New(config.Server, client.WithHTTPClient(httpClient), client.WithToken(token)),
+		userID:   userID,
+		location: location,
+		tz:       config.TZ,
+		output:   options.output,
+	}, nil
+}
+
+// parseArgs - разбирает флаги подкоманды, лишние аргументы считаются ошибкой
+func parseArgs(flags *flag.FlagSet, args []string) error {
+	// Ошибку разбора и справку FlagSet уже вывел
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(flags.Output(), "unexpected arguments: %s\n", strings.Join(flags.Args(), " "))
+		flags.Usage()
+		return errUsage
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"develop/dev11/client"
+	"develop/dev11/internal/auth"
+	"develop/dev11/internal/data"
+	"develop/dev11/internal/handler"
+	"develop/dev11/internal/models"
+	"develop/dev11/internal/service"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig - записывает файл настроек во временный каталог
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Config
+		wantErr bool
+	}{
+		{
+			name:    "Defaults",
+			content: "",
+			want:    Config{Server: defaultServer, Timeout: defaultTimeout},
+		},
+		{
+			name:    "File",
+			content: "server: https://calendar.example.com\nuser_id: 5\ntoken: abc\ntz: Europe/Moscow\ntimeout: 5s\n",
+			want:    Config{Server: "https://calendar.example.com", UserID: 5, Token: "abc", TZ: "Europe/Moscow", Timeout: 5 * time.Second},
+		},
+		{
+			name:    "Invalid",
+			content: "user_id: five\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadConfig(writeConfig(t, tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error: got %v want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("config: got %+v want %+v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Missing Explicit File", func(t *testing.T) {
+		if _, err := loadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+			t.Error("error: got nil want error")
+		}
+	})
+}
+
+func TestPeriodBounds(t *testing.T) {
+	location, _ := time.LoadLocation("Europe/Moscow")
+	day := time.Date(2036, 5, 14, 15, 4, 0, 0, location)
+
+	tests := []struct {
+		period    client.Period
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{period: client.Day, wantStart: time.Date(2036, 5, 14, 0, 0, 0, 0, location), wantEnd: time.Date(2036, 5, 15, 0, 0, 0, 0, location)},
+		{period: client.Week, wantStart: time.Date(2036, 5, 12, 0, 0, 0, 0, location), wantEnd: time.Date(2036, 5, 19, 0, 0, 0, 0, location)},
+		{period: client.Month, wantStart: time.Date(2036, 5, 1, 0, 0, 0, 0, location), wantEnd: time.Date(2036, 6, 1, 0, 0, 0, 0, location)},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.period), func(t *testing.T) {
+			start, end, err := periodBounds(day, tt.period)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("bounds: got [%v, %v) want [%v, %v)", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestRenderAgenda(t *testing.T) {
+	start := time.Date(2036, 5, 12, 10, 0, 0, 0, time.UTC)
+	end := start.Add(30 * time.Minute)
+	overnight := start.Add(24 * time.Hour)
+	standup := models.NewEvent(5, 0, start, "Standup", "Daily")
+	standup.End = &end
+	review := models.NewEvent(5, 1, start.Add(2*time.Hour), "Review", "")
+	review.CalendarID = 2
+	release := models.NewEvent(5, 2, start.Add(12*time.Hour), "Release", "")
+	release.End = &overnight
+
+	var out bytes.Buffer
+	if err := renderEvents(&out, outputTable, []*models.Event{standup, review, release}, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"DATE            TIME                    ID  TITLE    DESCRIPTION  CALENDAR\n" +
+		"Mon 2036-05-12  10:00-10:30             0   Standup  Daily        default\n" +
+		"                12:00                   1   Review                2\n" +
+		"                22:00-2036-05-13 10:00  2   Release               default\n"
+	if out.String() != want {
+		t.Errorf("agenda: got\n%s\nwant\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	if err := renderEvents(&out, outputTable, nil, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "No events\n" {
+		t.Errorf("empty agenda: got %q", out.String())
+	}
+}
+
+func TestRun(t *testing.T) {
+	hash, err := auth.HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := auth.NewUsers([]*auth.User{{ID: 5, Name: "alice", PasswordHash: hash}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := handler.New(service.New(data.New()), handler.WithAuth(auth.New("key", time.Hour, users)))
+	ts := httptest.NewServer(h.InitRouter())
+	defer ts.Close()
+	defer h.Shutdown()
+	configPath := writeConfig(t, "server: "+ts.URL+"\nname: alice\npassword: secret\ntz: Europe/Moscow\n")
+
+	// calctl - выполняет команду с файлом настроек и возвращает ее вывод
+	calctl := func(t *testing.T, args ...string) (string, error) {
+		t.Helper()
+		var out bytes.Buffer
+		err := run(context.Background(), append(args, "-config", configPath), &out)
+		return out.String(), err
+	}
+	// events - выполняет команду с выводом в JSON и разбирает события
+	events := func(t *testing.T, args ...string) []*models.Event {
+		t.Helper()
+		out, err := calctl(t, append(args, "-o", "json")...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var events []*models.Event
+		if err := json.Unmarshal([]byte(out), &events); err != nil {
+			t.Fatalf("decode %q: %v", out, err)
+		}
+		return events
+	}
+
+	created := events(t, "create", "-date", "2036-05-12 10:00", "-duration", "30m", "-title", "Standup", "-rrule", "FREQ=DAILY;COUNT=3", "-remind", "15m")
+	if len(created) != 1 || created[0].UserID != 5 || created[0].TZ != "Europe/Moscow" || created[0].Recurrence == nil || len(created[0].Reminders) != 1 {
+		t.Fatalf("create: got %+v", created)
+	}
+	if _, offset := created[0].Date.Zone(); offset != 3*60*60 || created[0].Date.Hour() != 10 {
+		t.Errorf("create date: got %v want 10:00 Europe/Moscow", created[0].Date)
+	}
+	id := strconv.Itoa(created[0].ID)
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantCount int
+	}{
+		{name: "Day", args: []string{"list", "-date", "2036-05-13"}, wantCount: 1},
+		{name: "Week", args: []string{"list", "-period", "week", "-date", "2036-05-12"}, wantCount: 3},
+		{name: "Month Filter", args: []string{"list", "-period", "month", "-date", "2036-05-01", "-q", "review"}, wantCount: 0},
+		{name: "Range", args: []string{"list", "-from", "2036-05-13", "-to", "2036-05-20"}, wantCount: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := events(t, tt.args...); len(got) != tt.wantCount {
+				t.Errorf("events: got %d want %d", len(got), tt.wantCount)
+			}
+		})
+	}
+
+	t.Run("Update", func(t *testing.T) {
+		updated := events(t, "update", "-id", id, "-title", "Sync", "-duration", "1h")
+		if updated[0].Title != "Sync" || updated[0].End.Sub(updated[0].Date) != time.Hour || updated[0].Version != 2 {
+			t.Errorf("update: got %+v", updated[0])
+		}
+		if _, err := calctl(t, "update", "-id", id, "-version", "1", "-title", "Stale"); err == nil || !strings.Contains(err.Error(), "412") {
+			t.Errorf("stale update: got %v want 412", err)
+		}
+		if _, err := calctl(t, "update", "-id", id, "-conflict", "maybe"); err == nil {
+			t.Error("invalid conflict: got nil want error")
+		}
+	})
+
+	t.Run("Agenda", func(t *testing.T) {
+		out, err := calctl(t, "list", "-date", "2036-05-12")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, "Mon 2036-05-12  10:00-11:00") || !strings.Contains(out, "Sync") {
+			t.Errorf("agenda: got\n%s", out)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		out, err := calctl(t, "delete", "-id", id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != "event "+id+" moved to trash\n" {
+			t.Errorf("delete: got %q", out)
+		}
+		if _, err := calctl(t, "get", "-id", id); err == nil || !strings.Contains(err.Error(), "404") {
+			t.Errorf("get deleted: got %v want 404", err)
+		}
+		if got := events(t, "list", "-period", "week", "-date", "2036-05-12"); len(got) != 0 {
+			t.Errorf("events after delete: got %d want 0", len(got))
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"develop/dev11/internal/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// renderEvents - выводит события в формате output, время событий представляется в поясе location
+func renderEvents(w io.Writer, output string, events []*models.Event, location *time.Location) error {
+	converted := make([]*models.Event, len(events))
+	for i, event := range events {
+		converted[i] = event.In(location)
+	}
+	if output == outputJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(converted)
+	}
+	return renderAgenda(w, converted)
+}
+
+// renderAgenda - выводит расписание таблицей: дата указывается в первой строке каждого дня
+func renderAgenda(w io.Writer, events []*models.Event) error {
+	if len(events) == 0 {
+		_, err := fmt.Fprintln(w, "No events")
+		return err
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "DATE\tTIME\tID\tTITLE\tDESCRIPTION\tCALENDAR")
+	previous := ""
+	for _, event := range events {
+		day := event.Date.Format("Mon 2006-01-02")
+		if day == previous {
+			day = ""
+		} else {
+			previous = day
+		}
+		fmt.Fprintf(table, "%s\t%s\t%d\t%s\t%s\t%s\n", day, eventTime(event), event.ID, event.Title, event.Description, calendarName(event.CalendarID))
+	}
+	return table.Flush()
+}
+
+// eventTime - возвращает время события: начало, начало-конец или конец с датой, если событие заканчивается в другой день
+func eventTime(event *models.Event) string {
+	start := event.Date.Format("15:04")
+	if event.End == nil {
+		return start
+	}
+	if event.End.Format(time.DateOnly) != event.Date.Format(time.DateOnly) {
+		return start + "-" + event.End.Format("2006-01-02 15:04")
+	}
+	return start + "-" + event.End.Format("15:04")
+}
+
+// calendarName - возвращает календарь события для таблицы
+func calendarName(id int) string {
+	if id == 0 {
+		return "default"
+	}
+	return strconv.Itoa(id)
+}
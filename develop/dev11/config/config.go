@@ -1,44 +1,172 @@
 package config
 
 import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"log/slog"
 	"os"
+	"strings"
 	"time"
+)
+
+// Типы хранилища событий
+const (
+	StorageMemory = "memory" // События хранятся только в памяти
+	StorageFile   = "file"   // События хранятся в файле (журнал + снимок)
+)
 
-	"github.com/joho/godotenv"
+// Получатели напоминаний
+const (
+	NotifierLog     = "log"     // Напоминания пишутся в лог сервера
+	NotifierWebhook = "webhook" // Напоминания отправляются POST-запросом
+	NotifierFile    = "file"    // Напоминания дописываются в файл
+	NotifierNone    = "none"    // Напоминания отключены
+)
+
+// Проверка сертификатов клиентов (mTLS)
+const (
+	ClientAuthRequire  = "require"  // Соединение без сертификата клиента отклоняется
+	ClientAuthOptional = "optional" // Сертификат клиента проверяется, только если передан
 )
 
 // Config содержит настройки сервера
 type Config struct {
-	// Порт, на котором запускается сервер
-	Port string
-	// Время ожидания запроса
-	Timeout time.Duration
+	// Адрес, на котором запускается сервер, например :8080
+	Addr string
+	// Время чтения запроса вместе с телом
+	ReadTimeout time.Duration
+	// Время записи ответа (потоки изменений не ограничиваются)
+	WriteTimeout time.Duration
 	// Время простоя до закрытия соединения
 	IdleTimeout time.Duration
+	// Сколько при остановке ждать завершения запросов, после чего соединения закрываются принудительно
+	ShutdownTimeout time.Duration
+	// Файл сертификата TLS, пустой путь - сервер работает по HTTP
+	TLSCertFile string
+	// Файл закрытого ключа TLS
+	TLSKeyFile string
+	// Файл сертификатов центров, которыми подписаны сертификаты клиентов, пустой путь отключает mTLS
+	TLSClientCAFile string
+	// Проверка сертификата клиента при заданном TLSClientCAFile
+	TLSClientAuth tls.ClientAuthType
+	// Период проверки изменения файлов сертификатов, 0 - только по SIGHUP
+	TLSReloadInterval time.Duration
+	// Уровень журнала: debug, info, warn или error
+	LogLevel slog.Level
+	// Тип хранилища событий: memory или file
+	Storage string
+	// Каталог файлового хранилища
+	StoragePath string
+	// Период сохранения снимка файлового хранилища
+	SnapshotInterval time.Duration
+	// Файл журнала изменений событий (используется с файловым хранилищем)
+	AuditPath string
+	// Ключ подписи токенов, пустой ключ отключает аутентификацию
+	AuthSecret string
+	// Путь к JSON-файлу с пользователями
+	AuthUsersPath string
+	// Время жизни токена доступа
+	TokenTTL time.Duration
+	// Получатели напоминаний, пустой список отключает напоминания
+	ReminderNotifiers []string
+	// Адрес, на который webhook отправляет напоминания
+	ReminderWebhookURL string
+	// Файл, в который file дописывает напоминания
+	ReminderFilePath string
+	// Файл учета доставленных напоминаний
+	ReminderStatePath string
+	// Период проверки напоминаний
+	ReminderInterval time.Duration
+	// За какой период в прошлом отправляются пропущенные напоминания
+	ReminderCatchUp time.Duration
+	// Запросов в секунду с одного IP-адреса, 0 отключает ограничение
+	RateLimitIP float64
+	// Сколько запросов подряд допускается с одного IP-адреса
+	RateLimitIPBurst int
+	// Запросов в секунду к одному user_id, 0 отключает ограничение
+	RateLimitUser float64
+	// Сколько запросов подряд допускается к одному user_id
+	RateLimitUserBurst int
+	// Максимальный размер формы или JSON в теле запроса
+	MaxBodyBytes int64
+	// Максимальный размер загружаемого файла календаря
+	MaxImportBytes int64
+	// Сколько удаленное событие хранится в корзине, 0 отключает очистку корзины
+	TrashRetention time.Duration
+	// Период очистки корзины
+	TrashPurgeInterval time.Duration
 }
 
-// InitConfig загружает настройки из файла .env и возвращает Config и ошибку, если таковая возникла
-func InitConfig(cfgPath string) (Config, error) {
-	// Загружаем переменные окружения из файла .env
-	err := godotenv.Load(cfgPath)
-	if err != nil {
-		return Config{}, err
+// ValidationError - ошибка конфигурации со списком всех неверных ключей
+type ValidationError struct {
+	Problems []string // Problems - описания ошибок вида "ключ (источник): причина"
+}
+
+// Error возвращает все ошибки конфигурации, по одной на строку
+func (v *ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(v.Problems, "\n  ")
+}
+
+// Loader - загрузчик конфигурации из нескольких слоев. Каждый следующий слой переопределяет предыдущий:
+// значения по умолчанию, файл YAML или JSON (-config или CONFIG_FILE), файл .env (-env), переменные окружения, флаги
+type Loader struct {
+	flags      *flag.FlagSet
+	configPath *string            // configPath - путь к файлу YAML или JSON
+	envPath    *string            // envPath - путь к файлу .env
+	values     map[string]*string // values - значения флагов настроек по ключам
+}
+
+// NewLoader - конструктор Loader. Регистрирует в flags флаги -config, -env и по флагу на каждую настройку,
+// имя флага совпадает с ключом настройки в файле (например, -server.addr=:9090)
+func NewLoader(flags *flag.FlagSet) *Loader {
+	loader := &Loader{
+		flags:      flags,
+		configPath: flags.String("config", "", "path to YAML or JSON config file (or CONFIG_FILE)"),
+		envPath:    flags.String("env", defaultEnvPath, "path to .env file, missing default file is ignored"),
+		values:     make(map[string]*string, len(settings)),
+	}
+	for _, s := range settings {
+		loader.values[s.key] = flags.String(s.key, "", fmt.Sprintf("%s (env %s, default %q)", s.usage, s.env[0], s.def))
 	}
+	return loader
+}
 
-	// Парсим значения таймаутов из переменных окружения
-	timeout, err := time.ParseDuration(os.Getenv("TIMEOUT"))
-	if err != nil {
-		return Config{}, err
+// Load собирает конфигурацию из всех слоев после разбора флагов и проверяет ее.
+// Возвращает *ValidationError, если неверны значения одного или нескольких ключей
+func (loader *Loader) Load() (Config, error) {
+	layers := newLayers()
+	set := loader.visited()
+
+	// Файл конфигурации задается флагом или переменной окружения
+	configPath := *loader.configPath
+	if configPath == "" {
+		configPath = os.Getenv(configFileEnv)
 	}
-	idleTimeout, err := time.ParseDuration(os.Getenv("IDLE_TIMEOUT"))
-	if err != nil {
-		return Config{}, err
+	if configPath != "" {
+		if err := layers.readFile(configPath); err != nil {
+			return Config{}, err
+		}
 	}
 
+	// Файл .env необязателен, если путь к нему не задан явно
+	if err := layers.readDotEnv(*loader.envPath, set["env"]); err != nil {
+		return Config{}, err
+	}
+	layers.readEnv(os.LookupEnv)
+	for _, s := range settings {
+		if set[s.key] {
+			layers.set(s.key, *loader.values[s.key], "flag -"+s.key)
+		}
+	}
+	return layers.build()
+}
 
-	return Config{
-		Port:        os.Getenv("APP_PORT"),
-		Timeout:     timeout,
-		IdleTimeout: idleTimeout,
-	}, nil
+// visited - возвращает имена флагов, явно заданных в командной строке
+func (loader *Loader) visited() map[string]bool {
+	set := make(map[string]bool)
+	loader.flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
 }
@@ -0,0 +1,276 @@
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// load - загружает конфигурацию с аргументами командной строки args
+func load(t *testing.T, args ...string) (Config, error) {
+	t.Helper()
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	loader := NewLoader(flags)
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return loader.Load()
+}
+
+// writeFile - создает файл с содержимым content во временном каталоге теста
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	t.Setenv(configFileEnv, "")
+	if _, err := load(t, "-env", filepath.Join(t.TempDir(), "missing.env")); err == nil {
+		t.Fatalf("explicit missing .env: got nil want error")
+	}
+
+	// Файл .env по умолчанию необязателен, запускаемся в пустом каталоге
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	cfg, err := load(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":8080" || cfg.ReadTimeout != 5*time.Second || cfg.WriteTimeout != 10*time.Second || cfg.IdleTimeout != time.Minute {
+		t.Errorf("server defaults: got %s %v %v %v", cfg.Addr, cfg.ReadTimeout, cfg.WriteTimeout, cfg.IdleTimeout)
+	}
+	if cfg.TLSClientAuth != tls.NoClientCert || cfg.TLSReloadInterval != 10*time.Second {
+		t.Errorf("tls defaults: got %v %v", cfg.TLSClientAuth, cfg.TLSReloadInterval)
+	}
+	if cfg.Storage != StorageMemory || cfg.LogLevel != slog.LevelInfo || !slices.Equal(cfg.ReminderNotifiers, []string{NotifierLog}) {
+		t.Errorf("defaults: got storage %s, log level %v, notifiers %v", cfg.Storage, cfg.LogLevel, cfg.ReminderNotifiers)
+	}
+	if cfg.AuditPath != filepath.Join("storage", "changes.log") || cfg.ReminderStatePath != filepath.Join("storage", "reminders.state") {
+		t.Errorf("derived paths: got %s, %s", cfg.AuditPath, cfg.ReminderStatePath)
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	yamlPath := writeFile(t, "config.yaml", `
+server:
+  addr: ":7000"
+  read_timeout: 2s
+  write_timeout: 3s
+  idle_timeout: 4s
+storage:
+  type: file
+  path: /var/lib/calendar
+reminders:
+  notifiers: [log, file]
+rate_limit:
+  ip: 0.5
+log:
+  level: debug
+`)
+	envPath := writeFile(t, "test.env", "WRITE_TIMEOUT=30s\nIDLE_TIMEOUT=40s\n")
+
+	tests := []struct {
+		name  string
+		env   map[string]string
+		args  []string
+		check func(t *testing.T, cfg Config)
+	}{
+		{
+			name: "File",
+			args: []string{"-config", yamlPath, "-env", envPath},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Addr != ":7000" || cfg.ReadTimeout != 2*time.Second || cfg.Storage != StorageFile || cfg.RateLimitIP != 0.5 {
+					t.Errorf("file values: got %s %v %s %v", cfg.Addr, cfg.ReadTimeout, cfg.Storage, cfg.RateLimitIP)
+				}
+				if !slices.Equal(cfg.ReminderNotifiers, []string{NotifierLog, NotifierFile}) || cfg.LogLevel != slog.LevelDebug {
+					t.Errorf("file lists and levels: got %v %v", cfg.ReminderNotifiers, cfg.LogLevel)
+				}
+				if cfg.AuditPath != filepath.Join("/var/lib/calendar", "changes.log") {
+					t.Errorf("derived audit path: got %s", cfg.AuditPath)
+				}
+				// .env важнее файла конфигурации
+				if cfg.WriteTimeout != 30*time.Second || cfg.IdleTimeout != 40*time.Second {
+					t.Errorf(".env values: got %v %v", cfg.WriteTimeout, cfg.IdleTimeout)
+				}
+			},
+		},
+		{
+			name: "Env Over Files",
+			env:  map[string]string{"IDLE_TIMEOUT": "50s", "ADDR": "127.0.0.1:7001", configFileEnv: yamlPath},
+			args: []string{"-env", envPath},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Addr != "127.0.0.1:7001" || cfg.IdleTimeout != 50*time.Second || cfg.WriteTimeout != 30*time.Second {
+					t.Errorf("env values: got %s %v %v", cfg.Addr, cfg.IdleTimeout, cfg.WriteTimeout)
+				}
+			},
+		},
+		{
+			name: "Flags Over Env",
+			env:  map[string]string{"ADDR": ":7001", "STORAGE": "file"},
+			args: []string{"-config", yamlPath, "-env", envPath, "-server.addr", ":7002", "-storage.type", "memory"},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Addr != ":7002" || cfg.Storage != StorageMemory {
+					t.Errorf("flag values: got %s %s", cfg.Addr, cfg.Storage)
+				}
+			},
+		},
+		{
+			name: "Legacy Env",
+			env:  map[string]string{"APP_PORT": "9090", "TIMEOUT": "7s"},
+			args: []string{"-env", envPath},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Addr != ":9090" || cfg.ReadTimeout != 7*time.Second {
+					t.Errorf("legacy values: got %s %v", cfg.Addr, cfg.ReadTimeout)
+				}
+			},
+		},
+		{
+			name: "Primary Env Over Legacy",
+			env:  map[string]string{"APP_PORT": "9090", "ADDR": ":9091"},
+			args: []string{"-env", envPath},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Addr != ":9091" {
+					t.Errorf("addr: got %s want :9091", cfg.Addr)
+				}
+			},
+		},
+		{
+			name: "Client Auth",
+			env:  map[string]string{"TLS_CLIENT_CA_FILE": "ca.pem", "TLS_CLIENT_AUTH": "optional"},
+			args: []string{"-env", envPath, "-tls.cert_file=cert.pem", "-tls.key_file=key.pem"},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.TLSClientCAFile != "ca.pem" || cfg.TLSClientAuth != tls.VerifyClientCertIfGiven {
+					t.Errorf("client auth: got %s %v", cfg.TLSClientCAFile, cfg.TLSClientAuth)
+				}
+			},
+		},
+		{
+			name: "JSON File",
+			args: []string{"-env", envPath, "-config", writeFile(t, "config.json", `{"server": {"addr": "9000"}, "rate_limit": {"ip_burst": 5}}`)},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Addr != ":9000" || cfg.RateLimitIPBurst != 5 {
+					t.Errorf("json values: got %s %d", cfg.Addr, cfg.RateLimitIPBurst)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(configFileEnv, "")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			cfg, err := load(t, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	t.Setenv(configFileEnv, "")
+	envPath := writeFile(t, "test.env", "")
+	configPath := writeFile(t, "config.yaml", `
+server:
+  read_timeout: 5
+  port: 8080
+storage:
+  type: disk
+`)
+
+	_, err := load(t, "-env", envPath, "-config", configPath,
+		"-rate_limit.user=-1", "-reminders.notifiers=webhook", "-tls.cert_file=cert.pem", "-tls.client_auth=always", "-log.level=loud",
+		"-auth.secret=change-me")
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("got %v want ValidationError", err)
+	}
+
+	// Ошибки перечислены по всем неверным ключам с источником значения
+	want := []string{
+		"server.port (file " + configPath + "): unknown key",
+		`server.read_timeout = "5" (file ` + configPath + "): must be a positive duration, e.g. 5s",
+		`tls.client_auth = "always" (flag -tls.client_auth): must be require or optional`,
+		`log.level = "loud" (flag -log.level): must be debug, info, warn or error`,
+		`storage.type = "disk" (file ` + configPath + "): must be memory or file",
+		`rate_limit.user = "-1" (flag -rate_limit.user): must be a non-negative number`,
+		"reminders.webhook_url: required for webhook notifier",
+		"auth.secret (flag -auth.secret): must not be a placeholder: generate a random key, e.g. openssl rand -hex 32",
+		"tls.key_file: required with tls.cert_file",
+	}
+	if !slices.Equal(validationErr.Problems, want) {
+		t.Errorf("problems:\ngot  %q\nwant %q", validationErr.Problems, want)
+	}
+}
+
+func TestParseLocalURL(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{value: "http://127.0.0.1:9000/reminders"},
+		{value: "http://127.1.2.3/reminders"},
+		{value: "https://localhost:9443/reminders"},
+		{value: "http://[::1]:9000/reminders"},
+		{value: "https://hooks.example.com/reminders", wantErr: true},
+		{value: "http://10.0.0.5:9000/reminders", wantErr: true},
+		{value: "http://localhost.example.com/reminders", wantErr: true},
+		{value: "ftp://127.0.0.1/reminders", wantErr: true},
+		{value: "/reminders", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseLocalURL(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error: got %v want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.value {
+				t.Errorf("url: got %v want %v", got, tt.value)
+			}
+		})
+	}
+}
+
+func TestCheckSecret(t *testing.T) {
+	tests := []struct {
+		secret  string
+		wantErr bool
+	}{
+		// Пустой ключ отключает аутентификацию
+		{secret: ""},
+		{secret: "3f9c1e7a5b2d4c6e8f0a1b2c3d4e5f60"},
+		{secret: "   ", wantErr: true},
+		{secret: "change-me", wantErr: true},
+		{secret: "Change-Me", wantErr: true},
+		{secret: " secret ", wantErr: true},
+		{secret: "short-key", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.secret, func(t *testing.T) {
+			if err := checkSecret(tt.secret); (err != nil) != tt.wantErr {
+				t.Errorf("checkSecret() error=%v wantErr=%v", err, tt.wantErr)
+			}
+		})
+	}
+}
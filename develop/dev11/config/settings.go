@@ -0,0 +1,306 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting - описание одной настройки: ключ в файле и имя флага, переменные окружения, значение по умолчанию
+// и функция, которая разбирает значение и записывает его в Config
+type setting struct {
+	key   string                                // key - ключ в файле конфигурации и имя флага
+	env   []string                              // env - переменные окружения, первая основная, остальные устаревшие
+	def   string                                // def - значение по умолчанию, пустое - не задано
+	usage string                                // usage - описание для справки по флагам
+	apply func(cfg *Config, value string) error // apply - разбирает значение, ошибка описывает причину без ключа
+}
+
+// settings - все настройки в порядке применения. Пути, зависящие от storage.path, вычисляются после применения
+var settings = []setting{
+	{key: "server.addr", env: []string{"ADDR", "APP_PORT"}, def: ":8080", usage: "listen address, host:port or port",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.Addr, err = parseAddr(value)
+			return err
+		}},
+	{key: "server.read_timeout", env: []string{"READ_TIMEOUT", "TIMEOUT"}, def: "5s", usage: "time to read a request with its body",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.ReadTimeout, err = parseDuration(value, true)
+			return err
+		}},
+	{key: "server.write_timeout", env: []string{"WRITE_TIMEOUT"}, def: "10s", usage: "time to write a response, event streams are not limited",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.WriteTimeout, err = parseDuration(value, true)
+			return err
+		}},
+	{key: "server.idle_timeout", env: []string{"IDLE_TIMEOUT"}, def: "60s", usage: "keep-alive connection idle time",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.IdleTimeout, err = parseDuration(value, true)
+			return err
+		}},
+	{key: "server.shutdown_timeout", env: []string{"SHUTDOWN_TIMEOUT"}, def: "15s", usage: "time to finish requests on shutdown before connections are closed",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.ShutdownTimeout, err = parseDuration(value, true)
+			return err
+		}},
+	{key: "tls.cert_file", env: []string{"TLS_CERT_FILE"}, usage: "TLS certificate file, empty - plain HTTP",
+		apply: func(cfg *Config, value string) error {
+			cfg.TLSCertFile = value
+			return nil
+		}},
+	{key: "tls.key_file", env: []string{"TLS_KEY_FILE"}, usage: "TLS private key file",
+		apply: func(cfg *Config, value string) error {
+			cfg.TLSKeyFile = value
+			return nil
+		}},
+	{key: "tls.client_ca_file", env: []string{"TLS_CLIENT_CA_FILE"}, usage: "CA certificates for client certificates, empty - mTLS is disabled",
+		apply: func(cfg *Config, value string) error {
+			cfg.TLSClientCAFile = value
+			return nil
+		}},
+	{key: "tls.client_auth", env: []string{"TLS_CLIENT_AUTH"}, def: ClientAuthRequire, usage: "client certificate check with tls.client_ca_file: require or optional",
+		apply: func(cfg *Config, value string) error {
+			switch value {
+			case ClientAuthRequire:
+				cfg.TLSClientAuth = tls.RequireAndVerifyClientCert
+			case ClientAuthOptional:
+				cfg.TLSClientAuth = tls.VerifyClientCertIfGiven
+			default:
+				return fmt.Errorf("must be %s or %s", ClientAuthRequire, ClientAuthOptional)
+			}
+			return nil
+		}},
+	{key: "tls.reload_interval", env: []string{"TLS_RELOAD_INTERVAL"}, def: "10s", usage: "certificate files change check period, 0 - reload only on SIGHUP",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.TLSReloadInterval, err = parseDuration(value, false)
+			return err
+		}},
+	{key: "log.level", env: []string{"LOG_LEVEL"}, def: "info", usage: "log level: debug, info, warn or error",
+		apply: func(cfg *Config, value string) error {
+			if err := cfg.LogLevel.UnmarshalText([]byte(value)); err != nil {
+				return fmt.Errorf("must be debug, info, warn or error")
+			}
+			return nil
+		}},
+	{key: "storage.type", env: []string{"STORAGE"}, def: StorageMemory, usage: "event storage: memory or file",
+		apply: func(cfg *Config, value string) error {
+			if value != StorageMemory && value != StorageFile {
+				return fmt.Errorf("must be %s or %s", StorageMemory, StorageFile)
+			}
+			cfg.Storage = value
+			return nil
+		}},
+	{key: "storage.path", env: []string{"STORAGE_PATH"}, def: "./storage", usage: "file storage directory",
+		apply: func(cfg *Config, value string) error {
+			cfg.StoragePath = value
+			return nil
+		}},
+	{key: "storage.snapshot_interval", env: []string{"SNAPSHOT_INTERVAL"}, def: "1m", usage: "file storage snapshot period, 0 - only on shutdown",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.SnapshotInterval, err = parseDuration(value, false)
+			return err
+		}},
+	{key: "storage.audit_path", env: []string{"AUDIT_PATH"}, usage: "event change log file, default storage.path/changes.log",
+		apply: func(cfg *Config, value string) error {
+			cfg.AuditPath = value
+			return nil
+		}},
+	{key: "auth.secret", env: []string{"AUTH_SECRET"}, usage: "token signing key of at least 16 random bytes, empty - authentication is disabled",
+		apply: func(cfg *Config, value string) error {
+			cfg.AuthSecret = value
+			return nil
+		}},
+	{key: "auth.users", env: []string{"AUTH_USERS"}, def: "./users.json", usage: "JSON file with users",
+		apply: func(cfg *Config, value string) error {
+			cfg.AuthUsersPath = value
+			return nil
+		}},
+	{key: "auth.token_ttl", env: []string{"TOKEN_TTL"}, def: "24h", usage: "access token lifetime",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.TokenTTL, err = parseDuration(value, true)
+			return err
+		}},
+	{key: "reminders.notifiers", env: []string{"REMINDER_NOTIFIERS"}, def: NotifierLog, usage: "reminder notifiers: log, webhook, file or none",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.ReminderNotifiers, err = parseNotifiers(value)
+			return err
+		}},
+	{key: "reminders.webhook_url", env: []string{"REMINDER_WEBHOOK_URL"}, usage: "local URL the webhook notifier posts reminders to",
+		apply: func(cfg *Config, value string) (err error) {
+			if value == "" {
+				return nil
+			}
+			cfg.ReminderWebhookURL, err = parseLocalURL(value)
+			return err
+		}},
+	{key: "reminders.file", env: []string{"REMINDER_FILE"}, def: "./reminders.jsonl", usage: "file the file notifier appends reminders to",
+		apply: func(cfg *Config, value string) error {
+			cfg.ReminderFilePath = value
+			return nil
+		}},
+	{key: "reminders.state", env: []string{"REMINDER_STATE"}, usage: "delivered reminders file, default storage.path/reminders.state",
+		apply: func(cfg *Config, value string) error {
+			cfg.ReminderStatePath = value
+			return nil
+		}},
+	{key: "reminders.interval", env: []string{"REMINDER_INTERVAL"}, def: "30s", usage: "reminder check period",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.ReminderInterval, err = parseDuration(value, true)
+			return err
+		}},
+	{key: "reminders.catch_up", env: []string{"REMINDER_CATCH_UP"}, def: "1h", usage: "how far back missed reminders are sent",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.ReminderCatchUp, err = parseDuration(value, false)
+			return err
+		}},
+	{key: "rate_limit.ip", env: []string{"RATE_LIMIT_IP"}, def: "20", usage: "requests per second from one IP address, 0 - unlimited",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.RateLimitIP, err = parseRate(value)
+			return err
+		}},
+	{key: "rate_limit.ip_burst", env: []string{"RATE_LIMIT_IP_BURST"}, def: "40", usage: "request burst from one IP address",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.RateLimitIPBurst, err = strconv.Atoi(value)
+			if err != nil || cfg.RateLimitIPBurst < 1 {
+				return fmt.Errorf("must be a positive number")
+			}
+			return nil
+		}},
+	{key: "rate_limit.user", env: []string{"RATE_LIMIT_USER"}, def: "10", usage: "requests per second to one user_id, 0 - unlimited",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.RateLimitUser, err = parseRate(value)
+			return err
+		}},
+	{key: "rate_limit.user_burst", env: []string{"RATE_LIMIT_USER_BURST"}, def: "20", usage: "request burst to one user_id",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.RateLimitUserBurst, err = strconv.Atoi(value)
+			if err != nil || cfg.RateLimitUserBurst < 1 {
+				return fmt.Errorf("must be a positive number")
+			}
+			return nil
+		}},
+	{key: "limits.max_body_bytes", env: []string{"MAX_BODY_BYTES"}, def: "1048576", usage: "maximum form or JSON request body size",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.MaxBodyBytes, err = parseSize(value)
+			return err
+		}},
+	{key: "limits.max_import_bytes", env: []string{"MAX_IMPORT_BYTES"}, def: "10485760", usage: "maximum imported calendar file size",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.MaxImportBytes, err = parseSize(value)
+			return err
+		}},
+	{key: "trash.retention", env: []string{"TRASH_RETENTION"}, def: "720h", usage: "how long deleted events stay in trash, 0 - forever",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.TrashRetention, err = parseDuration(value, false)
+			return err
+		}},
+	{key: "trash.purge_interval", env: []string{"TRASH_PURGE_INTERVAL"}, def: "1h", usage: "trash purge period",
+		apply: func(cfg *Config, value string) (err error) {
+			cfg.TrashPurgeInterval, err = parseDuration(value, true)
+			return err
+		}},
+}
+
+// parseAddr разбирает адрес сервера. Одиночный порт (как в устаревшей APP_PORT) означает все интерфейсы
+func parseAddr(value string) (string, error) {
+	if !strings.Contains(value, ":") {
+		value = ":" + value
+	}
+	_, port, err := net.SplitHostPort(value)
+	if err != nil {
+		return "", fmt.Errorf("must be host:port or port")
+	}
+	if number, err := strconv.Atoi(port); err != nil || number < 0 || number > 65535 {
+		return "", fmt.Errorf("port must be a number from 0 to 65535")
+	}
+	return value, nil
+}
+
+// parseDuration разбирает длительность. С positive=true нулевая длительность недопустима
+func parseDuration(value string, positive bool) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	switch {
+	case err != nil && positive, positive && duration <= 0:
+		return 0, fmt.Errorf("must be a positive duration, e.g. 5s")
+	case err != nil, duration < 0:
+		return 0, fmt.Errorf("must be a non-negative duration, e.g. 5s")
+	}
+	return duration, nil
+}
+
+// parseRate разбирает число запросов в секунду
+func parseRate(value string) (float64, error) {
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 {
+		return 0, fmt.Errorf("must be a non-negative number")
+	}
+	return rate, nil
+}
+
+// parseSize разбирает размер в байтах
+func parseSize(value string) (int64, error) {
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size < 1 {
+		return 0, fmt.Errorf("must be a positive number of bytes")
+	}
+	return size, nil
+}
+
+// parseLocalURL разбирает адрес http или https на этой машине: localhost или адрес loopback.
+// Напоминания содержат события пользователей, поэтому вебхук не отправляет их на чужие хосты
+func parseLocalURL(value string) (string, error) {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("must be an absolute http or https URL")
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); !strings.EqualFold(host, "localhost") && (ip == nil || !ip.IsLoopback()) {
+		return "", fmt.Errorf("must be a local URL: host must be localhost or a loopback address")
+	}
+	return value, nil
+}
+
+// minSecretLength - минимальная длина ключа подписи токенов в байтах
+const minSecretLength = 16
+
+// placeholderSecrets - ключи-заглушки из примеров конфигурации и документации
+var placeholderSecrets = []string{"change-me", "changeme", "change-me-please", "secret", "your-secret", "example", "placeholder"}
+
+// checkSecret проверяет ключ подписи токенов. Пустой ключ отключает аутентификацию, а ключ-заглушку из примеров
+// конфигурации знает кто угодно и может подписать им токен любого пользователя, поэтому с ним сервер не запускается
+func checkSecret(secret string) error {
+	if secret == "" {
+		return nil
+	}
+	trimmed := strings.TrimSpace(secret)
+	switch {
+	case trimmed == "":
+		return fmt.Errorf("must not be blank: leave it empty to disable authentication")
+	case slices.ContainsFunc(placeholderSecrets, func(placeholder string) bool { return strings.EqualFold(trimmed, placeholder) }):
+		return fmt.Errorf("must not be a placeholder: generate a random key, e.g. openssl rand -hex 32")
+	case len(secret) < minSecretLength:
+		return fmt.Errorf("must be at least %d bytes", minSecretLength)
+	}
+	return nil
+}
+
+// parseNotifiers разбирает список получателей напоминаний, разделенных запятой
+func parseNotifiers(value string) ([]string, error) {
+	notifiers := make([]string, 0)
+	for _, name := range strings.Split(value, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case NotifierNone:
+			return nil, nil
+		case NotifierLog, NotifierWebhook, NotifierFile:
+			notifiers = append(notifiers, name)
+		default:
+			return nil, fmt.Errorf("unknown notifier %q: must be %s, %s, %s or %s", name, NotifierLog, NotifierWebhook, NotifierFile, NotifierNone)
+		}
+	}
+	return notifiers, nil
+}
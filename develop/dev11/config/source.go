@@ -0,0 +1,195 @@
+package config
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileEnv - переменная окружения с путем к файлу конфигурации, если не задан флаг -config
+const configFileEnv = "CONFIG_FILE"
+
+// defaultEnvPath - файл .env по умолчанию
+const defaultEnvPath = "./.env"
+
+// entry - значение настройки и слой, из которого оно получено
+type entry struct {
+	value  string // value - значение в текстовом виде
+	source string // source - источник значения для сообщений об ошибках, например "env READ_TIMEOUT"
+}
+
+// layers - значения настроек после наложения слоев и найденные по пути ошибки
+type layers struct {
+	values   map[string]entry
+	problems []string
+}
+
+// newLayers - создает слои со значениями по умолчанию
+func newLayers() *layers {
+	l := &layers{values: make(map[string]entry, len(settings))}
+	for _, s := range settings {
+		l.set(s.key, s.def, "default")
+	}
+	return l
+}
+
+// set - переопределяет значение настройки
+func (l *layers) set(key, value, source string) {
+	l.values[key] = entry{value: strings.TrimSpace(value), source: source}
+}
+
+// readFile - накладывает значения из файла YAML или JSON. Вложенные секции задают ключи через точку:
+// секция server с полем addr - ключ server.addr. Неизвестные ключи считаются ошибкой конфигурации
+func (l *layers) readFile(path string) error {
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	document := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(payload))
+		decoder.UseNumber()
+		err = decoder.Decode(&document)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(payload, &document)
+	default:
+		return fmt.Errorf("config file %s: unsupported format, use .yaml, .yml or .json", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	source := "file " + path
+	values := make(map[string]string)
+	l.flatten("", document, values, source)
+
+	// Сортируем ключи, чтобы ошибки выводились в одном и том же порядке
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, ok := l.values[key]; !ok {
+			l.problems = append(l.problems, fmt.Sprintf("%s (%s): unknown key", key, source))
+			continue
+		}
+		l.set(key, values[key], source)
+	}
+	return nil
+}
+
+// flatten - раскладывает вложенные секции документа в ключи через точку.
+// Списки склеиваются через запятую, null пропускается
+func (l *layers) flatten(prefix string, document map[string]any, values map[string]string, source string) {
+	for name, value := range document {
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		switch v := value.(type) {
+		case nil:
+		case map[string]any:
+			l.flatten(key, v, values, source)
+		case []any:
+			parts := make([]string, 0, len(v))
+			for _, item := range v {
+				parts = append(parts, fmt.Sprint(item))
+			}
+			values[key] = strings.Join(parts, ",")
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+// readDotEnv - накладывает значения из файла .env. Отсутствующий файл пропускается, если путь не задан явно
+func (l *layers) readDotEnv(path string, explicit bool) error {
+	vars, err := godotenv.Read(path)
+	if os.IsNotExist(err) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read env file: %w", err)
+	}
+	l.readVars(func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}, ".env")
+	return nil
+}
+
+// readEnv - накладывает значения из переменных окружения
+func (l *layers) readEnv(lookup func(string) (string, bool)) {
+	l.readVars(lookup, "env")
+}
+
+// readVars - накладывает значения переменных. Пустая переменная считается незаданной.
+// Основное имя переменной важнее устаревшего, поэтому применяется последним
+func (l *layers) readVars(lookup func(string) (string, bool), source string) {
+	for _, s := range settings {
+		for i := len(s.env) - 1; i >= 0; i-- {
+			if value, ok := lookup(s.env[i]); ok && value != "" {
+				l.set(s.key, value, source+" "+s.env[i])
+			}
+		}
+	}
+}
+
+// build - разбирает значения всех настроек в Config и проверяет связанные настройки.
+// Ошибки собираются по всем ключам, а не только по первому
+func (l *layers) build() (Config, error) {
+	var cfg Config
+	problems := l.problems
+	for _, s := range settings {
+		e := l.values[s.key]
+		if err := s.apply(&cfg, e.value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s = %q (%s): %s", s.key, e.value, e.source, err))
+		}
+	}
+
+	// Пути по умолчанию лежат в каталоге хранилища
+	if cfg.AuditPath == "" {
+		cfg.AuditPath = filepath.Join(cfg.StoragePath, "changes.log")
+	}
+	if cfg.ReminderStatePath == "" {
+		cfg.ReminderStatePath = filepath.Join(cfg.StoragePath, "reminders.state")
+	}
+
+	// Проверяем настройки, которые имеют смысл только вместе
+	for _, notifier := range cfg.ReminderNotifiers {
+		if notifier == NotifierWebhook && l.values["reminders.webhook_url"].value == "" {
+			problems = append(problems, fmt.Sprintf("reminders.webhook_url: required for %s notifier", NotifierWebhook))
+		}
+	}
+	// Значение ключа подписи не выводится в ошибке, чтобы оно не попало в логи
+	if err := checkSecret(cfg.AuthSecret); err != nil {
+		problems = append(problems, fmt.Sprintf("auth.secret (%s): %s", l.values["auth.secret"].source, err))
+	}
+	switch {
+	case cfg.TLSCertFile != "" && cfg.TLSKeyFile == "":
+		problems = append(problems, "tls.key_file: required with tls.cert_file")
+	case cfg.TLSCertFile == "" && cfg.TLSKeyFile != "":
+		problems = append(problems, "tls.cert_file: required with tls.key_file")
+	case cfg.TLSCertFile == "" && cfg.TLSClientCAFile != "":
+		problems = append(problems, "tls.cert_file: required with tls.client_ca_file")
+	}
+	if cfg.TLSClientCAFile == "" {
+		cfg.TLSClientAuth = tls.NoClientCert
+	}
+
+	if len(problems) > 0 {
+		return Config{}, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,269 @@
+package audit
+
+import (
+	"bufio"
+	"develop/dev11/config"
+	"develop/dev11/internal/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// opCheckpoint - служебная запись в начале переписанного файла журнала. Хранит номер последнего изменения,
+// чтобы после перезапуска номера не повторялись, даже если последние изменения удалены из журнала
+const opCheckpoint = "checkpoint"
+
+// Log - журнал изменений событий, в который записи только дописываются.
+// Записи хранятся в памяти и, если журнал открыт из файла, дописываются в файл.
+// Изменения окончательно удаленных событий удаляются из журнала, см. Forget
+type Log struct {
+	mu      sync.RWMutex     // mu - мьютекс для безопасного доступа к данным
+	path    string           // path - путь к файлу журнала
+	file    *os.File         // file - файл журнала, nil - журнал только в памяти
+	changes []models.Change  // changes - все изменения по возрастанию Seq
+	byUser  map[int][]int    // byUser - индексы изменений каждого пользователя в changes
+	byEvent map[int][]int    // byEvent - индексы изменений каждого события в changes
+	now     func() time.Time // now - текущее время, подменяется в тестах
+	seq     int64            // seq - номер последнего изменения
+}
+
+// NewLog - конструктор Log без сохранения на диск
+func NewLog() *Log {
+	return &Log{
+		byUser:  make(map[int][]int),
+		byEvent: make(map[int][]int),
+		now:     time.Now,
+	}
+}
+
+// OpenLog - загружает журнал изменений из файла path и открывает его на дозапись
+func OpenLog(path string) (*Log, error) {
+	log := NewLog()
+	if err := log.load(path); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	log.path = path
+	log.file = file
+	return log, nil
+}
+
+// NewFromConfig - создает журнал изменений. Для файлового хранилища событий журнал тоже хранится в файле,
+// для хранилища в памяти - только в памяти, так как после перезапуска событий уже нет
+func NewFromConfig(cfg config.Config) (*Log, error) {
+	if cfg.Storage != config.StorageFile {
+		return NewLog(), nil
+	}
+	return OpenLog(cfg.AuditPath)
+}
+
+// Append - присваивает изменению номер и время, дописывает его в журнал и возвращает записанное изменение
+func (log *Log) Append(change models.Change) (models.Change, error) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	change.Seq = log.seq + 1
+	change.At = log.now().UTC()
+	if log.file != nil {
+		payload, err := json.Marshal(change)
+		if err != nil {
+			return models.Change{}, err
+		}
+		if _, err := log.file.Write(append(payload, '\n')); err != nil {
+			return models.Change{}, fmt.Errorf("write change log: %w", err)
+		}
+		if err := log.file.Sync(); err != nil {
+			return models.Change{}, fmt.Errorf("sync change log: %w", err)
+		}
+	}
+	log.add(change)
+	return change, nil
+}
+
+// History - возвращает изменения события пользователя по возрастанию Seq
+func (log *Log) History(userID, eventID int) ([]models.Change, error) {
+	log.mu.RLock()
+	defer log.mu.RUnlock()
+
+	history := make([]models.Change, 0)
+	for _, i := range log.byEvent[eventID] {
+		if log.changes[i].UserID == userID {
+			history = append(history, log.changes[i])
+		}
+	}
+	return history, nil
+}
+
+// Since - возвращает не более limit изменений пользователя с номером больше since по возрастанию Seq
+func (log *Log) Since(userID int, since int64, limit int) ([]models.Change, error) {
+	log.mu.RLock()
+	defer log.mu.RUnlock()
+
+	indexes := log.byUser[userID]
+	// Номера изменений возрастают, поэтому первое подходящее изменение ищем бинарным поиском
+	start, end := 0, len(indexes)
+	for start < end {
+		middle := (start + end) / 2
+		if log.changes[indexes[middle]].Seq <= since {
+			start = middle + 1
+		} else {
+			end = middle
+		}
+	}
+
+	changes := make([]models.Change, 0, min(limit, len(indexes)-start))
+	for _, i := range indexes[start:] {
+		if len(changes) == limit {
+			break
+		}
+		changes = append(changes, log.changes[i])
+	}
+	return changes, nil
+}
+
+// Forget - удаляет из журнала изменения событий eventIDs вместе со снимками событий.
+// Вызывается после окончательного удаления событий из корзины. Файл журнала переписывается без этих изменений,
+// при ошибке журнал остается прежним
+func (log *Log) Forget(eventIDs []int) error {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	forget := make(map[int]bool, len(eventIDs))
+	for _, id := range eventIDs {
+		if len(log.byEvent[id]) > 0 {
+			forget[id] = true
+		}
+	}
+	if len(forget) == 0 {
+		return nil
+	}
+
+	kept := make([]models.Change, 0, len(log.changes))
+	for _, change := range log.changes {
+		if !forget[change.EventID] {
+			kept = append(kept, change)
+		}
+	}
+	if log.file != nil {
+		if err := log.rewrite(kept); err != nil {
+			return fmt.Errorf("rewrite change log: %w", err)
+		}
+	}
+
+	log.changes = nil
+	log.byUser = make(map[int][]int)
+	log.byEvent = make(map[int][]int)
+	for _, change := range kept {
+		log.add(change)
+	}
+	return nil
+}
+
+// rewrite - переписывает файл журнала через временный файл: номер последнего изменения и изменения changes.
+// Вызывается под mu
+func (log *Log) rewrite(changes []models.Change) error {
+	tmpPath := log.path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	err = encoder.Encode(models.Change{Seq: log.seq, Op: opCheckpoint})
+	for i := 0; err == nil && i < len(changes); i++ {
+		err = encoder.Encode(changes[i])
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, log.path)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	// Временный файл открыт на дозапись и после переименования становится файлом журнала
+	log.file.Close()
+	log.file = file
+	return nil
+}
+
+// Close - закрывает файл журнала
+func (log *Log) Close() error {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	if log.file == nil {
+		return nil
+	}
+	err := log.file.Close()
+	log.file = nil
+	return err
+}
+
+// load - читает журнал из файла. Оборванная последняя строка пропускается и обрезается,
+// чтобы новые записи дописывались за последней целой записью
+func (log *Log) load(path string) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		var change models.Change
+		if err == io.EOF || json.Unmarshal(line, &change) != nil {
+			slog.Warn("change log truncated", "offset", offset)
+			if err := file.Truncate(offset); err != nil {
+				return err
+			}
+			return file.Sync()
+		}
+		// Служебная запись только продолжает нумерацию
+		if change.Op == opCheckpoint {
+			log.seq = max(log.seq, change.Seq)
+		} else {
+			log.add(change)
+		}
+		offset += int64(len(line))
+	}
+}
+
+// add - добавляет изменение в память. Вызывается под mu или до начала работы
+func (log *Log) add(change models.Change) {
+	log.changes = append(log.changes, change)
+	i := len(log.changes) - 1
+	log.byUser[change.UserID] = append(log.byUser[change.UserID], i)
+	log.byEvent[change.EventID] = append(log.byEvent[change.EventID], i)
+	log.seq = max(log.seq, change.Seq)
+}
@@ -0,0 +1,174 @@
+package audit
+
+import (
+	"develop/dev11/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogSince(t *testing.T) {
+	log := NewLog()
+	date := time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC)
+	log.now = func() time.Time { return date }
+
+	// Изменения двух пользователей вперемешку
+	for _, change := range []models.Change{
+		{Op: models.ChangeCreate, UserID: 5, EventID: 0},
+		{Op: models.ChangeCreate, UserID: 7, EventID: 1},
+		{Op: models.ChangeUpdate, UserID: 5, EventID: 0},
+		{Op: models.ChangeDelete, UserID: 5, EventID: 0},
+	} {
+		if _, err := log.Append(change); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		userID  int
+		since   int64
+		limit   int
+		wantSeq []int64
+	}{
+		{name: "From Start", userID: 5, since: 0, limit: 10, wantSeq: []int64{1, 3, 4}},
+		{name: "After Cursor", userID: 5, since: 1, limit: 10, wantSeq: []int64{3, 4}},
+		{name: "Limit", userID: 5, since: 0, limit: 2, wantSeq: []int64{1, 3}},
+		{name: "No New Changes", userID: 5, since: 4, limit: 10, wantSeq: []int64{}},
+		{name: "Other User", userID: 7, since: 0, limit: 10, wantSeq: []int64{2}},
+		{name: "Unknown User", userID: 9, since: 0, limit: 10, wantSeq: []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := log.Since(tt.userID, tt.since, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			seq := make([]int64, 0, len(changes))
+			for _, change := range changes {
+				seq = append(seq, change.Seq)
+				if !change.At.Equal(date) {
+					t.Errorf("change %d at: got %v want %v", change.Seq, change.At, date)
+				}
+			}
+			if len(seq) != len(tt.wantSeq) {
+				t.Fatalf("Since() got %v want %v", seq, tt.wantSeq)
+			}
+			for i := range seq {
+				if seq[i] != tt.wantSeq[i] {
+					t.Errorf("Since() got %v want %v", seq, tt.wantSeq)
+					break
+				}
+			}
+		})
+	}
+
+	history, _ := log.History(5, 0)
+	if len(history) != 3 || history[0].Op != models.ChangeCreate || history[2].Op != models.ChangeDelete {
+		t.Errorf("History() got %v want create, update, delete", history)
+	}
+}
+
+func TestLogReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "changes.log")
+	date := time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC)
+
+	log, err := OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := models.NewEvent(5, 0, date, "Test", "Test")
+	log.Append(models.Change{Op: models.ChangeCreate, UserID: 5, EventID: 0, Version: 1, After: event})
+	log.Append(models.Change{Op: models.ChangeUpdate, UserID: 5, EventID: 0, Version: 2, Before: event, After: event})
+	log.Close()
+
+	// Эмулируем падение процесса посреди записи
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"seq":3,"op":"delete"`)
+	file.Close()
+
+	reopened, err := OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	history, _ := reopened.History(5, 0)
+	if len(history) != 2 || history[1].Before == nil || history[1].After.Title != "Test" {
+		t.Fatalf("history after reopen: got %v want 2 changes with snapshots", history)
+	}
+
+	// Нумерация продолжается после последней целой записи
+	change, err := reopened.Append(models.Change{Op: models.ChangeDelete, UserID: 5, EventID: 0, Version: 3})
+	if err != nil || change.Seq != 3 {
+		t.Errorf("append after reopen: got seq %d, %v want 3, nil", change.Seq, err)
+	}
+}
+
+func TestLogForget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "changes.log")
+	date := time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC)
+
+	log, err := OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := models.NewEvent(5, 1, date, "Test", "Test")
+	log.Append(models.Change{Op: models.ChangeCreate, UserID: 5, EventID: 0, Version: 1, After: event})
+	log.Append(models.Change{Op: models.ChangeCreate, UserID: 5, EventID: 1, Version: 1, After: event})
+	log.Append(models.Change{Op: models.ChangeCreate, UserID: 6, EventID: 1, Version: 1, After: event})
+	log.Append(models.Change{Op: models.ChangeDelete, UserID: 5, EventID: 1, Version: 2, After: event})
+
+	if err := log.Forget([]int{1, 7}); err != nil {
+		t.Fatal(err)
+	}
+	// check - проверяет, что в журнале остались только изменения события 0
+	check := func(t *testing.T, log *Log) {
+		t.Helper()
+		if history, _ := log.History(5, 1); len(history) != 0 {
+			t.Errorf("history of forgotten event: got %v want []", history)
+		}
+		if changes, _ := log.Since(6, 0, 10); len(changes) != 0 {
+			t.Errorf("changes of attendee: got %v want []", changes)
+		}
+		if changes, _ := log.Since(5, 0, 10); len(changes) != 1 || changes[0].EventID != 0 {
+			t.Errorf("changes of owner: got %v want event 0", changes)
+		}
+	}
+	check(t, log)
+
+	// Запись после удаления попадает в переписанный файл
+	change, err := log.Append(models.Change{Op: models.ChangeUpdate, UserID: 5, EventID: 0, Version: 2, After: event})
+	if err != nil || change.Seq != 5 {
+		t.Fatalf("append after forget: got seq %d, %v want 5, nil", change.Seq, err)
+	}
+	log.Forget([]int{0})
+	log.Close()
+
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(payload), `"title":"Test"`) {
+		t.Errorf("file still contains forgotten snapshots: %s", payload)
+	}
+
+	// После перезапуска номера продолжаются с последнего выданного, даже если его изменение удалено
+	reopened, err := OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if changes, _ := reopened.Since(5, 0, 10); len(changes) != 0 {
+		t.Errorf("changes after reopen: got %v want []", changes)
+	}
+	change, err = reopened.Append(models.Change{Op: models.ChangeCreate, UserID: 5, EventID: 2, Version: 1})
+	if err != nil || change.Seq != 6 {
+		t.Errorf("append after reopen: got seq %d, %v want 6, nil", change.Seq, err)
+	}
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Ошибки аутентификации
+var (
+	ErrInvalidCredentials = errors.New("invalid user name or password")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrTokenExpired       = errors.New("token expired")
+)
+
+// Claims - содержимое токена доступа
+type Claims struct {
+	UserID    int   `json:"uid"` // ID пользователя
+	ExpiresAt int64 `json:"exp"` // Время истечения токена (Unix)
+}
+
+// Auth - выдача и проверка токенов доступа, подписанных HMAC-SHA256
+type Auth struct {
+	secret []byte        // secret - ключ подписи
+	ttl    time.Duration // ttl - время жизни токена
+	users  UserStore     // users - хранилище пользователей
+	now    func() time.Time
+}
+
+// New - конструктор Auth
+func New(secret string, ttl time.Duration, users UserStore) *Auth {
+	return &Auth{secret: []byte(secret), ttl: ttl, users: users, now: time.Now}
+}
+
+// Login - проверяет имя и пароль и выдает токен
+func (a *Auth) Login(name, password string) (string, Claims, error) {
+	user, ok := a.users.Authenticate(name, password)
+	if !ok {
+		return "", Claims{}, ErrInvalidCredentials
+	}
+	claims := Claims{UserID: user.ID, ExpiresAt: a.now().Add(a.ttl).Unix()}
+	token, err := a.Issue(claims)
+	return token, claims, err
+}
+
+// Issue - подписывает claims и возвращает токен вида "<payload>.<signature>" в base64url
+func (a *Auth) Issue(claims Claims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(a.sign(encoded)), nil
+}
+
+// Verify - проверяет подпись и срок действия токена
+func (a *Auth) Verify(token string) (Claims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return Claims{}, ErrInvalidToken
+	}
+	decodedSignature, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(decodedSignature, a.sign(encoded)) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	if a.now().Unix() >= claims.ExpiresAt {
+		return Claims{}, ErrTokenExpired
+	}
+	return claims, nil
+}
+
+// sign - вычисляет HMAC-SHA256 подпись
+func (a *Auth) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// userKey - ключ контекста для аутентифицированного пользователя
+type userKey struct{}
+
+// WithUser - возвращает контекст с аутентифицированным пользователем
+func WithUser(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+// UserFromContext - возвращает аутентифицированного пользователя из контекста
+func UserFromContext(ctx context.Context) (int, bool) {
+	userID, ok := ctx.Value(userKey{}).(int)
+	return userID, ok
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthLogin(t *testing.T) {
+	hash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := NewUsers([]*User{{ID: 5, Name: "alice", PasswordHash: hash}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New("key", time.Hour, users)
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		wantErr  error
+	}{
+		{name: "OK", user: "alice", password: "secret"},
+		{name: "Wrong Password", user: "alice", password: "wrong", wantErr: ErrInvalidCredentials},
+		{name: "Unknown User", user: "bob", password: "secret", wantErr: ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, claims, err := a.Login(tt.user, tt.password)
+			if err != tt.wantErr {
+				t.Fatalf("Auth.Login() error=%v\nwantErr=%v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			verified, err := a.Verify(token)
+			if err != nil || verified != claims || verified.UserID != 5 {
+				t.Errorf("Auth.Verify() got %+v, %v want %+v", verified, err, claims)
+			}
+		})
+	}
+}
+
+func TestAuthVerify(t *testing.T) {
+	a := New("key", time.Hour, nil)
+	valid, _ := a.Issue(Claims{UserID: 5, ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	expired, _ := a.Issue(Claims{UserID: 5, ExpiresAt: time.Now().Add(-time.Hour).Unix()})
+	otherKey, _ := New("other", time.Hour, nil).Issue(Claims{UserID: 5, ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	// Подменяем payload, оставляя подпись от исходного токена
+	forged, _ := a.Issue(Claims{UserID: 7, ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	payload7, _, _ := strings.Cut(forged, ".")
+	_, signature5, _ := strings.Cut(valid, ".")
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "OK", token: valid},
+		{name: "Expired", token: expired, wantErr: ErrTokenExpired},
+		{name: "Other Key", token: otherKey, wantErr: ErrInvalidToken},
+		{name: "Forged Payload", token: payload7 + "." + signature5, wantErr: ErrInvalidToken},
+		{name: "Malformed", token: "garbage", wantErr: ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := a.Verify(tt.token); err != tt.wantErr {
+				t.Errorf("Auth.Verify() error=%v\nwantErr=%v", err, tt.wantErr)
+			}
+		})
+	}
+}
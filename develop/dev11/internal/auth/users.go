@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// User - пользователь календаря
+type User struct {
+	ID           int    `json:"id"`            // ID пользователя (user_id событий)
+	Name         string `json:"name"`          // Имя для входа
+	PasswordHash string `json:"password_hash"` // Хеш пароля, см. HashPassword
+}
+
+// UserStore - интерфейс хранилища пользователей
+type UserStore interface {
+	// Authenticate возвращает пользователя, если имя и пароль верны
+	Authenticate(name, password string) (*User, bool)
+}
+
+// Users - хранилище пользователей в памяти
+type Users struct {
+	byName map[string]*User // byName - пользователи по имени
+}
+
+// NewUsers - конструктор Users
+func NewUsers(users []*User) (*Users, error) {
+	store := &Users{byName: make(map[string]*User, len(users))}
+	for _, user := range users {
+		if user.Name == "" {
+			return nil, fmt.Errorf("user %d has empty name", user.ID)
+		}
+		if _, ok := store.byName[user.Name]; ok {
+			return nil, fmt.Errorf("duplicate user name %q", user.Name)
+		}
+		store.byName[user.Name] = user
+	}
+	return store, nil
+}
+
+// LoadUsers - загружает пользователей из JSON-файла со списком User
+func LoadUsers(path string) (*Users, error) {
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]*User, 0)
+	if err := json.Unmarshal(payload, &users); err != nil {
+		return nil, fmt.Errorf("invalid users file %s: %w", path, err)
+	}
+	return NewUsers(users)
+}
+
+// Authenticate - проверяет имя и пароль пользователя
+func (users *Users) Authenticate(name, password string) (*User, bool) {
+	user, ok := users.byName[name]
+	if !ok {
+		// Хешируем пароль и для неизвестного пользователя, чтобы время ответа не выдавало существующие имена
+		CheckPassword(dummyHash(), password)
+		return nil, false
+	}
+	if !CheckPassword(user.PasswordHash, password) {
+		return nil, false
+	}
+	return user, true
+}
+
+// dummyHash - хеш для проверки пароля неизвестного пользователя, вычисляется при первом обращении
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := HashPassword("")
+	return hash
+})
@@ -0,0 +1,189 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"develop/dev11/config"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Reloader - сертификат сервера и центры сертификатов клиентов, которые перечитываются с диска
+// при изменении файлов или по вызову Reload. Новые файлы применяются к новым соединениям,
+// открытые соединения продолжают работать со старым сертификатом
+type Reloader struct {
+	certFile     string             // certFile - файл сертификата сервера
+	keyFile      string             // keyFile - файл закрытого ключа
+	clientCAFile string             // clientCAFile - файл центров сертификатов клиентов, пустой путь отключает mTLS
+	clientAuth   tls.ClientAuthType // clientAuth - проверка сертификата клиента
+	interval     time.Duration      // interval - период проверки изменения файлов
+
+	current atomic.Pointer[tls.Config] // current - настройки TLS для новых соединений
+	mu      sync.Mutex                 // mu - защищает от одновременной перезагрузки
+	stamps  []stamp                    // stamps - состояние файлов на момент последней перезагрузки
+
+	stop chan struct{} // stop - сигнал остановки
+	done chan struct{} // done - закрывается после остановки цикла
+	once sync.Once     // once - защищает от повторного запуска
+	halt sync.Once     // halt - защищает от повторной остановки
+}
+
+// stamp - время изменения и размер файла, по которым определяется, что файл изменился
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// New - конструктор Reloader. Сразу загружает сертификаты и возвращает ошибку, если их нельзя загрузить
+func New(certFile, keyFile, clientCAFile string, clientAuth tls.ClientAuthType, interval time.Duration) (*Reloader, error) {
+	reloader := &Reloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+		clientAuth:   clientAuth,
+		interval:     interval,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// NewFromConfig - создает Reloader с файлами из конфигурации.
+// Возвращает nil, если сертификат не задан и сервер работает по HTTP
+func NewFromConfig(cfg config.Config) (*Reloader, error) {
+	if cfg.TLSCertFile == "" {
+		return nil, nil
+	}
+	return New(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile, cfg.TLSClientAuth, cfg.TLSReloadInterval)
+}
+
+// TLSConfig - возвращает настройки TLS для http.Server. Сертификат и центры сертификатов клиентов
+// берутся при каждом рукопожатии из последней успешной загрузки. Включает HTTP/2
+func (reloader *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &reloader.current.Load().Certificates[0], nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return reloader.current.Load(), nil
+		},
+	}
+}
+
+// Reload - перечитывает сертификаты с диска. При ошибке продолжают использоваться прежние сертификаты
+func (reloader *Reloader) Reload() error {
+	reloader.mu.Lock()
+	defer reloader.mu.Unlock()
+
+	// Запоминаем состояние файлов до чтения: если файл заменят во время загрузки, он перечитается на следующей проверке
+	reloader.stamps = reloader.stat()
+
+	certificate, err := tls.LoadX509KeyPair(reloader.certFile, reloader.keyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS certificate: %w", err)
+	}
+
+	current := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+		Certificates: []tls.Certificate{certificate},
+	}
+	if reloader.clientCAFile != "" {
+		payload, err := os.ReadFile(reloader.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("load TLS client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(payload) {
+			return fmt.Errorf("load TLS client CA: no certificates in %s", reloader.clientCAFile)
+		}
+		current.ClientCAs = pool
+		current.ClientAuth = reloader.clientAuth
+	}
+	reloader.current.Store(current)
+	return nil
+}
+
+// Start - запускает проверку изменения файлов в горутине. С нулевым периодом сертификаты перечитываются только по Reload
+func (reloader *Reloader) Start() {
+	if reloader.interval <= 0 {
+		return
+	}
+	reloader.once.Do(func() {
+		go reloader.run()
+	})
+}
+
+// Stop - останавливает проверку изменения файлов
+func (reloader *Reloader) Stop() {
+	reloader.halt.Do(func() {
+		close(reloader.stop)
+		// Цикл, который не запускали, ждать не нужно
+		reloader.once.Do(func() {
+			close(reloader.done)
+		})
+		<-reloader.done
+	})
+}
+
+// run - цикл проверки изменения файлов до вызова Stop
+func (reloader *Reloader) run() {
+	defer close(reloader.done)
+
+	ticker := time.NewTicker(reloader.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-reloader.stop:
+			return
+		case <-ticker.C:
+			if !reloader.changed() {
+				continue
+			}
+			if err := reloader.Reload(); err != nil {
+				slog.Error("TLS certificate reload", "error", err.Error())
+				continue
+			}
+			slog.Info("TLS certificate reloaded", "cert", reloader.certFile)
+		}
+	}
+}
+
+// changed - проверяет, изменились ли файлы с последней перезагрузки
+func (reloader *Reloader) changed() bool {
+	stamps := reloader.stat()
+
+	reloader.mu.Lock()
+	defer reloader.mu.Unlock()
+	for i := range stamps {
+		if !stamps[i].modTime.Equal(reloader.stamps[i].modTime) || stamps[i].size != reloader.stamps[i].size {
+			return true
+		}
+	}
+	return false
+}
+
+// stat - возвращает состояние файлов сертификатов. Недоступный файл дает нулевое состояние
+func (reloader *Reloader) stat() []stamp {
+	files := []string{reloader.certFile, reloader.keyFile, reloader.clientCAFile}
+	stamps := make([]stamp, len(files))
+	for i, file := range files {
+		if file == "" {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil {
+			stamps[i] = stamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stamps
+}
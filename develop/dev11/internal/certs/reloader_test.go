@@ -0,0 +1,190 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// generate - создает самоподписанный сертификат для локального адреса в каталоге dir
+func generate(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := GenerateSelfSigned(certFile, keyFile, DevHosts, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// serve - запускает HTTPS сервер с сертификатами reloader и возвращает его адрес
+func serve(t *testing.T, reloader *Reloader) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Proto))
+		}),
+		TLSConfig: reloader.TLSConfig(),
+	}
+	go server.ServeTLS(listener, "", "")
+	t.Cleanup(func() { server.Close() })
+	return listener.Addr().String()
+}
+
+// serial - подключается к серверу и возвращает серийный номер его сертификата
+func serial(t *testing.T, addr string, config *tls.Config) (string, error) {
+	t.Helper()
+	conn, err := tls.Dial("tcp", addr, config)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.String(), nil
+}
+
+// pool - возвращает набор центров сертификатов из файла
+func pool(t *testing.T, certFile string) *x509.CertPool {
+	t.Helper()
+	payload, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(payload)
+	return pool
+}
+
+func TestReloaderHTTP2(t *testing.T) {
+	certFile, keyFile := generate(t, t.TempDir())
+	reloader, err := New(certFile, keyFile, "", tls.NoClientCert, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := serve(t, reloader)
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: pool(t, certFile)},
+		ForceAttemptHTTP2: true,
+	}}
+	resp, err := client.Get("https://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Errorf("protocol: got %s want HTTP/2.0", resp.Proto)
+	}
+}
+
+func TestReloaderReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := generate(t, dir)
+	reloader, err := New(certFile, keyFile, "", tls.NoClientCert, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := serve(t, reloader)
+	config := &tls.Config{InsecureSkipVerify: true}
+
+	first, err := serial(t, addr, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Открытое соединение переживает перезагрузку сертификата
+	conn, err := tls.Dial("tcp", addr, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Испорченный файл не заменяет рабочий сертификат
+	if err := os.WriteFile(keyFile, []byte("broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloader.Reload(); err == nil {
+		t.Errorf("broken key: got nil want error")
+	}
+	if got, err := serial(t, addr, config); err != nil || got != first {
+		t.Errorf("after failed reload: got %s, %v want %s", got, err, first)
+	}
+
+	// Новый сертификат подхватывается по изменению файлов
+	generate(t, dir)
+	reloader.Start()
+	defer reloader.Stop()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err := serial(t, addr, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != first {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("certificate was not reloaded on file change")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")); err != nil {
+		t.Errorf("open connection after reload: %v", err)
+	}
+}
+
+func TestReloaderClientAuth(t *testing.T) {
+	certFile, keyFile := generate(t, t.TempDir())
+	clientCert, clientKey := generate(t, t.TempDir())
+	client, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stranger, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		clientAuth   tls.ClientAuthType
+		certificates []tls.Certificate
+		wantErr      bool
+	}{
+		{name: "Required With Cert", clientAuth: tls.RequireAndVerifyClientCert, certificates: []tls.Certificate{client}},
+		{name: "Required Without Cert", clientAuth: tls.RequireAndVerifyClientCert, wantErr: true},
+		{name: "Required Unknown Cert", clientAuth: tls.RequireAndVerifyClientCert, certificates: []tls.Certificate{stranger}, wantErr: true},
+		{name: "Optional Without Cert", clientAuth: tls.VerifyClientCertIfGiven},
+		{name: "Optional Unknown Cert", clientAuth: tls.VerifyClientCertIfGiven, certificates: []tls.Certificate{stranger}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reloader, err := New(certFile, keyFile, clientCert, tt.clientAuth, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			addr := serve(t, reloader)
+
+			// В TLS 1.3 сервер сообщает об отклонении сертификата клиента после рукопожатия, поэтому делаем запрос
+			httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+				RootCAs:      pool(t, certFile),
+				Certificates: tt.certificates,
+			}}}
+			resp, err := httpClient.Get("https://" + addr)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
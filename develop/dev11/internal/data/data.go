@@ -1,50 +1,121 @@
 package data
 
 import (
+	"develop/dev11/config"
 	"develop/dev11/internal/errors"
 	"develop/dev11/internal/models"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 )
 
-// Eventer - интерфейс для работы с событиями
+// Eventer - интерфейс для работы с событиями и календарями, в которых они лежат
 type Eventer interface {
-	// Create создает новое событие
+	Calendarer
+
+	// Create создает новое событие. Календарь события должен принадлежать его владельцу
 	Create(newEvent *models.Event) (int, error)
-	// Update обновляет существующее событие
+	// Update обновляет существующее событие и увеличивает его версию.
+	// Если updataEvent.Version не 0, то событие обновляется, только если его текущая версия совпадает
 	Update(updataEvent *models.Event) error
-	// Delete удаляет событие
-	Delete(userID, id int) error
-	// GetFor возвращает события для за заданный период
-	GetFor(userID int, fromDate, toDate time.Time) ([]*models.Event, error)
+	// Delete перемещает событие в корзину и увеличивает его версию.
+	// Если version не 0, то событие удаляется, только если его текущая версия совпадает
+	Delete(userID, id, version int) error
+	// Restore возвращает событие из корзины и увеличивает его версию.
+	// Если календарь события удален, пока событие было в корзине, событие возвращается в календарь по умолчанию
+	Restore(userID, id int) (*models.Event, error)
+	// Trash возвращает события пользователя в корзине, начиная с удаленных последними
+	Trash(userID int) ([]*models.Event, error)
+	// Purge окончательно удаляет события, перемещенные в корзину раньше before, и возвращает их id
+	Purge(before time.Time) ([]int, error)
+	// Get возвращает событие пользователя или событие, на которое он приглашен, по id (события в корзине не возвращаются)
+	Get(userID, id int) (*models.Event, error)
+	// GetFor возвращает подходящие под фильтр события, пересекающиеся с заданным периодом, по дате и id.
+	// Повторяющиеся события возвращаются, если они начались до конца периода. События в корзине не возвращаются.
+	// Кроме событий пользователя возвращаются события, на которые он приглашен и от которых не отказался
+	GetFor(userID int, fromDate, toDate time.Time, filter models.Filter) ([]*models.Event, error)
+	// Users возвращает id всех пользователей хранилища
+	Users() ([]int, error)
+	// Stats возвращает размер хранилища
+	Stats() (Stats, error)
+	// Ready возвращает ошибку, если хранилище не готово обслуживать запросы
+	Ready() error
+	// Close освобождает ресурсы хранилища
+	Close() error
+}
+
+// Calendarer - интерфейс для работы с календарями пользователей
+type Calendarer interface {
+	// CreateCalendar создает новый календарь
+	CreateCalendar(newCalendar *models.Calendar) (int, error)
+	// UpdateCalendar изменяет существующий календарь
+	UpdateCalendar(updateCalendar *models.Calendar) error
+	// DeleteCalendar удаляет календарь, в котором нет событий (события в корзине не учитываются)
+	DeleteCalendar(userID, id int) error
+	// Calendar возвращает календарь пользователя по id
+	Calendar(userID, id int) (*models.Calendar, error)
+	// Calendars возвращает календари пользователя по возрастанию id
+	Calendars(userID int) ([]*models.Calendar, error)
+}
+
+// Stats - размер хранилища
+type Stats struct {
+	Users   int // Число пользователей
+	Events  int // Число событий (повторяющееся событие считается один раз), кроме событий в корзине
+	Trashed int // Число событий в корзине
 }
 
 // EventsData - структура для хранения событий
 type EventsData struct {
-	mu   sync.RWMutex                   // mu - мьютекс для безопасного доступа к данным
-	data map[int]map[uint]*models.Event // data - хранит события для каждого пользователя
-	id   uint                           // id - уникальный идентификатор события
+	mu         sync.RWMutex                      // mu - мьютекс для безопасного доступа к данным
+	data       map[int]map[uint]*models.Event    // data - хранит события для каждого пользователя
+	invites    map[int]map[uint]int              // invites - для каждого приглашенного пользователя id событий и их владельцы
+	calendars  map[int]map[uint]*models.Calendar // calendars - хранит календари для каждого пользователя
+	id         uint                              // id - уникальный идентификатор события
+	calendarID uint                              // calendarID - идентификатор следующего календаря, 0 - календарь по умолчанию
 }
 
 // New - конструктор EventsData
 func New() Eventer {
-	return &EventsData{data: make(map[int]map[uint]*models.Event)}
+	return newEventsData()
+}
+
+// newEventsData - создает пустое хранилище событий в памяти
+func newEventsData() *EventsData {
+	return &EventsData{
+		data:       make(map[int]map[uint]*models.Event),
+		invites:    make(map[int]map[uint]int),
+		calendars:  make(map[int]map[uint]*models.Calendar),
+		calendarID: 1,
+	}
+}
+
+// NewFromConfig - создает хранилище, выбранное в конфигурации
+func NewFromConfig(cfg config.Config) (Eventer, error) {
+	switch cfg.Storage {
+	case config.StorageFile:
+		return NewFile(cfg.StoragePath, cfg.SnapshotInterval)
+	default:
+		return New(), nil
+	}
 }
 
 // Create - создает новое событие
 func (eventsData *EventsData) Create(newEvent *models.Event) (int, error) {
 	eventsData.mu.Lock()
 	defer eventsData.mu.Unlock()
-	// Задаем id для события
-	newEvent.ID = int(eventsData.id)
-	// Создаем map для user если у него ещё не событий
-	if _, ok := eventsData.data[newEvent.UserID]; !ok {
-		eventsData.data[newEvent.UserID] = make(map[uint]*models.Event)
+
+	// Если календаря события нет, то возвращаем ошибку
+	if err := eventsData.checkEventCalendar(newEvent); err != nil {
+		return 0, err
 	}
+	// Задаем id и первую версию для события
+	newEvent.ID = int(eventsData.id)
+	newEvent.Version = 1
 
 	// Добавляем событие
-	eventsData.data[newEvent.UserID][eventsData.id] = newEvent
+	eventsData.store(newEvent)
 	// Инкрементим id
 	eventsData.id++
 	return newEvent.ID, nil
@@ -55,33 +126,56 @@ func (eventsData *EventsData) Update(updataEvent *models.Event) error {
 	eventsData.mu.Lock()
 	defer eventsData.mu.Unlock()
 
-	// Если пользователся или события нет, то возвращаем ошибку
-	if err := eventsData.checkEvent(updataEvent.UserID, updataEvent.ID); err != nil {
+	// Если пользователся или события нет или версия не совпадает, то возвращаем ошибку
+	current, err := eventsData.checkVersion(updataEvent.UserID, updataEvent.ID, updataEvent.Version)
+	if err != nil {
+		return err
+	}
+	// Если календаря события нет, то возвращаем ошибку
+	if err := eventsData.checkEventCalendar(updataEvent); err != nil {
 		return err
 	}
 
 	// Обновляем событие
-	eventsData.data[updataEvent.UserID][uint(updataEvent.ID)] = updataEvent
+	updataEvent.Version = current.Version + 1
+	eventsData.store(updataEvent)
 	return nil
 }
 
-// Delete - удаляет событие
-func (eventsData *EventsData) Delete(userID, id int) error {
+// Delete - перемещает событие в корзину
+func (eventsData *EventsData) Delete(userID, id, version int) error {
 	eventsData.mu.Lock()
 	defer eventsData.mu.Unlock()
 
-	// Если пользователся или события нет, то возвращаем ошибку
-	if err := eventsData.checkEvent(userID, id); err != nil {
+	// Если пользователся или события нет или версия не совпадает, то возвращаем ошибку
+	current, err := eventsData.checkVersion(userID, id, version)
+	if err != nil {
 		return err
 	}
 
-	// Удаляем событие
-	delete(eventsData.data[userID], uint(id))
+	// Сохраняем копию события с отметкой удаления, чтобы не менять уже выданные указатели
+	eventsData.store(trashed(current, time.Now()))
 	return nil
 }
 
-// GetFor - возвращает события для указанного пользователя за заданный период
-func (eventsData *EventsData) GetFor(userID int, fromDate, toDate time.Time) ([]*models.Event, error) {
+// Restore - возвращает событие из корзины
+func (eventsData *EventsData) Restore(userID, id int) (*models.Event, error) {
+	eventsData.mu.Lock()
+	defer eventsData.mu.Unlock()
+
+	// Если пользователя нет или события нет в корзине, то возвращаем ошибку
+	current, err := eventsData.checkTrashed(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	event := eventsData.restored(current)
+	eventsData.store(event)
+	return event, nil
+}
+
+// Trash - возвращает события пользователя в корзине, упорядоченные по времени удаления от последнего
+func (eventsData *EventsData) Trash(userID int) ([]*models.Event, error) {
 	eventsData.mu.RLock()
 	defer eventsData.mu.RUnlock()
 
@@ -90,25 +184,516 @@ func (eventsData *EventsData) GetFor(userID int, fromDate, toDate time.Time) ([]
 		return nil, errors.NewNotFoundError(fmt.Sprintf("user_id %d", userID))
 	}
 	events := make([]*models.Event, 0)
-	// Прохоимся по всем событиям пользователя
 	for _, event := range eventsData.data[userID] {
-		// Добавляем событие в слайс если date не привышает макс date и не меньше min date
-		if event.Date.Before(toDate) && !event.Date.Before(fromDate) {
+		if event.Deleted() {
 			events = append(events, event)
 		}
 	}
+	sortTrash(events)
 	return events, nil
 }
 
-// checkEvent - проверяет существование события
+// Purge - окончательно удаляет события, перемещенные в корзину раньше before, и возвращает их id
+func (eventsData *EventsData) Purge(before time.Time) ([]int, error) {
+	eventsData.mu.Lock()
+	defer eventsData.mu.Unlock()
+
+	purged := make([]int, 0)
+	for _, event := range eventsData.expired(before) {
+		eventsData.drop(event.UserID, event.ID)
+		purged = append(purged, event.ID)
+	}
+	return purged, nil
+}
+
+// Get - возвращает событие пользователя по id
+func (eventsData *EventsData) Get(userID, id int) (*models.Event, error) {
+	eventsData.mu.RLock()
+	defer eventsData.mu.RUnlock()
+
+	// Событие, на которое пользователь приглашен, доступно ему на чтение
+	if event := eventsData.invitation(userID, id); event != nil {
+		return event, nil
+	}
+	// Если пользователся или события нет, то возвращаем ошибку
+	if err := eventsData.checkEvent(userID, id); err != nil {
+		return nil, err
+	}
+	return eventsData.data[userID][uint(id)], nil
+}
+
+// GetFor - возвращает события для указанного пользователя за заданный период, упорядоченные по дате и id
+func (eventsData *EventsData) GetFor(userID int, fromDate, toDate time.Time, filter models.Filter) ([]*models.Event, error) {
+	eventsData.mu.RLock()
+	defer eventsData.mu.RUnlock()
+
+	// Если пользователся нет и его никуда не приглашали, то возвращаем ошибку
+	_, owner := eventsData.data[userID]
+	_, invited := eventsData.invites[userID]
+	if !owner && !invited {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("user_id %d", userID))
+	}
+	events := make([]*models.Event, 0)
+	add := func(event *models.Event, match func(*models.Event) bool) {
+		// События в корзине не показываются
+		if event.Deleted() {
+			return
+		}
+		// Добавляем событие в слайс если оно пересекается с периодом.
+		// Повторяющееся событие, начавшееся раньше периода, может повториться внутри него
+		if event.Recurrence != nil && event.Date.Before(toDate) || event.Overlaps(fromDate, toDate) {
+			// Отбрасываем события, не подходящие под фильтр
+			if match(event) {
+				events = append(events, event)
+			}
+		}
+	}
+	// Прохоимся по всем событиям пользователя
+	for _, event := range eventsData.data[userID] {
+		add(event, filter.Match)
+	}
+	// Добавляем события других пользователей, на которые пользователь приглашен и от которых не отказался.
+	// Календарь владельца не относится к календарям пользователя: фильтр по календарям к нему не применяется,
+	// а видимость приглашения определяет календарь владельца
+	for id, ownerID := range eventsData.invites[userID] {
+		if event := eventsData.data[ownerID][id]; event.InvitedTo(userID) {
+			calendar := eventsData.calendars[ownerID][uint(event.CalendarID)]
+			ownerHidden := calendar != nil && calendar.Visibility == models.CalendarHidden
+			add(event, func(event *models.Event) bool { return filter.MatchInvited(event, ownerHidden) })
+		}
+	}
+	// Порядок обхода map случайный, поэтому сортируем результат
+	models.SortEvents(events)
+	return events, nil
+}
+
+// Users - возвращает id всех пользователей по возрастанию
+func (eventsData *EventsData) Users() ([]int, error) {
+	eventsData.mu.RLock()
+	defer eventsData.mu.RUnlock()
+
+	users := make([]int, 0, len(eventsData.data))
+	for userID := range eventsData.data {
+		users = append(users, userID)
+	}
+	sort.Ints(users)
+	return users, nil
+}
+
+// Stats - возвращает число пользователей и событий
+func (eventsData *EventsData) Stats() (Stats, error) {
+	eventsData.mu.RLock()
+	defer eventsData.mu.RUnlock()
+
+	stats := Stats{Users: len(eventsData.data)}
+	for _, userEvents := range eventsData.data {
+		for _, event := range userEvents {
+			if event.Deleted() {
+				stats.Trashed++
+			} else {
+				stats.Events++
+			}
+		}
+	}
+	return stats, nil
+}
+
+// Ready - хранилище в памяти всегда готово
+func (eventsData *EventsData) Ready() error {
+	return nil
+}
+
+// Close - в памяти нечего освобождать, метод нужен для реализации интерфейса Eventer
+func (eventsData *EventsData) Close() error {
+	return nil
+}
+
+// CreateCalendar - создает новый календарь
+func (eventsData *EventsData) CreateCalendar(newCalendar *models.Calendar) (int, error) {
+	eventsData.mu.Lock()
+	defer eventsData.mu.Unlock()
+
+	// Задаем id календаря и добавляем его
+	newCalendar.ID = int(eventsData.calendarID)
+	eventsData.storeCalendar(newCalendar)
+	eventsData.calendarID++
+	return newCalendar.ID, nil
+}
+
+// UpdateCalendar - изменяет существующий календарь
+func (eventsData *EventsData) UpdateCalendar(updateCalendar *models.Calendar) error {
+	eventsData.mu.Lock()
+	defer eventsData.mu.Unlock()
+
+	// Если календаря нет, то возвращаем ошибку
+	if _, err := eventsData.checkCalendar(updateCalendar.UserID, updateCalendar.ID); err != nil {
+		return err
+	}
+	eventsData.storeCalendar(updateCalendar)
+	return nil
+}
+
+// DeleteCalendar - удаляет пустой календарь
+func (eventsData *EventsData) DeleteCalendar(userID, id int) error {
+	eventsData.mu.Lock()
+	defer eventsData.mu.Unlock()
+
+	// Если календаря нет или в нем есть события, то возвращаем ошибку
+	if err := eventsData.checkEmptyCalendar(userID, id); err != nil {
+		return err
+	}
+	eventsData.dropCalendar(userID, id)
+	return nil
+}
+
+// Calendar - возвращает календарь пользователя по id
+func (eventsData *EventsData) Calendar(userID, id int) (*models.Calendar, error) {
+	eventsData.mu.RLock()
+	defer eventsData.mu.RUnlock()
+
+	return eventsData.checkCalendar(userID, id)
+}
+
+// Calendars - возвращает календари пользователя по возрастанию id. У пользователя без календарей список пуст
+func (eventsData *EventsData) Calendars(userID int) ([]*models.Calendar, error) {
+	eventsData.mu.RLock()
+	defer eventsData.mu.RUnlock()
+
+	calendars := make([]*models.Calendar, 0, len(eventsData.calendars[userID]))
+	for _, calendar := range eventsData.calendars[userID] {
+		calendars = append(calendars, calendar)
+	}
+	sort.Slice(calendars, func(i, j int) bool { return calendars[i].ID < calendars[j].ID })
+	return calendars, nil
+}
+
+// put - сохраняет событие с уже заданным id (используется при восстановлении из файла)
+func (eventsData *EventsData) put(event *models.Event) {
+	eventsData.mu.Lock()
+	defer eventsData.mu.Unlock()
+
+	// События, сохраненные до появления версий, получают первую версию
+	event.Version = max(event.Version, 1)
+	eventsData.store(event)
+	// Следующий id должен быть больше любого известного
+	if uint(event.ID) >= eventsData.id {
+		eventsData.id = uint(event.ID) + 1
+	}
+}
+
+// remove - удаляет событие без проверки его существования (используется при восстановлении из файла)
+func (eventsData *EventsData) remove(userID, id int) {
+	eventsData.mu.Lock()
+	defer eventsData.mu.Unlock()
+
+	eventsData.drop(userID, id)
+}
+
+// putCalendar - сохраняет календарь с уже заданным id (используется при восстановлении из файла)
+func (eventsData *EventsData) putCalendar(calendar *models.Calendar) {
+	eventsData.mu.Lock()
+	defer eventsData.mu.Unlock()
+
+	eventsData.storeCalendar(calendar)
+	// Следующий id должен быть больше любого известного
+	if uint(calendar.ID) >= eventsData.calendarID {
+		eventsData.calendarID = uint(calendar.ID) + 1
+	}
+}
+
+// removeCalendar - удаляет календарь без проверки его существования (используется при восстановлении из файла)
+func (eventsData *EventsData) removeCalendar(userID, id int) {
+	eventsData.mu.Lock()
+	defer eventsData.mu.Unlock()
+
+	eventsData.dropCalendar(userID, id)
+}
+
+// current - возвращает событие, проверяя его существование и версию, под блокировкой на чтение
+func (eventsData *EventsData) current(userID, id, version int) (*models.Event, error) {
+	eventsData.mu.RLock()
+	defer eventsData.mu.RUnlock()
+
+	return eventsData.checkVersion(userID, id, version)
+}
+
+// store - сохраняет событие, заменяя прежнюю версию, и обновляет индекс приглашений. Вызывается под mu
+func (eventsData *EventsData) store(event *models.Event) {
+	if _, ok := eventsData.data[event.UserID]; !ok {
+		eventsData.data[event.UserID] = make(map[uint]*models.Event)
+	}
+	if previous, ok := eventsData.data[event.UserID][uint(event.ID)]; ok {
+		eventsData.unindex(previous)
+	}
+	eventsData.data[event.UserID][uint(event.ID)] = event
+	eventsData.index(event)
+}
+
+// drop - удаляет событие и его приглашения. Вызывается под mu
+func (eventsData *EventsData) drop(userID, id int) {
+	if event, ok := eventsData.data[userID][uint(id)]; ok {
+		eventsData.unindex(event)
+		delete(eventsData.data[userID], uint(id))
+	}
+}
+
+// index - добавляет приглашения события в индекс. Вызывается под mu
+func (eventsData *EventsData) index(event *models.Event) {
+	for _, attendee := range event.Attendees {
+		if _, ok := eventsData.invites[attendee.UserID]; !ok {
+			eventsData.invites[attendee.UserID] = make(map[uint]int)
+		}
+		eventsData.invites[attendee.UserID][uint(event.ID)] = event.UserID
+	}
+}
+
+// unindex - удаляет приглашения события из индекса. Вызывается под mu
+func (eventsData *EventsData) unindex(event *models.Event) {
+	for _, attendee := range event.Attendees {
+		delete(eventsData.invites[attendee.UserID], uint(event.ID))
+		if len(eventsData.invites[attendee.UserID]) == 0 {
+			delete(eventsData.invites, attendee.UserID)
+		}
+	}
+}
+
+// invitation - возвращает событие, на которое приглашен пользователь, или nil. Вызывается под mu
+func (eventsData *EventsData) invitation(userID, id int) *models.Event {
+	ownerID, ok := eventsData.invites[userID][uint(id)]
+	if !ok {
+		return nil
+	}
+	if event := eventsData.data[ownerID][uint(id)]; !event.Deleted() {
+		return event
+	}
+	return nil
+}
+
+// restoring - возвращает копию события из корзины, готовую к восстановлению, под блокировкой на чтение
+func (eventsData *EventsData) restoring(userID, id int) (*models.Event, error) {
+	eventsData.mu.RLock()
+	defer eventsData.mu.RUnlock()
+
+	current, err := eventsData.checkTrashed(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return eventsData.restored(current), nil
+}
+
+// calendarOf - проверяет, что календарь события существует, под блокировкой на чтение
+func (eventsData *EventsData) calendarOf(event *models.Event) error {
+	eventsData.mu.RLock()
+	defer eventsData.mu.RUnlock()
+
+	return eventsData.checkEventCalendar(event)
+}
+
+// calendar - возвращает календарь, проверяя его существование, под блокировкой на чтение
+func (eventsData *EventsData) calendar(userID, id int) (*models.Calendar, error) {
+	eventsData.mu.RLock()
+	defer eventsData.mu.RUnlock()
+
+	return eventsData.checkCalendar(userID, id)
+}
+
+// emptyCalendar - проверяет, что календарь существует и в нем нет событий, под блокировкой на чтение
+func (eventsData *EventsData) emptyCalendar(userID, id int) error {
+	eventsData.mu.RLock()
+	defer eventsData.mu.RUnlock()
+
+	return eventsData.checkEmptyCalendar(userID, id)
+}
+
+// storeCalendar - сохраняет календарь, заменяя прежний. Вызывается под mu
+func (eventsData *EventsData) storeCalendar(calendar *models.Calendar) {
+	if _, ok := eventsData.calendars[calendar.UserID]; !ok {
+		eventsData.calendars[calendar.UserID] = make(map[uint]*models.Calendar)
+	}
+	eventsData.calendars[calendar.UserID][uint(calendar.ID)] = calendar
+}
+
+// dropCalendar - удаляет календарь. Вызывается под mu
+func (eventsData *EventsData) dropCalendar(userID, id int) {
+	delete(eventsData.calendars[userID], uint(id))
+	if len(eventsData.calendars[userID]) == 0 {
+		delete(eventsData.calendars, userID)
+	}
+}
+
+// restored - возвращает копию события, возвращенную из корзины, со следующей версией.
+// Если календарь события уже удален, событие возвращается в календарь по умолчанию. Вызывается под mu
+func (eventsData *EventsData) restored(event *models.Event) *models.Event {
+	event = event.Clone()
+	event.DeletedAt = nil
+	event.Version++
+	if _, ok := eventsData.calendars[event.UserID][uint(event.CalendarID)]; !ok {
+		event.CalendarID = 0
+	}
+	return event
+}
+
+// expiredBefore - возвращает события, перемещенные в корзину раньше before, под блокировкой на чтение
+func (eventsData *EventsData) expiredBefore(before time.Time) []*models.Event {
+	eventsData.mu.RLock()
+	defer eventsData.mu.RUnlock()
+
+	return eventsData.expired(before)
+}
+
+// expired - возвращает события, перемещенные в корзину раньше before
+func (eventsData *EventsData) expired(before time.Time) []*models.Event {
+	events := make([]*models.Event, 0)
+	for _, userEvents := range eventsData.data {
+		for _, event := range userEvents {
+			if event.Deleted() && event.DeletedAt.Before(before) {
+				events = append(events, event)
+			}
+		}
+	}
+	return events
+}
+
+// nextID - возвращает id, который будет присвоен следующему событию
+func (eventsData *EventsData) nextID() int {
+	eventsData.mu.RLock()
+	defer eventsData.mu.RUnlock()
+
+	return int(eventsData.id)
+}
+
+// nextCalendarID - возвращает id, который будет присвоен следующему календарю
+func (eventsData *EventsData) nextCalendarID() int {
+	eventsData.mu.RLock()
+	defer eventsData.mu.RUnlock()
+
+	return int(eventsData.calendarID)
+}
+
+// addUser - регистрирует пользователя без событий (используется при восстановлении из файла)
+func (eventsData *EventsData) addUser(userID int) {
+	eventsData.mu.Lock()
+	defer eventsData.mu.Unlock()
+
+	if _, ok := eventsData.data[userID]; !ok {
+		eventsData.data[userID] = make(map[uint]*models.Event)
+	}
+}
+
+// snapshot - возвращает все события и календари, список пользователей и следующие id
+func (eventsData *EventsData) snapshot() snapshotFile {
+	eventsData.mu.RLock()
+	defer eventsData.mu.RUnlock()
+
+	snapshot := snapshotFile{
+		NextID:         int(eventsData.id),
+		NextCalendarID: int(eventsData.calendarID),
+		Users:          make([]int, 0, len(eventsData.data)),
+		Events:         make([]*models.Event, 0),
+	}
+	for userID, userEvents := range eventsData.data {
+		snapshot.Users = append(snapshot.Users, userID)
+		for _, event := range userEvents {
+			snapshot.Events = append(snapshot.Events, event)
+		}
+	}
+	for _, userCalendars := range eventsData.calendars {
+		for _, calendar := range userCalendars {
+			snapshot.Calendars = append(snapshot.Calendars, calendar)
+		}
+	}
+	return snapshot
+}
+
+// checkCalendar - проверяет существование календаря пользователя и возвращает его
+func (eventsData *EventsData) checkCalendar(userID, id int) (*models.Calendar, error) {
+	calendar, ok := eventsData.calendars[userID][uint(id)]
+	if !ok {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("calendar id %d", id))
+	}
+	return calendar, nil
+}
+
+// checkEmptyCalendar - проверяет, что календарь существует и в нем нет событий.
+// События в корзине не мешают удалению: при восстановлении они вернутся в календарь по умолчанию
+func (eventsData *EventsData) checkEmptyCalendar(userID, id int) error {
+	if _, err := eventsData.checkCalendar(userID, id); err != nil {
+		return err
+	}
+	for _, event := range eventsData.data[userID] {
+		if event.CalendarID == id && !event.Deleted() {
+			return errors.NewConflictError(fmt.Sprintf("calendar id %d has events, move or delete them first", id))
+		}
+	}
+	return nil
+}
+
+// checkEventCalendar - проверяет, что календарь события принадлежит владельцу события
+func (eventsData *EventsData) checkEventCalendar(event *models.Event) error {
+	if event.CalendarID == 0 {
+		return nil
+	}
+	if _, ok := eventsData.calendars[event.UserID][uint(event.CalendarID)]; !ok {
+		return errors.NewBadRequestError(fmt.Sprintf("unknown calendar_id %d", event.CalendarID))
+	}
+	return nil
+}
+
+// checkVersion - проверяет существование события и, если version не 0, совпадение его версии.
+// Возвращает текущее событие
+func (eventsData *EventsData) checkVersion(userID, id, version int) (*models.Event, error) {
+	if err := eventsData.checkEvent(userID, id); err != nil {
+		return nil, err
+	}
+	current := eventsData.data[userID][uint(id)]
+	if version != 0 && current.Version != version {
+		return nil, errors.NewPreconditionFailedError(fmt.Sprintf("event id %d has version %d", id, current.Version))
+	}
+	return current, nil
+}
+
+// checkEvent - проверяет существование события. Событие в корзине считается несуществующим
 func (eventsData *EventsData) checkEvent(userID, id int) error {
 	// Возвращаем ошибку если пользователя нет
 	if _, ok := eventsData.data[userID]; !ok {
 		return errors.NewNotFoundError(fmt.Sprintf("user_id %d", userID))
 	}
 	// Возвращаем ошибку если события нет
-	if _, ok := eventsData.data[userID][uint(id)]; !ok {
+	if event, ok := eventsData.data[userID][uint(id)]; !ok || event.Deleted() {
 		return errors.NewNotFoundError(fmt.Sprintf("event id %d", id))
 	}
 	return nil
 }
+
+// checkTrashed - проверяет, что событие находится в корзине, и возвращает его
+func (eventsData *EventsData) checkTrashed(userID, id int) (*models.Event, error) {
+	// Возвращаем ошибку если пользователя нет
+	if _, ok := eventsData.data[userID]; !ok {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("user_id %d", userID))
+	}
+	// Возвращаем ошибку если события нет в корзине
+	event, ok := eventsData.data[userID][uint(id)]
+	if !ok || !event.Deleted() {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("deleted event id %d", id))
+	}
+	return event, nil
+}
+
+// trashed - возвращает копию события, перемещенную в корзину в момент at, со следующей версией
+func trashed(event *models.Event, at time.Time) *models.Event {
+	deleted := event.Clone()
+	deletedAt := at.UTC()
+	deleted.DeletedAt = &deletedAt
+	deleted.Version++
+	return deleted
+}
+
+// sortTrash - сортирует события корзины по времени удаления от последнего, при совпадении - по id
+func sortTrash(events []*models.Event) {
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].DeletedAt.Equal(*events[j].DeletedAt) {
+			return events[i].DeletedAt.After(*events[j].DeletedAt)
+		}
+		return events[i].ID < events[j].ID
+	})
+}
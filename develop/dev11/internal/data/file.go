@@ -0,0 +1,480 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"develop/dev11/internal/errors"
+	"develop/dev11/internal/models"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Имена файлов внутри каталога хранилища
+const (
+	walFileName      = "events.wal"
+	snapshotFileName = "events.snapshot"
+)
+
+// Операции, которые записываются в журнал.
+// Перемещение в корзину и восстановление записываются как update, delete - окончательное удаление
+const (
+	opCreate = "create"
+	opUpdate = "update"
+	opDelete = "delete"
+
+	opPutCalendar    = "put_calendar"    // Создание или изменение календаря
+	opDeleteCalendar = "delete_calendar" // Удаление календаря
+)
+
+// walRecord - запись журнала изменений
+type walRecord struct {
+	Op       string           `json:"op"`                 // Операция
+	Event    *models.Event    `json:"event,omitempty"`    // Событие для create и update
+	Calendar *models.Calendar `json:"calendar,omitempty"` // Календарь для put_calendar
+	UserID   int              `json:"user_id,omitempty"`  // Пользователь для delete и delete_calendar
+	ID       int              `json:"id,omitempty"`       // ID события для delete или календаря для delete_calendar
+}
+
+// snapshotFile - содержимое файла снимка
+type snapshotFile struct {
+	NextID         int                `json:"next_id"`                    // Следующий id события
+	NextCalendarID int                `json:"next_calendar_id,omitempty"` // Следующий id календаря
+	Users          []int              `json:"users"`                      // Пользователи, в том числе без событий
+	Events         []*models.Event    `json:"events"`                     // Все события
+	Calendars      []*models.Calendar `json:"calendars,omitempty"`        // Все календари
+}
+
+// FileEventsData - файловое хранилище событий.
+// Каждое изменение сначала дописывается в журнал (write-ahead log), затем применяется к данным в памяти.
+// Периодически состояние сохраняется в снимок, после чего журнал очищается.
+type FileEventsData struct {
+	*EventsData // события в памяти, восстановленные из снимка и журнала
+
+	walMu sync.Mutex    // walMu - сериализует изменения, чтобы порядок в журнале совпадал с порядком в памяти
+	dir   string        // dir - каталог хранилища
+	wal   *os.File      // wal - открытый на дозапись журнал
+	stop  chan struct{} // stop - сигнал остановки периодического снимка
+	done  chan struct{} // done - закрывается после остановки периодического снимка
+}
+
+// NewFile - конструктор FileEventsData. Восстанавливает состояние из каталога dir
+// и, если snapshotInterval больше нуля, запускает периодическое сохранение снимка
+func NewFile(dir string, snapshotInterval time.Duration) (Eventer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	fileData := &FileEventsData{
+		EventsData: newEventsData(),
+		dir:        dir,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	// Восстанавливаем состояние: сначала снимок, затем изменения из журнала
+	if err := fileData.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := fileData.replayWAL(); err != nil {
+		return nil, err
+	}
+
+	wal, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	fileData.wal = wal
+
+	if snapshotInterval > 0 {
+		go fileData.snapshotLoop(snapshotInterval)
+	} else {
+		close(fileData.done)
+	}
+	return fileData, nil
+}
+
+// Create - записывает создание события в журнал и создает событие
+func (fileData *FileEventsData) Create(newEvent *models.Event) (int, error) {
+	fileData.walMu.Lock()
+	defer fileData.walMu.Unlock()
+
+	// Если календаря события нет, то возвращаем ошибку и ничего не пишем в журнал
+	if err := fileData.calendarOf(newEvent); err != nil {
+		return 0, err
+	}
+	// Пока держим walMu, id не может измениться, поэтому в журнал попадет тот же id, что и в память
+	newEvent.ID, newEvent.Version = fileData.nextID(), 1
+	if err := fileData.appendRecord(walRecord{Op: opCreate, Event: newEvent}); err != nil {
+		return 0, err
+	}
+	return fileData.EventsData.Create(newEvent)
+}
+
+// Update - записывает обновление события в журнал и обновляет событие
+func (fileData *FileEventsData) Update(updataEvent *models.Event) error {
+	fileData.walMu.Lock()
+	defer fileData.walMu.Unlock()
+
+	// Если пользователся или события нет или версия не совпадает, то возвращаем ошибку и ничего не пишем в журнал.
+	// Пока держим walMu, версия не может измениться, поэтому в журнал попадет итоговая версия
+	current, err := fileData.current(updataEvent.UserID, updataEvent.ID, updataEvent.Version)
+	if err != nil {
+		return err
+	}
+	if err := fileData.calendarOf(updataEvent); err != nil {
+		return err
+	}
+	updataEvent.Version = current.Version + 1
+	if err := fileData.appendRecord(walRecord{Op: opUpdate, Event: updataEvent}); err != nil {
+		return err
+	}
+	fileData.put(updataEvent)
+	return nil
+}
+
+// Delete - записывает перемещение события в корзину в журнал и перемещает событие
+func (fileData *FileEventsData) Delete(userID, id, version int) error {
+	fileData.walMu.Lock()
+	defer fileData.walMu.Unlock()
+
+	// Если пользователся или события нет или версия не совпадает, то возвращаем ошибку и ничего не пишем в журнал
+	current, err := fileData.current(userID, id, version)
+	if err != nil {
+		return err
+	}
+	event := trashed(current, time.Now())
+	if err := fileData.appendRecord(walRecord{Op: opUpdate, Event: event}); err != nil {
+		return err
+	}
+	fileData.put(event)
+	return nil
+}
+
+// Restore - записывает восстановление события из корзины в журнал и восстанавливает событие
+func (fileData *FileEventsData) Restore(userID, id int) (*models.Event, error) {
+	fileData.walMu.Lock()
+	defer fileData.walMu.Unlock()
+
+	// Если пользователя нет или события нет в корзине, то возвращаем ошибку и ничего не пишем в журнал
+	event, err := fileData.restoring(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := fileData.appendRecord(walRecord{Op: opUpdate, Event: event}); err != nil {
+		return nil, err
+	}
+	fileData.put(event)
+	return event, nil
+}
+
+// Purge - записывает окончательное удаление событий из корзины в журнал и удаляет их.
+// Если запись в журнал прервется, уже удаленные события останутся удаленными и вернутся вместе с ошибкой,
+// остальные - в корзине
+func (fileData *FileEventsData) Purge(before time.Time) ([]int, error) {
+	fileData.walMu.Lock()
+	defer fileData.walMu.Unlock()
+
+	purged := make([]int, 0)
+	for _, event := range fileData.expiredBefore(before) {
+		if err := fileData.appendRecord(walRecord{Op: opDelete, UserID: event.UserID, ID: event.ID}); err != nil {
+			return purged, err
+		}
+		fileData.remove(event.UserID, event.ID)
+		purged = append(purged, event.ID)
+	}
+	return purged, nil
+}
+
+// CreateCalendar - записывает создание календаря в журнал и создает календарь
+func (fileData *FileEventsData) CreateCalendar(newCalendar *models.Calendar) (int, error) {
+	fileData.walMu.Lock()
+	defer fileData.walMu.Unlock()
+
+	// Пока держим walMu, id не может измениться, поэтому в журнал попадет тот же id, что и в память
+	newCalendar.ID = fileData.nextCalendarID()
+	if err := fileData.appendRecord(walRecord{Op: opPutCalendar, Calendar: newCalendar}); err != nil {
+		return 0, err
+	}
+	fileData.putCalendar(newCalendar)
+	return newCalendar.ID, nil
+}
+
+// UpdateCalendar - записывает изменение календаря в журнал и изменяет календарь
+func (fileData *FileEventsData) UpdateCalendar(updateCalendar *models.Calendar) error {
+	fileData.walMu.Lock()
+	defer fileData.walMu.Unlock()
+
+	// Если календаря нет, то возвращаем ошибку и ничего не пишем в журнал
+	if _, err := fileData.calendar(updateCalendar.UserID, updateCalendar.ID); err != nil {
+		return err
+	}
+	if err := fileData.appendRecord(walRecord{Op: opPutCalendar, Calendar: updateCalendar}); err != nil {
+		return err
+	}
+	fileData.putCalendar(updateCalendar)
+	return nil
+}
+
+// DeleteCalendar - записывает удаление календаря в журнал и удаляет календарь
+func (fileData *FileEventsData) DeleteCalendar(userID, id int) error {
+	fileData.walMu.Lock()
+	defer fileData.walMu.Unlock()
+
+	// Если календаря нет или в нем есть события, то возвращаем ошибку и ничего не пишем в журнал
+	if err := fileData.emptyCalendar(userID, id); err != nil {
+		return err
+	}
+	if err := fileData.appendRecord(walRecord{Op: opDeleteCalendar, UserID: userID, ID: id}); err != nil {
+		return err
+	}
+	fileData.removeCalendar(userID, id)
+	return nil
+}
+
+// Close - останавливает периодический снимок, сохраняет финальный снимок и закрывает журнал
+func (fileData *FileEventsData) Close() error {
+	select {
+	case <-fileData.stop:
+		// Хранилище уже закрыто
+		return nil
+	default:
+		close(fileData.stop)
+	}
+	<-fileData.done
+
+	fileData.walMu.Lock()
+	defer fileData.walMu.Unlock()
+
+	if err := fileData.writeSnapshot(); err != nil {
+		fileData.wal.Close()
+		return err
+	}
+	return fileData.wal.Close()
+}
+
+// Ready - возвращает ошибку после закрытия хранилища
+func (fileData *FileEventsData) Ready() error {
+	select {
+	case <-fileData.stop:
+		return errors.NewInternalServerError("storage is closed")
+	default:
+		return nil
+	}
+}
+
+// Snapshot - сохраняет снимок текущего состояния и очищает журнал
+func (fileData *FileEventsData) Snapshot() error {
+	fileData.walMu.Lock()
+	defer fileData.walMu.Unlock()
+
+	return fileData.writeSnapshot()
+}
+
+// snapshotLoop - периодически сохраняет снимок до вызова Close
+func (fileData *FileEventsData) snapshotLoop(interval time.Duration) {
+	defer close(fileData.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-fileData.stop:
+			return
+		case <-ticker.C:
+			if err := fileData.Snapshot(); err != nil {
+				slog.Error("storage snapshot", "error", err.Error())
+			}
+		}
+	}
+}
+
+// appendRecord - дописывает запись в журнал и сбрасывает ее на диск.
+// Формат строки: "<crc32 в hex> <json>\n", контрольная сумма позволяет обнаружить оборванную запись
+func (fileData *FileEventsData) appendRecord(record walRecord) error {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+
+	line := fmt.Sprintf("%08x %s\n", crc32.ChecksumIEEE(payload), payload)
+	if _, err := fileData.wal.WriteString(line); err != nil {
+		return errors.NewInternalServerError("write storage log: " + err.Error())
+	}
+	if err := fileData.wal.Sync(); err != nil {
+		return errors.NewInternalServerError("sync storage log: " + err.Error())
+	}
+	return nil
+}
+
+// writeSnapshot - атомарно записывает снимок (через временный файл и rename) и очищает журнал.
+// Вызывается под walMu
+func (fileData *FileEventsData) writeSnapshot() error {
+	payload, err := json.Marshal(fileData.snapshot())
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(fileData.dir, snapshotFileName)
+	tmpPath := path + ".tmp"
+	if err := writeFileSync(tmpPath, payload); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	if err := syncDir(fileData.dir); err != nil {
+		return err
+	}
+
+	// Снимок уже содержит все изменения из журнала.
+	// Если процесс упадет до очистки журнала, повторное применение записей ничего не сломает
+	if err := fileData.wal.Truncate(0); err != nil {
+		return err
+	}
+	_, err = fileData.wal.Seek(0, io.SeekStart)
+	return err
+}
+
+// loadSnapshot - загружает снимок, если он существует
+func (fileData *FileEventsData) loadSnapshot() error {
+	payload, err := os.ReadFile(filepath.Join(fileData.dir, snapshotFileName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var snapshot snapshotFile
+	if err := json.Unmarshal(payload, &snapshot); err != nil {
+		return fmt.Errorf("storage snapshot is corrupted: %w", err)
+	}
+
+	for _, userID := range snapshot.Users {
+		fileData.addUser(userID)
+	}
+	// Календари загружаем до событий, чтобы события могли на них ссылаться
+	for _, calendar := range snapshot.Calendars {
+		fileData.putCalendar(calendar)
+	}
+	for _, event := range snapshot.Events {
+		fileData.put(event)
+	}
+	fileData.id = uint(max(int(fileData.id), snapshot.NextID))
+	fileData.calendarID = uint(max(int(fileData.calendarID), snapshot.NextCalendarID))
+	return nil
+}
+
+// replayWAL - применяет записи журнала к данным в памяти.
+// Оборванная или поврежденная запись и все, что после нее, отбрасываются, а журнал обрезается до последней целой записи
+func (fileData *FileEventsData) replayWAL() error {
+	path := filepath.Join(fileData.dir, walFileName)
+	file, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		record, decodeErr := decodeRecord(line)
+		if decodeErr != nil {
+			// Последняя запись могла не дописаться до конца при падении процесса
+			slog.Warn("storage log truncated", "error", decodeErr.Error(), "offset", offset)
+			if err := file.Truncate(offset); err != nil {
+				return err
+			}
+			return file.Sync()
+		}
+
+		fileData.apply(record)
+		offset += int64(len(line))
+	}
+}
+
+// apply - применяет запись журнала к данным в памяти
+func (fileData *FileEventsData) apply(record walRecord) {
+	switch record.Op {
+	case opCreate, opUpdate:
+		fileData.put(record.Event)
+	case opDelete:
+		fileData.remove(record.UserID, record.ID)
+	case opPutCalendar:
+		fileData.putCalendar(record.Calendar)
+	case opDeleteCalendar:
+		fileData.removeCalendar(record.UserID, record.ID)
+	}
+}
+
+// decodeRecord - разбирает строку журнала и проверяет ее контрольную сумму
+func decodeRecord(line []byte) (walRecord, error) {
+	var record walRecord
+
+	if !bytes.HasSuffix(line, []byte("\n")) {
+		return record, fmt.Errorf("torn record")
+	}
+	checksum, payload, ok := bytes.Cut(bytes.TrimSuffix(line, []byte("\n")), []byte(" "))
+	if !ok {
+		return record, fmt.Errorf("malformed record")
+	}
+	if fmt.Sprintf("%08x", crc32.ChecksumIEEE(payload)) != string(checksum) {
+		return record, fmt.Errorf("checksum mismatch")
+	}
+	if err := json.Unmarshal(payload, &record); err != nil {
+		return record, fmt.Errorf("malformed record: %w", err)
+	}
+	if (record.Op == opCreate || record.Op == opUpdate) && record.Event == nil {
+		return record, fmt.Errorf("record without event")
+	}
+	if record.Op == opPutCalendar && record.Calendar == nil {
+		return record, fmt.Errorf("record without calendar")
+	}
+	return record, nil
+}
+
+// writeFileSync - записывает файл и сбрасывает его на диск
+func writeFileSync(path string, payload []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(payload); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// syncDir - сбрасывает на диск содержимое каталога, чтобы rename пережил падение системы
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return file.Sync()
+}
@@ -0,0 +1,535 @@
+package data
+
+import (
+	"develop/dev11/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileEventsDataRestart(t *testing.T) {
+	dir := t.TempDir()
+	date := time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC)
+
+	store, err := NewFile(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Create(models.NewEvent(5, 0, date, "Test1", "Test1"))
+	store.Create(models.NewEvent(5, 0, date, "Test2", "Test2"))
+	store.Create(models.NewEvent(7, 0, date, "Test3", "Test3"))
+	store.Update(models.NewEvent(5, 1, date, "Updated", "Test2"))
+	store.Delete(7, 2, 0)
+
+	tests := []struct {
+		name   string
+		reopen func() (Eventer, error)
+	}{
+		{
+			name: "Replay Log",
+			// Журнал не очищен снимком: состояние восстанавливается только из журнала
+			reopen: func() (Eventer, error) { return NewFile(dir, 0) },
+		},
+		{
+			name: "Load Snapshot",
+			// После Close состояние восстанавливается из снимка
+			reopen: func() (Eventer, error) {
+				if err := store.Close(); err != nil {
+					return nil, err
+				}
+				return NewFile(dir, 0)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restored, err := tt.reopen()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer restored.Close()
+
+			events, err := restored.GetFor(5, date, date.AddDate(0, 0, 1), models.Filter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != 2 {
+				t.Fatalf("events: got %d want 2", len(events))
+			}
+			for _, event := range events {
+				if event.ID == 1 && (event.Title != "Updated" || event.Version != 2) {
+					t.Errorf("event 1: got %s version %d want Updated version 2", event.Title, event.Version)
+				}
+			}
+
+			// Удаленное событие пользователя осталось в корзине
+			if events, err := restored.GetFor(7, date, date.AddDate(0, 0, 1), models.Filter{}); err != nil || len(events) != 0 {
+				t.Errorf("user 7: got %v, %v want [], nil", events, err)
+			}
+			if trash, err := restored.Trash(7); err != nil || len(trash) != 1 || trash[0].ID != 2 || !trash[0].Deleted() {
+				t.Errorf("user 7 trash: got %v, %v want event 2", trash, err)
+			}
+
+			// Новое событие не должно получить уже использованный id
+			id, _ := restored.Create(models.NewEvent(5, 0, date, "Test4", "Test4"))
+			if id != 3 {
+				t.Errorf("new id: got %d want 3", id)
+			}
+		})
+	}
+}
+
+func TestFileEventsDataTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	date := time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC)
+
+	store, err := NewFile(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Create(models.NewEvent(5, 0, date, "Test1", "Test1"))
+	// Эмулируем падение процесса: журнал не закрыт, снимок не сохранен
+	store.(*FileEventsData).wal.Close()
+
+	// Дописываем оборванную запись в конец журнала
+	path := filepath.Join(dir, walFileName)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`00000000 {"op":"create","event":{"user_id":5`)
+	file.Close()
+
+	restored, err := NewFile(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := restored.GetFor(5, date, date.AddDate(0, 0, 1), models.Filter{})
+	if err != nil || len(events) != 1 {
+		t.Fatalf("events: got %v, %v want 1 event", events, err)
+	}
+
+	// После восстановления новые записи дописываются за последней целой записью
+	restored.Create(models.NewEvent(5, 0, date, "Test2", "Test2"))
+	restored.(*FileEventsData).wal.Close()
+
+	reopened, err := NewFile(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	events, err = reopened.GetFor(5, date, date.AddDate(0, 0, 1), models.Filter{})
+	if err != nil || len(events) != 2 {
+		t.Errorf("events after reopen: got %v, %v want 2 events", events, err)
+	}
+}
+
+func TestEventsDataVersion(t *testing.T) {
+	date := time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		store func(t *testing.T) Eventer
+	}{
+		{
+			name:  "Memory",
+			store: func(t *testing.T) Eventer { return New() },
+		},
+		{
+			name: "File",
+			store: func(t *testing.T) Eventer {
+				store, err := NewFile(t.TempDir(), 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return store
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.store(t)
+			defer store.Close()
+
+			event := models.NewEvent(5, 0, date, "Test1", "Test1")
+			store.Create(event)
+			if event.Version != 1 {
+				t.Fatalf("version after create: got %d want 1", event.Version)
+			}
+
+			// Обновление с текущей версией увеличивает версию
+			updated := models.NewEvent(5, 0, date, "Updated", "Test1")
+			updated.Version = 1
+			if err := store.Update(updated); err != nil || updated.Version != 2 {
+				t.Fatalf("update: got %v, version %d want nil, 2", err, updated.Version)
+			}
+
+			// Устаревшая версия отклоняется и ничего не меняет
+			stale := models.NewEvent(5, 0, date, "Stale", "Test1")
+			stale.Version = 1
+			if err := store.Update(stale); err == nil || err.Error() != "precondition failed: event id 0 has version 2" {
+				t.Errorf("stale update: got %v", err)
+			}
+			if err := store.Delete(5, 0, 1); err == nil {
+				t.Errorf("stale delete: got nil want error")
+			}
+			if current, _ := store.Get(5, 0); current.Title != "Updated" || current.Version != 2 {
+				t.Errorf("event after stale update: got %s version %d", current.Title, current.Version)
+			}
+
+			// Версия 0 означает изменение без проверки
+			unconditional := models.NewEvent(5, 0, date, "Forced", "Test1")
+			if err := store.Update(unconditional); err != nil || unconditional.Version != 3 {
+				t.Errorf("unconditional update: got %v, version %d want nil, 3", err, unconditional.Version)
+			}
+			if err := store.Delete(5, 0, 3); err != nil {
+				t.Errorf("delete: got %v want nil", err)
+			}
+		})
+	}
+}
+
+func TestEventsDataTrash(t *testing.T) {
+	date := time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		store func(t *testing.T, dir string) Eventer
+	}{
+		{
+			name:  "Memory",
+			store: func(t *testing.T, dir string) Eventer { return New() },
+		},
+		{
+			name: "File",
+			store: func(t *testing.T, dir string) Eventer {
+				store, err := NewFile(dir, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return store
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store := tt.store(t, dir)
+			defer store.Close()
+
+			store.Create(models.NewEvent(5, 0, date, "Test1", "Test1"))
+			store.Create(models.NewEvent(5, 0, date, "Test2", "Test2"))
+			if err := store.Delete(5, 0, 1); err != nil {
+				t.Fatalf("delete: got %v want nil", err)
+			}
+
+			// Событие в корзине не видно через Get и GetFor и не может быть изменено или удалено повторно
+			if _, err := store.Get(5, 0); err == nil || err.Error() != "event id 0 not found" {
+				t.Errorf("get deleted: got %v", err)
+			}
+			if events, _ := store.GetFor(5, date, date.AddDate(0, 0, 1), models.Filter{}); len(events) != 1 || events[0].ID != 1 {
+				t.Errorf("events: got %v want only event 1", events)
+			}
+			if err := store.Update(models.NewEvent(5, 0, date, "Updated", "Test1")); err == nil {
+				t.Errorf("update deleted: got nil want error")
+			}
+			if err := store.Delete(5, 0, 0); err == nil {
+				t.Errorf("delete deleted: got nil want error")
+			}
+			if stats, _ := store.Stats(); stats.Events != 1 || stats.Trashed != 1 {
+				t.Errorf("stats: got %+v want 1 event and 1 trashed", stats)
+			}
+
+			trash, err := store.Trash(5)
+			if err != nil || len(trash) != 1 || trash[0].ID != 0 || trash[0].Version != 2 || trash[0].DeletedAt == nil {
+				t.Fatalf("trash: got %v, %v want event 0 version 2", trash, err)
+			}
+
+			// Восстановленное событие снова доступно и получает новую версию
+			if _, err := store.Restore(5, 1); err == nil || err.Error() != "deleted event id 1 not found" {
+				t.Errorf("restore not deleted: got %v", err)
+			}
+			event, err := store.Restore(5, 0)
+			if err != nil || event.Version != 3 || event.Deleted() {
+				t.Fatalf("restore: got %v, %v want event 0 version 3", event, err)
+			}
+			if current, err := store.Get(5, 0); err != nil || current.Title != "Test1" {
+				t.Errorf("get restored: got %v, %v", current, err)
+			}
+
+			// Очистка удаляет только события, удаленные раньше границы
+			store.Delete(5, 1, 0)
+			if purged, err := store.Purge(time.Now().Add(-time.Hour)); err != nil || len(purged) != 0 {
+				t.Errorf("purge recent: got %v, %v want [], nil", purged, err)
+			}
+			if purged, err := store.Purge(time.Now().Add(time.Hour)); err != nil || len(purged) != 1 || purged[0] != 1 {
+				t.Errorf("purge: got %v, %v want [1], nil", purged, err)
+			}
+			if trash, _ := store.Trash(5); len(trash) != 0 {
+				t.Errorf("trash after purge: got %v want []", trash)
+			}
+			if _, err := store.Restore(5, 1); err == nil {
+				t.Errorf("restore purged: got nil want error")
+			}
+
+			// Файловое хранилище восстанавливает корзину и очистку после перезапуска
+			if tt.name == "File" {
+				store.Delete(5, 0, 0)
+				store.(*FileEventsData).wal.Close()
+				reopened, err := NewFile(dir, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer reopened.Close()
+				if trash, _ := reopened.Trash(5); len(trash) != 1 || trash[0].ID != 0 {
+					t.Errorf("trash after reopen: got %v want event 0", trash)
+				}
+				if stats, _ := reopened.Stats(); stats.Events != 0 || stats.Trashed != 1 {
+					t.Errorf("stats after reopen: got %+v want 0 events and 1 trashed", stats)
+				}
+			}
+		})
+	}
+}
+
+func TestEventsDataAttendees(t *testing.T) {
+	date := time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		store  func(t *testing.T, dir string) Eventer
+		reopen bool
+	}{
+		{
+			name:  "Memory",
+			store: func(t *testing.T, dir string) Eventer { return New() },
+		},
+		{
+			name: "File",
+			store: func(t *testing.T, dir string) Eventer {
+				store, err := NewFile(dir, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return store
+			},
+			reopen: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store := tt.store(t, dir)
+			defer store.Close()
+
+			event := models.NewEvent(5, 0, date, "Test1", "Test1")
+			event.Attendees = []models.Attendee{{UserID: 6, Status: models.RSVPNeedsAction}, {UserID: 7, Status: models.RSVPDeclined}}
+			store.Create(event)
+
+			// Приглашенный пользователь без своих событий видит событие, отказавшийся - нет
+			if events, err := store.GetFor(6, date, date.AddDate(0, 0, 1), models.Filter{}); err != nil || len(events) != 1 || events[0].UserID != 5 {
+				t.Errorf("attendee events: got %v, %v want event of user 5", events, err)
+			}
+			if events, err := store.GetFor(7, date, date.AddDate(0, 0, 1), models.Filter{}); err != nil || len(events) != 0 {
+				t.Errorf("declined attendee events: got %v, %v want []", events, err)
+			}
+			if _, err := store.GetFor(8, date, date.AddDate(0, 0, 1), models.Filter{}); err == nil {
+				t.Errorf("not invited user: got nil want error")
+			}
+			if got, err := store.Get(6, 0); err != nil || got.UserID != 5 {
+				t.Errorf("attendee get: got %v, %v want event of user 5", got, err)
+			}
+
+			// Участник не может изменить или удалить событие от своего имени
+			if err := store.Update(models.NewEvent(6, 0, date, "Updated", "Test1")); err == nil {
+				t.Errorf("attendee update: got nil want error")
+			}
+			if err := store.Delete(6, 0, 0); err == nil {
+				t.Errorf("attendee delete: got nil want error")
+			}
+
+			// Владелец убирает участника 6: событие пропадает из его календаря
+			updated := models.NewEvent(5, 0, date, "Updated", "Test1")
+			updated.Attendees = []models.Attendee{{UserID: 7, Status: models.RSVPAccepted}}
+			store.Update(updated)
+			if _, err := store.Get(6, 0); err == nil {
+				t.Errorf("removed attendee get: got nil want error")
+			}
+
+			if tt.reopen {
+				store.(*FileEventsData).wal.Close()
+				reopened, err := NewFile(dir, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer reopened.Close()
+				store = reopened
+			}
+			if events, err := store.GetFor(7, date, date.AddDate(0, 0, 1), models.Filter{}); err != nil || len(events) != 1 || events[0].Title != "Updated" {
+				t.Errorf("accepted attendee events: got %v, %v want updated event", events, err)
+			}
+
+			// Событие в корзине пропадает и из календарей участников
+			store.Delete(5, 0, 0)
+			if events, _ := store.GetFor(7, date, date.AddDate(0, 0, 1), models.Filter{}); len(events) != 0 {
+				t.Errorf("attendee events after delete: got %v want []", events)
+			}
+		})
+	}
+}
+
+func TestEventsDataInvitedCalendars(t *testing.T) {
+	date := time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC)
+	store := New()
+
+	// Владелец кладет событие с участником в свой именованный календарь
+	work, _ := store.CreateCalendar(models.NewCalendar(5, 0, "work"))
+	event := models.NewEvent(5, 0, date, "Test", "Test")
+	event.CalendarID = work
+	event.Attendees = []models.Attendee{{UserID: 6, Status: models.RSVPAccepted}}
+	store.Create(event)
+
+	tests := []struct {
+		name        string
+		ownerHidden bool
+		filter      models.Filter
+		want        int
+	}{
+		{name: "No Filter", filter: models.Filter{}, want: 1},
+		// Приглашение относится к календарю участника по умолчанию
+		{name: "Default Calendar", filter: models.Filter{Calendars: []int{0}}, want: 1},
+		// Календарь участника с тем же id, что у календаря владельца, не выбирает и не скрывает приглашение
+		{name: "Same Calendar ID", filter: models.Filter{Calendars: []int{work}}, want: 0},
+		{name: "Same Hidden Calendar ID", filter: models.Filter{Hidden: []int{work}}, want: 1},
+		// Календарь, скрытый владельцем, скрывает приглашение, пока участник явно не выберет календарь по умолчанию
+		{name: "Owner Hidden", ownerHidden: true, filter: models.Filter{}, want: 0},
+		{name: "Owner Hidden Default Calendar", ownerHidden: true, filter: models.Filter{Calendars: []int{0}}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calendar := models.NewCalendar(5, work, "work")
+			if tt.ownerHidden {
+				calendar.Visibility = models.CalendarHidden
+			}
+			if err := store.UpdateCalendar(calendar); err != nil {
+				t.Fatal(err)
+			}
+
+			events, err := store.GetFor(6, date, date.AddDate(0, 0, 1), tt.filter)
+			if err != nil || len(events) != tt.want {
+				t.Errorf("attendee events: got %v, %v want %d events", events, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventsDataCalendars(t *testing.T) {
+	date := time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		store  func(t *testing.T, dir string) Eventer
+		reopen bool
+	}{
+		{
+			name:  "Memory",
+			store: func(t *testing.T, dir string) Eventer { return New() },
+		},
+		{
+			name: "File",
+			store: func(t *testing.T, dir string) Eventer {
+				store, err := NewFile(dir, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return store
+			},
+			reopen: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store := tt.store(t, dir)
+			defer store.Close()
+
+			// Календари нумеруются с 1: 0 - календарь по умолчанию
+			work, _ := store.CreateCalendar(models.NewCalendar(5, 0, "work"))
+			onCall, _ := store.CreateCalendar(models.NewCalendar(5, 0, "on-call"))
+			if work != 1 || onCall != 2 {
+				t.Fatalf("calendar ids: got %d, %d want 1, 2", work, onCall)
+			}
+
+			// Событие нельзя положить в чужой или несуществующий календарь
+			event := models.NewEvent(7, 0, date, "Test", "Test")
+			event.CalendarID = work
+			if _, err := store.Create(event); err == nil {
+				t.Errorf("create in calendar of other user: got nil want error")
+			}
+			event = models.NewEvent(5, 0, date, "Test", "Test")
+			event.CalendarID = onCall
+			if _, err := store.Create(event); err != nil {
+				t.Fatal(err)
+			}
+
+			// Календарь с событием не удаляется, пустой - удаляется
+			if err := store.DeleteCalendar(5, onCall); err == nil {
+				t.Errorf("delete calendar with events: got nil want error")
+			}
+			if err := store.DeleteCalendar(5, work); err != nil {
+				t.Errorf("delete empty calendar: got %v want nil", err)
+			}
+			hidden := models.NewCalendar(5, onCall, "on-call")
+			hidden.Visibility = models.CalendarHidden
+			if err := store.UpdateCalendar(hidden); err != nil {
+				t.Errorf("update calendar: got %v want nil", err)
+			}
+
+			if tt.reopen {
+				store.(*FileEventsData).wal.Close()
+				reopened, err := NewFile(dir, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer reopened.Close()
+				store = reopened
+			}
+
+			calendars, err := store.Calendars(5)
+			if err != nil || len(calendars) != 1 || calendars[0].ID != onCall || calendars[0].Visibility != models.CalendarHidden {
+				t.Fatalf("calendars: got %v, %v want hidden on-call calendar", calendars, err)
+			}
+			// Нумерация календарей продолжается после удаленных
+			if id, _ := store.CreateCalendar(models.NewCalendar(5, 0, "personal")); id != 3 {
+				t.Errorf("next calendar id: got %d want 3", id)
+			}
+
+			// Событие из удаленного календаря восстанавливается в календарь по умолчанию
+			store.Delete(5, 0, 0)
+			if err := store.DeleteCalendar(5, onCall); err != nil {
+				t.Errorf("delete calendar with trashed events: got %v want nil", err)
+			}
+			restored, err := store.Restore(5, 0)
+			if err != nil || restored.CalendarID != 0 {
+				t.Errorf("restore: got %v, %v want event in default calendar", restored, err)
+			}
+
+			// Календари и их нумерация сохраняются в снимке
+			if tt.reopen {
+				store.Close()
+				reopened, err := NewFile(dir, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer reopened.Close()
+				if calendars, _ := reopened.Calendars(5); len(calendars) != 1 || calendars[0].Name != "personal" {
+					t.Errorf("calendars from snapshot: got %v want personal calendar", calendars)
+				}
+				if id, _ := reopened.CreateCalendar(models.NewCalendar(5, 0, "travel")); id != 4 {
+					t.Errorf("next calendar id from snapshot: got %d want 4", id)
+				}
+			}
+		})
+	}
+}
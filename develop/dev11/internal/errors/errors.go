@@ -1,6 +1,10 @@
 package errors
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"time"
+)
 
 // HTTPError - интерфейс для представления ошибок HTTP
 type HTTPError interface {
@@ -127,3 +131,224 @@ func (i InternalServerError) Error() string {
 func (i InternalServerError) StatusCode() int {
 	return i.statusCode
 }
+
+// NotAcceptableError - ошибка "Неприемлемый формат ответа"
+type NotAcceptableError struct {
+	accept     string // Запрошенные форматы
+	statusCode int    // Код состояния HTTP
+}
+
+// NewNotAcceptableError - конструктор для создания NotAcceptableError
+func NewNotAcceptableError(accept string) *NotAcceptableError {
+	return &NotAcceptableError{
+		accept:     accept,
+		statusCode: 406,
+	}
+}
+
+// Error возвращает текст ошибки
+func (n NotAcceptableError) Error() string {
+	return fmt.Sprintf("not acceptable: cannot produce %s", n.accept)
+}
+
+// StatusCode возвращает код ошибки
+func (n NotAcceptableError) StatusCode() int {
+	return n.statusCode
+}
+
+// UnsupportedMediaTypeError - ошибка "Неподдерживаемый тип содержимого"
+type UnsupportedMediaTypeError struct {
+	contentType string // Тип содержимого запроса
+	statusCode  int    // Код состояния HTTP
+}
+
+// NewUnsupportedMediaTypeError - конструктор для создания UnsupportedMediaTypeError
+func NewUnsupportedMediaTypeError(contentType string) *UnsupportedMediaTypeError {
+	return &UnsupportedMediaTypeError{
+		contentType: contentType,
+		statusCode:  415,
+	}
+}
+
+// Error возвращает текст ошибки
+func (u UnsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("unsupported media type %q: use application/json or application/x-www-form-urlencoded", u.contentType)
+}
+
+// StatusCode возвращает код ошибки
+func (u UnsupportedMediaTypeError) StatusCode() int {
+	return u.statusCode
+}
+
+// UnauthorizedError - ошибка "Требуется аутентификация"
+type UnauthorizedError struct {
+	err        string // Описание ошибки
+	statusCode int    // Код состояния HTTP
+}
+
+// NewUnauthorizedError - конструктор для создания UnauthorizedError
+func NewUnauthorizedError(err string) *UnauthorizedError {
+	return &UnauthorizedError{
+		err:        err,
+		statusCode: 401,
+	}
+}
+
+// Error возвращает текст ошибки
+func (u UnauthorizedError) Error() string {
+	return fmt.Sprintf("unauthorized: %s", u.err)
+}
+
+// StatusCode возвращает код ошибки
+func (u UnauthorizedError) StatusCode() int {
+	return u.statusCode
+}
+
+// ForbiddenError - ошибка "Доступ запрещен"
+type ForbiddenError struct {
+	err        string // Описание ошибки
+	statusCode int    // Код состояния HTTP
+}
+
+// NewForbiddenError - конструктор для создания ForbiddenError
+func NewForbiddenError(err string) *ForbiddenError {
+	return &ForbiddenError{
+		err:        err,
+		statusCode: 403,
+	}
+}
+
+// Error возвращает текст ошибки
+func (f ForbiddenError) Error() string {
+	return fmt.Sprintf("forbidden: %s", f.err)
+}
+
+// StatusCode возвращает код ошибки
+func (f ForbiddenError) StatusCode() int {
+	return f.statusCode
+}
+
+// ConflictError - ошибка "Конфликт"
+type ConflictError struct {
+	err        string // Описание ошибки
+	statusCode int    // Код состояния HTTP
+}
+
+// NewConflictError - конструктор для создания ConflictError
+func NewConflictError(err string) *ConflictError {
+	return &ConflictError{
+		err:        err,
+		statusCode: 409,
+	}
+}
+
+// Error возвращает текст ошибки
+func (c ConflictError) Error() string {
+	return fmt.Sprintf("conflict: %s", c.err)
+}
+
+// StatusCode возвращает код ошибки
+func (c ConflictError) StatusCode() int {
+	return c.statusCode
+}
+
+// TooManyRequestsError - ошибка "Слишком много запросов"
+type TooManyRequestsError struct {
+	retryAfter time.Duration // Через сколько можно повторить запрос
+	statusCode int           // Код состояния HTTP
+}
+
+// NewTooManyRequestsError - конструктор для создания TooManyRequestsError
+func NewTooManyRequestsError(retryAfter time.Duration) *TooManyRequestsError {
+	return &TooManyRequestsError{
+		retryAfter: retryAfter,
+		statusCode: 429,
+	}
+}
+
+// Error возвращает текст ошибки
+func (t TooManyRequestsError) Error() string {
+	return fmt.Sprintf("too many requests: retry after %ds", t.RetryAfterSeconds())
+}
+
+// StatusCode возвращает код ошибки
+func (t TooManyRequestsError) StatusCode() int {
+	return t.statusCode
+}
+
+// RetryAfterSeconds возвращает значение заголовка Retry-After: целое число секунд, не меньше 1
+func (t TooManyRequestsError) RetryAfterSeconds() int {
+	return max(int(math.Ceil(t.retryAfter.Seconds())), 1)
+}
+
+// PayloadTooLargeError - ошибка "Слишком большое тело запроса"
+type PayloadTooLargeError struct {
+	limit      int64 // Максимальный размер тела в байтах
+	statusCode int   // Код состояния HTTP
+}
+
+// NewPayloadTooLargeError - конструктор для создания PayloadTooLargeError
+func NewPayloadTooLargeError(limit int64) *PayloadTooLargeError {
+	return &PayloadTooLargeError{
+		limit:      limit,
+		statusCode: 413,
+	}
+}
+
+// Error возвращает текст ошибки
+func (p PayloadTooLargeError) Error() string {
+	return fmt.Sprintf("payload too large: maximum %d bytes", p.limit)
+}
+
+// StatusCode возвращает код ошибки
+func (p PayloadTooLargeError) StatusCode() int {
+	return p.statusCode
+}
+
+// PreconditionFailedError - ошибка "Условие запроса не выполнено"
+type PreconditionFailedError struct {
+	err        string // Описание ошибки
+	statusCode int    // Код состояния HTTP
+}
+
+// NewPreconditionFailedError - конструктор для создания PreconditionFailedError
+func NewPreconditionFailedError(err string) *PreconditionFailedError {
+	return &PreconditionFailedError{
+		err:        err,
+		statusCode: 412,
+	}
+}
+
+// Error возвращает текст ошибки
+func (p PreconditionFailedError) Error() string {
+	return fmt.Sprintf("precondition failed: %s", p.err)
+}
+
+// StatusCode возвращает код ошибки
+func (p PreconditionFailedError) StatusCode() int {
+	return p.statusCode
+}
+
+// PreconditionRequiredError - ошибка "Требуется условие запроса"
+type PreconditionRequiredError struct {
+	err        string // Описание ошибки
+	statusCode int    // Код состояния HTTP
+}
+
+// NewPreconditionRequiredError - конструктор для создания PreconditionRequiredError
+func NewPreconditionRequiredError(err string) *PreconditionRequiredError {
+	return &PreconditionRequiredError{
+		err:        err,
+		statusCode: 428,
+	}
+}
+
+// Error возвращает текст ошибки
+func (p PreconditionRequiredError) Error() string {
+	return fmt.Sprintf("precondition required: %s", p.err)
+}
+
+// StatusCode возвращает код ошибки
+func (p PreconditionRequiredError) StatusCode() int {
+	return p.statusCode
+}
@@ -0,0 +1,301 @@
+package handler
+
+import (
+	"develop/dev11/internal/errors"
+	"develop/dev11/internal/ical"
+	"develop/dev11/internal/models"
+	"develop/dev11/internal/service"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// apiEvents обрабатывает /api/v1/users/{id}/events: GET - список событий, POST - создание события
+func (h *Handler) apiEvents(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathInt(r, "id")
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.apiListEvents(w, r, userID)
+	case http.MethodPost:
+		h.apiCreateEvent(w, r, userID)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, "GET or POST"), http.StatusMethodNotAllowed)
+	}
+}
+
+// apiEvent обрабатывает /api/v1/users/{id}/events/{eventID}: GET - событие, PUT - замена,
+// PATCH - изменение переданных полей, DELETE - удаление
+func (h *Handler) apiEvent(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathInt(r, "id")
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	eventID, err := pathInt(r, "eventID")
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.apiGetEvent(w, r, userID, eventID)
+	case http.MethodPut:
+		h.apiReplaceEvent(w, r, userID, eventID)
+	case http.MethodPatch:
+		h.apiPatchEvent(w, r, userID, eventID)
+	case http.MethodDelete:
+		h.apiDeleteEvent(w, r, userID, eventID)
+	default:
+		w.Header().Set("Allow", "GET, PUT, PATCH, DELETE")
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, "GET, PUT, PATCH or DELETE"), http.StatusMethodNotAllowed)
+	}
+}
+
+// apiListEvents возвращает события пользователя в JSON или iCalendar.
+// С параметром date (и mode=day|week|month) возвращаются экземпляры событий за период, без него - все события.
+// Параметр tz задает часовой пояс границ периода и времени в ответе, title, description и q - фильтр,
+// limit и cursor - страницу (курсор следующей страницы возвращается в заголовке X-Next-Cursor)
+func (h *Handler) apiListEvents(w http.ResponseWriter, r *http.Request, userID int) {
+	format, err := negotiate(r, contentTypeJSON, contentTypeCalendar)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusNotAcceptable)
+		return
+	}
+
+	location, err := parseQueryLocation(r)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	query, err := parseEventsQuery(r)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var page service.Page
+	if r.URL.Query().Get("date") != "" {
+		date, err := parseQueryDay(r, location)
+		if err != nil {
+			responsErrorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+		mode := r.URL.Query().Get("mode")
+		switch mode {
+		case "":
+			mode = service.ModeForDay
+		case service.ModeForDay, service.ModeForWeek, service.ModeForMonth:
+		default:
+			responsErrorJSON(w, errors.NewBadRequestError("invalid mode: must be day, week or month"), http.StatusBadRequest)
+			return
+		}
+		page, err = h.service.GetFor(userID, date, mode, query)
+	} else {
+		page, err = h.service.GetAll(userID, query)
+	}
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	setNextCursor(w, page)
+	if format == contentTypeCalendar {
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if err := ical.Encode(w, page.Events); err != nil {
+			slog.ErrorContext(r.Context(), "apiListEvents", "error", err.Error())
+		}
+		return
+	}
+	responsJSON(w, eventsIn(page.Events, location), http.StatusOK)
+}
+
+// apiCreateEvent создает событие пользователя из JSON или form тела запроса
+func (h *Handler) apiCreateEvent(w http.ResponseWriter, r *http.Request, userID int) {
+	if err := checkAPIRequest(r); err != nil {
+		responsError(w, err)
+		return
+	}
+
+	// Проверяем наличие необходимых параметров в теле запроса
+	if err := checkPostRequrst(r, "date", "title"); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Парсим и проверяем событие, пользователь берется из пути
+	event, err := parseEventFields(r, userID, 0)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if err := event.Validate(); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	conflicts, err := h.checkConflicts(r, event, false)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	if _, err := h.service.Create(event); err != nil {
+		responsError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/users/%d/events/%d", userID, event.ID))
+	setETag(w, event)
+	responsJSONWithConflicts(w, event, conflicts, http.StatusCreated)
+}
+
+// apiGetEvent возвращает событие пользователя
+func (h *Handler) apiGetEvent(w http.ResponseWriter, r *http.Request, userID, eventID int) {
+	format, err := negotiate(r, contentTypeJSON, contentTypeCalendar)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusNotAcceptable)
+		return
+	}
+
+	event, err := h.service.Get(userID, eventID)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	setETag(w, event)
+	if format == contentTypeCalendar {
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if err := ical.Encode(w, []*models.Event{event}); err != nil {
+			slog.ErrorContext(r.Context(), "apiGetEvent", "error", err.Error())
+		}
+		return
+	}
+	responsJSON(w, event, http.StatusOK)
+}
+
+// apiReplaceEvent заменяет событие пользователя целиком
+func (h *Handler) apiReplaceEvent(w http.ResponseWriter, r *http.Request, userID, eventID int) {
+	if err := checkAPIRequest(r); err != nil {
+		responsError(w, err)
+		return
+	}
+
+	// Проверяем наличие необходимых параметров в теле запроса
+	if err := checkPostRequrst(r, "date", "title"); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Парсим и проверяем событие, пользователь и id берутся из пути
+	event, err := parseEventFields(r, userID, eventID)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	// Прошедшую дату можно оставить как есть, но нельзя перенести событие в прошлое
+	current, err := h.service.Get(userID, eventID)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+	if err := event.ValidateChange(current); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if event.Version, err = h.parseVersion(r, userID, eventID); err != nil {
+		responsError(w, err)
+		return
+	}
+
+	conflicts, err := h.checkConflicts(r, event, true)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	if err := h.service.Update(event); err != nil {
+		responsError(w, err)
+		return
+	}
+	setETag(w, event)
+	responsJSONWithConflicts(w, event, conflicts, http.StatusOK)
+}
+
+// apiPatchEvent изменяет только переданные поля события пользователя
+func (h *Handler) apiPatchEvent(w http.ResponseWriter, r *http.Request, userID, eventID int) {
+	if err := checkAPIRequest(r); err != nil {
+		responsError(w, err)
+		return
+	}
+	version, err := h.parseVersion(r, userID, eventID)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	event, err := h.mergeEvent(r, userID, eventID, version)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	conflicts, err := h.checkConflicts(r, event, true)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	if err := h.service.Update(event); err != nil {
+		responsError(w, err)
+		return
+	}
+	setETag(w, event)
+	responsJSONWithConflicts(w, event, conflicts, http.StatusOK)
+}
+
+// apiDeleteEvent удаляет событие пользователя
+func (h *Handler) apiDeleteEvent(w http.ResponseWriter, r *http.Request, userID, eventID int) {
+	version, err := h.parseVersion(r, userID, eventID)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+	if err := h.service.Delete(userID, eventID, version); err != nil {
+		responsError(w, err)
+		return
+	}
+	responsJSON(w, "OK", http.StatusOK)
+}
+
+// checkAPIRequest проверяет, что тело запроса передано в поддерживаемом формате
+// и что клиент принимает JSON в ответ
+func checkAPIRequest(r *http.Request) error {
+	switch mediaType(r) {
+	case contentTypeJSON, contentTypeForm:
+	default:
+		return errors.NewUnsupportedMediaTypeError(r.Header.Get("Content-Type"))
+	}
+	_, err := negotiate(r, contentTypeJSON)
+	return err
+}
+
+// pathInt извлекает целочисленный параметр пути
+func pathInt(r *http.Request, name string) (int, error) {
+	value, err := strconv.Atoi(r.PathValue(name))
+	if err != nil {
+		return 0, errors.NewBadRequestError(fmt.Sprintf("invalid %s: use only numbers", name))
+	}
+	return value, nil
+}
@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"develop/dev11/internal/errors"
+	"develop/dev11/internal/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// createCalendar обрабатывает запрос на создание календаря: name, color (#RRGGBB) и visibility (visible или hidden)
+func (h *Handler) createCalendar(w http.ResponseWriter, r *http.Request) {
+	// Проверяем метод запроса на соответствие POST
+	if r.Method != http.MethodPost {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodPost), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Проверяем наличие необходимых параметров в теле POST запроса
+	if err := checkPostRequrst(r, "user_id", "name"); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем и проверяем целочисленное значение user_id из тела POST запроса
+	userID, err := strconv.Atoi(r.PostFormValue("user_id"))
+	if err != nil {
+		responsErrorJSON(w, errors.NewBadRequestError("invalid user_id: use only numbers"), http.StatusBadRequest)
+		return
+	}
+
+	// Парсим и проверяем календарь, незаданные цвет и видимость остаются по умолчанию
+	calendar := parseCalendarFields(r, models.NewCalendar(userID, 0, ""))
+	if err := calendar.Validate(); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Создаем календарь через service
+	calendarID, err := h.service.CreateCalendar(calendar)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	// Возвращаем успешный ответ с ID созданного календаря
+	responsJSON(w, map[string]any{"calendarID": calendarID}, http.StatusCreated)
+}
+
+// updateCalendar обрабатывает запрос на изменение календаря: меняются только переданные name, color и visibility
+func (h *Handler) updateCalendar(w http.ResponseWriter, r *http.Request) {
+	// Проверяем метод запроса на соответствие POST
+	if r.Method != http.MethodPost {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodPost), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Проверяем наличие необходимых параметров в теле POST запроса
+	if err := checkPostRequrst(r, "user_id", "id"); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем и проверяем целочисленные значения user_id и id из тела POST запроса
+	userID, id, err := parseCalendarIDs(r)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Изменяем календарь через service
+	if _, err := h.replaceCalendar(r, userID, id); err != nil {
+		responsError(w, err)
+		return
+	}
+
+	// Возвращаем успешный ответ
+	responsJSON(w, "OK", http.StatusOK)
+}
+
+// deleteCalendar обрабатывает запрос на удаление календаря, в котором нет событий
+func (h *Handler) deleteCalendar(w http.ResponseWriter, r *http.Request) {
+	// Проверяем метод запроса на соответствие POST
+	if r.Method != http.MethodPost {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodPost), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Проверяем наличие необходимых параметров в теле POST запроса
+	if err := checkPostRequrst(r, "user_id", "id"); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем и проверяем целочисленные значения user_id и id из тела POST запроса
+	userID, id, err := parseCalendarIDs(r)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Удаляем календарь через service
+	if err := h.service.DeleteCalendar(userID, id); err != nil {
+		responsError(w, err)
+		return
+	}
+
+	// Возвращаем успешный ответ
+	responsJSON(w, "OK", http.StatusOK)
+}
+
+// getCalendars обрабатывает запрос на получение календарей пользователя
+func (h *Handler) getCalendars(w http.ResponseWriter, r *http.Request) {
+	// Проверяем метод запроса на соответствие GET
+	if r.Method != http.MethodGet {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodGet), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Проверяем наличие необходимых параметров в запросе
+	if err := checkGetRequrst(r, "user_id"); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем и проверяем целочисленное значение user_id из строки запроса
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil {
+		responsErrorJSON(w, errors.NewBadRequestError("invalid user_id: use only numbers"), http.StatusBadRequest)
+		return
+	}
+
+	// Получаем календари через service
+	calendars, err := h.service.Calendars(userID)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	// Возвращаем календари в формате JSON по возрастанию id
+	responsJSON(w, calendars, http.StatusOK)
+}
+
+// apiCalendars обрабатывает /api/v1/users/{id}/calendars: GET - список календарей, POST - создание календаря
+func (h *Handler) apiCalendars(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathInt(r, "id")
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		calendars, err := h.service.Calendars(userID)
+		if err != nil {
+			responsError(w, err)
+			return
+		}
+		responsJSON(w, calendars, http.StatusOK)
+	case http.MethodPost:
+		if err := checkAPIRequest(r); err != nil {
+			responsError(w, err)
+			return
+		}
+		if err := checkPostRequrst(r, "name"); err != nil {
+			responsErrorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+		calendar := parseCalendarFields(r, models.NewCalendar(userID, 0, ""))
+		if err := calendar.Validate(); err != nil {
+			responsErrorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+		if _, err := h.service.CreateCalendar(calendar); err != nil {
+			responsError(w, err)
+			return
+		}
+		w.Header().Set("Location", fmt.Sprintf("/api/v1/users/%d/calendars/%d", userID, calendar.ID))
+		responsJSON(w, calendar, http.StatusCreated)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, "GET or POST"), http.StatusMethodNotAllowed)
+	}
+}
+
+// apiCalendar обрабатывает /api/v1/users/{id}/calendars/{calendarID}: GET - календарь,
+// PATCH - изменение переданных полей, DELETE - удаление календаря без событий
+func (h *Handler) apiCalendar(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathInt(r, "id")
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	calendarID, err := pathInt(r, "calendarID")
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		calendar, err := h.service.Calendar(userID, calendarID)
+		if err != nil {
+			responsError(w, err)
+			return
+		}
+		responsJSON(w, calendar, http.StatusOK)
+	case http.MethodPatch:
+		if err := checkAPIRequest(r); err != nil {
+			responsError(w, err)
+			return
+		}
+		calendar, err := h.replaceCalendar(r, userID, calendarID)
+		if err != nil {
+			responsError(w, err)
+			return
+		}
+		responsJSON(w, calendar, http.StatusOK)
+	case http.MethodDelete:
+		if err := h.service.DeleteCalendar(userID, calendarID); err != nil {
+			responsError(w, err)
+			return
+		}
+		responsJSON(w, "OK", http.StatusOK)
+	default:
+		w.Header().Set("Allow", "GET, PATCH, DELETE")
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, "GET, PATCH or DELETE"), http.StatusMethodNotAllowed)
+	}
+}
+
+// replaceCalendar - изменяет в календаре пользователя переданные в запросе поля и сохраняет его через service
+func (h *Handler) replaceCalendar(r *http.Request, userID, id int) (*models.Calendar, error) {
+	current, err := h.service.Calendar(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	// Меняем копию, чтобы не затронуть календарь в хранилище до проверки
+	calendar := *current
+	parseCalendarFields(r, &calendar)
+	if err := calendar.Validate(); err != nil {
+		return nil, err
+	}
+	if err := h.service.UpdateCalendar(&calendar); err != nil {
+		return nil, err
+	}
+	return &calendar, nil
+}
+
+// parseCalendarIDs - функция для парсинга user_id и id календаря из тела запроса
+func parseCalendarIDs(r *http.Request) (int, int, error) {
+	userID, err := strconv.Atoi(r.PostFormValue("user_id"))
+	if err != nil {
+		return 0, 0, errors.NewBadRequestError("invalid user_id: use only numbers")
+	}
+	id, err := strconv.Atoi(r.PostFormValue("id"))
+	if err != nil {
+		return 0, 0, errors.NewBadRequestError("invalid id: use only numbers")
+	}
+	return userID, id, nil
+}
+
+// parseCalendarFields - функция для переноса переданных в теле запроса name, color и visibility в календарь.
+// Незаданные поля сохраняют прежние значения
+func parseCalendarFields(r *http.Request, calendar *models.Calendar) *models.Calendar {
+	if supplied(r, "name") {
+		calendar.Name = strings.TrimSpace(r.PostFormValue("name"))
+	}
+	if value := strings.TrimSpace(r.PostFormValue("color")); value != "" {
+		calendar.Color = strings.ToLower(value)
+	}
+	if value := strings.TrimSpace(r.PostFormValue("visibility")); value != "" {
+		calendar.Visibility = strings.ToLower(value)
+	}
+	return calendar
+}
@@ -32,6 +32,13 @@ func (h *Handler) createEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Ищем пересекающиеся события пользователя
+	conflicts, err := h.checkConflicts(r, createEvent, false)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
 	// Создаем событие через service
 	eventID, err := h.service.Create(createEvent)
 	if err != nil {
@@ -44,6 +51,7 @@ func (h *Handler) createEvent(w http.ResponseWriter, r *http.Request) {
 		responsErrorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
-	// Возвращаем успешный ответ с ID созданного события
-	responsJSON(w, map[string]any{"eventID": eventID}, http.StatusCreated)
+	// Возвращаем успешный ответ с ID созданного события, версия передается в ETag
+	setETag(w, createEvent)
+	responsJSONWithConflicts(w, map[string]any{"eventID": eventID}, conflicts, http.StatusCreated)
 }
@@ -6,7 +6,7 @@ import (
 	"strconv"
 )
 
-// deleteEvent обрабатывает запрос на удаление события
+// deleteEvent обрабатывает запрос на удаление события (событие перемещается в корзину)
 func (h *Handler) deleteEvent(w http.ResponseWriter, r *http.Request) {
 	// Проверяем метод запроса на соответствие POST
 	if r.Method != http.MethodPost {
@@ -32,8 +32,15 @@ func (h *Handler) deleteEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Удаляем событие через service
-	err = h.service.Delete(userID, id)
+	// Событие удаляется, только если клиент видел его текущую версию
+	version, err := h.parseVersion(r, userID, id)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	// Перемещаем событие в корзину через service
+	err = h.service.Delete(userID, id, version)
 	if err != nil {
 		// Если произошла ошибка, проверяем, соответствует ли она ошибке нашего интерфейса (HTTPError)
 		if httpError, ok := err.(errors.HTTPError); ok {
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"develop/dev11/internal/errors"
+	"net/http"
+	"strconv"
+)
+
+// getEvents обрабатывает запрос на получение событий пользователя в произвольном периоде [from, to)
+func (h *Handler) getEvents(w http.ResponseWriter, r *http.Request) {
+	// Проверяем метод запроса на соответствие GET
+	if r.Method != http.MethodGet {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodGet), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Проверяем наличие необходимых параметров в запросе
+	if err := checkGetRequrst(r, "user_id", "from", "to"); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем и проверяем целочисленное значение user_id из строки запроса
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil {
+		responsErrorJSON(w, errors.NewBadRequestError("invalid user_id: use only numbers"), http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем часовой пояс, в котором заданы границы периода
+	location, err := parseQueryLocation(r)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем и проверяем границы периода
+	fromDate, toDate, err := parseRange(r, location)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем фильтр и параметры страницы
+	query, err := parseEventsQuery(r)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Получаем события за период через service
+	page, err := h.service.GetRange(userID, fromDate, toDate, query)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	// Возвращаем страницу событий в формате JSON во времени запрошенного часового пояса, курсор следующей страницы - в заголовке
+	setNextCursor(w, page)
+	responsJSON(w, eventsIn(page.Events, location), http.StatusOK)
+}
@@ -5,7 +5,6 @@ import (
 	"develop/dev11/internal/service"
 	"net/http"
 	"strconv"
-	"time"
 )
 
 // getEventsForMonth обрабатывает запрос на получение событий на месяц
@@ -26,14 +25,28 @@ func (h *Handler) getEventsForMonth(w http.ResponseWriter, r *http.Request) {
 		responsErrorJSON(w, errors.NewBadRequestError("invalid user_id: use only numbers"), http.StatusBadRequest)
 		return
 	}
+	// Извлекаем часовой пояс, в котором считаются границы периода
+	location, err := parseQueryLocation(r)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
 	// Извлекаем и парсим дату из строки запроса
-	date, err := time.Parse(time.DateOnly, r.URL.Query().Get("date"))
+	date, err := parseQueryDay(r, location)
 	if err != nil {
-		responsErrorJSON(w, errors.NewBadRequestError("invalid date format: correct format 2006-01-02"), http.StatusBadRequest)
+		responsErrorJSON(w, err, http.StatusBadRequest)
 		return
 	}
+	// Извлекаем фильтр и параметры страницы
+	query, err := parseEventsQuery(r)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
 	// Получаем события для указанного пользователя и даты через service
-	events, err := h.service.GetFor(userID, date, service.ModeForMonth)
+	page, err := h.service.GetFor(userID, date, service.ModeForMonth, query)
 	if err != nil {
 		// Если произошла ошибка, проверяем, соответствует ли она ошибке нашего интерфейса (HTTPError)
 		if httpError, ok := err.(errors.HTTPError); ok {
@@ -44,6 +57,7 @@ func (h *Handler) getEventsForMonth(w http.ResponseWriter, r *http.Request) {
 		responsErrorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
-	// Возвращаем полученные события в формате JSON
-	responsJSON(w, events, http.StatusOK)
+	// Возвращаем страницу событий в формате JSON во времени запрошенного часового пояса, курсор следующей страницы - в заголовке
+	setNextCursor(w, page)
+	responsJSON(w, eventsIn(page.Events, location), http.StatusOK)
 }
@@ -5,7 +5,6 @@ import (
 	"develop/dev11/internal/service"
 	"net/http"
 	"strconv"
-	"time"
 )
 
 // getEventsForWeek обрабатывает запрос на получение событий на 7 дней
@@ -26,15 +25,29 @@ func (h *Handler) getEventsForWeek(w http.ResponseWriter, r *http.Request) {
 		responsErrorJSON(w, errors.NewBadRequestError("invalid user_id: use only numbers"), http.StatusBadRequest)
 		return
 	}
+	// Извлекаем часовой пояс, в котором считаются границы периода
+	location, err := parseQueryLocation(r)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
 	// Извлекаем и парсим дату из строки запроса
-	date, err := time.Parse(time.DateOnly, r.URL.Query().Get("date"))
+	date, err := parseQueryDay(r, location)
 	if err != nil {
-		responsErrorJSON(w, errors.NewBadRequestError("invalid date format: correct format 2006-01-02"), http.StatusBadRequest)
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем фильтр и параметры страницы
+	query, err := parseEventsQuery(r)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
 		return
 	}
 
 	// Получаем события для указанного пользователя и даты через service
-	events, err := h.service.GetFor(userID, date, service.ModeForWeek)
+	page, err := h.service.GetFor(userID, date, service.ModeForWeek, query)
 	if err != nil {
 		// Если произошла ошибка, проверяем, соответствует ли она ошибке нашего интерфейса (HTTPError)
 		if httpError, ok := err.(errors.HTTPError); ok {
@@ -45,6 +58,7 @@ func (h *Handler) getEventsForWeek(w http.ResponseWriter, r *http.Request) {
 		responsErrorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
-	// Возвращаем полученные события в формате JSON
-	responsJSON(w, events, http.StatusOK)
+	// Возвращаем страницу событий в формате JSON во времени запрошенного часового пояса, курсор следующей страницы - в заголовке
+	setNextCursor(w, page)
+	responsJSON(w, eventsIn(page.Events, location), http.StatusOK)
 }
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"develop/dev11/internal/errors"
+	"develop/dev11/internal/ical"
+	"develop/dev11/internal/models"
+	"develop/dev11/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// exportICS обрабатывает запрос на выгрузку событий пользователя в формате iCalendar
+func (h *Handler) exportICS(w http.ResponseWriter, r *http.Request) {
+	// Проверяем метод запроса на соответствие GET
+	if r.Method != http.MethodGet {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodGet), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Проверяем наличие необходимых параметров в запросе
+	if err := checkGetRequrst(r, "user_id"); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем и проверяем целочисленное значение user_id из строки запроса
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil {
+		responsErrorJSON(w, errors.NewBadRequestError("invalid user_id: use only numbers"), http.StatusBadRequest)
+		return
+	}
+
+	// Экспортируются выбранные календари (calendars=1,2), по умолчанию - все, кроме скрытых
+	calendars, err := parseCalendars(r.URL.Query().Get("calendars"))
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Получаем все события пользователя через service, экспорт не разбивается на страницы
+	page, err := h.service.GetAll(userID, service.Query{Filter: models.Filter{Calendars: calendars}})
+	if err != nil {
+		// Если произошла ошибка, проверяем, соответствует ли она ошибке нашего интерфейса (HTTPError)
+		if httpError, ok := err.(errors.HTTPError); ok {
+			responsErrorJSON(w, httpError, httpError.StatusCode())
+			return
+		}
+		// Если это другая ошибка, возвращаем внутреннюю серверную ошибку
+		responsErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// Отправляем календарь как файл
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="calendar.ics"`)
+	w.WriteHeader(http.StatusOK)
+	if err := ical.Encode(w, page.Events); err != nil {
+		slog.ErrorContext(r.Context(), "exportICS", "error", err.Error())
+	}
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"develop/dev11/internal/errors"
+	"net/http"
+	"strconv"
+)
+
+// getFreeBusy обрабатывает запрос на получение занятых промежутков пользователя в периоде [from, to)
+func (h *Handler) getFreeBusy(w http.ResponseWriter, r *http.Request) {
+	// Проверяем метод запроса на соответствие GET
+	if r.Method != http.MethodGet {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodGet), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Проверяем наличие необходимых параметров в запросе
+	if err := checkGetRequrst(r, "user_id", "from", "to"); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем и проверяем целочисленное значение user_id из строки запроса
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil {
+		responsErrorJSON(w, errors.NewBadRequestError("invalid user_id: use only numbers"), http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем часовой пояс, в котором заданы границы периода
+	location, err := parseQueryLocation(r)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем и проверяем границы периода
+	fromDate, toDate, err := parseRange(r, location)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Получаем занятые промежутки через service
+	busy, err := h.service.FreeBusy(userID, fromDate, toDate)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	// Возвращаем занятые промежутки в формате JSON во времени запрошенного часового пояса
+	for i := range busy {
+		busy[i].Start, busy[i].End = busy[i].Start.In(location), busy[i].End.In(location)
+	}
+	responsJSON(w, busy, http.StatusOK)
+}
@@ -1,30 +1,126 @@
 package handler
 
 import (
+	"develop/dev11/internal/auth"
 	"develop/dev11/internal/errors"
+	"develop/dev11/internal/ratelimit"
 	"develop/dev11/internal/service"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Handler - структура обработчика HTTP-запросов
 type Handler struct {
-	service *service.Service 
+	service *service.Service
+	auth    *auth.Auth // auth - аутентификация запросов, nil - запросы не аутентифицируются
+
+	ipLimiter      *ratelimit.Limiter // ipLimiter - лимит запросов с одного IP-адреса, nil - без ограничения
+	userLimiter    *ratelimit.Limiter // userLimiter - лимит запросов к одному user_id, nil - без ограничения
+	maxBodyBytes   int64              // maxBodyBytes - максимальный размер формы или JSON в теле запроса
+	maxImportBytes int64              // maxImportBytes - максимальный размер файла календаря
+
+	heartbeat time.Duration // heartbeat - период пульса в потоке изменений
+
+	metrics      *httpMetrics  // metrics - метрики запросов для /metrics
+	shuttingDown atomic.Bool   // shuttingDown - сервер останавливается, /readyz отвечает 503
+	stop         chan struct{} // stop - закрывается при остановке сервера, чтобы завершить потоки изменений
+	stopOnce     sync.Once     // stopOnce - защищает stop от повторного закрытия
+}
+
+// Option - необязательная настройка Handler
+type Option func(*Handler)
+
+// WithAuth - включает аутентификацию по токенам и проверку доступа к user_id
+func WithAuth(a *auth.Auth) Option {
+	return func(h *Handler) {
+		h.auth = a
+	}
 }
 
 // New - конструктор для Handler
-func New(service *service.Service) *Handler {
-	return &Handler{service: service}
+func New(service *service.Service, options ...Option) *Handler {
+	h := &Handler{
+		service:        service,
+		maxBodyBytes:   defaultMaxBodyBytes,
+		maxImportBytes: defaultMaxImportBytes,
+		heartbeat:      defaultHeartbeat,
+		stop:           make(chan struct{}),
+	}
+	for _, option := range options {
+		option(h)
+	}
+	h.metrics = h.newHTTPMetrics()
+	return h
+}
+
+// Shutdown - помечает сервер как останавливающийся, чтобы балансировщик перестал направлять на него запросы,
+// отклоняет новые потоки изменений и завершает открытые: иначе http.Server.Shutdown ждал бы их до истечения таймаута
+func (h *Handler) Shutdown() {
+	h.shuttingDown.Store(true)
+	h.stopOnce.Do(func() {
+		close(h.stop)
+	})
+}
+
+// route - маршрут роутера: шаблон пути и его обработчик
+type route struct {
+	pattern string
+	handler http.Handler
+}
+
+// routes - возвращает все маршруты API. По этому списку проверяется, что спецификация OpenAPI описывает каждый маршрут
+func (h *Handler) routes() []route {
+	routes := []route{
+		{"/create_event", h.authenticate(h.createEvent)},
+		{"/update_event", h.authenticate(h.updateEvent)},
+		{"/delete_event", h.authenticate(h.deleteEvent)},
+		{"/restore_event", h.authenticate(h.restoreEvent)},
+		{"/respond_event", h.authenticate(h.respondEvent)},
+		{"/trash", h.authenticate(h.getTrash)},
+		{"/create_calendar", h.authenticate(h.createCalendar)},
+		{"/update_calendar", h.authenticate(h.updateCalendar)},
+		{"/delete_calendar", h.authenticate(h.deleteCalendar)},
+		{"/calendars", h.authenticate(h.getCalendars)},
+		{"/events_for_day", h.authenticate(h.getEventsForDay)},
+		{"/events_for_week", h.authenticate(h.getEventsForWeek)},
+		{"/events_for_month", h.authenticate(h.getEventsForMonth)},
+		{"/events", h.authenticate(h.getEvents)},
+		{"/events/upcoming", h.authenticate(h.getUpcomingEvents)},
+		{"/events/stream", h.authenticate(h.streamEvents)},
+		{"/events/{eventID}/history", h.authenticate(h.getHistory)},
+		{"/changes", h.authenticate(h.getChanges)},
+		{"/free_busy", h.authenticate(h.getFreeBusy)},
+		{"/export.ics", h.authenticate(h.exportICS)},
+		{"/import_ics", h.authenticate(h.importICS)},
+
+		// REST API: ресурсы пользователя, его событий и календарей
+		{"/api/v1/users/{id}/events", h.authenticate(h.apiEvents)},
+		{"/api/v1/users/{id}/events/{eventID}", h.authenticate(h.apiEvent)},
+		{"/api/v1/users/{id}/calendars", h.authenticate(h.apiCalendars)},
+		{"/api/v1/users/{id}/calendars/{calendarID}", h.authenticate(h.apiCalendar)},
+
+		// Служебные маршруты: метрики, проверки состояния и спецификация не требуют аутентификации
+		{"/metrics", http.HandlerFunc(h.getMetrics)},
+		{"/healthz", http.HandlerFunc(h.getHealthz)},
+		{"/readyz", http.HandlerFunc(h.getReadyz)},
+		{"/openapi.json", http.HandlerFunc(getOpenAPI)},
+	}
+
+	// Выдача токенов доступна только при включенной аутентификации
+	if h.auth != nil {
+		routes = append(routes, route{"/login", http.HandlerFunc(h.login)})
+	}
+	return routes
 }
 
 // InitRouter - метод инициализации роутера HTTP-запросов
 func (h *Handler) InitRouter() http.Handler {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/create_event", h.createEvent)
-	mux.HandleFunc("/update_event", h.updateEvent)
-	mux.HandleFunc("/delete_event", h.deleteEvent)
-	mux.HandleFunc("/events_for_day", h.getEventsForDay)
-	mux.HandleFunc("/events_for_week", h.getEventsForWeek)
-	mux.HandleFunc("/events_for_month", h.getEventsForMonth)
+	for _, rt := range h.routes() {
+		mux.Handle(rt.pattern, rt.handler)
+	}
 
 	// Обработка запросов, которые не соответствуют ни одному из обработчиков
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -33,5 +129,5 @@ func (h *Handler) InitRouter() http.Handler {
 		}
 	})
 
-	return httpLogger(mux)
+	return requestID(httpLogger(mux, h.instrument(mux, h.limitIP(h.limitBody(jsonBody(mux))))))
 }
@@ -1,11 +1,21 @@
 package handler
 
 import (
+	"bufio"
+	"develop/dev11/internal/auth"
 	"develop/dev11/internal/data"
+	"develop/dev11/internal/logger"
 	"develop/dev11/internal/models"
+	"develop/dev11/internal/ratelimit"
 	"develop/dev11/internal/service"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -24,56 +34,56 @@ func TestHandlerCreateEvent(t *testing.T) {
 			name:       "OK",
 			url:        "http://localhost:8080/create_event",
 			method:     "POST",
-			body:       "user_id=5&date=2026-05-12 15:04:05&title=Test&description=Test",
-			want:       "{\"result\":{\"eventID\":0}}\n",
+			body:       "user_id=5&date=2036-05-12 15:04:05&title=Test&description=Test",
+			want:       "{\"request_id\":\"test\",\"result\":{\"eventID\":0}}\n",
 			wantStatus: http.StatusCreated,
 		},
 		{
 			name:       "Not Found Path",
 			url:        "http://localhost:8080/wrong_path",
 			method:     "GET",
-			body:       "user_id=5&date=2026-05-12 15:04:05&title=Test&description=Test",
-			want:       "{\"error\":\"path /wrong_path not found\"}\n",
+			body:       "user_id=5&date=2036-05-12 15:04:05&title=Test&description=Test",
+			want:       "{\"error\":\"path /wrong_path not found\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusNotFound,
 		},
 		{
 			name:       "Method",
 			url:        "http://localhost:8080/create_event",
 			method:     "GET",
-			body:       "user_id=5&date=2026-05-12 15:04:05&title=Test&description=Test",
-			want:       "{\"error\":\"method not allowed: bad method GET, method must be POST\"}\n",
+			body:       "user_id=5&date=2036-05-12 15:04:05&title=Test&description=Test",
+			want:       "{\"error\":\"method not allowed: bad method GET, method must be POST\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusMethodNotAllowed,
 		},
 		{
 			name:       "No UserID",
 			url:        "http://localhost:8080/create_event",
 			method:     "POST",
-			body:       "date=2026-05-12 15:04:05&title=Test&description=Test",
-			want:       "{\"error\":\"bad request: empty parameter: user_id\"}\n",
+			body:       "date=2036-05-12 15:04:05&title=Test&description=Test",
+			want:       "{\"error\":\"bad request: empty parameter: user_id\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
 			name:       "Invalid UserID",
 			url:        "http://localhost:8080/create_event",
 			method:     "POST",
-			body:       "user_id=five&&date=2026-05-12 15:04:05&title=Test&description=Test",
-			want:       "{\"error\":\"bad request: invalid user_id: use only numbers\"}\n",
+			body:       "user_id=five&&date=2036-05-12 15:04:05&title=Test&description=Test",
+			want:       "{\"error\":\"bad request: invalid user_id: use only numbers\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
 			name:       "No Title",
 			url:        "http://localhost:8080/create_event",
 			method:     "POST",
-			body:       "user_id=5&date=2026-05-12 15:04:05&description=Test",
-			want:       "{\"error\":\"bad request: empty parameter: title\"}\n",
+			body:       "user_id=5&date=2036-05-12 15:04:05&description=Test",
+			want:       "{\"error\":\"bad request: empty parameter: title\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
 			name:       "No Description",
 			url:        "http://localhost:8080/create_event",
 			method:     "POST",
-			body:       "user_id=5&date=2026-05-12 15:04:05&title=Test",
-			want:       "{\"result\":{\"eventID\":0}}\n",
+			body:       "user_id=5&date=2036-05-12 15:04:05&title=Test",
+			want:       "{\"request_id\":\"test\",\"result\":{\"eventID\":0}}\n",
 			wantStatus: http.StatusCreated,
 		},
 		{
@@ -81,15 +91,71 @@ func TestHandlerCreateEvent(t *testing.T) {
 			url:        "http://localhost:8080/create_event",
 			method:     "POST",
 			body:       "user_id=5&title=Test&description=Test",
-			want:       "{\"error\":\"bad request: empty parameter: date\"}\n",
+			want:       "{\"error\":\"bad request: empty parameter: date\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "OK Recurrence",
+			url:        "http://localhost:8080/create_event",
+			method:     "POST",
+			body:       "user_id=5&date=2036-05-12 15:04:05&title=Test&rrule=FREQ%3DWEEKLY%3BBYDAY%3DMO%2CWE&exdate=2036-05-14 15:04:05",
+			want:       "{\"request_id\":\"test\",\"result\":{\"eventID\":0}}\n",
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "Invalid Recurrence",
+			url:        "http://localhost:8080/create_event",
+			method:     "POST",
+			body:       "user_id=5&date=2036-05-12 15:04:05&title=Test&rrule=FREQ=HOURLY",
+			want:       "{\"error\":\"bad request: invalid rrule FREQ: must be DAILY, WEEKLY, MONTHLY or YEARLY\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "OK Time Zone",
+			url:        "http://localhost:8080/create_event",
+			method:     "POST",
+			body:       "user_id=5&date=2036-05-12 15:04:05&tz=America/New_York&title=Test&rrule=FREQ%3DDAILY",
+			want:       "{\"request_id\":\"test\",\"result\":{\"eventID\":0}}\n",
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "Invalid Time Zone",
+			url:        "http://localhost:8080/create_event",
+			method:     "POST",
+			body:       "user_id=5&date=2036-05-12 15:04:05&tz=Moscow&title=Test",
+			want:       "{\"error\":\"bad request: invalid tz: unknown time zone Moscow\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "OK Reminders",
+			url:        "http://localhost:8080/create_event",
+			method:     "POST",
+			body:       "user_id=5&date=2036-05-12 15:04:05&title=Test&remind=15m,1h",
+			want:       "{\"request_id\":\"test\",\"result\":{\"eventID\":0}}\n",
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "Invalid Reminder",
+			url:        "http://localhost:8080/create_event",
+			method:     "POST",
+			body:       "user_id=5&date=2036-05-12 15:04:05&title=Test&remind=soon",
+			want:       "{\"error\":\"bad request: invalid remind format: correct format 15m,1h\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Negative Reminder",
+			url:        "http://localhost:8080/create_event",
+			method:     "POST",
+			body:       "user_id=5&date=2036-05-12 15:04:05&title=Test&remind=-15m",
+			want:       "{\"error\":\"bad request: reminder must be between 0 and 720h\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
 			name:       "Format Date",
 			url:        "http://localhost:8080/create_event",
 			method:     "POST",
-			body:       "user_id=5&date=2026.05.12 15:04:05&title=Test&description=Test",
-			want:       "{\"error\":\"bad request: invalid date format: correct format 2006-01-02 15:04:05\"}\n",
+			body:       "user_id=5&date=2036.05.12 15:04:05&title=Test&description=Test",
+			want:       "{\"error\":\"bad request: invalid date format: correct format 2006-01-02 15:04:05\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 	}
@@ -101,6 +167,7 @@ func TestHandlerCreateEvent(t *testing.T) {
 				return
 			}
 			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			request.Header.Set("X-Request-ID", "test")
 			responseRecorder := httptest.NewRecorder()
 			handler := New(service.New(data.New())).InitRouter()
 			handler.ServeHTTP(responseRecorder, request)
@@ -128,127 +195,229 @@ func TestHandlerUpdateEvent(t *testing.T) {
 			name:       "OK Update Title",
 			url:        "http://localhost:8080/update_event",
 			method:     "POST",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
-			body:       "user_id=5&id=0&date=2026-05-12 15:04:05&title=UpdateTitle&description=Test",
-			want:       "{\"result\":\"OK\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0&date=2036-05-12 15:04:05&title=UpdateTitle&description=Test&version=1",
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
 			wantStatus: http.StatusOK,
 		},
 		{
 			name:       "Not Found Path",
 			url:        "http://localhost:8080/wrong_path",
 			method:     "GET",
-			body:       "user_id=5&date=2026-05-12 15:04:05&title=Test&description=Test",
-			want:       "{\"error\":\"path /wrong_path not found\"}\n",
+			body:       "user_id=5&date=2036-05-12 15:04:05&title=Test&description=Test",
+			want:       "{\"error\":\"path /wrong_path not found\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusNotFound,
 		},
 		{
 			name:       "OK Update Description",
 			url:        "http://localhost:8080/update_event",
 			method:     "POST",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
-			body:       "user_id=5&id=0&date=2026-05-12 15:04:05&title=Title&description=UpdateTest",
-			want:       "{\"result\":\"OK\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0&date=2036-05-12 15:04:05&title=Title&description=UpdateTest&version=1",
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
 			wantStatus: http.StatusOK,
 		},
 		{
 			name:       "OK Update Date",
 			url:        "http://localhost:8080/update_event",
 			method:     "POST",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
-			body:       "user_id=5&id=0&date=2027-07-07 15:04:05&title=Title&description=Test",
-			want:       "{\"result\":\"OK\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0&date=2027-07-07 15:04:05&title=Title&description=Test&version=1",
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
 			wantStatus: http.StatusOK,
 		},
 		{
 			name:       "UserID Not Found",
 			url:        "http://localhost:8080/update_event",
 			method:     "POST",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
-			body:       "user_id=1&id=0&date=2027-07-07 15:04:05&title=Title&description=Test",
-			want:       "{\"error\":\"user_id 1 not found\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=1&id=0&date=2027-07-07 15:04:05&title=Title&description=Test&version=1",
+			want:       "{\"error\":\"user_id 1 not found\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusNotFound,
 		},
 		{
 			name:       "EventID Not Found",
 			url:        "http://localhost:8080/update_event",
 			method:     "POST",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
-			body:       "user_id=5&id=10&date=2027-07-07 15:04:05&title=Title&description=Test",
-			want:       "{\"error\":\"event id 10 not found\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=10&date=2027-07-07 15:04:05&title=Title&description=Test&version=1",
+			want:       "{\"error\":\"event id 10 not found\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusNotFound,
 		},
 		{
 			name:       "Method",
 			url:        "http://localhost:8080/update_event",
 			method:     "GET",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
-			body:       "user_id=5&id=0date=2026-05-12 15:04:05&title=Test&description=Test",
-			want:       "{\"error\":\"method not allowed: bad method GET, method must be POST\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0date=2036-05-12 15:04:05&title=Test&description=Test&version=1",
+			want:       "{\"error\":\"method not allowed: bad method GET, method must be POST\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusMethodNotAllowed,
 		},
 		{
 			name:       "No UserID",
 			url:        "http://localhost:8080/update_event",
 			method:     "POST",
-			body:       "id=10&date=2026-05-12 15:04:05&title=Test&description=Test",
-			want:       "{\"error\":\"bad request: empty parameter: user_id\"}\n",
+			body:       "id=10&date=2036-05-12 15:04:05&title=Test&description=Test&version=1",
+			want:       "{\"error\":\"bad request: empty parameter: user_id\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
 			name:       "No Event ID",
 			url:        "http://localhost:8080/update_event",
 			method:     "POST",
-			body:       "user_id=5date=2026-05-12 15:04:05&title=Test&description=Test",
-			want:       "{\"error\":\"bad request: empty parameter: id\"}\n",
+			body:       "user_id=5date=2036-05-12 15:04:05&title=Test&description=Test&version=1",
+			want:       "{\"error\":\"bad request: empty parameter: id\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
 			name:       "Invalid UserID",
 			url:        "http://localhost:8080/update_event",
 			method:     "POST",
-			body:       "user_id=five&id=0&date=2026-05-12 15:04:05&title=Test&description=Test",
-			want:       "{\"error\":\"bad request: invalid user_id: use only numbers\"}\n",
+			body:       "user_id=five&id=0&date=2036-05-12 15:04:05&title=Test&description=Test&version=1",
+			want:       "{\"error\":\"bad request: invalid user_id: use only numbers\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
 			name:       "Invalid Event ID",
 			url:        "http://localhost:8080/update_event",
 			method:     "POST",
-			body:       "user_id=5&id=zero&date=2026-05-12 15:04:05&title=Test&description=Test",
-			want:       "{\"error\":\"bad request: invalid id: use only numbers\"}\n",
+			body:       "user_id=5&id=zero&date=2036-05-12 15:04:05&title=Test&description=Test&version=1",
+			want:       "{\"error\":\"bad request: invalid id: use only numbers\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
-			name:       "No Title",
+			name:       "OK Keep Title",
+			url:        "http://localhost:8080/update_event",
+			method:     "POST",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0&date=2036-05-12 15:04:05&description=Test&version=1",
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Clear Title",
 			url:        "http://localhost:8080/update_event",
 			method:     "POST",
-			body:       "user_id=5&id=0&date=2026-05-12 15:04:05&description=Test",
-			want:       "{\"error\":\"bad request: empty parameter: title\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0&title=&version=1",
+			want:       "{\"error\":\"bad request: empty parameter: title\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
 			name:       "No Description",
 			url:        "http://localhost:8080/update_event",
 			method:     "POST",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
-			body:       "user_id=5&id=0&date=2026-05-12 15:04:05&title=TestUpdate",
-			want:       "{\"result\":\"OK\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0&date=2036-05-12 15:04:05&title=TestUpdate&version=1",
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
 			wantStatus: http.StatusOK,
 		},
 		{
-			name:       "No Date",
+			name:       "OK Keep Date",
+			url:        "http://localhost:8080/update_event",
+			method:     "POST",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0&title=Test&description=Test&version=1",
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Clear Date",
+			url:        "http://localhost:8080/update_event",
+			method:     "POST",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0&date=&version=1",
+			want:       "{\"error\":\"bad request: empty parameter: date\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "OK Update Conflict Warn",
+			url:    "http://localhost:8080/update_event",
+			method: "POST",
+			events: []*models.Event{
+				withEnd(models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 0, 0, 0, time.UTC), "Test", "Test"), time.Hour),
+				withEnd(models.NewEvent(5, 0, time.Date(2036, 5, 12, 17, 0, 0, 0, time.UTC), "Test2", "Test2"), time.Hour),
+			},
+			body:       "user_id=5&id=1&date=2036-05-12 15:30:00&duration=1h&title=Test2&version=1",
+			want:       "{\"conflicts\":[{\"user_id\":5,\"id\":0,\"version\":1,\"date\":\"2036-05-12T15:00:00Z\",\"title\":\"Test\",\"description\":\"Test\",\"end\":\"2036-05-12T16:00:00Z\"}],\"request_id\":\"test\",\"result\":\"OK\"}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "Update Conflict Reject",
+			url:    "http://localhost:8080/update_event",
+			method: "POST",
+			events: []*models.Event{
+				withEnd(models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 0, 0, 0, time.UTC), "Test", "Test"), time.Hour),
+				withEnd(models.NewEvent(5, 0, time.Date(2036, 5, 12, 17, 0, 0, 0, time.UTC), "Test2", "Test2"), time.Hour),
+			},
+			body:       "user_id=5&id=1&date=2036-05-12 15:30:00&end=2036-05-12 16:30:00&title=Test2&conflict=reject&version=1",
+			want:       "{\"error\":\"conflict: event overlaps events with id 0\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:   "OK Update Adjacent",
+			url:    "http://localhost:8080/update_event",
+			method: "POST",
+			events: []*models.Event{
+				withEnd(models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 0, 0, 0, time.UTC), "Test", "Test"), time.Hour),
+				withEnd(models.NewEvent(5, 0, time.Date(2036, 5, 12, 17, 0, 0, 0, time.UTC), "Test2", "Test2"), time.Hour),
+			},
+			body:       "user_id=5&id=1&date=2036-05-12 16:00:00&duration=1h&title=Test2&conflict=reject&version=1",
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "End And Duration",
+			url:        "http://localhost:8080/update_event",
+			method:     "POST",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0&date=2036-05-12 15:04:05&end=2036-05-12 16:04:05&duration=1h&title=Test&version=1",
+			want:       "{\"error\":\"bad request: use either end or duration\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "End Before Date",
 			url:        "http://localhost:8080/update_event",
 			method:     "POST",
-			body:       "user_id=5&id=0&title=Test&description=Test",
-			want:       "{\"error\":\"bad request: empty parameter: date\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0&date=2036-05-12 15:04:05&end=2036-05-12 14:04:05&title=Test&version=1",
+			want:       "{\"error\":\"bad request: event end must be after event date\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
 			name:       "Format Date",
 			url:        "http://localhost:8080/update_event",
 			method:     "POST",
-			body:       "user_id=5&id=0&date=2026.05.12 15:04:05&title=Test&description=Test",
-			want:       "{\"error\":\"bad request: invalid date format: correct format 2006-01-02 15:04:05\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0&date=2036.05.12 15:04:05&title=Test&description=Test&version=1",
+			want:       "{\"error\":\"bad request: invalid date format: correct format 2006-01-02 15:04:05\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Stale Version",
+			url:        "http://localhost:8080/update_event",
+			method:     "POST",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0&date=2036-05-12 15:04:05&title=UpdateTitle&version=2",
+			want:       "{\"error\":\"precondition failed: event id 0 has version 1\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:       "No Version",
+			url:        "http://localhost:8080/update_event",
+			method:     "POST",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0&date=2036-05-12 15:04:05&title=UpdateTitle",
+			want:       "{\"error\":\"precondition required: If-Match header or version parameter is required\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusPreconditionRequired,
+		},
+		{
+			name:       "Invalid Version",
+			url:        "http://localhost:8080/update_event",
+			method:     "POST",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0&date=2036-05-12 15:04:05&title=UpdateTitle&version=one",
+			want:       "{\"error\":\"bad request: invalid version: must be a positive number\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 	}
@@ -260,6 +429,7 @@ func TestHandlerUpdateEvent(t *testing.T) {
 				return
 			}
 			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			request.Header.Set("X-Request-ID", "test")
 			responseRecorder := httptest.NewRecorder()
 			service := service.New(data.New())
 			for _, event := range tt.events {
@@ -277,6 +447,73 @@ func TestHandlerUpdateEvent(t *testing.T) {
 	}
 }
 
+func TestPatchEvent(t *testing.T) {
+	current := models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 0, 0, 0, time.UTC), "Test", "Test")
+	end := current.Date.Add(time.Hour)
+	current.End = &end
+	current.Recurrence = &models.Recurrence{Freq: models.FreqDaily, Count: 3, ExDates: []time.Time{time.Date(2036, 5, 13, 15, 0, 0, 0, time.UTC)}}
+
+	tests := []struct {
+		name    string
+		body    string
+		check   func(event *models.Event) bool
+		wantErr string
+	}{
+		{
+			name:  "Date Shifts End",
+			body:  "date=2036-05-14 10:00:00",
+			check: func(event *models.Event) bool { return event.End.Equal(time.Date(2036, 5, 14, 11, 0, 0, 0, time.UTC)) },
+		},
+		{
+			name:  "Clear End",
+			body:  "end=",
+			check: func(event *models.Event) bool { return event.End == nil },
+		},
+		{
+			name: "Keep ExDates When Rule Changes",
+			body: "rrule=FREQ%3DDAILY%3BCOUNT%3D5",
+			check: func(event *models.Event) bool {
+				return event.Recurrence.Count == 5 && len(event.Recurrence.ExDates) == 1
+			},
+		},
+		{
+			name:  "Clear Recurrence",
+			body:  "rrule=",
+			check: func(event *models.Event) bool { return event.Recurrence == nil },
+		},
+		{
+			name:    "ExDate Without Rule",
+			body:    "rrule=&exdate=2036-05-13 15:00:00",
+			wantErr: "bad request: exdate requires rrule",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest("POST", "http://localhost:8080/update_event", strings.NewReader(tt.body))
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if err := request.ParseForm(); err != nil {
+				t.Fatal(err)
+			}
+			event, err := patchEvent(request, current)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("patchEvent() error got %v want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("patchEvent() error %v", err)
+			}
+			if !tt.check(event) {
+				t.Errorf("patchEvent() got %+v", event)
+			}
+			if current.End == nil || current.Recurrence == nil || current.Recurrence.Count != 3 {
+				t.Errorf("patchEvent() changed the current event: %+v", current)
+			}
+		})
+	}
+}
+
 func TestHandlerDeleteEvent(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -291,78 +528,96 @@ func TestHandlerDeleteEvent(t *testing.T) {
 			name:       "OK Delete",
 			url:        "http://localhost:8080/delete_event",
 			method:     "POST",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
-			body:       "user_id=5&id=0",
-			want:       "{\"result\":\"OK\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0&version=1",
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
 			wantStatus: http.StatusOK,
 		},
 		{
 			name:       "Not Found Path",
 			url:        "http://localhost:8080/wrong_path",
 			method:     "GET",
-			body:       "user_id=5&date=2026-05-12 15:04:05&title=Test&description=Test",
-			want:       "{\"error\":\"path /wrong_path not found\"}\n",
+			body:       "user_id=5&date=2036-05-12 15:04:05&title=Test&description=Test",
+			want:       "{\"error\":\"path /wrong_path not found\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusNotFound,
 		},
 		{
 			name:       "UserID Not Found",
 			url:        "http://localhost:8080/delete_event",
 			method:     "POST",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
-			body:       "user_id=1&id=0",
-			want:       "{\"error\":\"user_id 1 not found\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=1&id=0&version=1",
+			want:       "{\"error\":\"user_id 1 not found\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusNotFound,
 		},
 		{
 			name:       "EventID Not Found",
 			url:        "http://localhost:8080/delete_event",
 			method:     "POST",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
-			body:       "user_id=5&id=10",
-			want:       "{\"error\":\"event id 10 not found\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=10&version=1",
+			want:       "{\"error\":\"event id 10 not found\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusNotFound,
 		},
 		{
 			name:       "Method",
 			url:        "http://localhost:8080/delete_event",
 			method:     "GET",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
-			body:       "user_id=5&id=0",
-			want:       "{\"error\":\"method not allowed: bad method GET, method must be POST\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0&version=1",
+			want:       "{\"error\":\"method not allowed: bad method GET, method must be POST\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusMethodNotAllowed,
 		},
 		{
 			name:       "No UserID",
 			url:        "http://localhost:8080/delete_event",
 			method:     "POST",
-			body:       "id=0",
-			want:       "{\"error\":\"bad request: empty parameter: user_id\"}\n",
+			body:       "id=0&version=1",
+			want:       "{\"error\":\"bad request: empty parameter: user_id\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
 			name:       "No Event ID",
 			url:        "http://localhost:8080/delete_event",
 			method:     "POST",
-			body:       "user_id=5",
-			want:       "{\"error\":\"bad request: empty parameter: id\"}\n",
+			body:       "user_id=5&version=1",
+			want:       "{\"error\":\"bad request: empty parameter: id\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
 			name:       "Invalid UserID",
 			url:        "http://localhost:8080/delete_event",
 			method:     "POST",
-			body:       "user_id=five&id=0",
-			want:       "{\"error\":\"bad request: invalid user_id: use only numbers\"}\n",
+			body:       "user_id=five&id=0&version=1",
+			want:       "{\"error\":\"bad request: invalid user_id: use only numbers\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
 			name:       "Invalid Event ID",
 			url:        "http://localhost:8080/delete_event",
 			method:     "POST",
-			body:       "user_id=5&id=zero",
-			want:       "{\"error\":\"bad request: invalid id: use only numbers\"}\n",
+			body:       "user_id=5&id=zero&version=1",
+			want:       "{\"error\":\"bad request: invalid id: use only numbers\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
+		{
+			name:       "Stale Version",
+			url:        "http://localhost:8080/delete_event",
+			method:     "POST",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0&version=2",
+			want:       "{\"error\":\"precondition failed: event id 0 has version 1\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:       "No Version",
+			url:        "http://localhost:8080/delete_event",
+			method:     "POST",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			body:       "user_id=5&id=0",
+			want:       "{\"error\":\"precondition required: If-Match header or version parameter is required\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusPreconditionRequired,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -372,6 +627,7 @@ func TestHandlerDeleteEvent(t *testing.T) {
 				return
 			}
 			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			request.Header.Set("X-Request-ID", "test")
 			responseRecorder := httptest.NewRecorder()
 			service := service.New(data.New())
 			for _, event := range tt.events {
@@ -389,243 +645,262 @@ func TestHandlerDeleteEvent(t *testing.T) {
 	}
 }
 
-func TestHandlerEventsForDay(t *testing.T) {
+func TestHandlerTrash(t *testing.T) {
+	// Время удаления зависит от текущего времени, поэтому в ответе оно заменяется на *
+	deletedAt := regexp.MustCompile(`"deleted_at":"[^"]*"`)
+
 	tests := []struct {
 		name       string
-		method     string
 		url        string
+		method     string
+		body       string
 		events     []*models.Event
+		deleted    []int
 		want       string
 		wantStatus int
+		wantETag   string
 	}{
 		{
-			name:   "OK Event For Day",
+			name:   "OK Trash",
+			url:    "http://localhost:8080/trash?user_id=5",
 			method: "GET",
 			events: []*models.Event{
-				models.NewEvent(5, 0, time.Date(2026, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
-				models.NewEvent(5, 0, time.Date(2026, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
-				models.NewEvent(5, 0, time.Date(2026, 5, 30, 14, 04, 04, 0, time.UTC), "Test3", "Test3"),
-				models.NewEvent(5, 0, time.Date(2026, 6, 20, 14, 04, 04, 0, time.UTC), "Test4", "Test4"),
+				models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test1", "Test1"),
+				models.NewEvent(5, 0, time.Date(2036, 5, 13, 15, 04, 04, 0, time.UTC), "Test2", "Test2"),
 			},
-			url:        "http://localhost:8080/events_for_day?user_id=5&date=2026-05-12",
-			want:       "{\"result\":[{\"user_id\":5,\"id\":0,\"date\":\"2026-05-12T14:04:04Z\",\"title\":\"Test1\",\"description\":\"Test1\"}]}\n",
+			deleted:    []int{1},
+			want:       "{\"request_id\":\"test\",\"result\":[{\"user_id\":5,\"id\":1,\"version\":2,\"date\":\"2036-05-13T15:04:04Z\",\"title\":\"Test2\",\"description\":\"Test2\",\"deleted_at\":*}]}\n",
 			wantStatus: http.StatusOK,
 		},
 		{
-			name:   "Not Found Path",
-			method: "GET",
-			url:    "http://localhost:8080/wrong_path",
-			events: []*models.Event{
-				models.NewEvent(5, 0, time.Date(2026, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
-				models.NewEvent(5, 0, time.Date(2026, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
-				models.NewEvent(5, 0, time.Date(2026, 5, 30, 14, 04, 04, 0, time.UTC), "Test3", "Test3"),
-				models.NewEvent(5, 0, time.Date(2026, 6, 20, 14, 04, 04, 0, time.UTC), "Test4", "Test4"),
-			},
-			want:       "{\"error\":\"path /wrong_path not found\"}\n",
-			wantStatus: http.StatusNotFound,
+			name:       "Deleted Event Hidden",
+			url:        "http://localhost:8080/events_for_day?user_id=5&date=2036-05-12",
+			method:     "GET",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			deleted:    []int{0},
+			want:       "{\"request_id\":\"test\",\"result\":[]}\n",
+			wantStatus: http.StatusOK,
 		},
 		{
-			name:   "Empty Event For Day",
-			method: "GET",
-			events: []*models.Event{
-				models.NewEvent(5, 0, time.Date(2026, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
-				models.NewEvent(5, 1, time.Date(2026, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
-				models.NewEvent(5, 2, time.Date(2026, 5, 30, 14, 04, 04, 0, time.UTC), "Test3", "Test3"),
-				models.NewEvent(5, 3, time.Date(2026, 6, 20, 14, 04, 04, 0, time.UTC), "Test4", "Test4"),
-			},
-			url:        "http://localhost:8080/events_for_day?user_id=5&date=2026-05-13",
-			want:       "{\"result\":[]}\n",
+			name:       "Empty Trash",
+			url:        "http://localhost:8080/trash?user_id=5",
+			method:     "GET",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			want:       "{\"request_id\":\"test\",\"result\":[]}\n",
 			wantStatus: http.StatusOK,
 		},
-
 		{
-			name:       "UserID Not Found",
+			name:       "Trash UserID Not Found",
+			url:        "http://localhost:8080/trash?user_id=1",
 			method:     "GET",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
-			url:        "http://localhost:8080/events_for_day?user_id=1&date=2026-05-13",
-			want:       "{\"error\":\"user_id 1 not found\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			want:       "{\"error\":\"user_id 1 not found\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusNotFound,
 		},
 		{
-			name:       "Method",
-			method:     "POST",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
-			url:        "http://localhost:8080/events_for_day?user_id=5&date=2026-05-13",
-			want:       "{\"error\":\"method not allowed: bad method POST, method must be GET\"}\n",
-			wantStatus: http.StatusMethodNotAllowed,
-		},
-		{
-			name:       "Invalid UserID",
+			name:       "Trash No UserID",
+			url:        "http://localhost:8080/trash",
 			method:     "GET",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
-			url:        "http://localhost:8080/events_for_day?user_id=one&date=2026-05-13",
-			want:       "{\"error\":\"bad request: invalid user_id: use only numbers\"}\n",
+			want:       "{\"error\":\"bad request: empty parameter: user_id\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
-			name:       "No UserID",
-			method:     "GET",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
-			url:        "http://localhost:8080/events_for_day?date=2026-05-13",
-			want:       "{\"error\":\"bad request: empty parameter: user_id\"}\n",
-			wantStatus: http.StatusBadRequest,
+			name:       "OK Restore",
+			url:        "http://localhost:8080/restore_event",
+			method:     "POST",
+			body:       "user_id=5&id=0",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			deleted:    []int{0},
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
+			wantStatus: http.StatusOK,
+			wantETag:   `"3"`,
 		},
 		{
-			name:       "No Date",
-			method:     "GET",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
-			url:        "http://localhost:8080/events_for_day?user_id=5",
-			want:       "{\"error\":\"bad request: empty parameter: date\"}\n",
+			name:       "Restore Not Deleted",
+			url:        "http://localhost:8080/restore_event",
+			method:     "POST",
+			body:       "user_id=5&id=0",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			want:       "{\"error\":\"deleted event id 0 not found\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Restore No ID",
+			url:        "http://localhost:8080/restore_event",
+			method:     "POST",
+			body:       "user_id=5",
+			want:       "{\"error\":\"bad request: empty parameter: id\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
-			name:       "Format Date",
+			name:       "Restore Method",
+			url:        "http://localhost:8080/restore_event",
 			method:     "GET",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
-			url:        "http://localhost:8080/events_for_day?user_id=5&date=2026.05.13",
-			want:       "{\"error\":\"bad request: invalid date format: correct format 2006-01-02\"}\n",
-			wantStatus: http.StatusBadRequest,
+			want:       "{\"error\":\"method not allowed: bad method GET, method must be POST\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusMethodNotAllowed,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			request, err := http.NewRequest(tt.method, tt.url, nil)
+			request, err := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
 			if err != nil {
 				t.Error(err)
 				return
 			}
 			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			request.Header.Set("X-Request-ID", "test")
 			responseRecorder := httptest.NewRecorder()
 			service := service.New(data.New())
 			for _, event := range tt.events {
 				service.Create(event)
 			}
+			for _, id := range tt.deleted {
+				service.Delete(5, id, 0)
+			}
 			handler := New(service).InitRouter()
 			handler.ServeHTTP(responseRecorder, request)
 			if responseRecorder.Code != tt.wantStatus {
 				t.Errorf("status: got %v want %v", responseRecorder.Code, tt.wantStatus)
 			}
-			if responseRecorder.Body.String() != tt.want {
-				t.Errorf("result: got %v want %v", responseRecorder.Body.String(), tt.want)
+			if got := deletedAt.ReplaceAllString(responseRecorder.Body.String(), `"deleted_at":*`); got != tt.want {
+				t.Errorf("result: got %v want %v", got, tt.want)
+			}
+			if tt.wantETag != "" && responseRecorder.Header().Get("ETag") != tt.wantETag {
+				t.Errorf("ETag: got %v want %v", responseRecorder.Header().Get("ETag"), tt.wantETag)
 			}
 		})
 	}
 }
 
-func TestHandlerEventsForWeek(t *testing.T) {
+func TestHandlerAttendees(t *testing.T) {
+	// Шаги выполняются по порядку над одним сервисом: владелец 5 приглашает пользователей 6 и 7
 	tests := []struct {
 		name       string
-		method     string
 		url        string
-		events     []*models.Event
+		method     string
+		body       string
 		want       string
 		wantStatus int
 	}{
 		{
-			name:   "OK Event For Week",
-			method: "GET",
-			events: []*models.Event{
-				models.NewEvent(5, 0, time.Date(2026, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
-				models.NewEvent(5, 0, time.Date(2026, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
-				models.NewEvent(5, 0, time.Date(2026, 5, 30, 14, 04, 04, 0, time.UTC), "Test3", "Test3"),
-				models.NewEvent(5, 0, time.Date(2026, 6, 20, 14, 04, 04, 0, time.UTC), "Test4", "Test4"),
-			},
-			url: "http://localhost:8080/events_for_week?user_id=5&date=2026-05-12",
-			want: "{\"result\":[" +
-				"{\"user_id\":5,\"id\":0,\"date\":\"2026-05-12T14:04:04Z\",\"title\":\"Test1\",\"description\":\"Test1\"}," +
-				"{\"user_id\":5,\"id\":1,\"date\":\"2026-05-18T14:04:04Z\",\"title\":\"Test2\",\"description\":\"Test2\"}" +
-				"]}\n",
+			name:       "Create With Attendees",
+			url:        "http://localhost:8080/create_event",
+			method:     "POST",
+			body:       "user_id=5&date=2036-05-12 15:04:05&title=Test&description=Test&attendees=6,7",
+			want:       "{\"request_id\":\"test\",\"result\":{\"eventID\":0}}\n",
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "Attendee Sees Event",
+			url:        "http://localhost:8080/events_for_day?user_id=6&date=2036-05-12",
+			method:     "GET",
+			want:       "{\"request_id\":\"test\",\"result\":[{\"user_id\":5,\"id\":0,\"version\":1,\"date\":\"2036-05-12T15:04:05Z\",\"title\":\"Test\",\"description\":\"Test\",\"attendees\":[{\"user_id\":6,\"status\":\"needs-action\"},{\"user_id\":7,\"status\":\"needs-action\"}]}]}\n",
 			wantStatus: http.StatusOK,
 		},
 		{
-			name:   "Not Found Path",
-			method: "GET",
-			url:    "http://localhost:8080/wrong_path",
-			events: []*models.Event{
-				models.NewEvent(5, 0, time.Date(2026, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
-				models.NewEvent(5, 0, time.Date(2026, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
-				models.NewEvent(5, 0, time.Date(2026, 5, 30, 14, 04, 04, 0, time.UTC), "Test3", "Test3"),
-				models.NewEvent(5, 0, time.Date(2026, 6, 20, 14, 04, 04, 0, time.UTC), "Test4", "Test4"),
-			},
-			want:       "{\"error\":\"path /wrong_path not found\"}\n",
-			wantStatus: http.StatusNotFound,
+			name:       "OK Respond",
+			url:        "http://localhost:8080/respond_event",
+			method:     "POST",
+			body:       "user_id=6&id=0&status=Declined",
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
+			wantStatus: http.StatusOK,
 		},
 		{
-			name:   "Empty Event For Week",
-			method: "GET",
-			events: []*models.Event{
-				models.NewEvent(5, 0, time.Date(2026, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
-				models.NewEvent(5, 1, time.Date(2026, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
-				models.NewEvent(5, 2, time.Date(2026, 5, 30, 14, 04, 04, 0, time.UTC), "Test3", "Test3"),
-				models.NewEvent(5, 3, time.Date(2026, 6, 20, 14, 04, 04, 0, time.UTC), "Test4", "Test4"),
-			},
-			url:        "http://localhost:8080/events_for_week?user_id=5&date=2026-07-13",
-			want:       "{\"result\":[]}\n",
+			name:       "Declined Event Hidden",
+			url:        "http://localhost:8080/events_for_day?user_id=6&date=2036-05-12",
+			method:     "GET",
+			want:       "{\"request_id\":\"test\",\"result\":[]}\n",
 			wantStatus: http.StatusOK,
 		},
 		{
-			name:       "UserID Not Found",
+			name:       "Attendee Cannot Update",
+			url:        "http://localhost:8080/update_event",
+			method:     "POST",
+			body:       "user_id=7&id=0&version=2&date=2036-05-12 15:04:05&title=Changed&description=Test",
+			want:       "{\"error\":\"forbidden: only the owner can change event id 0\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Attendee Cannot Delete",
+			url:        "http://localhost:8080/delete_event",
+			method:     "POST",
+			body:       "user_id=7&id=0&version=2",
+			want:       "{\"error\":\"forbidden: only the owner can change event id 0\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Owner Update Keeps Responses",
+			url:        "http://localhost:8080/update_event",
+			method:     "POST",
+			body:       "user_id=5&id=0&version=2&date=2036-05-12 15:04:05&title=Changed&description=Test&attendees=6,8",
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Owner Sees Responses",
+			url:        "http://localhost:8080/events_for_day?user_id=5&date=2036-05-12",
 			method:     "GET",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
-			url:        "http://localhost:8080/events_for_week?user_id=1&date=2026-05-13",
-			want:       "{\"error\":\"user_id 1 not found\"}\n",
+			want:       "{\"request_id\":\"test\",\"result\":[{\"user_id\":5,\"id\":0,\"version\":3,\"date\":\"2036-05-12T15:04:05Z\",\"title\":\"Changed\",\"description\":\"Test\",\"attendees\":[{\"user_id\":6,\"status\":\"declined\"},{\"user_id\":8,\"status\":\"needs-action\"}]}]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Removed Attendee Cannot Respond",
+			url:        "http://localhost:8080/respond_event",
+			method:     "POST",
+			body:       "user_id=7&id=0&status=accepted",
+			want:       "{\"error\":\"user_id 7 not found\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusNotFound,
 		},
 		{
-			name:       "Method",
+			name:       "Owner Cannot Respond",
+			url:        "http://localhost:8080/respond_event",
 			method:     "POST",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
-			url:        "http://localhost:8080/events_for_week?user_id=5&date=2026-05-13",
-			want:       "{\"error\":\"method not allowed: bad method POST, method must be GET\"}\n",
-			wantStatus: http.StatusMethodNotAllowed,
+			body:       "user_id=5&id=0&status=accepted",
+			want:       "{\"error\":\"forbidden: owner cannot respond to event id 0\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusForbidden,
 		},
 		{
-			name:       "Invalid UserID",
-			method:     "GET",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
-			url:        "http://localhost:8080/events_for_week?user_id=one&date=2026-05-13",
-			want:       "{\"error\":\"bad request: invalid user_id: use only numbers\"}\n",
+			name:       "Invalid Status",
+			url:        "http://localhost:8080/respond_event",
+			method:     "POST",
+			body:       "user_id=6&id=0&status=needs-action",
+			want:       "{\"error\":\"bad request: invalid status: must be accepted, declined or tentative\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
-			name:       "No UserID",
-			method:     "GET",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
-			url:        "http://localhost:8080/events_for_week?date=2026-05-13",
-			want:       "{\"error\":\"bad request: empty parameter: user_id\"}\n",
+			name:       "Owner As Attendee",
+			url:        "http://localhost:8080/create_event",
+			method:     "POST",
+			body:       "user_id=5&date=2036-05-12 15:04:05&title=Test&description=Test&attendees=5",
+			want:       "{\"error\":\"bad request: event owner cannot be an attendee\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
-			name:       "No Date",
-			method:     "GET",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
-			url:        "http://localhost:8080/events_for_week?user_id=5",
-			want:       "{\"error\":\"bad request: empty parameter: date\"}\n",
+			name:       "Invalid Attendees",
+			url:        "http://localhost:8080/create_event",
+			method:     "POST",
+			body:       "user_id=5&date=2036-05-12 15:04:05&title=Test&description=Test&attendees=6,x",
+			want:       "{\"error\":\"bad request: invalid attendees: use user ids separated by commas\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
-			name:       "Format Date",
+			name:       "Respond Method",
+			url:        "http://localhost:8080/respond_event",
 			method:     "GET",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
-			url:        "http://localhost:8080/events_for_week?user_id=5&date=2026.05.13",
-			want:       "{\"error\":\"bad request: invalid date format: correct format 2006-01-02\"}\n",
-			wantStatus: http.StatusBadRequest,
+			want:       "{\"error\":\"method not allowed: bad method GET, method must be POST\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusMethodNotAllowed,
 		},
 	}
+	handler := New(service.New(data.New())).InitRouter()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			request, err := http.NewRequest(tt.method, tt.url, nil)
+			request, err := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
 			if err != nil {
-				t.Error(err)
-				return
+				t.Fatal(err)
 			}
 			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			request.Header.Set("X-Request-ID", "test")
 			responseRecorder := httptest.NewRecorder()
-			service := service.New(data.New())
-			for _, event := range tt.events {
-				service.Create(event)
-			}
-			handler := New(service).InitRouter()
 			handler.ServeHTTP(responseRecorder, request)
 			if responseRecorder.Code != tt.wantStatus {
 				t.Errorf("status: got %v want %v", responseRecorder.Code, tt.wantStatus)
@@ -637,30 +912,507 @@ func TestHandlerEventsForWeek(t *testing.T) {
 	}
 }
 
-func TestHandlerEventsForMonth(t *testing.T) {
+func TestHandlerCalendars(t *testing.T) {
+	// Шаги выполняются по порядку над одним сервисом: пользователь 5 заводит календари и раскладывает по ним события
 	tests := []struct {
 		name       string
-		method     string
 		url        string
-		events     []*models.Event
+		method     string
+		body       string
 		want       string
 		wantStatus int
 	}{
 		{
-			name:   "OK Event For Month",
+			name:       "Create Calendar",
+			url:        "http://localhost:8080/create_calendar",
+			method:     "POST",
+			body:       "user_id=5&name=work&color=%2300AA00",
+			want:       "{\"request_id\":\"test\",\"result\":{\"calendarID\":1}}\n",
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "Create Hidden Calendar",
+			url:        "http://localhost:8080/api/v1/users/5/calendars",
+			method:     "POST",
+			body:       "name=on-call&visibility=hidden",
+			want:       "{\"request_id\":\"test\",\"result\":{\"user_id\":5,\"id\":2,\"name\":\"on-call\",\"color\":\"#4285f4\",\"visibility\":\"hidden\"}}\n",
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "Invalid Color",
+			url:        "http://localhost:8080/create_calendar",
+			method:     "POST",
+			body:       "user_id=5&name=work&color=green",
+			want:       "{\"error\":\"bad request: invalid color: correct format #RRGGBB\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Calendars",
+			url:        "http://localhost:8080/calendars?user_id=5",
+			method:     "GET",
+			want:       "{\"request_id\":\"test\",\"result\":[{\"user_id\":5,\"id\":1,\"name\":\"work\",\"color\":\"#00aa00\",\"visibility\":\"visible\"},{\"user_id\":5,\"id\":2,\"name\":\"on-call\",\"color\":\"#4285f4\",\"visibility\":\"hidden\"}]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "No Calendars",
+			url:        "http://localhost:8080/calendars?user_id=6",
+			method:     "GET",
+			want:       "{\"request_id\":\"test\",\"result\":[]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Event In Work",
+			url:        "http://localhost:8080/create_event",
+			method:     "POST",
+			body:       "user_id=5&calendar_id=1&date=2036-05-12 10:00:00&title=Work&description=Test",
+			want:       "{\"request_id\":\"test\",\"result\":{\"eventID\":0}}\n",
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "Event In On-Call",
+			url:        "http://localhost:8080/create_event",
+			method:     "POST",
+			body:       "user_id=5&calendar_id=2&date=2036-05-12 11:00:00&title=OnCall&description=Test",
+			want:       "{\"request_id\":\"test\",\"result\":{\"eventID\":1}}\n",
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "Event In Default",
+			url:        "http://localhost:8080/create_event",
+			method:     "POST",
+			body:       "user_id=5&date=2036-05-12 12:00:00&title=Default&description=Test",
+			want:       "{\"request_id\":\"test\",\"result\":{\"eventID\":2}}\n",
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "Event In Unknown Calendar",
+			url:        "http://localhost:8080/create_event",
+			method:     "POST",
+			body:       "user_id=5&calendar_id=9&date=2036-05-12 12:00:00&title=Test&description=Test",
+			want:       "{\"error\":\"bad request: unknown calendar_id 9\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Hidden Calendar Skipped",
+			url:        "http://localhost:8080/events_for_day?user_id=5&date=2036-05-12",
+			method:     "GET",
+			want:       "{\"request_id\":\"test\",\"result\":[{\"user_id\":5,\"id\":0,\"version\":1,\"date\":\"2036-05-12T10:00:00Z\",\"title\":\"Work\",\"description\":\"Test\",\"calendar_id\":1},{\"user_id\":5,\"id\":2,\"version\":1,\"date\":\"2036-05-12T12:00:00Z\",\"title\":\"Default\",\"description\":\"Test\"}]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Filter By Calendars",
+			url:        "http://localhost:8080/events_for_day?user_id=5&date=2036-05-12&calendars=2,0",
+			method:     "GET",
+			want:       "{\"request_id\":\"test\",\"result\":[{\"user_id\":5,\"id\":1,\"version\":1,\"date\":\"2036-05-12T11:00:00Z\",\"title\":\"OnCall\",\"description\":\"Test\",\"calendar_id\":2},{\"user_id\":5,\"id\":2,\"version\":1,\"date\":\"2036-05-12T12:00:00Z\",\"title\":\"Default\",\"description\":\"Test\"}]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Filter Unknown Calendar",
+			url:        "http://localhost:8080/events_for_week?user_id=5&date=2036-05-12&calendars=7",
+			method:     "GET",
+			want:       "{\"error\":\"calendar id 7 not found\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Invalid Calendars",
+			url:        "http://localhost:8080/events_for_month?user_id=5&date=2036-05-01&calendars=work",
+			method:     "GET",
+			want:       "{\"error\":\"bad request: invalid calendars: use calendar ids separated by commas\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Update Calendar",
+			url:        "http://localhost:8080/update_calendar",
+			method:     "POST",
+			body:       "user_id=5&id=2&visibility=visible",
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Shown After Update",
+			url:        "http://localhost:8080/events_for_day?user_id=5&date=2036-05-12&calendars=",
+			method:     "GET",
+			want:       "{\"request_id\":\"test\",\"result\":[{\"user_id\":5,\"id\":0,\"version\":1,\"date\":\"2036-05-12T10:00:00Z\",\"title\":\"Work\",\"description\":\"Test\",\"calendar_id\":1},{\"user_id\":5,\"id\":1,\"version\":1,\"date\":\"2036-05-12T11:00:00Z\",\"title\":\"OnCall\",\"description\":\"Test\",\"calendar_id\":2},{\"user_id\":5,\"id\":2,\"version\":1,\"date\":\"2036-05-12T12:00:00Z\",\"title\":\"Default\",\"description\":\"Test\"}]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "API Patch Calendar",
+			url:        "http://localhost:8080/api/v1/users/5/calendars/2",
+			method:     "PATCH",
+			body:       "name=duty&color=%23FF0000",
+			want:       "{\"request_id\":\"test\",\"result\":{\"user_id\":5,\"id\":2,\"name\":\"duty\",\"color\":\"#ff0000\",\"visibility\":\"visible\"}}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Update Unknown Calendar",
+			url:        "http://localhost:8080/update_calendar",
+			method:     "POST",
+			body:       "user_id=6&id=2&name=mine",
+			want:       "{\"error\":\"calendar id 2 not found\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Delete Calendar With Events",
+			url:        "http://localhost:8080/delete_calendar",
+			method:     "POST",
+			body:       "user_id=5&id=1",
+			want:       "{\"error\":\"conflict: calendar id 1 has events, move or delete them first\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "Move Event To Default",
+			url:        "http://localhost:8080/update_event",
+			method:     "POST",
+			body:       "user_id=5&id=0&version=1&calendar_id=",
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Delete Calendar",
+			url:        "http://localhost:8080/delete_calendar",
+			method:     "POST",
+			body:       "user_id=5&id=1",
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Get Deleted Calendar",
+			url:        "http://localhost:8080/api/v1/users/5/calendars/1",
+			method:     "GET",
+			want:       "{\"error\":\"calendar id 1 not found\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Delete Calendar Method",
+			url:        "http://localhost:8080/delete_calendar",
+			method:     "GET",
+			want:       "{\"error\":\"method not allowed: bad method GET, method must be POST\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	handler := New(service.New(data.New())).InitRouter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			request.Header.Set("X-Request-ID", "test")
+			responseRecorder := httptest.NewRecorder()
+			handler.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != tt.wantStatus {
+				t.Errorf("status: got %v want %v", responseRecorder.Code, tt.wantStatus)
+			}
+			if responseRecorder.Body.String() != tt.want {
+				t.Errorf("result: got %v want %v", responseRecorder.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerHistory(t *testing.T) {
+	// Время изменения и удаления зависит от текущего времени, поэтому в ответе оно заменяется на *
+	changedAt := regexp.MustCompile(`"(at|deleted_at)":"[^"]*"`)
+
+	tests := []struct {
+		name       string
+		url        string
+		events     []*models.Event
+		deleted    []int
+		want       string
+		wantStatus int
+		wantCursor string
+	}{
+		{
+			name:       "OK History",
+			url:        "http://localhost:8080/events/0/history?user_id=5",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test1", "Test1"), models.NewEvent(5, 0, time.Date(2036, 5, 13, 15, 04, 04, 0, time.UTC), "Test2", "Test2")},
+			deleted:    []int{0},
+			want:       "{\"request_id\":\"test\",\"result\":[{\"seq\":1,\"op\":\"create\",\"user_id\":5,\"event_id\":0,\"version\":1,\"at\":*,\"after\":{\"user_id\":5,\"id\":0,\"version\":1,\"date\":\"2036-05-12T15:04:04Z\",\"title\":\"Test1\",\"description\":\"Test1\"}},{\"seq\":3,\"op\":\"delete\",\"user_id\":5,\"event_id\":0,\"version\":2,\"at\":*,\"before\":{\"user_id\":5,\"id\":0,\"version\":1,\"date\":\"2036-05-12T15:04:04Z\",\"title\":\"Test1\",\"description\":\"Test1\"},\"after\":{\"user_id\":5,\"id\":0,\"version\":2,\"date\":\"2036-05-12T15:04:04Z\",\"title\":\"Test1\",\"description\":\"Test1\",\"deleted_at\":*}}]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "History Not Found",
+			url:        "http://localhost:8080/events/3/history?user_id=5",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:       "{\"error\":\"history of event id 3 not found\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "History Other User",
+			url:        "http://localhost:8080/events/0/history?user_id=7",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:       "{\"error\":\"history of event id 0 not found\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Invalid EventID",
+			url:        "http://localhost:8080/events/first/history?user_id=5",
+			want:       "{\"error\":\"bad request: invalid eventID: use only numbers\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "OK Changes Since Cursor",
+			url:        "http://localhost:8080/changes?user_id=5&since=1",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test1", "Test1"), models.NewEvent(5, 0, time.Date(2036, 5, 13, 15, 04, 04, 0, time.UTC), "Test2", "Test2")},
+			want:       "{\"request_id\":\"test\",\"result\":[{\"seq\":2,\"op\":\"create\",\"user_id\":5,\"event_id\":1,\"version\":1,\"at\":*,\"after\":{\"user_id\":5,\"id\":1,\"version\":1,\"date\":\"2036-05-13T15:04:04Z\",\"title\":\"Test2\",\"description\":\"Test2\"}}]}\n",
+			wantStatus: http.StatusOK,
+			wantCursor: "2",
+		},
+		{
+			name:       "OK Changes Limit",
+			url:        "http://localhost:8080/changes?user_id=5&limit=1",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test1", "Test1"), models.NewEvent(5, 0, time.Date(2036, 5, 13, 15, 04, 04, 0, time.UTC), "Test2", "Test2")},
+			want:       "{\"request_id\":\"test\",\"result\":[{\"seq\":1,\"op\":\"create\",\"user_id\":5,\"event_id\":0,\"version\":1,\"at\":*,\"after\":{\"user_id\":5,\"id\":0,\"version\":1,\"date\":\"2036-05-12T15:04:04Z\",\"title\":\"Test1\",\"description\":\"Test1\"}}]}\n",
+			wantStatus: http.StatusOK,
+			wantCursor: "1",
+		},
+		{
+			name:       "OK No New Changes",
+			url:        "http://localhost:8080/changes?user_id=5&since=2",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test1", "Test1"), models.NewEvent(5, 0, time.Date(2036, 5, 13, 15, 04, 04, 0, time.UTC), "Test2", "Test2")},
+			want:       "{\"request_id\":\"test\",\"result\":[]}\n",
+			wantStatus: http.StatusOK,
+			wantCursor: "2",
+		},
+		{
+			name:       "Invalid Since",
+			url:        "http://localhost:8080/changes?user_id=5&since=-1",
+			want:       "{\"error\":\"bad request: invalid since: must be a cursor from X-Next-Cursor\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Changes No UserID",
+			url:        "http://localhost:8080/changes",
+			want:       "{\"error\":\"bad request: empty parameter: user_id\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			request.Header.Set("X-Request-ID", "test")
+			responseRecorder := httptest.NewRecorder()
+			service := service.New(data.New())
+			for _, event := range tt.events {
+				service.Create(event)
+			}
+			for _, id := range tt.deleted {
+				service.Delete(5, id, 0)
+			}
+			handler := New(service).InitRouter()
+			handler.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != tt.wantStatus {
+				t.Errorf("status: got %v want %v", responseRecorder.Code, tt.wantStatus)
+			}
+			if got := changedAt.ReplaceAllString(responseRecorder.Body.String(), `"$1":*`); got != tt.want {
+				t.Errorf("result: got %v want %v", got, tt.want)
+			}
+			if got := responseRecorder.Header().Get("X-Next-Cursor"); got != tt.wantCursor {
+				t.Errorf("cursor: got %v want %v", got, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestHandlerChangesPurge(t *testing.T) {
+	service := service.New(data.New())
+	handler := New(service).InitRouter()
+	// changes - возвращает операции из ленты изменений пользователя
+	changes := func(t *testing.T, url string) []string {
+		t.Helper()
+		responseRecorder := httptest.NewRecorder()
+		handler.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, url, nil))
+		var response struct {
+			Result []models.Change `json:"result"`
+		}
+		if err := json.Unmarshal(responseRecorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("decode %q: %v", responseRecorder.Body.String(), err)
+		}
+		ops := make([]string, 0, len(response.Result))
+		for _, change := range response.Result {
+			ops = append(ops, fmt.Sprintf("%s %d", change.Op, change.EventID))
+		}
+		return ops
+	}
+
+	// Владелец 5 приглашает 6, затем меняет участника на 7 и удаляет событие
+	event := models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")
+	event.Attendees = []models.Attendee{{UserID: 6}}
+	service.Create(event)
+	changed := event.Clone()
+	changed.Attendees = []models.Attendee{{UserID: 7}}
+	service.Update(changed)
+	service.Create(models.NewEvent(5, 0, time.Date(2036, 5, 13, 15, 04, 04, 0, time.UTC), "Kept", "Kept"))
+	service.Delete(5, 0, 0)
+
+	tests := []struct {
+		name string
+		url  string
+		want []string
+	}{
+		{name: "Owner", url: "http://localhost:8080/changes?user_id=5", want: []string{"create 0", "update 0", "create 1", "delete 0"}},
+		{name: "Removed Attendee", url: "http://localhost:8080/changes?user_id=6", want: []string{"create 0", "update 0"}},
+		{name: "Added Attendee", url: "http://localhost:8080/changes?user_id=7", want: []string{"update 0", "delete 0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := changes(t, tt.url); !slices.Equal(got, tt.want) {
+				t.Errorf("changes: got %v want %v", got, tt.want)
+			}
+		})
+	}
+
+	// После окончательного удаления история события больше не читается ни владельцем, ни участниками
+	if purged, err := service.Purge(-time.Second); err != nil || purged != 1 {
+		t.Fatalf("purge: got %d, %v want 1, nil", purged, err)
+	}
+	for _, userID := range []int{5, 6, 7} {
+		url := fmt.Sprintf("http://localhost:8080/changes?user_id=%d", userID)
+		want := []string{}
+		if userID == 5 {
+			want = []string{"create 1"}
+		}
+		if got := changes(t, url); !slices.Equal(got, want) {
+			t.Errorf("changes of user %d after purge: got %v want %v", userID, got, want)
+		}
+	}
+	responseRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, "http://localhost:8080/events/0/history?user_id=5", nil))
+	if responseRecorder.Code != http.StatusNotFound {
+		t.Errorf("history after purge: got %v want %v", responseRecorder.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandlerStream(t *testing.T) {
+	date := time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC)
+	service := service.New(data.New())
+	service.Create(models.NewEvent(5, 0, date, "Test1", "Test1"))
+	handler := New(service, WithHeartbeat(20*time.Millisecond))
+	server := httptest.NewServer(handler.InitRouter())
+	defer server.Close()
+
+	// readField - читает поток до строки с префиксом prefix и возвращает остаток строки
+	readField := func(t *testing.T, reader *bufio.Reader, prefix string) string {
+		t.Helper()
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read %q: %v", prefix, err)
+			}
+			if value, ok := strings.CutPrefix(line, prefix); ok {
+				return strings.TrimSpace(value)
+			}
+		}
+	}
+
+	t.Run("Invalid Last-Event-ID", func(t *testing.T) {
+		request, _ := http.NewRequest(http.MethodGet, server.URL+"/events/stream?user_id=5", nil)
+		request.Header.Set("Last-Event-ID", "first")
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("status: got %v want %v", response.StatusCode, http.StatusBadRequest)
+		}
+	})
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/events/stream?user_id=5", nil)
+	// Клиент переподключается, не получив ни одного изменения
+	request.Header.Set("Last-Event-ID", "0")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("stream: got %v %v", response.StatusCode, response.Header.Get("Content-Type"))
+	}
+	reader := bufio.NewReader(response.Body)
+
+	if retry := readField(t, reader, "retry: "); retry != "3000" {
+		t.Errorf("retry: got %v want 3000", retry)
+	}
+
+	// Пропущенное изменение отправляется из журнала
+	if id := readField(t, reader, "id: "); id != "1" {
+		t.Errorf("missed change id: got %v want 1", id)
+	}
+	if event := readField(t, reader, "event: "); event != models.ChangeCreate {
+		t.Errorf("missed change event: got %v want %v", event, models.ChangeCreate)
+	}
+
+	// Новое изменение отправляется по мере записи
+	updated := models.NewEvent(5, 0, date, "Updated", "Test1")
+	if err := service.Update(updated); err != nil {
+		t.Fatal(err)
+	}
+	if id := readField(t, reader, "id: "); id != "2" {
+		t.Errorf("new change id: got %v want 2", id)
+	}
+	readField(t, reader, "event: ")
+	var change models.Change
+	if err := json.Unmarshal([]byte(readField(t, reader, "data: ")), &change); err != nil || change.After.Title != "Updated" || change.Before.Title != "Test1" {
+		t.Errorf("new change data: got %+v, %v", change, err)
+	}
+
+	// Пока изменений нет, поток поддерживается пульсом
+	readField(t, reader, ": heartbeat")
+
+	// Остановка сервера завершает поток
+	handler.Shutdown()
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, reader)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("stream after shutdown: got %v want EOF", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("stream is not closed after shutdown")
+	}
+
+	// Новые потоки после остановки не принимаются
+	response, err = http.Get(server.URL + "/events/stream?user_id=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusServiceUnavailable || response.Header.Get("Retry-After") != "3" {
+		t.Errorf("stream after shutdown: got %v, Retry-After %q want %v, 3", response.StatusCode, response.Header.Get("Retry-After"), http.StatusServiceUnavailable)
+	}
+}
+
+func TestHandlerEventsForDay(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		events     []*models.Event
+		want       string
+		wantStatus int
+	}{
+		{
+			name:   "OK Event For Day",
 			method: "GET",
 			events: []*models.Event{
-				models.NewEvent(5, 0, time.Date(2026, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
-				models.NewEvent(5, 0, time.Date(2026, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
-				models.NewEvent(5, 0, time.Date(2026, 5, 30, 14, 04, 04, 0, time.UTC), "Test3", "Test3"),
-				models.NewEvent(5, 0, time.Date(2026, 6, 20, 14, 04, 04, 0, time.UTC), "Test4", "Test4"),
+				models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
+				models.NewEvent(5, 0, time.Date(2036, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
+				models.NewEvent(5, 0, time.Date(2036, 5, 30, 14, 04, 04, 0, time.UTC), "Test3", "Test3"),
+				models.NewEvent(5, 0, time.Date(2036, 6, 20, 14, 04, 04, 0, time.UTC), "Test4", "Test4"),
 			},
-			url: "http://localhost:8080/events_for_month?user_id=5&date=2026-05-12",
-			want: "{\"result\":[" +
-				"{\"user_id\":5,\"id\":0,\"date\":\"2026-05-12T14:04:04Z\",\"title\":\"Test1\",\"description\":\"Test1\"}," +
-				"{\"user_id\":5,\"id\":1,\"date\":\"2026-05-18T14:04:04Z\",\"title\":\"Test2\",\"description\":\"Test2\"}," +
-				"{\"user_id\":5,\"id\":2,\"date\":\"2026-05-30T14:04:04Z\",\"title\":\"Test3\",\"description\":\"Test3\"}" +
-				"]}\n",
+			url:        "http://localhost:8080/events_for_day?user_id=5&date=2036-05-12",
+			want:       "{\"request_id\":\"test\",\"result\":[{\"user_id\":5,\"id\":0,\"version\":1,\"date\":\"2036-05-12T14:04:04Z\",\"title\":\"Test1\",\"description\":\"Test1\"}]}\n",
 			wantStatus: http.StatusOK,
 		},
 		{
@@ -668,73 +1420,98 @@ func TestHandlerEventsForMonth(t *testing.T) {
 			method: "GET",
 			url:    "http://localhost:8080/wrong_path",
 			events: []*models.Event{
-				models.NewEvent(5, 0, time.Date(2026, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
-				models.NewEvent(5, 0, time.Date(2026, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
-				models.NewEvent(5, 0, time.Date(2026, 5, 30, 14, 04, 04, 0, time.UTC), "Test3", "Test3"),
-				models.NewEvent(5, 0, time.Date(2026, 6, 20, 14, 04, 04, 0, time.UTC), "Test4", "Test4"),
+				models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
+				models.NewEvent(5, 0, time.Date(2036, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
+				models.NewEvent(5, 0, time.Date(2036, 5, 30, 14, 04, 04, 0, time.UTC), "Test3", "Test3"),
+				models.NewEvent(5, 0, time.Date(2036, 6, 20, 14, 04, 04, 0, time.UTC), "Test4", "Test4"),
 			},
-			want:       "{\"error\":\"path /wrong_path not found\"}\n",
+			want:       "{\"error\":\"path /wrong_path not found\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusNotFound,
 		},
 		{
-			name:   "Empty Event For Month",
+			name:   "Empty Event For Day",
 			method: "GET",
 			events: []*models.Event{
-				models.NewEvent(5, 0, time.Date(2026, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
-				models.NewEvent(5, 1, time.Date(2026, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
-				models.NewEvent(5, 2, time.Date(2026, 5, 30, 14, 04, 04, 0, time.UTC), "Test3", "Test3"),
-				models.NewEvent(5, 3, time.Date(2026, 6, 20, 14, 04, 04, 0, time.UTC), "Test4", "Test4"),
+				models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
+				models.NewEvent(5, 1, time.Date(2036, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
+				models.NewEvent(5, 2, time.Date(2036, 5, 30, 14, 04, 04, 0, time.UTC), "Test3", "Test3"),
+				models.NewEvent(5, 3, time.Date(2036, 6, 20, 14, 04, 04, 0, time.UTC), "Test4", "Test4"),
 			},
-			url:        "http://localhost:8080/events_for_month?user_id=5&date=2026-07-13",
-			want:       "{\"result\":[]}\n",
+			url:        "http://localhost:8080/events_for_day?user_id=5&date=2036-05-13",
+			want:       "{\"request_id\":\"test\",\"result\":[]}\n",
 			wantStatus: http.StatusOK,
 		},
+
 		{
 			name:       "UserID Not Found",
 			method:     "GET",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
-			url:        "http://localhost:8080/events_for_month?user_id=1&date=2026-05-13",
-			want:       "{\"error\":\"user_id 1 not found\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_day?user_id=1&date=2036-05-13",
+			want:       "{\"error\":\"user_id 1 not found\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusNotFound,
 		},
 		{
 			name:       "Method",
 			method:     "POST",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
-			url:        "http://localhost:8080/events_for_month?user_id=5&date=2026-05-13",
-			want:       "{\"error\":\"method not allowed: bad method POST, method must be GET\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_day?user_id=5&date=2036-05-13",
+			want:       "{\"error\":\"method not allowed: bad method POST, method must be GET\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusMethodNotAllowed,
 		},
 		{
 			name:       "Invalid UserID",
 			method:     "GET",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
-			url:        "http://localhost:8080/events_for_month?user_id=one&date=2026-05-13",
-			want:       "{\"error\":\"bad request: invalid user_id: use only numbers\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_day?user_id=one&date=2036-05-13",
+			want:       "{\"error\":\"bad request: invalid user_id: use only numbers\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
 			name:       "No UserID",
 			method:     "GET",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
-			url:        "http://localhost:8080/events_for_month?date=2026-05-13",
-			want:       "{\"error\":\"bad request: empty parameter: user_id\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_day?date=2036-05-13",
+			want:       "{\"error\":\"bad request: empty parameter: user_id\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
 			name:       "No Date",
 			method:     "GET",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
-			url:        "http://localhost:8080/events_for_month?user_id=5",
-			want:       "{\"error\":\"bad request: empty parameter: date\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_day?user_id=5",
+			want:       "{\"error\":\"bad request: empty parameter: date\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "OK Event For Day In Time Zone",
+			method:     "GET",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 22, 30, 0, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_day?user_id=5&date=2036-05-13&tz=Europe/Moscow",
+			want:       "{\"request_id\":\"test\",\"result\":[{\"user_id\":5,\"id\":0,\"version\":1,\"date\":\"2036-05-13T01:30:00+03:00\",\"title\":\"Test1\",\"description\":\"Test1\"}]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Empty Event For Day In UTC",
+			method:     "GET",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 22, 30, 0, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_day?user_id=5&date=2036-05-13",
+			want:       "{\"request_id\":\"test\",\"result\":[]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Invalid Time Zone",
+			method:     "GET",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_day?user_id=5&date=2036-05-13&tz=Mars/Olympus",
+			want:       "{\"error\":\"bad request: invalid tz: unknown time zone Mars/Olympus\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 		{
 			name:       "Format Date",
 			method:     "GET",
-			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2026, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
-			url:        "http://localhost:8080/events_for_month?user_id=5&date=2026.05.13",
-			want:       "{\"error\":\"bad request: invalid date format: correct format 2006-01-02\"}\n",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_day?user_id=5&date=2036.05.13",
+			want:       "{\"error\":\"bad request: invalid date format: correct format 2006-01-02\",\"request_id\":\"test\"}\n",
 			wantStatus: http.StatusBadRequest,
 		},
 	}
@@ -746,6 +1523,7 @@ func TestHandlerEventsForMonth(t *testing.T) {
 				return
 			}
 			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			request.Header.Set("X-Request-ID", "test")
 			responseRecorder := httptest.NewRecorder()
 			service := service.New(data.New())
 			for _, event := range tt.events {
@@ -762,3 +1540,1830 @@ func TestHandlerEventsForMonth(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlerEventsForWeek(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		events     []*models.Event
+		want       string
+		wantStatus int
+	}{
+		{
+			name:   "OK Event For Week",
+			method: "GET",
+			events: []*models.Event{
+				models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
+				models.NewEvent(5, 0, time.Date(2036, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
+				models.NewEvent(5, 0, time.Date(2036, 5, 30, 14, 04, 04, 0, time.UTC), "Test3", "Test3"),
+				models.NewEvent(5, 0, time.Date(2036, 6, 20, 14, 04, 04, 0, time.UTC), "Test4", "Test4"),
+			},
+			url: "http://localhost:8080/events_for_week?user_id=5&date=2036-05-12",
+			want: "{\"request_id\":\"test\",\"result\":[" +
+				"{\"user_id\":5,\"id\":0,\"version\":1,\"date\":\"2036-05-12T14:04:04Z\",\"title\":\"Test1\",\"description\":\"Test1\"}," +
+				"{\"user_id\":5,\"id\":1,\"version\":1,\"date\":\"2036-05-18T14:04:04Z\",\"title\":\"Test2\",\"description\":\"Test2\"}" +
+				"]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "Not Found Path",
+			method: "GET",
+			url:    "http://localhost:8080/wrong_path",
+			events: []*models.Event{
+				models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
+				models.NewEvent(5, 0, time.Date(2036, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
+				models.NewEvent(5, 0, time.Date(2036, 5, 30, 14, 04, 04, 0, time.UTC), "Test3", "Test3"),
+				models.NewEvent(5, 0, time.Date(2036, 6, 20, 14, 04, 04, 0, time.UTC), "Test4", "Test4"),
+			},
+			want:       "{\"error\":\"path /wrong_path not found\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:   "OK Recurring Event For Week",
+			method: "GET",
+			events: []*models.Event{
+				func() *models.Event {
+					event := models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")
+					event.Recurrence = &models.Recurrence{Freq: models.FreqDaily, Interval: 3}
+					return event
+				}(),
+			},
+			url: "http://localhost:8080/events_for_week?user_id=5&date=2036-05-13",
+			want: "{\"request_id\":\"test\",\"result\":[" +
+				"{\"user_id\":5,\"id\":0,\"version\":1,\"date\":\"2036-05-15T14:04:04Z\",\"title\":\"Test1\",\"description\":\"Test1\",\"recurrence\":{\"freq\":\"DAILY\",\"interval\":3}}," +
+				"{\"user_id\":5,\"id\":0,\"version\":1,\"date\":\"2036-05-18T14:04:04Z\",\"title\":\"Test1\",\"description\":\"Test1\",\"recurrence\":{\"freq\":\"DAILY\",\"interval\":3}}" +
+				"]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "Empty Event For Week",
+			method: "GET",
+			events: []*models.Event{
+				models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
+				models.NewEvent(5, 1, time.Date(2036, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
+				models.NewEvent(5, 2, time.Date(2036, 5, 30, 14, 04, 04, 0, time.UTC), "Test3", "Test3"),
+				models.NewEvent(5, 3, time.Date(2036, 6, 20, 14, 04, 04, 0, time.UTC), "Test4", "Test4"),
+			},
+			url:        "http://localhost:8080/events_for_week?user_id=5&date=2036-07-13",
+			want:       "{\"request_id\":\"test\",\"result\":[]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "UserID Not Found",
+			method:     "GET",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_week?user_id=1&date=2036-05-13",
+			want:       "{\"error\":\"user_id 1 not found\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Method",
+			method:     "POST",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_week?user_id=5&date=2036-05-13",
+			want:       "{\"error\":\"method not allowed: bad method POST, method must be GET\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "Invalid UserID",
+			method:     "GET",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_week?user_id=one&date=2036-05-13",
+			want:       "{\"error\":\"bad request: invalid user_id: use only numbers\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "No UserID",
+			method:     "GET",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_week?date=2036-05-13",
+			want:       "{\"error\":\"bad request: empty parameter: user_id\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "No Date",
+			method:     "GET",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_week?user_id=5",
+			want:       "{\"error\":\"bad request: empty parameter: date\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Format Date",
+			method:     "GET",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_week?user_id=5&date=2036.05.13",
+			want:       "{\"error\":\"bad request: invalid date format: correct format 2006-01-02\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(tt.method, tt.url, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			request.Header.Set("X-Request-ID", "test")
+			responseRecorder := httptest.NewRecorder()
+			service := service.New(data.New())
+			for _, event := range tt.events {
+				service.Create(event)
+			}
+			handler := New(service).InitRouter()
+			handler.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != tt.wantStatus {
+				t.Errorf("status: got %v want %v", responseRecorder.Code, tt.wantStatus)
+			}
+			if responseRecorder.Body.String() != tt.want {
+				t.Errorf("result: got %v want %v", responseRecorder.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerEventsForMonth(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		events     []*models.Event
+		want       string
+		wantStatus int
+	}{
+		{
+			name:   "OK Event For Month",
+			method: "GET",
+			events: []*models.Event{
+				models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
+				models.NewEvent(5, 0, time.Date(2036, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
+				models.NewEvent(5, 0, time.Date(2036, 5, 30, 14, 04, 04, 0, time.UTC), "Test3", "Test3"),
+				models.NewEvent(5, 0, time.Date(2036, 6, 20, 14, 04, 04, 0, time.UTC), "Test4", "Test4"),
+			},
+			url: "http://localhost:8080/events_for_month?user_id=5&date=2036-05-12",
+			want: "{\"request_id\":\"test\",\"result\":[" +
+				"{\"user_id\":5,\"id\":0,\"version\":1,\"date\":\"2036-05-12T14:04:04Z\",\"title\":\"Test1\",\"description\":\"Test1\"}," +
+				"{\"user_id\":5,\"id\":1,\"version\":1,\"date\":\"2036-05-18T14:04:04Z\",\"title\":\"Test2\",\"description\":\"Test2\"}," +
+				"{\"user_id\":5,\"id\":2,\"version\":1,\"date\":\"2036-05-30T14:04:04Z\",\"title\":\"Test3\",\"description\":\"Test3\"}" +
+				"]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "Not Found Path",
+			method: "GET",
+			url:    "http://localhost:8080/wrong_path",
+			events: []*models.Event{
+				models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
+				models.NewEvent(5, 0, time.Date(2036, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
+				models.NewEvent(5, 0, time.Date(2036, 5, 30, 14, 04, 04, 0, time.UTC), "Test3", "Test3"),
+				models.NewEvent(5, 0, time.Date(2036, 6, 20, 14, 04, 04, 0, time.UTC), "Test4", "Test4"),
+			},
+			want:       "{\"error\":\"path /wrong_path not found\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:   "Empty Event For Month",
+			method: "GET",
+			events: []*models.Event{
+				models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
+				models.NewEvent(5, 1, time.Date(2036, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
+				models.NewEvent(5, 2, time.Date(2036, 5, 30, 14, 04, 04, 0, time.UTC), "Test3", "Test3"),
+				models.NewEvent(5, 3, time.Date(2036, 6, 20, 14, 04, 04, 0, time.UTC), "Test4", "Test4"),
+			},
+			url:        "http://localhost:8080/events_for_month?user_id=5&date=2036-07-13",
+			want:       "{\"request_id\":\"test\",\"result\":[]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "UserID Not Found",
+			method:     "GET",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_month?user_id=1&date=2036-05-13",
+			want:       "{\"error\":\"user_id 1 not found\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Method",
+			method:     "POST",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_month?user_id=5&date=2036-05-13",
+			want:       "{\"error\":\"method not allowed: bad method POST, method must be GET\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "Invalid UserID",
+			method:     "GET",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_month?user_id=one&date=2036-05-13",
+			want:       "{\"error\":\"bad request: invalid user_id: use only numbers\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "No UserID",
+			method:     "GET",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_month?date=2036-05-13",
+			want:       "{\"error\":\"bad request: empty parameter: user_id\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "No Date",
+			method:     "GET",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_month?user_id=5",
+			want:       "{\"error\":\"bad request: empty parameter: date\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Format Date",
+			method:     "GET",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 13, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			url:        "http://localhost:8080/events_for_month?user_id=5&date=2036.05.13",
+			want:       "{\"error\":\"bad request: invalid date format: correct format 2006-01-02\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(tt.method, tt.url, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			request.Header.Set("X-Request-ID", "test")
+			responseRecorder := httptest.NewRecorder()
+			service := service.New(data.New())
+			for _, event := range tt.events {
+				service.Create(event)
+			}
+			handler := New(service).InitRouter()
+			handler.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != tt.wantStatus {
+				t.Errorf("status: got %v want %v", responseRecorder.Code, tt.wantStatus)
+			}
+			if responseRecorder.Body.String() != tt.want {
+				t.Errorf("result: got %v want %v", responseRecorder.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerEventsPagination(t *testing.T) {
+	service := service.New(data.New())
+	service.Create(models.NewEvent(5, 0, time.Date(2036, 5, 14, 10, 0, 0, 0, time.UTC), "Test1", "Planning"))
+	service.Create(models.NewEvent(5, 0, time.Date(2036, 5, 12, 10, 0, 0, 0, time.UTC), "Test2", "Test2"))
+	recurring := models.NewEvent(5, 0, time.Date(2036, 5, 13, 9, 0, 0, 0, time.UTC), "Meeting", "Daily")
+	recurring.Recurrence = &models.Recurrence{Freq: models.FreqDaily, Count: 3}
+	service.Create(recurring)
+	// Даты после 2262 года не помещаются в наносекунды Unix
+	service.Create(models.NewEvent(5, 0, time.Date(2300, 5, 12, 10, 0, 0, 0, time.UTC), "Far1", "Far1"))
+	service.Create(models.NewEvent(5, 0, time.Date(2300, 5, 13, 10, 0, 0, 0, time.UTC), "Far2", "Far2"))
+	handler := New(service).InitRouter()
+
+	get := func(url string) *httptest.ResponseRecorder {
+		request, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set("X-Request-ID", "test")
+		responseRecorder := httptest.NewRecorder()
+		handler.ServeHTTP(responseRecorder, request)
+		return responseRecorder
+	}
+	titles := func(responseRecorder *httptest.ResponseRecorder) string {
+		var body struct {
+			Result []*models.Event `json:"result"`
+		}
+		if err := json.NewDecoder(responseRecorder.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		parts := make([]string, 0, len(body.Result))
+		for _, event := range body.Result {
+			parts = append(parts, event.Title+"@"+event.Date.Format("02T15"))
+		}
+		return strings.Join(parts, ",")
+	}
+
+	t.Run("Pages", func(t *testing.T) {
+		url := "http://localhost:8080/events_for_week?user_id=5&date=2036-05-12&limit=2"
+		want := []string{"Test2@12T10,Meeting@13T09", "Meeting@14T09,Test1@14T10", "Meeting@15T09"}
+		for i, wantTitles := range want {
+			responseRecorder := get(url)
+			if responseRecorder.Code != http.StatusOK {
+				t.Fatalf("page %d: status got %v want %v", i, responseRecorder.Code, http.StatusOK)
+			}
+			cursor := responseRecorder.Header().Get("X-Next-Cursor")
+			if got := titles(responseRecorder); got != wantTitles {
+				t.Errorf("page %d: got %s want %s", i, got, wantTitles)
+			}
+			if last := i == len(want)-1; last != (cursor == "") {
+				t.Fatalf("page %d: cursor %q", i, cursor)
+			}
+			url = "http://localhost:8080/events_for_week?user_id=5&date=2036-05-12&limit=2&cursor=" + cursor
+		}
+	})
+
+	t.Run("Pages After 2262", func(t *testing.T) {
+		responseRecorder := get("http://localhost:8080/events_for_week?user_id=5&date=2300-05-12&limit=1")
+		cursor := responseRecorder.Header().Get("X-Next-Cursor")
+		if got := titles(responseRecorder); got != "Far1@12T10" || cursor == "" {
+			t.Fatalf("first page: got %s, cursor %q", got, cursor)
+		}
+		responseRecorder = get("http://localhost:8080/events_for_week?user_id=5&date=2300-05-12&limit=1&cursor=" + cursor)
+		if got := titles(responseRecorder); got != "Far2@13T10" {
+			t.Errorf("second page: got %s want Far2@13T10", got)
+		}
+	})
+
+	tests := []struct {
+		name       string
+		url        string
+		want       string
+		wantStatus int
+	}{
+		{
+			name:       "Filter Title",
+			url:        "http://localhost:8080/events_for_month?user_id=5&date=2036-05-01&title=meet",
+			want:       "Meeting@13T09,Meeting@14T09,Meeting@15T09",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Filter Text",
+			url:        "http://localhost:8080/events_for_month?user_id=5&date=2036-05-01&q=PLAN",
+			want:       "Test1@14T10",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Invalid Limit",
+			url:        "http://localhost:8080/events_for_day?user_id=5&date=2036-05-12&limit=0",
+			want:       "{\"error\":\"bad request: invalid limit: must be between 1 and 1000\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Invalid Cursor",
+			url:        "http://localhost:8080/events_for_day?user_id=5&date=2036-05-12&cursor=bm9wZQ",
+			want:       "{\"error\":\"bad request: invalid cursor\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responseRecorder := get(tt.url)
+			if responseRecorder.Code != tt.wantStatus {
+				t.Errorf("status: got %v want %v", responseRecorder.Code, tt.wantStatus)
+			}
+			got := responseRecorder.Body.String()
+			if responseRecorder.Code == http.StatusOK {
+				got = titles(responseRecorder)
+			}
+			if got != tt.want {
+				t.Errorf("result: got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerEventsRange(t *testing.T) {
+	events := []*models.Event{
+		models.NewEvent(5, 0, time.Date(2036, 5, 12, 10, 0, 0, 0, time.UTC), "Test1", "Test1"),
+		models.NewEvent(5, 0, time.Date(2036, 5, 14, 10, 0, 0, 0, time.UTC), "Test2", "Test2"),
+		models.NewEvent(5, 0, time.Date(2036, 5, 20, 10, 0, 0, 0, time.UTC), "Test3", "Test3"),
+	}
+	event := func(id, day int) string {
+		return fmt.Sprintf("{\"user_id\":5,\"id\":%d,\"version\":1,\"date\":\"2036-05-%dT10:00:00Z\",\"title\":\"Test%d\",\"description\":\"Test%d\"}", id, day, id+1, id+1)
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		want       string
+		wantStatus int
+	}{
+		{
+			name:       "OK Exclusive",
+			url:        "http://localhost:8080/events?user_id=5&from=2036-05-12&to=2036-05-20",
+			want:       "{\"request_id\":\"test\",\"result\":[" + event(0, 12) + "," + event(1, 14) + "]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "OK Inclusive Date",
+			url:        "http://localhost:8080/events?user_id=5&from=2036-05-12&to=2036-05-20&inclusive=true",
+			want:       "{\"request_id\":\"test\",\"result\":[" + event(0, 12) + "," + event(1, 14) + "," + event(2, 20) + "]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "OK Inclusive Date Time",
+			url:        "http://localhost:8080/events?user_id=5&from=2036-05-13&to=2036-05-14T10:00:00Z&inclusive=true",
+			want:       "{\"request_id\":\"test\",\"result\":[" + event(1, 14) + "]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Too Long",
+			url:        "http://localhost:8080/events?user_id=5&from=2036-01-01&to=2037-01-03",
+			want:       "{\"error\":\"bad request: period is too long, maximum 366 days\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Invalid Inclusive",
+			url:        "http://localhost:8080/events?user_id=5&from=2036-05-12&to=2036-05-20&inclusive=maybe",
+			want:       "{\"error\":\"bad request: invalid inclusive: must be true or false\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "No To",
+			url:        "http://localhost:8080/events?user_id=5&from=2036-05-12",
+			want:       "{\"error\":\"bad request: empty parameter: to\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "OK Upcoming",
+			url:        "http://localhost:8080/events/upcoming?user_id=5&from=2036-05-12 10:00:01&n=1",
+			want:       "{\"request_id\":\"test\",\"result\":[" + event(1, 14) + "]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "OK Upcoming All",
+			url:        "http://localhost:8080/events/upcoming?user_id=5&from=2036-05-01",
+			want:       "{\"request_id\":\"test\",\"result\":[" + event(0, 12) + "," + event(1, 14) + "," + event(2, 20) + "]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Upcoming Invalid N",
+			url:        "http://localhost:8080/events/upcoming?user_id=5&n=-1",
+			want:       "{\"error\":\"bad request: invalid n: must be between 1 and 1000\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest("GET", tt.url, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			request.Header.Set("X-Request-ID", "test")
+			responseRecorder := httptest.NewRecorder()
+			service := service.New(data.New())
+			for _, event := range events {
+				copied := *event
+				service.Create(&copied)
+			}
+			handler := New(service).InitRouter()
+			handler.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != tt.wantStatus {
+				t.Errorf("status: got %v want %v", responseRecorder.Code, tt.wantStatus)
+			}
+			if responseRecorder.Body.String() != tt.want {
+				t.Errorf("result: got %v want %v", responseRecorder.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerFreeBusy(t *testing.T) {
+	events := []*models.Event{
+		withEnd(models.NewEvent(5, 0, time.Date(2036, 5, 12, 9, 0, 0, 0, time.UTC), "Test1", "Test1"), time.Hour),
+		withEnd(models.NewEvent(5, 0, time.Date(2036, 5, 12, 9, 30, 0, 0, time.UTC), "Test2", "Test2"), time.Hour),
+		withEnd(models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 0, 0, 0, time.UTC), "Test3", "Test3"), 2*time.Hour),
+		models.NewEvent(5, 0, time.Date(2036, 5, 12, 12, 0, 0, 0, time.UTC), "Test4", "Test4"),
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		method     string
+		want       string
+		wantStatus int
+	}{
+		{
+			name:       "OK Merge",
+			url:        "http://localhost:8080/free_busy?user_id=5&from=2036-05-12&to=2036-05-13",
+			method:     "GET",
+			want:       "{\"request_id\":\"test\",\"result\":[{\"start\":\"2036-05-12T09:00:00Z\",\"end\":\"2036-05-12T10:30:00Z\"},{\"start\":\"2036-05-12T14:00:00Z\",\"end\":\"2036-05-12T16:00:00Z\"}]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "OK Clip",
+			url:        "http://localhost:8080/free_busy?user_id=5&from=2036-05-12 10:00:00&to=2036-05-12 15:00:00",
+			method:     "GET",
+			want:       "{\"request_id\":\"test\",\"result\":[{\"start\":\"2036-05-12T10:00:00Z\",\"end\":\"2036-05-12T10:30:00Z\"},{\"start\":\"2036-05-12T14:00:00Z\",\"end\":\"2036-05-12T15:00:00Z\"}]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "To Before From",
+			url:        "http://localhost:8080/free_busy?user_id=5&from=2036-05-13&to=2036-05-12",
+			method:     "GET",
+			want:       "{\"error\":\"bad request: to must be after from\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "No From",
+			url:        "http://localhost:8080/free_busy?user_id=5&to=2036-05-12",
+			method:     "GET",
+			want:       "{\"error\":\"bad request: empty parameter: from\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Method",
+			url:        "http://localhost:8080/free_busy?user_id=5&from=2036-05-12&to=2036-05-13",
+			method:     "POST",
+			want:       "{\"error\":\"method not allowed: bad method POST, method must be GET\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(tt.method, tt.url, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			request.Header.Set("X-Request-ID", "test")
+			responseRecorder := httptest.NewRecorder()
+			service := service.New(data.New())
+			for _, event := range events {
+				service.Create(event)
+			}
+			handler := New(service).InitRouter()
+			handler.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != tt.wantStatus {
+				t.Errorf("status: got %v want %v", responseRecorder.Code, tt.wantStatus)
+			}
+			if responseRecorder.Body.String() != tt.want {
+				t.Errorf("result: got %v want %v", responseRecorder.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+// withEnd - задает событию окончание через duration после начала
+func withEnd(event *models.Event, duration time.Duration) *models.Event {
+	end := event.Date.Add(duration)
+	event.End = &end
+	return event
+}
+
+func TestHandlerHealth(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		prepare    func(h *Handler, storage data.Eventer)
+		want       string
+		wantStatus int
+	}{
+		{
+			name:       "OK Healthz",
+			url:        "http://localhost:8080/healthz",
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "OK Readyz",
+			url:        "http://localhost:8080/readyz",
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Readyz Shutting Down",
+			url:        "http://localhost:8080/readyz",
+			prepare:    func(h *Handler, _ data.Eventer) { h.Shutdown() },
+			want:       "{\"error\":\"service unavailable\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "Readyz Storage Closed",
+			url:        "http://localhost:8080/readyz",
+			prepare:    func(_ *Handler, storage data.Eventer) { storage.Close() },
+			want:       "{\"error\":\"service unavailable\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "Healthz Storage Closed",
+			url:        "http://localhost:8080/healthz",
+			prepare:    func(_ *Handler, storage data.Eventer) { storage.Close() },
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, err := data.NewFile(t.TempDir(), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer storage.Close()
+			h := New(service.New(storage))
+			if tt.prepare != nil {
+				tt.prepare(h, storage)
+			}
+
+			request, err := http.NewRequest("GET", tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			request.Header.Set("X-Request-ID", "test")
+			responseRecorder := httptest.NewRecorder()
+			h.InitRouter().ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != tt.wantStatus {
+				t.Errorf("status: got %v want %v", responseRecorder.Code, tt.wantStatus)
+			}
+			if responseRecorder.Body.String() != tt.want {
+				t.Errorf("result: got %v want %v", responseRecorder.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerMetrics(t *testing.T) {
+	service := service.New(data.New())
+	service.Create(models.NewEvent(5, 0, time.Date(2036, 5, 12, 10, 0, 0, 0, time.UTC), "Test1", "Test1"))
+	service.Create(models.NewEvent(7, 0, time.Date(2036, 5, 12, 10, 0, 0, 0, time.UTC), "Test2", "Test2"))
+	handler := New(service).InitRouter()
+
+	for _, url := range []string{
+		"http://localhost:8080/events_for_day?user_id=5&date=2036-05-12",
+		"http://localhost:8080/events_for_day?user_id=5&date=2036-05-12",
+		"http://localhost:8080/events_for_day?user_id=1&date=2036-05-12",
+		"http://localhost:8080/api/v1/users/5/events/0",
+	} {
+		request, _ := http.NewRequest("GET", url, nil)
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+	}
+	// Нестандартные методы не создают новых рядов
+	for _, method := range []string{"FOO", "get", "PROPFIND"} {
+		request, _ := http.NewRequest(method, "http://localhost:8080/healthz", nil)
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	request, _ := http.NewRequest("GET", "http://localhost:8080/metrics", nil)
+	request.Header.Set("X-Request-ID", "test")
+	responseRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(responseRecorder, request)
+	if responseRecorder.Code != http.StatusOK {
+		t.Fatalf("status: got %v want %v", responseRecorder.Code, http.StatusOK)
+	}
+	if contentType := responseRecorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("content type: got %s want text/plain", contentType)
+	}
+
+	body := responseRecorder.Body.String()
+	for _, want := range []string{
+		"calendar_http_requests_total{route=\"/events_for_day\",method=\"GET\",status=\"200\"} 2\n",
+		"calendar_http_requests_total{route=\"/events_for_day\",method=\"GET\",status=\"404\"} 1\n",
+		"calendar_http_requests_total{route=\"/api/v1/users/{id}/events/{eventID}\",method=\"GET\",status=\"200\"} 1\n",
+		"calendar_http_requests_total{route=\"/healthz\",method=\"OTHER\",status=\"405\"} 3\n",
+		"calendar_http_request_duration_seconds_count{route=\"/events_for_day\",status=\"200\"} 2\n",
+		"calendar_http_requests_in_flight 1\n",
+		"calendar_users 2\n",
+		"calendar_events 2\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics: missing %q in\n%s", want, body)
+		}
+	}
+}
+
+func TestHandlerRequestID(t *testing.T) {
+	tests := []struct {
+		name       string
+		requestID  string
+		url        string
+		wantID     string
+		wantStatus int
+		wantLog    map[string]any
+	}{
+		{
+			name:       "Propagated",
+			requestID:  "client-id-1",
+			url:        "http://localhost:8080/events_for_day?user_id=5&date=2036-05-12",
+			wantID:     "client-id-1",
+			wantStatus: http.StatusOK,
+			wantLog:    map[string]any{"level": "INFO", "route": "/events_for_day", "status": float64(200), "user_id": "5"},
+		},
+		{
+			name:       "Generated",
+			url:        "http://localhost:8080/api/v1/users/5/events/10",
+			wantStatus: http.StatusNotFound,
+			wantLog:    map[string]any{"level": "WARN", "route": "/api/v1/users/{id}/events/{eventID}", "status": float64(404), "user_id": "5", "error": "event id 10 not found"},
+		},
+		{
+			name:       "Invalid Replaced",
+			requestID:  "bad id",
+			url:        "http://localhost:8080/events_for_day?user_id=5&date=2036-05-12",
+			wantStatus: http.StatusOK,
+			wantLog:    map[string]any{"route": "/events_for_day"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs strings.Builder
+			defaultLogger := slog.Default()
+			slog.SetDefault(logger.New(&logs, slog.LevelInfo))
+			defer slog.SetDefault(defaultLogger)
+
+			request, err := http.NewRequest("GET", tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.requestID != "" {
+				request.Header.Set("X-Request-ID", tt.requestID)
+			}
+			service := service.New(data.New())
+			service.Create(models.NewEvent(5, 0, time.Date(2036, 5, 12, 10, 0, 0, 0, time.UTC), "Test1", "Test1"))
+			responseRecorder := httptest.NewRecorder()
+			New(service).InitRouter().ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != tt.wantStatus {
+				t.Errorf("status: got %v want %v", responseRecorder.Code, tt.wantStatus)
+			}
+
+			requestID := responseRecorder.Header().Get("X-Request-ID")
+			if tt.wantID != "" && requestID != tt.wantID {
+				t.Errorf("X-Request-ID: got %q want %q", requestID, tt.wantID)
+			}
+			if requestID == "" || requestID == tt.requestID && tt.wantID == "" {
+				t.Errorf("X-Request-ID: got %q want generated", requestID)
+			}
+
+			bodyLen := responseRecorder.Body.Len()
+			var body map[string]any
+			if err := json.NewDecoder(responseRecorder.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body["request_id"] != requestID {
+				t.Errorf("body request_id: got %v want %s", body["request_id"], requestID)
+			}
+
+			var record map[string]any
+			if err := json.Unmarshal([]byte(logs.String()), &record); err != nil {
+				t.Fatalf("log is not a single JSON record: %v %s", err, logs.String())
+			}
+			if record["request_id"] != requestID {
+				t.Errorf("log request_id: got %v want %s", record["request_id"], requestID)
+			}
+			if record["bytes"] != float64(bodyLen) {
+				t.Errorf("log bytes: got %v want %d", record["bytes"], bodyLen)
+			}
+			for key, want := range tt.wantLog {
+				if record[key] != want {
+					t.Errorf("log %s: got %v want %v", key, record[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlerLimits(t *testing.T) {
+	type limitRequest struct {
+		remoteAddr  string
+		method      string
+		url         string
+		contentType string
+		body        string
+	}
+	dayRequest := func(remoteAddr string, userID int) limitRequest {
+		return limitRequest{remoteAddr: remoteAddr, method: "GET", url: fmt.Sprintf("http://localhost:8080/events_for_day?user_id=%d&date=2036-05-12", userID)}
+	}
+
+	tests := []struct {
+		name           string
+		options        []Option
+		requests       []limitRequest
+		want           string
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{
+			name:           "IP Limited",
+			options:        []Option{WithRateLimit(ratelimit.New(1, 2), nil)},
+			requests:       []limitRequest{dayRequest("10.0.0.1:1000", 5), dayRequest("10.0.0.1:1001", 5), dayRequest("10.0.0.1:1002", 5)},
+			want:           "{\"error\":\"too many requests: retry after 1s\",\"request_id\":\"test\"}\n",
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: "1",
+		},
+		{
+			name:       "IP Limit Per Address",
+			options:    []Option{WithRateLimit(ratelimit.New(1, 1), nil)},
+			requests:   []limitRequest{dayRequest("10.0.0.1:1000", 5), dayRequest("10.0.0.2:1000", 5)},
+			want:       "{\"request_id\":\"test\",\"result\":[]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:           "User Limited",
+			options:        []Option{WithRateLimit(nil, ratelimit.New(0.5, 1))},
+			requests:       []limitRequest{dayRequest("10.0.0.1:1000", 5), dayRequest("10.0.0.2:1000", 5)},
+			want:           "{\"error\":\"too many requests: retry after 2s\",\"request_id\":\"test\"}\n",
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: "2",
+		},
+		{
+			name:       "User Limit Per User",
+			options:    []Option{WithRateLimit(nil, ratelimit.New(1, 1))},
+			requests:   []limitRequest{dayRequest("10.0.0.1:1000", 5), dayRequest("10.0.0.1:1000", 7)},
+			want:       "{\"error\":\"user_id 7 not found\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:    "Form Too Large",
+			options: []Option{WithBodyLimit(32, 1024)},
+			requests: []limitRequest{{
+				method:      "POST",
+				url:         "http://localhost:8080/create_event",
+				contentType: "application/x-www-form-urlencoded",
+				body:        "user_id=5&date=2036-05-12&title=" + strings.Repeat("a", 64),
+			}},
+			want:       "{\"error\":\"payload too large: maximum 32 bytes\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:    "JSON Too Large",
+			options: []Option{WithBodyLimit(32, 1024)},
+			requests: []limitRequest{{
+				method:      "POST",
+				url:         "http://localhost:8080/create_event",
+				contentType: "application/json",
+				body:        `{"user_id":5,"date":"2036-05-12","title":"` + strings.Repeat("a", 64) + `"}`,
+			}},
+			want:       "{\"error\":\"payload too large: maximum 32 bytes\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:    "Form Within Limit",
+			options: []Option{WithBodyLimit(128, 1024)},
+			requests: []limitRequest{{
+				method:      "POST",
+				url:         "http://localhost:8080/create_event",
+				contentType: "application/x-www-form-urlencoded",
+				body:        "user_id=5&date=2036-05-12 15:04:05&title=Test",
+			}},
+			want:       "{\"request_id\":\"test\",\"result\":{\"eventID\":1}}\n",
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:    "Calendar Too Large",
+			options: []Option{WithBodyLimit(32, 64)},
+			requests: []limitRequest{{
+				method:      "POST",
+				url:         "http://localhost:8080/import_ics?user_id=5",
+				contentType: "text/calendar",
+				body:        "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:20360512T100000Z\r\nSUMMARY:" + strings.Repeat("a", 64) + "\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+			}},
+			want:       "{\"error\":\"payload too large: maximum 64 bytes\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := service.New(data.New())
+			service.Create(models.NewEvent(5, 0, time.Date(2036, 5, 13, 10, 0, 0, 0, time.UTC), "Test1", "Test1"))
+			handler := New(service, tt.options...).InitRouter()
+
+			var responseRecorder *httptest.ResponseRecorder
+			for _, limitRequest := range tt.requests {
+				request, err := http.NewRequest(limitRequest.method, limitRequest.url, strings.NewReader(limitRequest.body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if limitRequest.remoteAddr != "" {
+					request.RemoteAddr = limitRequest.remoteAddr
+				}
+				if limitRequest.contentType != "" {
+					request.Header.Set("Content-Type", limitRequest.contentType)
+				}
+				request.Header.Set("X-Request-ID", "test")
+				responseRecorder = httptest.NewRecorder()
+				handler.ServeHTTP(responseRecorder, request)
+			}
+
+			if responseRecorder.Code != tt.wantStatus {
+				t.Errorf("status: got %v want %v", responseRecorder.Code, tt.wantStatus)
+			}
+			if responseRecorder.Body.String() != tt.want {
+				t.Errorf("result: got %v want %v", responseRecorder.Body.String(), tt.want)
+			}
+			if retryAfter := responseRecorder.Header().Get("Retry-After"); retryAfter != tt.wantRetryAfter {
+				t.Errorf("Retry-After: got %q want %q", retryAfter, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestHandlerExportICS(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		events     []*models.Event
+		want       []string
+		wantStatus int
+	}{
+		{
+			name:   "OK Export",
+			method: "GET",
+			events: []*models.Event{
+				models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
+				models.NewEvent(7, 0, time.Date(2036, 5, 18, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
+			},
+			url:        "http://localhost:8080/export.ics?user_id=5",
+			want:       []string{"BEGIN:VCALENDAR\r\n", "UID:5-0@dev11\r\n", "DTSTART:20360512T140404Z\r\n", "SUMMARY:Test1\r\n", "END:VCALENDAR\r\n"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "UserID Not Found",
+			method:     "GET",
+			url:        "http://localhost:8080/export.ics?user_id=1",
+			want:       []string{"{\"error\":\"user_id 1 not found\",\"request_id\":\"test\"}\n"},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "No UserID",
+			method:     "GET",
+			url:        "http://localhost:8080/export.ics",
+			want:       []string{"{\"error\":\"bad request: empty parameter: user_id\",\"request_id\":\"test\"}\n"},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(tt.method, tt.url, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			request.Header.Set("X-Request-ID", "test")
+			responseRecorder := httptest.NewRecorder()
+			service := service.New(data.New())
+			for _, event := range tt.events {
+				service.Create(event)
+			}
+			handler := New(service).InitRouter()
+			handler.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != tt.wantStatus {
+				t.Errorf("status: got %v want %v", responseRecorder.Code, tt.wantStatus)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(responseRecorder.Body.String(), want) {
+					t.Errorf("result: got %v want contains %q", responseRecorder.Body.String(), want)
+				}
+			}
+			if strings.Contains(responseRecorder.Body.String(), "Test2") {
+				t.Errorf("result: got event of another user %v", responseRecorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlerImportICS(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		method      string
+		contentType string
+		body        string
+		events      []*models.Event
+		want        string
+		wantStatus  int
+	}{
+		{
+			name:        "OK Import With Failures",
+			url:         "http://localhost:8080/import_ics?user_id=5",
+			method:      "POST",
+			contentType: "text/calendar",
+			body: "BEGIN:VCALENDAR\r\n" +
+				"BEGIN:VEVENT\r\nUID:a\r\nDTSTART:20360512T150405Z\r\nSUMMARY:Test\r\nEND:VEVENT\r\n" +
+				"BEGIN:VEVENT\r\nUID:b\r\nDTSTART:20070512T150405Z\r\nSUMMARY:Past\r\nEND:VEVENT\r\n" +
+				"END:VCALENDAR\r\n",
+			want: "{\"request_id\":\"test\",\"result\":{\"failed\":[{\"index\":1,\"uid\":\"b\",\"error\":\"bad request: event date cannot be in the past\"}]," +
+				"\"imported\":[{\"index\":0,\"uid\":\"a\",\"id\":0,\"status\":\"created\"}]}}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "OK Reimport By UID",
+			url:         "http://localhost:8080/import_ics?user_id=5",
+			method:      "POST",
+			contentType: "text/calendar",
+			body: "BEGIN:VCALENDAR\r\n" +
+				"BEGIN:VEVENT\r\nUID:a\r\nDTSTART:20360512T150405Z\r\nSUMMARY:Changed\r\nEND:VEVENT\r\n" +
+				"BEGIN:VEVENT\r\nUID:5-1@dev11\r\nDTSTART:20360513T150405Z\r\nSUMMARY:Own\r\nEND:VEVENT\r\n" +
+				"BEGIN:VEVENT\r\nUID:c\r\nDTSTART:20360514T150405Z\r\nSUMMARY:New\r\nEND:VEVENT\r\n" +
+				"BEGIN:VEVENT\r\nUID:c\r\nDTSTART:20360514T150405Z\r\nSUMMARY:New\r\nEND:VEVENT\r\n" +
+				"END:VCALENDAR\r\n",
+			events: []*models.Event{
+				{UserID: 5, Date: time.Date(2036, 5, 12, 15, 04, 05, 0, time.UTC), Title: "Test", UID: "a"},
+				models.NewEvent(5, 0, time.Date(2036, 5, 13, 15, 04, 05, 0, time.UTC), "Own", ""),
+			},
+			want: "{\"request_id\":\"test\",\"result\":{\"failed\":[],\"imported\":[" +
+				"{\"index\":0,\"uid\":\"a\",\"id\":0,\"status\":\"updated\"}," +
+				"{\"index\":1,\"uid\":\"5-1@dev11\",\"id\":1,\"status\":\"unchanged\"}," +
+				"{\"index\":2,\"uid\":\"c\",\"id\":2,\"status\":\"created\"}," +
+				"{\"index\":3,\"uid\":\"c\",\"id\":2,\"status\":\"unchanged\"}]}}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "OK Master And Override",
+			url:         "http://localhost:8080/import_ics?user_id=5",
+			method:      "POST",
+			contentType: "text/calendar",
+			body: "BEGIN:VCALENDAR\r\n" +
+				"BEGIN:VEVENT\r\nUID:a\r\nRECURRENCE-ID:20360519T150405Z\r\nDTSTART:20360519T170000Z\r\nSUMMARY:Moved\r\nEND:VEVENT\r\n" +
+				"BEGIN:VEVENT\r\nUID:a\r\nDTSTART:20360512T150405Z\r\nRRULE:FREQ=WEEKLY\r\nSUMMARY:Weekly\r\nEND:VEVENT\r\n" +
+				"BEGIN:VEVENT\r\nUID:z\r\nRECURRENCE-ID:20360519T150405Z\r\nDTSTART:20360519T170000Z\r\nSUMMARY:Orphan\r\nEND:VEVENT\r\n" +
+				"END:VCALENDAR\r\n",
+			want: "{\"request_id\":\"test\",\"result\":{\"failed\":[" +
+				"{\"index\":2,\"uid\":\"z\",\"error\":\"bad request: RECURRENCE-ID refers to no recurring event with UID \\\"z\\\"\"}]," +
+				"\"imported\":[{\"index\":0,\"uid\":\"a\",\"id\":1,\"status\":\"created\"}," +
+				"{\"index\":1,\"uid\":\"a\",\"id\":0,\"status\":\"created\"}]}}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "No Events",
+			url:         "http://localhost:8080/import_ics?user_id=5",
+			method:      "POST",
+			contentType: "text/calendar",
+			body:        "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n",
+			want:        "{\"error\":\"bad request: calendar has no events\",\"request_id\":\"test\"}\n",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "Content Type",
+			url:         "http://localhost:8080/import_ics?user_id=5",
+			method:      "POST",
+			contentType: "application/json",
+			body:        "{}",
+			want:        "{\"error\":\"bad request: unsupported content type: use multipart/form-data or text/calendar\",\"request_id\":\"test\"}\n",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "No UserID",
+			url:         "http://localhost:8080/import_ics",
+			method:      "POST",
+			contentType: "text/calendar",
+			body:        "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n",
+			want:        "{\"error\":\"bad request: empty parameter: user_id\",\"request_id\":\"test\"}\n",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "Method",
+			url:         "http://localhost:8080/import_ics?user_id=5",
+			method:      "GET",
+			contentType: "text/calendar",
+			want:        "{\"error\":\"method not allowed: bad method GET, method must be POST\",\"request_id\":\"test\"}\n",
+			wantStatus:  http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			request.Header.Set("Content-Type", tt.contentType)
+			request.Header.Set("X-Request-ID", "test")
+			responseRecorder := httptest.NewRecorder()
+			service := service.New(data.New())
+			for _, event := range tt.events {
+				service.Create(event)
+			}
+			handler := New(service).InitRouter()
+			handler.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != tt.wantStatus {
+				t.Errorf("status: got %v want %v", responseRecorder.Code, tt.wantStatus)
+			}
+			if responseRecorder.Body.String() != tt.want {
+				t.Errorf("result: got %v want %v", responseRecorder.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerImportICSOverride(t *testing.T) {
+	body := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:a\r\nDTSTART:20360512T150405Z\r\nRRULE:FREQ=WEEKLY\r\nSUMMARY:Weekly\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:a\r\nRECURRENCE-ID:20360519T150405Z\r\nDTSTART:20360519T170000Z\r\nSUMMARY:Moved\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	wants := []string{
+		"{\"request_id\":\"test\",\"result\":{\"failed\":[],\"imported\":[" +
+			"{\"index\":0,\"uid\":\"a\",\"id\":0,\"status\":\"created\"}," +
+			"{\"index\":1,\"uid\":\"a\",\"id\":1,\"status\":\"created\"}]}}\n",
+		"{\"request_id\":\"test\",\"result\":{\"failed\":[],\"imported\":[" +
+			"{\"index\":0,\"uid\":\"a\",\"id\":0,\"status\":\"unchanged\"}," +
+			"{\"index\":1,\"uid\":\"a\",\"id\":1,\"status\":\"unchanged\"}]}}\n",
+	}
+
+	eventService := service.New(data.New())
+	handler := New(eventService).InitRouter()
+	for i, want := range wants {
+		request, err := http.NewRequest("POST", "http://localhost:8080/import_ics?user_id=5", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set("Content-Type", "text/calendar")
+		request.Header.Set("X-Request-ID", "test")
+		responseRecorder := httptest.NewRecorder()
+		handler.ServeHTTP(responseRecorder, request)
+		if responseRecorder.Body.String() != want {
+			t.Errorf("import %d: got %v want %v", i, responseRecorder.Body.String(), want)
+		}
+	}
+
+	// Серия сохраняет правило и дату, а измененное повторение исключено из нее
+	master, err := eventService.Get(5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := time.Date(2036, 5, 19, 15, 4, 5, 0, time.UTC)
+	if master.Recurrence == nil || !master.Date.Equal(time.Date(2036, 5, 12, 15, 4, 5, 0, time.UTC)) {
+		t.Fatalf("master: got date %v recurrence %v", master.Date, master.Recurrence)
+	}
+	if len(master.Recurrence.ExDates) != 1 || !master.Recurrence.ExDates[0].Equal(moved) {
+		t.Errorf("master exdates: got %v want [%v]", master.Recurrence.ExDates, moved)
+	}
+	override, err := eventService.Get(5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if override.Recurrence != nil || !override.Date.Equal(time.Date(2036, 5, 19, 17, 0, 0, 0, time.UTC)) || override.Title != "Moved" {
+		t.Errorf("override: got date %v title %q recurrence %v", override.Date, override.Title, override.Recurrence)
+	}
+}
+
+func TestHandlerAPIv1(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		url         string
+		contentType string
+		accept      string
+		ifMatch     string
+		body        string
+		events      []*models.Event
+		want        string
+		wantStatus  int
+		wantETag    string
+	}{
+		{
+			name:        "OK Create JSON",
+			method:      "POST",
+			url:         "http://localhost:8080/api/v1/users/5/events",
+			contentType: "application/json",
+			body:        `{"date":"2036-05-12T15:04:05Z","title":"Test","description":"Test"}`,
+			want:        "{\"request_id\":\"test\",\"result\":{\"user_id\":5,\"id\":0,\"version\":1,\"date\":\"2036-05-12T15:04:05Z\",\"title\":\"Test\",\"description\":\"Test\"}}\n",
+			wantStatus:  http.StatusCreated,
+			wantETag:    `"1"`,
+		},
+		{
+			name:        "OK Create JSON Recurrence Object",
+			method:      "POST",
+			url:         "http://localhost:8080/api/v1/users/5/events",
+			contentType: "application/json",
+			body: `{"date":"2036-05-12T15:04:05Z","title":"Test","description":"Test",` +
+				`"recurrence":{"freq":"WEEKLY","by_day":["MO"],"count":3,"exdates":["2036-05-19T15:04:05Z"]}}`,
+			want: "{\"request_id\":\"test\",\"result\":{\"user_id\":5,\"id\":0,\"version\":1,\"date\":\"2036-05-12T15:04:05Z\",\"title\":\"Test\",\"description\":\"Test\"," +
+				"\"recurrence\":{\"freq\":\"WEEKLY\",\"by_day\":[\"MO\"],\"count\":3,\"exdates\":[\"2036-05-19T15:04:05Z\"]}}}\n",
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:        "Create JSON Recurrence And Rrule",
+			method:      "POST",
+			url:         "http://localhost:8080/api/v1/users/5/events",
+			contentType: "application/json",
+			body:        `{"date":"2036-05-12T15:04:05Z","title":"Test","rrule":"FREQ=DAILY","recurrence":{"freq":"WEEKLY"}}`,
+			want:        "{\"error\":\"bad request: use either rrule or recurrence\",\"request_id\":\"test\"}\n",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "Create JSON Invalid Recurrence",
+			method:      "POST",
+			url:         "http://localhost:8080/api/v1/users/5/events",
+			contentType: "application/json",
+			body:        `{"date":"2036-05-12T15:04:05Z","title":"Test","recurrence":"FREQ=DAILY"}`,
+			want:        "{\"error\":\"bad request: invalid JSON value of recurrence\",\"request_id\":\"test\"}\n",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "OK Create Form",
+			method:      "POST",
+			url:         "http://localhost:8080/api/v1/users/5/events",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "date=2036-05-12 15:04:05&title=Test",
+			want:        "{\"request_id\":\"test\",\"result\":{\"user_id\":5,\"id\":0,\"version\":1,\"date\":\"2036-05-12T15:04:05Z\",\"title\":\"Test\",\"description\":\"\"}}\n",
+			wantStatus:  http.StatusCreated,
+		},
+		{
+			name:        "Invalid JSON",
+			method:      "POST",
+			url:         "http://localhost:8080/api/v1/users/5/events",
+			contentType: "application/json",
+			body:        `{"date":`,
+			want:        "{\"error\":\"bad request: invalid JSON body: unexpected EOF\",\"request_id\":\"test\"}\n",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "Unsupported Media Type",
+			method:      "POST",
+			url:         "http://localhost:8080/api/v1/users/5/events",
+			contentType: "text/plain",
+			body:        "date",
+			want:        "{\"error\":\"unsupported media type \\\"text/plain\\\": use application/json or application/x-www-form-urlencoded\",\"request_id\":\"test\"}\n",
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:       "OK Get",
+			method:     "GET",
+			url:        "http://localhost:8080/api/v1/users/5/events/0",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:       "{\"request_id\":\"test\",\"result\":{\"user_id\":5,\"id\":0,\"version\":1,\"date\":\"2036-05-12T14:04:04Z\",\"title\":\"Test1\",\"description\":\"Test1\"}}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Get Not Found",
+			method:     "GET",
+			url:        "http://localhost:8080/api/v1/users/5/events/3",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:       "{\"error\":\"event id 3 not found\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Not Acceptable",
+			method:     "GET",
+			url:        "http://localhost:8080/api/v1/users/5/events/0",
+			accept:     "text/html",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:       "{\"error\":\"not acceptable: cannot produce text/html\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusNotAcceptable,
+		},
+		{
+			name:   "OK List For Week",
+			method: "GET",
+			url:    "http://localhost:8080/api/v1/users/5/events?date=2036-05-12&mode=week",
+			accept: "text/html;q=0.9, application/json",
+			events: []*models.Event{
+				models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1"),
+				models.NewEvent(5, 0, time.Date(2036, 6, 20, 14, 04, 04, 0, time.UTC), "Test2", "Test2"),
+			},
+			want:       "{\"request_id\":\"test\",\"result\":[{\"user_id\":5,\"id\":0,\"version\":1,\"date\":\"2036-05-12T14:04:04Z\",\"title\":\"Test1\",\"description\":\"Test1\"}]}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Invalid Mode",
+			method:     "GET",
+			url:        "http://localhost:8080/api/v1/users/5/events?date=2036-05-12&mode=year",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:       "{\"error\":\"bad request: invalid mode: must be day, week or month\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "OK Replace",
+			method:      "PUT",
+			url:         "http://localhost:8080/api/v1/users/5/events/0",
+			contentType: "application/json",
+			ifMatch:     `"1"`,
+			body:        `{"date":"2036-05-13 10:00:00","title":"Updated"}`,
+			events:      []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:        "{\"request_id\":\"test\",\"result\":{\"user_id\":5,\"id\":0,\"version\":2,\"date\":\"2036-05-13T10:00:00Z\",\"title\":\"Updated\",\"description\":\"\"}}\n",
+			wantStatus:  http.StatusOK,
+			wantETag:    `"2"`,
+		},
+		{
+			name:        "Replace Stale Version",
+			method:      "PUT",
+			url:         "http://localhost:8080/api/v1/users/5/events/0",
+			contentType: "application/json",
+			ifMatch:     `"2"`,
+			body:        `{"date":"2036-05-13 10:00:00","title":"Updated"}`,
+			events:      []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:        "{\"error\":\"precondition failed: event id 0 has version 1\",\"request_id\":\"test\"}\n",
+			wantStatus:  http.StatusPreconditionFailed,
+		},
+		{
+			name:        "OK Replace ETag List",
+			method:      "PUT",
+			url:         "http://localhost:8080/api/v1/users/5/events/0",
+			contentType: "application/json",
+			ifMatch:     `"3", "1"`,
+			body:        `{"date":"2036-05-13 10:00:00","title":"Updated"}`,
+			events:      []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:        "{\"request_id\":\"test\",\"result\":{\"user_id\":5,\"id\":0,\"version\":2,\"date\":\"2036-05-13T10:00:00Z\",\"title\":\"Updated\",\"description\":\"\"}}\n",
+			wantStatus:  http.StatusOK,
+			wantETag:    `"2"`,
+		},
+		{
+			name:        "Replace Stale ETag List",
+			method:      "PUT",
+			url:         "http://localhost:8080/api/v1/users/5/events/0",
+			contentType: "application/json",
+			ifMatch:     `"2","3"`,
+			body:        `{"date":"2036-05-13 10:00:00","title":"Updated"}`,
+			events:      []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:        "{\"error\":\"precondition failed: event id 0 has version 1\",\"request_id\":\"test\"}\n",
+			wantStatus:  http.StatusPreconditionFailed,
+		},
+		{
+			name:        "Replace ETag List With Star",
+			method:      "PUT",
+			url:         "http://localhost:8080/api/v1/users/5/events/0",
+			contentType: "application/json",
+			ifMatch:     `"1", *`,
+			body:        `{"date":"2036-05-13 10:00:00","title":"Updated"}`,
+			events:      []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:        "{\"error\":\"bad request: invalid If-Match: must be ETags like \\\"1\\\" separated by commas or *\",\"request_id\":\"test\"}\n",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:       "OK Delete ETag List",
+			method:     "DELETE",
+			url:        "http://localhost:8080/api/v1/users/5/events/0",
+			ifMatch:    `"4", "1"`,
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "Replace Without Precondition",
+			method:      "PUT",
+			url:         "http://localhost:8080/api/v1/users/5/events/0",
+			contentType: "application/json",
+			body:        `{"date":"2036-05-13 10:00:00","title":"Updated"}`,
+			events:      []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:        "{\"error\":\"precondition required: If-Match header or version parameter is required\",\"request_id\":\"test\"}\n",
+			wantStatus:  http.StatusPreconditionRequired,
+		},
+		{
+			name:        "Replace Weak ETag",
+			method:      "PUT",
+			url:         "http://localhost:8080/api/v1/users/5/events/0",
+			contentType: "application/json",
+			ifMatch:     `W/"1"`,
+			body:        `{"date":"2036-05-13 10:00:00","title":"Updated"}`,
+			events:      []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:        "{\"error\":\"bad request: invalid If-Match: must be ETags like \\\"1\\\" separated by commas or *\",\"request_id\":\"test\"}\n",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:       "OK Delete",
+			method:     "DELETE",
+			url:        "http://localhost:8080/api/v1/users/5/events/0",
+			ifMatch:    "*",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:       "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Delete Stale Version Query",
+			method:     "DELETE",
+			url:        "http://localhost:8080/api/v1/users/5/events/0?version=3",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:       "{\"error\":\"precondition failed: event id 0 has version 1\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:        "OK Patch Keeps Omitted Fields",
+			method:      "PATCH",
+			url:         "http://localhost:8080/api/v1/users/5/events/0",
+			contentType: "application/json",
+			ifMatch:     `"1"`,
+			body:        `{"title":"Updated"}`,
+			events:      []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:        "{\"request_id\":\"test\",\"result\":{\"user_id\":5,\"id\":0,\"version\":2,\"date\":\"2036-05-12T14:04:04Z\",\"title\":\"Updated\",\"description\":\"Test1\"}}\n",
+			wantStatus:  http.StatusOK,
+			wantETag:    `"2"`,
+		},
+		{
+			name:        "OK Patch Clear Description",
+			method:      "PATCH",
+			url:         "http://localhost:8080/api/v1/users/5/events/0",
+			contentType: "application/json",
+			ifMatch:     "*",
+			body:        `{"description":null}`,
+			events:      []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:        "{\"request_id\":\"test\",\"result\":{\"user_id\":5,\"id\":0,\"version\":2,\"date\":\"2036-05-12T14:04:04Z\",\"title\":\"Test1\",\"description\":\"\"}}\n",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "OK Patch Clear Recurrence Object",
+			method:      "PATCH",
+			url:         "http://localhost:8080/api/v1/users/5/events/0",
+			contentType: "application/json",
+			ifMatch:     "*",
+			body:        `{"recurrence":null}`,
+			events: []*models.Event{{UserID: 5, Date: time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), Title: "Test1",
+				Recurrence: &models.Recurrence{Freq: models.FreqDaily}}},
+			want:       "{\"request_id\":\"test\",\"result\":{\"user_id\":5,\"id\":0,\"version\":2,\"date\":\"2036-05-12T14:04:04Z\",\"title\":\"Test1\",\"description\":\"\"}}\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "Patch Clear Title",
+			method:      "PATCH",
+			url:         "http://localhost:8080/api/v1/users/5/events/0",
+			contentType: "application/json",
+			ifMatch:     `"1"`,
+			body:        `{"title":""}`,
+			events:      []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:        "{\"error\":\"bad request: empty parameter: title\",\"request_id\":\"test\"}\n",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "Patch Stale Version",
+			method:      "PATCH",
+			url:         "http://localhost:8080/api/v1/users/5/events/0",
+			contentType: "application/json",
+			ifMatch:     `"2"`,
+			body:        `{"title":"Updated"}`,
+			events:      []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:        "{\"error\":\"precondition failed: event id 0 has version 1\",\"request_id\":\"test\"}\n",
+			wantStatus:  http.StatusPreconditionFailed,
+		},
+		{
+			name:        "OK Patch Past Event",
+			method:      "PATCH",
+			url:         "http://localhost:8080/api/v1/users/5/events/0",
+			contentType: "application/json",
+			ifMatch:     `"1"`,
+			body:        `{"title":"Updated"}`,
+			events:      []*models.Event{models.NewEvent(5, 0, time.Date(2020, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:        "{\"request_id\":\"test\",\"result\":{\"user_id\":5,\"id\":0,\"version\":2,\"date\":\"2020-05-12T14:04:04Z\",\"title\":\"Updated\",\"description\":\"Test1\"}}\n",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "OK Replace Past Event Same Date",
+			method:      "PUT",
+			url:         "http://localhost:8080/api/v1/users/5/events/0",
+			contentType: "application/json",
+			ifMatch:     `"1"`,
+			body:        `{"date":"2020-05-12 14:04:04","title":"Updated"}`,
+			events:      []*models.Event{models.NewEvent(5, 0, time.Date(2020, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:        "{\"request_id\":\"test\",\"result\":{\"user_id\":5,\"id\":0,\"version\":2,\"date\":\"2020-05-12T14:04:04Z\",\"title\":\"Updated\",\"description\":\"\"}}\n",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "Patch Date Into Past",
+			method:      "PATCH",
+			url:         "http://localhost:8080/api/v1/users/5/events/0",
+			contentType: "application/json",
+			ifMatch:     `"1"`,
+			body:        `{"date":"2020-05-13 14:04:04"}`,
+			events:      []*models.Event{models.NewEvent(5, 0, time.Date(2020, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:        "{\"error\":\"bad request: event date cannot be in the past\",\"request_id\":\"test\"}\n",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "Replace Missing Event",
+			method:      "PUT",
+			url:         "http://localhost:8080/api/v1/users/5/events/3",
+			contentType: "application/json",
+			ifMatch:     `"1"`,
+			body:        `{"date":"2036-05-13 10:00:00","title":"Updated"}`,
+			events:      []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 14, 04, 04, 0, time.UTC), "Test1", "Test1")},
+			want:        "{\"error\":\"event id 3 not found\",\"request_id\":\"test\"}\n",
+			wantStatus:  http.StatusNotFound,
+		},
+		{
+			name:       "Method",
+			method:     "PATCH",
+			url:        "http://localhost:8080/api/v1/users/5/events",
+			want:       "{\"error\":\"method not allowed: bad method PATCH, method must be GET or POST\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "Invalid UserID",
+			method:     "GET",
+			url:        "http://localhost:8080/api/v1/users/five/events",
+			want:       "{\"error\":\"bad request: invalid id: use only numbers\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "OK Legacy Create JSON",
+			method:      "POST",
+			url:         "http://localhost:8080/create_event",
+			contentType: "application/json",
+			body:        `{"user_id":5,"date":"2036-05-12 15:04:05","title":"Test"}`,
+			want:        "{\"request_id\":\"test\",\"result\":{\"eventID\":0}}\n",
+			wantStatus:  http.StatusCreated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.accept != "" {
+				request.Header.Set("Accept", tt.accept)
+			}
+			if tt.ifMatch != "" {
+				request.Header.Set("If-Match", tt.ifMatch)
+			}
+			request.Header.Set("X-Request-ID", "test")
+			responseRecorder := httptest.NewRecorder()
+			service := service.New(data.New())
+			for _, event := range tt.events {
+				service.Create(event)
+			}
+			handler := New(service).InitRouter()
+			handler.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != tt.wantStatus {
+				t.Errorf("status: got %v want %v", responseRecorder.Code, tt.wantStatus)
+			}
+			if responseRecorder.Body.String() != tt.want {
+				t.Errorf("result: got %v want %v", responseRecorder.Body.String(), tt.want)
+			}
+			if tt.wantETag != "" && responseRecorder.Header().Get("ETag") != tt.wantETag {
+				t.Errorf("ETag: got %v want %v", responseRecorder.Header().Get("ETag"), tt.wantETag)
+			}
+		})
+	}
+}
+
+func TestHandlerAuth(t *testing.T) {
+	hash, err := auth.HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := auth.NewUsers([]*auth.User{{ID: 5, Name: "alice", PasswordHash: hash}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := auth.New("key", time.Hour, users)
+	token, _ := authenticator.Issue(auth.Claims{UserID: 5, ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	expired, _ := authenticator.Issue(auth.Claims{UserID: 5, ExpiresAt: time.Now().Add(-time.Hour).Unix()})
+
+	tests := []struct {
+		name          string
+		method        string
+		url           string
+		body          string
+		authorization string
+		events        []*models.Event
+		want          string
+		wantStatus    int
+	}{
+		{
+			name:          "OK Delete",
+			method:        "POST",
+			url:           "http://localhost:8080/delete_event",
+			body:          "user_id=5&id=0&version=1",
+			authorization: "Bearer " + token,
+			events:        []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			want:          "{\"request_id\":\"test\",\"result\":\"OK\"}\n",
+			wantStatus:    http.StatusOK,
+		},
+		{
+			name:       "No Token",
+			method:     "POST",
+			url:        "http://localhost:8080/delete_event",
+			body:       "user_id=5&id=0",
+			events:     []*models.Event{models.NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			want:       "{\"error\":\"unauthorized: missing bearer token\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:          "Expired Token",
+			method:        "GET",
+			url:           "http://localhost:8080/events_for_day?user_id=5&date=2036-05-12",
+			authorization: "Bearer " + expired,
+			want:          "{\"error\":\"unauthorized: token expired\",\"request_id\":\"test\"}\n",
+			wantStatus:    http.StatusUnauthorized,
+		},
+		{
+			name:          "Other User Form",
+			method:        "POST",
+			url:           "http://localhost:8080/delete_event",
+			body:          "user_id=7&id=0",
+			authorization: "Bearer " + token,
+			events:        []*models.Event{models.NewEvent(7, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")},
+			want:          "{\"error\":\"forbidden: access to user_id 7 is denied\",\"request_id\":\"test\"}\n",
+			wantStatus:    http.StatusForbidden,
+		},
+		{
+			name:          "Other User Query",
+			method:        "GET",
+			url:           "http://localhost:8080/events_for_week?user_id=7&date=2036-05-12",
+			authorization: "Bearer " + token,
+			want:          "{\"error\":\"forbidden: access to user_id 7 is denied\",\"request_id\":\"test\"}\n",
+			wantStatus:    http.StatusForbidden,
+		},
+		{
+			name:          "Other User Path",
+			method:        "GET",
+			url:           "http://localhost:8080/api/v1/users/7/events",
+			authorization: "Bearer " + token,
+			want:          "{\"error\":\"forbidden: access to user_id 7 is denied\",\"request_id\":\"test\"}\n",
+			wantStatus:    http.StatusForbidden,
+		},
+		{
+			name:       "Login Wrong Password",
+			method:     "POST",
+			url:        "http://localhost:8080/login",
+			body:       "name=alice&password=wrong",
+			want:       "{\"error\":\"unauthorized: invalid user name or password\",\"request_id\":\"test\"}\n",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			request.Header.Set("X-Request-ID", "test")
+			responseRecorder := httptest.NewRecorder()
+			service := service.New(data.New())
+			for _, event := range tt.events {
+				service.Create(event)
+			}
+			handler := New(service, WithAuth(authenticator)).InitRouter()
+			handler.ServeHTTP(responseRecorder, request)
+			if responseRecorder.Code != tt.wantStatus {
+				t.Errorf("status: got %v want %v", responseRecorder.Code, tt.wantStatus)
+			}
+			if responseRecorder.Body.String() != tt.want {
+				t.Errorf("result: got %v want %v", responseRecorder.Body.String(), tt.want)
+			}
+		})
+	}
+
+	t.Run("Login", func(t *testing.T) {
+		request, _ := http.NewRequest("POST", "http://localhost:8080/login", strings.NewReader("name=alice&password=secret"))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.Header.Set("X-Request-ID", "test")
+		responseRecorder := httptest.NewRecorder()
+		New(service.New(data.New()), WithAuth(authenticator)).InitRouter().ServeHTTP(responseRecorder, request)
+		if responseRecorder.Code != http.StatusOK || !strings.Contains(responseRecorder.Body.String(), "\"token\":") {
+			t.Errorf("login: got %v %v", responseRecorder.Code, responseRecorder.Body.String())
+		}
+	})
+}
+
+// openAPIDocument - часть спецификации OpenAPI, которую проверяют тесты
+type openAPIDocument struct {
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components struct {
+		Parameters map[string]openAPIParameter `json:"parameters"`
+	} `json:"components"`
+}
+
+// openAPIOperation - операция спецификации: параметры, тело и коды ответов
+type openAPIOperation struct {
+	Parameters  []openAPIParameter `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]struct {
+			Schema struct {
+				Required []string `json:"required"`
+			} `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]json.RawMessage `json:"responses"`
+}
+
+// openAPIParameter - параметр операции или ссылка на общий параметр
+type openAPIParameter struct {
+	Ref      string `json:"$ref"`
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+}
+
+// required - возвращает имена обязательных параметров и полей тела операции
+func (doc *openAPIDocument) required(op openAPIOperation) map[string]bool {
+	required := make(map[string]bool)
+	for _, parameter := range op.Parameters {
+		if parameter.Ref != "" {
+			parameter = doc.Components.Parameters[strings.TrimPrefix(parameter.Ref, "#/components/parameters/")]
+		}
+		if parameter.Required {
+			required[parameter.Name] = true
+		}
+	}
+	if op.RequestBody != nil {
+		for _, content := range op.RequestBody.Content {
+			for _, name := range content.Schema.Required {
+				required[name] = true
+			}
+		}
+	}
+	return required
+}
+
+// resolveRefs - проверяет, что каждая ссылка $ref указывает на существующий элемент документа
+func resolveRefs(t *testing.T, root, node any) {
+	t.Helper()
+	switch node := node.(type) {
+	case map[string]any:
+		for key, value := range node {
+			if key != "$ref" {
+				resolveRefs(t, root, value)
+				continue
+			}
+			ref, _ := value.(string)
+			var target any = root
+			for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+				object, ok := target.(map[string]any)
+				if !ok {
+					target = nil
+					break
+				}
+				target = object[part]
+			}
+			if target == nil {
+				t.Errorf("unresolved $ref %q", ref)
+			}
+		}
+	case []any:
+		for _, value := range node {
+			resolveRefs(t, root, value)
+		}
+	}
+}
+
+func TestHandlerOpenAPI(t *testing.T) {
+	hash, err := auth.HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := auth.NewUsers([]*auth.User{{ID: 1, Name: "alice", PasswordHash: hash}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := auth.New("key", time.Hour, users)
+	token, _ := authenticator.Issue(auth.Claims{UserID: 1, ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	h := New(service.New(data.New()), WithAuth(authenticator))
+	router := h.InitRouter()
+
+	// Спецификация отдается как есть и является корректным JSON
+	request, _ := http.NewRequest("GET", "http://localhost:8080/openapi.json", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, request)
+	if responseRecorder.Code != http.StatusOK || responseRecorder.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("openapi.json: got %v %q want %v application/json", responseRecorder.Code, responseRecorder.Header().Get("Content-Type"), http.StatusOK)
+	}
+	var root any
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &root); err != nil {
+		t.Fatal(err)
+	}
+	resolveRefs(t, root, root)
+	var doc openAPIDocument
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Paths Match Routes", func(t *testing.T) {
+		routes := make(map[string]bool)
+		for _, rt := range h.routes() {
+			routes[rt.pattern] = true
+			if _, ok := doc.Paths[rt.pattern]; !ok {
+				t.Errorf("route %s is not documented", rt.pattern)
+			}
+		}
+		for path := range doc.Paths {
+			if !routes[path] {
+				t.Errorf("documented path %s is not routed", path)
+			}
+		}
+	})
+
+	// Каждый метод каждого пути запрашивается без параметров: документированные методы не отвечают 405,
+	// остальные отвечают 405, коды ответов и обязательные параметры из ошибок описаны в спецификации
+	pathParams := strings.NewReplacer("{id}", "1", "{eventID}", "1", "{calendarID}", "1")
+	emptyParameter := regexp.MustCompile(`empty parameter: (\w+)`)
+	for path, operations := range doc.Paths {
+		for _, method := range []string{"GET", "POST", "PUT", "PATCH", "DELETE"} {
+			t.Run(method+" "+path, func(t *testing.T) {
+				op, documented := operations[strings.ToLower(method)]
+				request, err := http.NewRequest(method, "http://localhost:8080"+pathParams.Replace(path), nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if method != "GET" && method != "DELETE" {
+					request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				}
+				request.Header.Set("Authorization", "Bearer "+token)
+				responseRecorder := httptest.NewRecorder()
+				router.ServeHTTP(responseRecorder, request)
+
+				if !documented {
+					if responseRecorder.Code != http.StatusMethodNotAllowed {
+						t.Errorf("undocumented method: got %v want %v", responseRecorder.Code, http.StatusMethodNotAllowed)
+					}
+					return
+				}
+				if _, ok := op.Responses[fmt.Sprint(responseRecorder.Code)]; !ok {
+					t.Errorf("status %v is not documented: %s", responseRecorder.Code, responseRecorder.Body.String())
+				}
+				if match := emptyParameter.FindStringSubmatch(responseRecorder.Body.String()); responseRecorder.Code == http.StatusBadRequest && match != nil && !doc.required(op)[match[1]] {
+					t.Errorf("parameter %s is required but not documented as required", match[1])
+				}
+			})
+		}
+	}
+}
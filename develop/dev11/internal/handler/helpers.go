@@ -3,12 +3,25 @@ package handler
 import (
 	"develop/dev11/internal/errors"
 	"develop/dev11/internal/models"
+	"develop/dev11/internal/service"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// Типы содержимого, которые понимают обработчики
+const (
+	contentTypeJSON     = "application/json"
+	contentTypeForm     = "application/x-www-form-urlencoded"
+	contentTypeCalendar = "text/calendar"
+)
+
 // checkGetRequrst - функция для проверки GET-запроса на наличие в URL Query обязательных параметров
 func checkGetRequrst(r *http.Request, keys ...string) error {
 	for _, key := range keys {
@@ -42,13 +55,495 @@ func parseEventFromRequest(r *http.Request, checkEventID bool) (*models.Event, e
 		return nil, errors.NewBadRequestError("invalid id: use only numbers")
 	}
 
-	date, err := time.Parse(time.DateTime, r.PostFormValue("date"))
+	return parseEventFields(r, userID, id)
+}
+
+// parseEventFields - функция для парсинга полей события из тела запроса, user_id и id передаются отдельно
+func parseEventFields(r *http.Request, userID, id int) (*models.Event, error) {
+	// Часовой пояс необязателен: без него даты без смещения считаются UTC
+	tz := strings.TrimSpace(r.PostFormValue("tz"))
+	location, err := models.LoadLocation(tz)
+	if err != nil {
+		return nil, err
+	}
+
+	date, err := parseDateTime(r.PostFormValue("date"), location)
 	if err != nil {
-		return nil, errors.NewBadRequestError("invalid date format: correct format 2006-01-02 15:04:05")
+		return nil, err
 	}
 
 	title := strings.TrimSpace(r.PostFormValue("title"))
 	description := strings.TrimSpace(r.PostFormValue("description"))
 
-	return models.NewEvent(userID, id, date, title, description), nil
+	event := models.NewEvent(userID, id, date, title, description)
+	event.TZ = tz
+
+	// Календарь необязателен: без него событие попадает в календарь по умолчанию
+	event.CalendarID, err = parseCalendarID(r.PostFormValue("calendar_id"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Окончание события необязательно: end=2006-01-02 15:04:05 или duration=1h30m
+	event.End, err = parseEventEnd(r, date, location)
+	if err != nil {
+		return nil, err
+	}
+
+	// Напоминания необязательны: remind=15m,1h
+	event.Reminders, err = parseReminders(r.PostFormValue("remind"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Участники необязательны: attendees=6,7
+	event.Attendees, err = parseAttendees(r.PostFormValue("attendees"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Правило повторения необязательно: rrule=FREQ=WEEKLY;BYDAY=MO&exdate=2006-01-02 15:04:05,...
+	if rule := strings.TrimSpace(r.PostFormValue("rrule")); rule != "" {
+		exDates, err := parseExDates(r.PostFormValue("exdate"), location)
+		if err != nil {
+			return nil, err
+		}
+		event.Recurrence, err = models.ParseRecurrence(rule, exDates, location)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return event, nil
+}
+
+// parseEventEnd - функция для парсинга окончания события из параметров end или duration
+func parseEventEnd(r *http.Request, date time.Time, location *time.Location) (*time.Time, error) {
+	endValue := r.PostFormValue("end")
+	durationValue := r.PostFormValue("duration")
+
+	switch {
+	case endValue != "" && durationValue != "":
+		return nil, errors.NewBadRequestError("use either end or duration")
+	case endValue != "":
+		end, err := parseDateTime(endValue, location)
+		if err != nil {
+			return nil, errors.NewBadRequestError("invalid end format: correct format 2006-01-02 15:04:05")
+		}
+		return &end, nil
+	case durationValue != "":
+		duration, err := time.ParseDuration(durationValue)
+		if err != nil {
+			return nil, errors.NewBadRequestError("invalid duration format: correct format 1h30m")
+		}
+		if duration <= 0 {
+			return nil, errors.NewBadRequestError("duration must be positive")
+		}
+		end := date.Add(duration)
+		return &end, nil
+	}
+	return nil, nil
+}
+
+// parseReminders - функция для парсинга напоминаний события, разделенных запятой
+func parseReminders(value string) ([]models.Reminder, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	reminders := make([]models.Reminder, 0)
+	for _, part := range strings.Split(value, ",") {
+		reminder, err := models.ParseReminder(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, reminder)
+	}
+	return reminders, nil
+}
+
+// parseAttendees - функция для парсинга id приглашенных пользователей, разделенных запятой.
+// Ответы участников задает сервис, поэтому здесь все участники еще не ответили
+func parseAttendees(value string) ([]models.Attendee, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	attendees := make([]models.Attendee, 0)
+	for _, part := range strings.Split(value, ",") {
+		userID, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, errors.NewBadRequestError("invalid attendees: use user ids separated by commas")
+		}
+		attendees = append(attendees, models.Attendee{UserID: userID, Status: models.RSVPNeedsAction})
+	}
+	return attendees, nil
+}
+
+// parseCalendarID - функция для парсинга id календаря события, пустое значение - календарь по умолчанию
+func parseCalendarID(value string) (int, error) {
+	if strings.TrimSpace(value) == "" {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, errors.NewBadRequestError("invalid calendar_id: use only numbers")
+	}
+	return id, nil
+}
+
+// parseCalendars - функция для парсинга id календарей фильтра, разделенных запятой
+func parseCalendars(value string) ([]int, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	calendars := make([]int, 0)
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, errors.NewBadRequestError("invalid calendars: use calendar ids separated by commas")
+		}
+		calendars = append(calendars, id)
+	}
+	return calendars, nil
+}
+
+// parseRangeDate - функция для парсинга границы периода в формате 2006-01-02, 2006-01-02 15:04:05 или RFC 3339
+func parseRangeDate(parameter, value string, location *time.Location) (time.Time, error) {
+	if date, err := time.ParseInLocation(time.DateOnly, value, location); err == nil {
+		return date, nil
+	}
+	date, err := parseDateTime(value, location)
+	if err != nil {
+		return time.Time{}, errors.NewBadRequestError("invalid " + parameter + " format: correct format 2006-01-02 or 2006-01-02 15:04:05")
+	}
+	return date, nil
+}
+
+// parseRange - функция для парсинга периода [from, to) из строки запроса с проверкой его длины.
+// С inclusive=true конец периода включается: для даты без времени - весь день to, для даты со временем - момент to
+func parseRange(r *http.Request, location *time.Location) (time.Time, time.Time, error) {
+	fromDate, err := parseRangeDate("from", r.URL.Query().Get("from"), location)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	toValue := r.URL.Query().Get("to")
+	toDate, err := parseRangeDate("to", toValue, location)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if value := r.URL.Query().Get("inclusive"); value != "" {
+		inclusive, err := strconv.ParseBool(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.NewBadRequestError("invalid inclusive: must be true or false")
+		}
+		if inclusive {
+			if _, err := time.Parse(time.DateOnly, toValue); err == nil {
+				toDate = toDate.AddDate(0, 0, 1)
+			} else {
+				toDate = toDate.Add(time.Nanosecond)
+			}
+		}
+	}
+
+	if !toDate.After(fromDate) {
+		return time.Time{}, time.Time{}, errors.NewBadRequestError("to must be after from")
+	}
+	if toDate.Sub(fromDate) > service.MaxRange {
+		return time.Time{}, time.Time{}, errors.NewBadRequestError("period is too long, maximum 366 days")
+	}
+	return fromDate, toDate, nil
+}
+
+// parseDateTime - функция для парсинга даты и времени в формате 2006-01-02 15:04:05 (в часовом поясе location) или RFC 3339
+func parseDateTime(value string, location *time.Location) (time.Time, error) {
+	date, err := time.ParseInLocation(time.DateTime, value, location)
+	if err == nil {
+		return date, nil
+	}
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
+	return time.Time{}, errors.NewBadRequestError("invalid date format: correct format 2006-01-02 15:04:05")
+}
+
+// parseExDates - функция для парсинга исключенных дат повторяющегося события, разделенных запятой
+func parseExDates(value string, location *time.Location) ([]time.Time, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	exDates := make([]time.Time, 0)
+	for _, part := range strings.Split(value, ",") {
+		exDate, err := parseDateTime(strings.TrimSpace(part), location)
+		if err != nil {
+			return nil, errors.NewBadRequestError("invalid exdate format: correct format 2006-01-02 15:04:05")
+		}
+		exDates = append(exDates, exDate)
+	}
+	return exDates, nil
+}
+
+// parseQueryLocation - функция для получения часового пояса из параметра tz строки запроса (по умолчанию UTC)
+func parseQueryLocation(r *http.Request) (*time.Location, error) {
+	return models.LoadLocation(strings.TrimSpace(r.URL.Query().Get("tz")))
+}
+
+// parseQueryDay - функция для парсинга параметра date (2006-01-02) как начала дня в часовом поясе location
+func parseQueryDay(r *http.Request, location *time.Location) (time.Time, error) {
+	date, err := time.ParseInLocation(time.DateOnly, r.URL.Query().Get("date"), location)
+	if err != nil {
+		return time.Time{}, errors.NewBadRequestError("invalid date format: correct format 2006-01-02")
+	}
+	return date, nil
+}
+
+// Размер страницы событий (параметр limit)
+const (
+	defaultLimit = 100  // Размер страницы, если limit не задан
+	maxLimit     = 1000 // Максимальный размер страницы
+)
+
+// nextCursorHeader - заголовок ответа с курсором следующей страницы
+const nextCursorHeader = "X-Next-Cursor"
+
+// parseEventsQuery - функция для парсинга фильтра (title, description, q, calendars) и страницы (limit, cursor) из строки запроса
+func parseEventsQuery(r *http.Request) (service.Query, error) {
+	values := r.URL.Query()
+	query := service.Query{
+		Filter: models.Filter{
+			Title:       strings.TrimSpace(values.Get("title")),
+			Description: strings.TrimSpace(values.Get("description")),
+			Text:        strings.TrimSpace(values.Get("q")),
+		},
+		Limit:  defaultLimit,
+		Cursor: values.Get("cursor"),
+	}
+
+	// Без календарей в фильтре показываются все календари, кроме скрытых
+	calendars, err := parseCalendars(values.Get("calendars"))
+	if err != nil {
+		return service.Query{}, err
+	}
+	query.Filter.Calendars = calendars
+
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxLimit {
+			return service.Query{}, errors.NewBadRequestError(fmt.Sprintf("invalid limit: must be between 1 and %d", maxLimit))
+		}
+		query.Limit = limit
+	}
+	return query, nil
+}
+
+// setNextCursor - функция для передачи курсора следующей страницы в заголовке ответа
+func setNextCursor(w http.ResponseWriter, page service.Page) {
+	if page.NextCursor != "" {
+		w.Header().Set(nextCursorHeader, page.NextCursor)
+	}
+}
+
+// eventsIn - функция для представления времени событий в часовом поясе location
+func eventsIn(events []*models.Event, location *time.Location) []*models.Event {
+	converted := make([]*models.Event, 0, len(events))
+	for _, event := range events {
+		converted = append(converted, event.In(location))
+	}
+	return converted
+}
+
+// mediaType - функция для получения типа содержимого запроса без параметров
+func mediaType(r *http.Request) string {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType
+}
+
+// parseJSONForm - функция для парсинга JSON-объекта из тела запроса в r.PostForm.
+// Скалярные значения приводятся к строкам, массивы склеиваются через запятую, null - пустая строка.
+// Объект recurrence в том виде, в котором событие возвращается в ответах, заменяется параметрами rrule и exdate.
+// После этого обработчики читают параметры через PostFormValue так же, как из www-url-form-encoded
+func parseJSONForm(r *http.Request) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+
+	body := make(map[string]any)
+	if err := decoder.Decode(&body); err != nil && err != io.EOF {
+		if tooLarge := payloadTooLarge(err); tooLarge != nil {
+			return tooLarge
+		}
+		return errors.NewBadRequestError("invalid JSON body: " + err.Error())
+	}
+
+	values := make(url.Values, len(body))
+	for key, value := range body {
+		if key == "recurrence" {
+			if err := recurrenceForm(value, values, body); err != nil {
+				return err
+			}
+			continue
+		}
+		if array, ok := value.([]any); ok {
+			parts := make([]string, 0, len(array))
+			for _, item := range array {
+				part, err := jsonScalar(key, item)
+				if err != nil {
+					return err
+				}
+				parts = append(parts, part)
+			}
+			values.Set(key, strings.Join(parts, ","))
+			continue
+		}
+		part, err := jsonScalar(key, value)
+		if err != nil {
+			return err
+		}
+		values.Set(key, part)
+	}
+
+	// Параметры строки запроса остаются доступны через FormValue
+	r.PostForm = values
+	r.Form = make(url.Values)
+	for key, value := range r.URL.Query() {
+		r.Form[key] = value
+	}
+	for key, value := range values {
+		r.Form[key] = append(value, r.Form[key]...)
+	}
+	return nil
+}
+
+// recurrenceForm - функция для замены объекта recurrence параметрами rrule и exdate.
+// null очищает правило повторения так же, как пустой rrule
+func recurrenceForm(value any, values url.Values, body map[string]any) error {
+	if _, ok := body["rrule"]; ok {
+		return errors.NewBadRequestError("use either rrule or recurrence")
+	}
+	if _, ok := body["exdate"]; ok {
+		return errors.NewBadRequestError("use either exdate or recurrence")
+	}
+	if value == nil {
+		values.Set("rrule", "")
+		return nil
+	}
+	if _, ok := value.(map[string]any); !ok {
+		return errors.NewBadRequestError("invalid JSON value of recurrence")
+	}
+
+	// Объект читается в тот же тип, в котором он отдается в ответах
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return errors.NewBadRequestError("invalid JSON value of recurrence")
+	}
+	var recurrence models.Recurrence
+	if err := json.Unmarshal(raw, &recurrence); err != nil {
+		return errors.NewBadRequestError("invalid recurrence: " + err.Error())
+	}
+	exDates := make([]string, 0, len(recurrence.ExDates))
+	for _, exDate := range recurrence.ExDates {
+		exDates = append(exDates, exDate.Format(time.RFC3339Nano))
+	}
+	values.Set("rrule", recurrence.String())
+	values.Set("exdate", strings.Join(exDates, ","))
+	return nil
+}
+
+// jsonScalar - функция для приведения скалярного JSON-значения к строке
+func jsonScalar(key string, value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", errors.NewBadRequestError("invalid JSON value of " + key)
+}
+
+// negotiate - функция для выбора формата ответа по заголовку Accept.
+// Первый из offers используется по умолчанию, если Accept не задан
+func negotiate(r *http.Request, offers ...string) (string, error) {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return offers[0], nil
+	}
+
+	best, bestQuality := "", 0.0
+	for _, offer := range offers {
+		quality := acceptQuality(accept, offer)
+		if quality > bestQuality {
+			best, bestQuality = offer, quality
+		}
+	}
+	if best == "" {
+		return "", errors.NewNotAcceptableError(accept)
+	}
+	return best, nil
+}
+
+// acceptQuality - функция для получения веса q, с которым Accept допускает тип offer
+func acceptQuality(accept, offer string) float64 {
+	offerType, _, _ := strings.Cut(offer, "/")
+	quality, specificity := 0.0, -1
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+
+		// Более точное совпадение важнее: type/subtype > type/* > */*
+		rangeSpecificity := -1
+		switch {
+		case mediaType == offer:
+			rangeSpecificity = 2
+		case mediaType == offerType+"/*":
+			rangeSpecificity = 1
+		case mediaType == "*/*":
+			rangeSpecificity = 0
+		}
+		if rangeSpecificity <= specificity {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		quality, specificity = q, rangeSpecificity
+	}
+	return quality
+}
+
+// Режимы обработки конфликтов при создании и обновлении события (параметр conflict)
+const (
+	conflictWarn   = "warn"   // Событие сохраняется, конфликты возвращаются в ответе
+	conflictReject = "reject" // Событие не сохраняется, возвращается 409
+)
+
+// checkConflicts - функция для поиска событий, пересекающихся с event, с учетом параметра conflict.
+// Проверка выполняется до сохранения и не блокирует одновременное создание пересекающихся событий
+func (h *Handler) checkConflicts(r *http.Request, event *models.Event, existing bool) ([]*models.Event, error) {
+	mode := r.FormValue("conflict")
+	switch mode {
+	case "", conflictWarn, conflictReject:
+	default:
+		return nil, errors.NewBadRequestError("invalid conflict: must be warn or reject")
+	}
+
+	conflicts, err := h.service.Conflicts(event, existing)
+	if err != nil {
+		return nil, err
+	}
+	if mode == conflictReject && len(conflicts) > 0 {
+		ids := make([]string, 0, len(conflicts))
+		for _, conflict := range conflicts {
+			ids = append(ids, strconv.Itoa(conflict.ID))
+		}
+		return nil, errors.NewConflictError("event overlaps events with id " + strings.Join(ids, ", "))
+	}
+	return conflicts, nil
 }
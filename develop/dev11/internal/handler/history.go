@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"develop/dev11/internal/errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// getHistory обрабатывает запрос на получение истории изменений события /events/{eventID}/history
+func (h *Handler) getHistory(w http.ResponseWriter, r *http.Request) {
+	// Проверяем метод запроса на соответствие GET
+	if r.Method != http.MethodGet {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodGet), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Проверяем наличие необходимых параметров в запросе
+	if err := checkGetRequrst(r, "user_id"); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем и проверяем целочисленные значения user_id из строки запроса и id события из пути
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil {
+		responsErrorJSON(w, errors.NewBadRequestError("invalid user_id: use only numbers"), http.StatusBadRequest)
+		return
+	}
+	id, err := pathInt(r, "eventID")
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Получаем историю изменений через service
+	history, err := h.service.History(userID, id)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	// Возвращаем изменения в формате JSON от первого к последнему
+	responsJSON(w, history, http.StatusOK)
+}
+
+// getChanges обрабатывает запрос на получение ленты изменений событий пользователя.
+// Параметр since - курсор: номер последнего полученного изменения (0 или не задан - с начала ленты).
+// Курсор для следующего запроса возвращается в заголовке X-Next-Cursor, даже если новых изменений нет
+func (h *Handler) getChanges(w http.ResponseWriter, r *http.Request) {
+	// Проверяем метод запроса на соответствие GET
+	if r.Method != http.MethodGet {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodGet), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Проверяем наличие необходимых параметров в запросе
+	if err := checkGetRequrst(r, "user_id"); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем и проверяем целочисленное значение user_id из строки запроса
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil {
+		responsErrorJSON(w, errors.NewBadRequestError("invalid user_id: use only numbers"), http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем и проверяем курсор и размер страницы
+	var since int64
+	if value := r.URL.Query().Get("since"); value != "" {
+		since, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || since < 0 {
+			responsErrorJSON(w, errors.NewBadRequestError("invalid since: must be a cursor from X-Next-Cursor"), http.StatusBadRequest)
+			return
+		}
+	}
+	limit := defaultLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxLimit {
+			responsErrorJSON(w, errors.NewBadRequestError(fmt.Sprintf("invalid limit: must be between 1 and %d", maxLimit)), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Получаем изменения после курсора через service
+	changes, err := h.service.Changes(userID, since, limit)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	// Следующий запрос продолжает с последнего полученного изменения
+	next := since
+	if len(changes) > 0 {
+		next = changes[len(changes)-1].Seq
+	}
+	w.Header().Set(nextCursorHeader, strconv.FormatInt(next, 10))
+	responsJSON(w, changes, http.StatusOK)
+}
@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"develop/dev11/internal/errors"
+	"develop/dev11/internal/ical"
+	"develop/dev11/internal/models"
+	"develop/dev11/internal/service"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Результаты импорта события
+const (
+	importCreated   = "created"   // Создано новое событие
+	importUpdated   = "updated"   // Изменено событие, ранее импортированное с тем же UID
+	importUnchanged = "unchanged" // Событие с тем же UID уже совпадает с файлом
+)
+
+// importedEvent - успешно импортированное событие
+type importedEvent struct {
+	Index  int    `json:"index"`         // Порядковый номер VEVENT в файле
+	UID    string `json:"uid,omitempty"` // UID из файла
+	ID     int    `json:"id"`            // ID созданного или измененного события
+	Status string `json:"status"`        // Результат: created, updated или unchanged
+}
+
+// failedEvent - событие, которое не удалось импортировать
+type failedEvent struct {
+	Index int    `json:"index"`         // Порядковый номер VEVENT в файле
+	UID   string `json:"uid,omitempty"` // UID из файла
+	Error string `json:"error"`         // Причина ошибки
+}
+
+// importICS обрабатывает запрос на загрузку событий пользователя из файла iCalendar.
+// Файл передается полем file в multipart/form-data или телом запроса с Content-Type text/calendar
+func (h *Handler) importICS(w http.ResponseWriter, r *http.Request) {
+	// Проверяем метод запроса на соответствие POST
+	if r.Method != http.MethodPost {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodPost), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Извлекаем файл календаря из запроса
+	calendar, err := icsFromRequest(r)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+	defer calendar.Close()
+
+	// user_id передается в форме или в строке запроса
+	if r.FormValue("user_id") == "" {
+		responsErrorJSON(w, errors.NewBadRequestError("empty parameter: user_id"), http.StatusBadRequest)
+		return
+	}
+	userID, err := strconv.Atoi(r.FormValue("user_id"))
+	if err != nil {
+		responsErrorJSON(w, errors.NewBadRequestError("invalid user_id: use only numbers"), http.StatusBadRequest)
+		return
+	}
+
+	// Разбираем календарь
+	items, err := ical.Decode(calendar)
+	if err != nil {
+		if tooLarge := payloadTooLarge(err); tooLarge != nil {
+			responsError(w, tooLarge)
+			return
+		}
+		responsErrorJSON(w, errors.NewBadRequestError("invalid calendar: "+err.Error()), http.StatusBadRequest)
+		return
+	}
+	if len(items) == 0 {
+		responsErrorJSON(w, errors.NewBadRequestError("calendar has no events"), http.StatusBadRequest)
+		return
+	}
+
+	// UID - ключ идемпотентности: событие, уже импортированное или выгруженное с тем же UID, обновляется
+	existing, err := h.eventsByUID(userID)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	// Повторение, измененное отдельным VEVENT с RECURRENCE-ID, исключается из серии и импортируется
+	// отдельным событием. Исключения добавляются к серии из файла, чтобы повторный импорт ее не менял
+	overrides := make(map[string][]time.Time)
+	for _, item := range items {
+		if item.Err == nil && item.RecurrenceID != nil {
+			overrides[item.UID] = append(overrides[item.UID], *item.RecurrenceID)
+		}
+	}
+	for _, item := range items {
+		if item.Err == nil && item.RecurrenceID == nil && item.Event.Recurrence != nil {
+			for _, recurrenceID := range overrides[item.UID] {
+				if !slices.ContainsFunc(item.Event.Recurrence.ExDates, recurrenceID.Equal) {
+					item.Event.Recurrence.ExDates = append(item.Event.Recurrence.ExDates, recurrenceID)
+				}
+			}
+		}
+	}
+
+	// Импортируем каждое событие отдельно, ошибки собираем по событиям.
+	// Сначала импортируются серии, затем измененные повторения, которые на них ссылаются
+	imported := make([]importedEvent, 0, len(items))
+	failed := make([]failedEvent, 0)
+	for _, overridesPass := range []bool{false, true} {
+		for i, item := range items {
+			if (item.Err == nil && item.RecurrenceID != nil) != overridesPass {
+				continue
+			}
+			if item.Err != nil {
+				failed = append(failed, failedEvent{Index: i, UID: item.UID, Error: item.Err.Error()})
+				continue
+			}
+
+			importer := h.importEvent
+			if overridesPass {
+				importer = h.importOverride
+			}
+			result, err := importer(userID, item, existing)
+			if err != nil {
+				failed = append(failed, failedEvent{Index: i, UID: item.UID, Error: err.Error()})
+				continue
+			}
+			result.Index = i
+			imported = append(imported, result)
+		}
+	}
+	slices.SortFunc(imported, func(a, b importedEvent) int { return a.Index - b.Index })
+	slices.SortFunc(failed, func(a, b failedEvent) int { return a.Index - b.Index })
+
+	responsJSON(w, map[string]any{"imported": imported, "failed": failed}, http.StatusOK)
+}
+
+// importEvent - создает событие из файла или обновляет событие existing с тем же ключом (UID и RECURRENCE-ID)
+func (h *Handler) importEvent(userID int, item ical.Item, existing map[string]*models.Event) (importedEvent, error) {
+	event := item.Event
+	event.UserID = userID
+	event.UID = item.Key()
+
+	current, ok := existing[event.UID]
+	if item.UID == "" || !ok {
+		if err := event.Validate(); err != nil {
+			return importedEvent{}, err
+		}
+		eventID, err := h.service.Create(event)
+		if err != nil {
+			return importedEvent{}, err
+		}
+		if item.UID != "" {
+			existing[event.UID] = event
+		}
+		return importedEvent{UID: item.UID, ID: eventID, Status: importCreated}, nil
+	}
+
+	// Файл задает только поля iCalendar: календарь, напоминания и участники события сохраняются
+	if sameICS(current, event) {
+		return importedEvent{UID: item.UID, ID: current.ID, Status: importUnchanged}, nil
+	}
+	updated := current.Clone()
+	updated.Date, updated.TZ, updated.End = event.Date, event.TZ, event.End
+	updated.Title, updated.Description = event.Title, event.Description
+	updated.Recurrence = event.Recurrence
+	if err := updated.ValidateChange(current); err != nil {
+		return importedEvent{}, err
+	}
+	if err := h.service.Update(updated); err != nil {
+		return importedEvent{}, err
+	}
+	existing[event.UID] = updated
+	return importedEvent{UID: item.UID, ID: current.ID, Status: importUpdated}, nil
+}
+
+// importOverride - исключает измененное повторение из серии existing с тем же UID и импортирует его отдельным событием.
+// Серия при этом не перезаписывается
+func (h *Handler) importOverride(userID int, item ical.Item, existing map[string]*models.Event) (importedEvent, error) {
+	master, ok := existing[item.UID]
+	if !ok || master.Recurrence == nil {
+		return importedEvent{}, errors.NewBadRequestError("RECURRENCE-ID refers to no recurring event with UID " + strconv.Quote(item.UID))
+	}
+	if !slices.ContainsFunc(master.Recurrence.ExDates, item.RecurrenceID.Equal) {
+		updated := master.Clone()
+		updated.Recurrence.ExDates = append(updated.Recurrence.ExDates, *item.RecurrenceID)
+		if err := h.service.Update(updated); err != nil {
+			return importedEvent{}, err
+		}
+		existing[item.UID] = updated
+	}
+	return h.importEvent(userID, item, existing)
+}
+
+// eventsByUID - возвращает события пользователя (без событий, на которые он приглашен) по их UID в iCalendar,
+// включая события скрытых календарей
+func (h *Handler) eventsByUID(userID int) (map[string]*models.Event, error) {
+	byUID := make(map[string]*models.Event)
+	calendars, err := h.service.Calendars(userID)
+	if err != nil {
+		return nil, err
+	}
+	filter := models.Filter{Calendars: []int{0}}
+	for _, calendar := range calendars {
+		filter.Calendars = append(filter.Calendars, calendar.ID)
+	}
+	page, err := h.service.GetAll(userID, service.Query{Filter: filter})
+	if err != nil {
+		// У нового пользователя еще нет событий
+		if _, ok := err.(*errors.NotFoundError); ok {
+			return byUID, nil
+		}
+		return nil, err
+	}
+	for _, event := range page.Events {
+		if event.UserID == userID {
+			byUID[ical.UID(event)] = event
+		}
+	}
+	return byUID, nil
+}
+
+// sameICS - проверяет, что поля iCalendar событий совпадают
+func sameICS(current, event *models.Event) bool {
+	sameEnd := current.End == nil && event.End == nil || current.End != nil && event.End != nil && current.End.Equal(*event.End)
+	sameRecurrence := current.Recurrence == nil && event.Recurrence == nil ||
+		current.Recurrence != nil && event.Recurrence != nil && current.Recurrence.String() == event.Recurrence.String() &&
+			slices.EqualFunc(current.Recurrence.ExDates, event.Recurrence.ExDates, time.Time.Equal)
+	return current.Date.Equal(event.Date) && current.TZ == event.TZ && sameEnd &&
+		current.Title == event.Title && current.Description == event.Description && sameRecurrence
+}
+
+// icsFromRequest - возвращает содержимое файла календаря из запроса
+func icsFromRequest(r *http.Request) (io.ReadCloser, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data":
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			if tooLarge := payloadTooLarge(err); tooLarge != nil {
+				return nil, tooLarge
+			}
+			return nil, errors.NewBadRequestError("empty parameter: file")
+		}
+		return file, nil
+	case "text/calendar":
+		return r.Body, nil
+	}
+	return nil, errors.NewBadRequestError("unsupported content type: use multipart/form-data or text/calendar")
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"develop/dev11/internal/auth"
+	"develop/dev11/internal/errors"
+	"develop/dev11/internal/ratelimit"
+	stderrors "errors"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// Ограничения размера тела запроса по умолчанию
+const (
+	defaultMaxBodyBytes   = 1 << 20  // Формы и JSON
+	defaultMaxImportBytes = 10 << 20 // Файлы календаря для /import_ics
+)
+
+// WithRateLimit - включает ограничение частоты запросов с одного IP-адреса и к одному user_id.
+// nil отключает соответствующее ограничение
+func WithRateLimit(ipLimiter, userLimiter *ratelimit.Limiter) Option {
+	return func(h *Handler) {
+		h.ipLimiter = ipLimiter
+		h.userLimiter = userLimiter
+	}
+}
+
+// WithBodyLimit - задает максимальный размер тела запроса для форм и JSON и для файлов календаря
+func WithBodyLimit(maxBodyBytes, maxImportBytes int64) Option {
+	return func(h *Handler) {
+		h.maxBodyBytes = maxBodyBytes
+		h.maxImportBytes = maxImportBytes
+	}
+}
+
+// limitIP - middleware, ограничивающее частоту запросов с одного IP-адреса
+func (h *Handler) limitIP(handler http.Handler) http.Handler {
+	if h.ipLimiter == nil {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, retryAfter := h.ipLimiter.Allow(clientIP(r)); !ok {
+			responsError(w, errors.NewTooManyRequestsError(retryAfter))
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// limitUser - middleware, ограничивающее частоту запросов к одному пользователю.
+// Вызывается после аутентификации, чтобы чужие запросы с подложным user_id не расходовали лимит пользователя
+func (h *Handler) limitUser(handler http.Handler) http.Handler {
+	if h.userLimiter == nil {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			// Без аутентификации лимит считается по user_id запроса, некорректный user_id обработчик отклонит сам
+			id, err := strconv.Atoi(requestUserID(r))
+			if err != nil {
+				handler.ServeHTTP(w, r)
+				return
+			}
+			userID = id
+		}
+
+		if ok, retryAfter := h.userLimiter.Allow(strconv.Itoa(userID)); !ok {
+			responsError(w, errors.NewTooManyRequestsError(retryAfter))
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// limitBody - middleware, ограничивающее размер тела запроса.
+// Файлы календаря (multipart/form-data и text/calendar) ограничиваются отдельно, так как они больше обычных форм
+func (h *Handler) limitBody(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := h.maxBodyBytes
+		switch mediaType(r) {
+		case "multipart/form-data", contentTypeCalendar:
+			limit = h.maxImportBytes
+		}
+		if limit > 0 && r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// payloadTooLarge - возвращает ошибку 413, если чтение тела прервано из-за превышения лимита, иначе nil
+func payloadTooLarge(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if stderrors.As(err, &maxBytesErr) {
+		return errors.NewPayloadTooLargeError(maxBytesErr.Limit)
+	}
+	return nil
+}
+
+// clientIP - возвращает IP-адрес клиента из адреса соединения.
+// Заголовки X-Forwarded-For не учитываются: без доверенного прокси клиент может подставить в них любой адрес
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
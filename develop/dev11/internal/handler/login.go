@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"develop/dev11/internal/auth"
+	"develop/dev11/internal/errors"
+	"net/http"
+	"time"
+)
+
+// login обрабатывает запрос на получение токена доступа по имени и паролю
+func (h *Handler) login(w http.ResponseWriter, r *http.Request) {
+	// Проверяем метод запроса на соответствие POST
+	if r.Method != http.MethodPost {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodPost), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Проверяем наличие необходимых параметров в теле POST запроса
+	if err := checkPostRequrst(r, "name", "password"); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Проверяем пароль и выдаем токен
+	token, claims, err := h.auth.Login(r.PostFormValue("name"), r.PostFormValue("password"))
+	if err == auth.ErrInvalidCredentials {
+		responsErrorJSON(w, errors.NewUnauthorizedError(err.Error()), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	responsJSON(w, map[string]any{
+		"token":      token,
+		"token_type": "Bearer",
+		"user_id":    claims.UserID,
+		"expires_at": time.Unix(claims.ExpiresAt, 0).UTC(),
+	}, http.StatusOK)
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"develop/dev11/internal/errors"
+	"develop/dev11/internal/metrics"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// httpMetrics - метрики HTTP-запросов и хранилища событий
+type httpMetrics struct {
+	registry *metrics.Registry  // registry - все метрики сервера
+	requests *metrics.Counter   // requests - число запросов по маршруту, методу и статусу
+	duration *metrics.Histogram // duration - длительность запросов по маршруту и статусу
+	inFlight *metrics.Gauge     // inFlight - число обрабатываемых запросов
+}
+
+// newHTTPMetrics - конструктор httpMetrics
+func (h *Handler) newHTTPMetrics() *httpMetrics {
+	registry := metrics.NewRegistry()
+	m := &httpMetrics{
+		registry: registry,
+		requests: registry.NewCounter("calendar_http_requests_total", "Total number of HTTP requests.", "route", "method", "status"),
+		duration: registry.NewHistogram("calendar_http_request_duration_seconds", "HTTP request latency in seconds.", metrics.DefaultBuckets, "route", "status"),
+		inFlight: registry.NewGauge("calendar_http_requests_in_flight", "Number of HTTP requests being served."),
+	}
+
+	// Размер хранилища вычисляется при каждом запросе /metrics
+	registry.NewGaugeFunc("calendar_users", "Number of users in the event store.", func() float64 {
+		stats, err := h.service.Stats()
+		if err != nil {
+			return math.NaN()
+		}
+		return float64(stats.Users)
+	})
+	registry.NewGaugeFunc("calendar_events", "Number of events in the event store.", func() float64 {
+		stats, err := h.service.Stats()
+		if err != nil {
+			return math.NaN()
+		}
+		return float64(stats.Events)
+	})
+	registry.NewGaugeFunc("calendar_events_trashed", "Number of deleted events kept in the trash.", func() float64 {
+		stats, err := h.service.Stats()
+		if err != nil {
+			return math.NaN()
+		}
+		return float64(stats.Trashed)
+	})
+	return m
+}
+
+// instrument - middleware для сбора метрик запросов.
+// Маршрут берется из шаблона mux, чтобы число рядов не зависело от параметров пути
+func (h *Handler) instrument(mux *http.ServeMux, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := mux.Handler(r)
+
+		h.metrics.inFlight.Add(1)
+		defer h.metrics.inFlight.Add(-1)
+
+		startTime := time.Now()
+		rec := NewResponseRecorder(w)
+		handler.ServeHTTP(rec, r)
+
+		status := rec.Status()
+		h.metrics.requests.Inc(route, methodLabel(r.Method), strconv.Itoa(status))
+		h.metrics.duration.Observe(time.Since(startTime).Seconds(), route, strconv.Itoa(status))
+	})
+}
+
+// methodLabel - возвращает метод запроса для метки метрики. Метод задает клиент,
+// поэтому нестандартные методы объединяются в OTHER, чтобы число рядов было ограничено
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// getMetrics отдает метрики в текстовом формате Prometheus
+func (h *Handler) getMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodGet), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", metrics.ContentType)
+	w.WriteHeader(http.StatusOK)
+	if err := h.metrics.registry.Write(w); err != nil {
+		slog.ErrorContext(r.Context(), "getMetrics", "error", err.Error())
+	}
+}
+
+// getHealthz отвечает, что процесс жив
+func (h *Handler) getHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodGet), http.StatusMethodNotAllowed)
+		return
+	}
+	responsJSON(w, "OK", http.StatusOK)
+}
+
+// getReadyz отвечает, готов ли сервер принимать запросы: хранилище доступно и сервер не останавливается
+func (h *Handler) getReadyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodGet), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.shuttingDown.Load() {
+		slog.WarnContext(r.Context(), "getReadyz", "error", "server is shutting down")
+		responsErrorJSON(w, errors.NewServiceUnavailableError(), http.StatusServiceUnavailable)
+		return
+	}
+	if err := h.service.Ready(); err != nil {
+		slog.WarnContext(r.Context(), "getReadyz", "error", err.Error())
+		responsErrorJSON(w, errors.NewServiceUnavailableError(), http.StatusServiceUnavailable)
+		return
+	}
+	responsJSON(w, "OK", http.StatusOK)
+}
@@ -1,40 +1,187 @@
 package handler
 
 import (
-	"log"
+	"develop/dev11/internal/auth"
+	"develop/dev11/internal/errors"
+	"develop/dev11/internal/logger"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
-// httpLogger - функция для логирования HTTP-запросов
-func httpLogger(handler http.Handler) http.Handler {
+// requestIDHeader - заголовок с идентификатором запроса
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength - максимальная длина идентификатора запроса, принимаемого от клиента
+const maxRequestIDLength = 128
+
+// requestID - middleware, которое берет идентификатор запроса из заголовка X-Request-ID или генерирует новый.
+// Идентификатор возвращается в заголовке ответа и кладется в контекст запроса для журнала
+func requestID(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = logger.NewRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		handler.ServeHTTP(w, r.WithContext(logger.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID - проверяет, что идентификатор запроса от клиента не пустой, не слишком длинный
+// и состоит только из печатных ASCII-символов, чтобы его можно было безопасно писать в журнал
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// httpLogger - middleware для структурированного логирования HTTP-запросов
+func httpLogger(mux *http.ServeMux, handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := mux.Handler(r)
 		startTime := time.Now()
 		rec := NewResponseRecorder(w)
 		handler.ServeHTTP(rec, r)
 		duration := time.Since(startTime)
-		
-		level := "[INFO]"
+
+		level := slog.LevelInfo
 		switch {
-		case rec.statusCode >= 500:
-			level = "[ERROR]"
-		case rec.statusCode >= 400:
-			level = "[WARN]"
+		case rec.Status() >= 500:
+			level = slog.LevelError
+		case rec.Status() >= 400:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", route),
+			slog.Int("status", rec.Status()),
+			slog.Int64("bytes", rec.bytes),
+			slog.Float64("duration_ms", float64(duration.Microseconds())/1000),
+		}
+		if userID := logUserID(r); userID != "" {
+			attrs = append(attrs, slog.String("user_id", userID))
+		}
+		if rec.err != nil {
+			attrs = append(attrs, slog.String("error", rec.err.Error()))
+		}
+		slog.LogAttrs(r.Context(), level, "http request", attrs...)
+	})
+}
+
+// logUserID - возвращает user_id запроса для журнала.
+// Тело запроса к этому моменту уже прочитано, поэтому параметры тела берутся только из уже разобранной формы
+func logUserID(r *http.Request) string {
+	if userID := r.PathValue("id"); userID != "" {
+		return userID
+	}
+	if r.Form != nil {
+		return r.Form.Get("user_id")
+	}
+	return r.URL.Query().Get("user_id")
+}
+
+// jsonBody - middleware для разбора JSON-тела запроса в r.PostForm,
+// благодаря которому все обработчики принимают как www-url-form-encoded, так и application/json.
+// Тело формы тоже разбирается заранее, чтобы превышение лимита размера отклонялось одинаково
+func jsonBody(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			switch mediaType(r) {
+			case contentTypeJSON:
+				if err := parseJSONForm(r); err != nil {
+					responsError(w, err)
+					return
+				}
+			case contentTypeForm:
+				// Форма разбирается здесь, чтобы слишком большое тело сразу отклонялось с 413
+				if err := r.ParseForm(); err != nil {
+					if tooLarge := payloadTooLarge(err); tooLarge != nil {
+						responsError(w, tooLarge)
+						return
+					}
+				}
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// authenticate - middleware для проверки токена доступа из заголовка Authorization.
+// Аутентифицированный пользователь кладется в контекст запроса, а запрос к чужому user_id отклоняется с 403
+func (h *Handler) authenticate(handler http.HandlerFunc) http.Handler {
+	limited := h.limitUser(handler)
+	// Без настроенной аутентификации запросы проходят как раньше
+	if h.auth == nil {
+		return limited
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="calendar"`)
+			responsErrorJSON(w, errors.NewUnauthorizedError("missing bearer token"), http.StatusUnauthorized)
+			return
+		}
+		claims, err := h.auth.Verify(token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="calendar", error="invalid_token"`)
+			responsErrorJSON(w, errors.NewUnauthorizedError(err.Error()), http.StatusUnauthorized)
+			return
 		}
 
-		log.Printf("%s received a HTTP request Method=%s Status=%d Path=%s Duration=%s\n", level, r.Method, rec.statusCode, r.RequestURI, duration)
+		// Некорректный user_id пропускаем: обработчик сам вернет 400
+		if userID, err := strconv.Atoi(requestUserID(r)); err == nil && userID != claims.UserID {
+			responsErrorJSON(w, errors.NewForbiddenError("access to user_id "+strconv.Itoa(userID)+" is denied"), http.StatusForbidden)
+			return
+		}
 
+		limited.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), claims.UserID)))
 	})
 }
 
-// ResponseRecorder - структура для записи статус-кода HTTP-ответа
+// bearerToken - извлекает токен из заголовка "Authorization: Bearer <token>"
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// requestUserID - возвращает user_id запроса из пути (/api/v1/users/{id}) или из параметров
+func requestUserID(r *http.Request) string {
+	if userID := r.PathValue("id"); userID != "" {
+		return userID
+	}
+	return r.FormValue("user_id")
+}
+
+// ResponseRecorder - структура для записи статус-кода, размера и ошибки HTTP-ответа
 type ResponseRecorder struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int64 // bytes - число записанных байт тела ответа
+	err        error // err - ошибка, отправленная клиенту в теле ответа
 }
 
-// NewResponseRecorder - конструктор для ResponseRecorder
+// NewResponseRecorder - конструктор для ResponseRecorder.
+// Если w уже является ResponseRecorder, то он и возвращается, чтобы все middleware видели один ответ
 func NewResponseRecorder(w http.ResponseWriter) *ResponseRecorder {
+	if rec, ok := w.(*ResponseRecorder); ok {
+		return rec
+	}
 	return &ResponseRecorder{ResponseWriter: w}
 }
 
@@ -43,3 +190,24 @@ func (rec *ResponseRecorder) WriteHeader(statusCode int) {
 	rec.statusCode = statusCode
 	rec.ResponseWriter.WriteHeader(statusCode)
 }
+
+// Write - записывает тело ответа и считает записанные байты
+func (rec *ResponseRecorder) Write(b []byte) (int, error) {
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap - возвращает исходный ResponseWriter, чтобы http.ResponseController мог сбросить буфер ответа
+// и изменить таймауты соединения
+func (rec *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Status - возвращает статус-код ответа; обработчик, не вызвавший WriteHeader, отвечает 200
+func (rec *ResponseRecorder) Status() int {
+	if rec.statusCode == 0 {
+		return http.StatusOK
+	}
+	return rec.statusCode
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"develop/dev11/internal/errors"
+	_ "embed"
+	"log/slog"
+	"net/http"
+)
+
+// openAPISpec - спецификация OpenAPI 3 всех маршрутов сервера. Тесты проверяют, что она совпадает с роутером
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// getOpenAPI отдает спецификацию OpenAPI в формате JSON
+func getOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodGet), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(openAPISpec); err != nil {
+		slog.ErrorContext(r.Context(), "getOpenAPI", "error", err.Error())
+	}
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"develop/dev11/internal/errors"
+	"develop/dev11/internal/models"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// patchEvent - возвращает копию события current, в которой изменены только поля, переданные в запросе.
+// Переданное пустое значение очищает необязательное поле (description=, end=, tz=, rrule=, remind=, exdate=, attendees=, calendar_id=),
+// а для обязательных полей (date, title) приводит к ошибке валидации.
+// Если меняется только начало события, окончание сдвигается вместе с ним, чтобы сохранить длительность
+func patchEvent(r *http.Request, current *models.Event) (*models.Event, error) {
+	event := current.Clone()
+
+	// Даты без смещения разбираются в новом часовом поясе, если он передан, иначе - в поясе события
+	if supplied(r, "tz") {
+		event.TZ = strings.TrimSpace(r.PostFormValue("tz"))
+	}
+	location, err := models.LoadLocation(event.TZ)
+	if err != nil {
+		return nil, err
+	}
+
+	if supplied(r, "date") {
+		value := r.PostFormValue("date")
+		if value == "" {
+			return nil, errors.NewBadRequestError("empty parameter: date")
+		}
+		date, err := parseDateTime(value, location)
+		if err != nil {
+			return nil, err
+		}
+		if event.End != nil {
+			end := date.Add(current.Duration())
+			event.End = &end
+		}
+		event.Date = date
+	}
+
+	if supplied(r, "title") {
+		event.Title = strings.TrimSpace(r.PostFormValue("title"))
+	}
+	if supplied(r, "description") {
+		event.Description = strings.TrimSpace(r.PostFormValue("description"))
+	}
+
+	if supplied(r, "end") || supplied(r, "duration") {
+		// Пустые end и duration убирают длительность события
+		if event.End, err = parseEventEnd(r, event.Date, location); err != nil {
+			return nil, err
+		}
+	}
+
+	if supplied(r, "remind") {
+		if event.Reminders, err = parseReminders(r.PostFormValue("remind")); err != nil {
+			return nil, err
+		}
+	}
+
+	if supplied(r, "calendar_id") {
+		if event.CalendarID, err = parseCalendarID(r.PostFormValue("calendar_id")); err != nil {
+			return nil, err
+		}
+	}
+
+	if supplied(r, "attendees") {
+		if event.Attendees, err = parseAttendees(r.PostFormValue("attendees")); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := patchRecurrence(r, event, location); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// patchRecurrence - изменяет правило повторения и исключенные даты события, если они переданы в запросе
+func patchRecurrence(r *http.Request, event *models.Event, location *time.Location) error {
+	if !supplied(r, "rrule") && !supplied(r, "exdate") {
+		return nil
+	}
+
+	rule := ""
+	if event.Recurrence != nil {
+		rule = event.Recurrence.String()
+	}
+	if supplied(r, "rrule") {
+		rule = strings.TrimSpace(r.PostFormValue("rrule"))
+	}
+	if rule == "" {
+		if r.PostFormValue("exdate") != "" {
+			return errors.NewBadRequestError("exdate requires rrule")
+		}
+		event.Recurrence = nil
+		return nil
+	}
+
+	var exDates []time.Time
+	if event.Recurrence != nil {
+		exDates = event.Recurrence.ExDates
+	}
+	if supplied(r, "exdate") {
+		var err error
+		if exDates, err = parseExDates(r.PostFormValue("exdate"), location); err != nil {
+			return err
+		}
+	}
+
+	recurrence, err := models.ParseRecurrence(rule, exDates, location)
+	if err != nil {
+		return err
+	}
+	event.Recurrence = recurrence
+	return nil
+}
+
+// supplied - проверяет, передан ли параметр в теле запроса (в том числе с пустым значением)
+func supplied(r *http.Request, key string) bool {
+	_, ok := r.PostForm[key]
+	return ok
+}
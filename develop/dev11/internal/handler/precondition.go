@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"develop/dev11/internal/errors"
+	"develop/dev11/internal/models"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// setETag - возвращает версию события в заголовке ETag
+func setETag(w http.ResponseWriter, event *models.Event) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(event.Version)+`"`)
+}
+
+// parseVersion - возвращает версию события, которую ожидает клиент, из заголовка If-Match или параметра version.
+// If-Match: * разрешает изменение любой версии и возвращает 0. Если If-Match перечисляет несколько ETag,
+// возвращается текущая версия события, когда она есть в списке, иначе - первая из перечисленных,
+// и изменение отклоняется с 412 при проверке версии. Без условия изменение отклоняется с 428,
+// чтобы клиенты не перезаписывали чужие изменения
+func (h *Handler) parseVersion(r *http.Request, userID, eventID int) (int, error) {
+	if ifMatch := strings.TrimSpace(strings.Join(r.Header.Values("If-Match"), ",")); ifMatch != "" {
+		versions, err := parseIfMatch(ifMatch)
+		if err != nil {
+			return 0, err
+		}
+		switch len(versions) {
+		case 0:
+			return 0, nil
+		case 1:
+			return versions[0], nil
+		}
+		current, err := h.service.Get(userID, eventID)
+		if err != nil {
+			return 0, err
+		}
+		if slices.Contains(versions, current.Version) {
+			return current.Version, nil
+		}
+		return versions[0], nil
+	}
+
+	if value := r.FormValue("version"); value != "" {
+		version, err := strconv.Atoi(value)
+		if err != nil || version < 1 {
+			return 0, errors.NewBadRequestError("invalid version: must be a positive number")
+		}
+		return version, nil
+	}
+	return 0, errors.NewPreconditionRequiredError("If-Match header or version parameter is required")
+}
+
+// parseIfMatch - разбирает список ETag из If-Match (RFC 9110, раздел 13.1.1) в версии событий.
+// Для * возвращает пустой список
+func parseIfMatch(ifMatch string) ([]int, error) {
+	if ifMatch == "*" {
+		return nil, nil
+	}
+	versions := make([]int, 0, 1)
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		// Пустые элементы списка допускаются и пропускаются
+		if tag == "" {
+			continue
+		}
+		// Сравнение в If-Match строгое, поэтому слабые ETag (W/"1") не подходят
+		value, ok := strings.CutPrefix(tag, `"`)
+		value, ok2 := strings.CutSuffix(value, `"`)
+		version, err := strconv.Atoi(value)
+		if !ok || !ok2 || err != nil || version < 1 {
+			return nil, errors.NewBadRequestError("invalid If-Match: must be ETags like \"1\" separated by commas or *")
+		}
+		versions = append(versions, version)
+	}
+	if len(versions) == 0 {
+		return nil, errors.NewBadRequestError("invalid If-Match: must be ETags like \"1\" separated by commas or *")
+	}
+	return versions, nil
+}
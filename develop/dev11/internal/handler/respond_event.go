@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"develop/dev11/internal/errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// respondEvent обрабатывает ответ приглашенного пользователя на событие: status=accepted, declined или tentative
+func (h *Handler) respondEvent(w http.ResponseWriter, r *http.Request) {
+	// Проверяем метод запроса на соответствие POST
+	if r.Method != http.MethodPost {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodPost), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Проверяем наличие необходимых параметров в теле POST запроса
+	if err := checkPostRequrst(r, "user_id", "id", "status"); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем и проверяем целочисленные значения user_id и id из тела POST запроса
+	userID, err := strconv.Atoi(r.PostFormValue("user_id"))
+	if err != nil {
+		responsErrorJSON(w, errors.NewBadRequestError("invalid user_id: use only numbers"), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(r.PostFormValue("id"))
+	if err != nil {
+		responsErrorJSON(w, errors.NewBadRequestError("invalid id: use only numbers"), http.StatusBadRequest)
+		return
+	}
+
+	// Записываем ответ через service, права участника проверяет service
+	event, err := h.service.Respond(userID, id, strings.ToLower(strings.TrimSpace(r.PostFormValue("status"))))
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	// Возвращаем успешный ответ, новая версия события - в заголовке ETag
+	setETag(w, event)
+	responsJSON(w, "OK", http.StatusOK)
+}
@@ -1,41 +1,69 @@
 package handler
 
 import (
+	"develop/dev11/internal/errors"
+	"develop/dev11/internal/logger"
+	"develop/dev11/internal/models"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 )
 
 // responsJSON выполняет сериализацию объектов доменной области в JSON и отправляет ответ клиенту
 func responsJSON(w http.ResponseWriter, data any, status int) {
+	writeJSON(w, map[string]any{"result": data}, status)
+}
+
+// responsJSONWithConflicts отправляет результат и, если они есть, пересекающиеся события в поле conflicts
+func responsJSONWithConflicts(w http.ResponseWriter, data any, conflicts []*models.Event, status int) {
+	body := map[string]any{"result": data}
+	if len(conflicts) > 0 {
+		body["conflicts"] = conflicts
+	}
+	writeJSON(w, body, status)
+}
+
+// writeJSON выполняет сериализацию тела ответа в JSON и отправляет ответ клиенту
+func writeJSON(w http.ResponseWriter, body map[string]any, status int) {
+	// Возвращаем идентификатор запроса, чтобы клиент мог сопоставить ответ с записью журнала
+	if requestID := w.Header().Get(requestIDHeader); requestID != "" {
+		body[logger.RequestIDKey] = requestID
+	}
 	// Устанавливаем тип контента ответа
 	w.Header().Set("Content-Type", "application/json")
 	// Устанавливаем HTTP-статус ответа
 	w.WriteHeader(status)
 
 	// Кодируем данные в JSON и отправляем клиенту
-	if err := json.NewEncoder(w).Encode(map[string]any{"result": data}); err != nil {
-		log.Printf("[ERROR] responsJSON: %s\n", err.Error())
+	if err := json.NewEncoder(w).Encode(body); err != nil {
 		// Логируем ошибку, если не удалось отправить ответ
+		slog.Error("writeJSON", logger.RequestIDKey, w.Header().Get(requestIDHeader), "error", err.Error())
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 }
 
-// responsErrorJSON выполняет сериализацию ошибки в JSON и отправляет ответ клиенту
+// responsErrorJSON выполняет сериализацию ошибки в JSON и отправляет ответ клиенту.
+// Ошибка попадает в запись журнала о запросе
 func responsErrorJSON(w http.ResponseWriter, err error, status int) {
-	// Устанавливаем тип контента ответа
-	w.Header().Set("Content-Type", "application/json")
-	// Устанавливаем HTTP-статус ответа
-	w.WriteHeader(status)
+	if rec, ok := w.(*ResponseRecorder); ok {
+		rec.err = err
+	}
+	// Клиенту, превысившему лимит запросов, сообщаем, когда можно повторить запрос
+	if tooMany, ok := err.(*errors.TooManyRequestsError); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(tooMany.RetryAfterSeconds()))
+	}
+	writeJSON(w, map[string]any{"error": err.Error()}, status)
+}
 
-	// Кодируем информацию об ошибке в JSON и отправляем клиенту
-	if err := json.NewEncoder(w).Encode(map[string]any{"error": err.Error()}); err != nil {
-		log.Printf("[ERROR] responsErrorJSON: %s\n", err.Error())
-		// Логируем ошибку, если не удалось отправить ответ
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+// responsError отправляет ошибку с кодом из HTTPError или 500 для остальных ошибок
+func responsError(w http.ResponseWriter, err error) {
+	// Проверяем, соответствует ли ошибка нашему интерфейсу (HTTPError)
+	if httpError, ok := err.(errors.HTTPError); ok {
+		responsErrorJSON(w, httpError, httpError.StatusCode())
 		return
 	}
-	// Дополнительно логируем ошибку как предупреждение
-	log.Printf("[WARN] responsErrorJSON: %s\n", err.Error())
+	// Если это другая ошибка, возвращаем внутреннюю серверную ошибку
+	responsErrorJSON(w, err, http.StatusInternalServerError)
 }
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"develop/dev11/internal/errors"
+	"develop/dev11/internal/models"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultHeartbeat - период пульса в потоке изменений, чтобы прокси не закрывали простаивающее соединение
+const defaultHeartbeat = 15 * time.Second
+
+// streamRetry - через сколько миллисекунд EventSource переподключается после обрыва потока
+const streamRetry = 3000
+
+// WithHeartbeat - задает период пульса в потоке изменений
+func WithHeartbeat(interval time.Duration) Option {
+	return func(h *Handler) {
+		h.heartbeat = interval
+	}
+}
+
+// streamEvents обрабатывает запрос на поток изменений событий пользователя в формате Server-Sent Events.
+// Каждое изменение отправляется событием с id, равным номеру изменения в журнале. При переподключении
+// с заголовком Last-Event-ID (или параметром last_event_id) сначала отправляются пропущенные изменения
+func (h *Handler) streamEvents(w http.ResponseWriter, r *http.Request) {
+	// Проверяем метод запроса на соответствие GET
+	if r.Method != http.MethodGet {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodGet), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Останавливающийся сервер не принимает новые потоки: клиент переподключится к другому экземпляру
+	if h.shuttingDown.Load() {
+		w.Header().Set("Retry-After", strconv.Itoa(streamRetry/1000))
+		responsErrorJSON(w, errors.NewServiceUnavailableError(), http.StatusServiceUnavailable)
+		return
+	}
+
+	// Проверяем наличие необходимых параметров в запросе
+	if err := checkGetRequrst(r, "user_id"); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем и проверяем целочисленное значение user_id из строки запроса
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil {
+		responsErrorJSON(w, errors.NewBadRequestError("invalid user_id: use only numbers"), http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем номер последнего полученного изменения
+	lastEventID, resume, err := parseLastEventID(r)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Поток живет дольше таймаута записи сервера, поэтому снимаем его для этого соединения
+	controller := http.NewResponseController(w)
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil && !stderrors.Is(err, http.ErrNotSupported) {
+		responsError(w, err)
+		return
+	}
+
+	// Подписываемся до чтения журнала, чтобы не потерять изменения между чтением и подпиской
+	changes, unsubscribe := h.service.Subscribe(userID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	stream := &changeStream{w: w, controller: controller, last: lastEventID}
+	// Отправляем изменения, пропущенные с момента обрыва
+	if resume {
+		for {
+			missed, err := h.service.Changes(userID, stream.last, maxLimit)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "streamEvents", "error", err.Error())
+				return
+			}
+			for _, change := range missed {
+				if err := stream.send(change); err != nil {
+					return
+				}
+			}
+			if len(missed) < maxLimit {
+				break
+			}
+		}
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.stop:
+			return
+		case change, ok := <-changes:
+			// Подписка закрыта, потому что клиент не успевал читать: он переподключится с Last-Event-ID
+			if !ok {
+				return
+			}
+			if err := stream.send(change); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := stream.comment("heartbeat"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// changeStream - поток изменений одного клиента
+type changeStream struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+	last       int64 // last - номер последнего отправленного изменения
+}
+
+// send - отправляет изменение, если оно не было отправлено раньше (при догоне журнала и подписке оно может прийти дважды)
+func (stream *changeStream) send(change models.Change) error {
+	if change.Seq <= stream.last {
+		return nil
+	}
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(stream.w, "id: %d\nevent: %s\ndata: %s\n\n", change.Seq, change.Op, payload); err != nil {
+		return err
+	}
+	stream.last = change.Seq
+	return stream.controller.Flush()
+}
+
+// comment - отправляет комментарий, который клиенты игнорируют, но который поддерживает соединение
+func (stream *changeStream) comment(text string) error {
+	if _, err := fmt.Fprintf(stream.w, ": %s\n\n", text); err != nil {
+		return err
+	}
+	return stream.controller.Flush()
+}
+
+// parseLastEventID - возвращает номер последнего полученного клиентом изменения из заголовка Last-Event-ID
+// или параметра last_event_id и признак того, что клиент переподключается
+func parseLastEventID(r *http.Request) (int64, bool, error) {
+	value := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, false, nil
+	}
+	lastEventID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || lastEventID < 0 {
+		return 0, false, errors.NewBadRequestError("invalid Last-Event-ID: must be an id of a received event")
+	}
+	return lastEventID, true, nil
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"develop/dev11/internal/errors"
+	"net/http"
+	"strconv"
+)
+
+// getTrash обрабатывает запрос на получение событий пользователя в корзине
+func (h *Handler) getTrash(w http.ResponseWriter, r *http.Request) {
+	// Проверяем метод запроса на соответствие GET
+	if r.Method != http.MethodGet {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodGet), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Проверяем наличие необходимых параметров в запросе
+	if err := checkGetRequrst(r, "user_id"); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем и проверяем целочисленное значение user_id из строки запроса
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil {
+		responsErrorJSON(w, errors.NewBadRequestError("invalid user_id: use only numbers"), http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем часовой пояс ответа
+	location, err := parseQueryLocation(r)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Получаем события в корзине через service
+	events, err := h.service.Trash(userID)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	// Возвращаем события в формате JSON во времени запрошенного часового пояса
+	responsJSON(w, eventsIn(events, location), http.StatusOK)
+}
+
+// restoreEvent обрабатывает запрос на восстановление события из корзины
+func (h *Handler) restoreEvent(w http.ResponseWriter, r *http.Request) {
+	// Проверяем метод запроса на соответствие POST
+	if r.Method != http.MethodPost {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodPost), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Проверяем наличие необходимых параметров в теле POST запроса
+	if err := checkPostRequrst(r, "user_id", "id"); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем и проверяем целочисленные значения user_id и id из тела POST запроса
+	userID, err := strconv.Atoi(r.PostFormValue("user_id"))
+	if err != nil {
+		responsErrorJSON(w, errors.NewBadRequestError("invalid user_id: use only numbers"), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(r.PostFormValue("id"))
+	if err != nil {
+		responsErrorJSON(w, errors.NewBadRequestError("invalid id: use only numbers"), http.StatusBadRequest)
+		return
+	}
+
+	// Восстанавливаем событие через service
+	event, err := h.service.Restore(userID, id)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	// Возвращаем успешный ответ, новая версия события - в заголовке ETag
+	setETag(w, event)
+	responsJSON(w, "OK", http.StatusOK)
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"develop/dev11/internal/errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultUpcoming - число ближайших событий, если n не задан
+const defaultUpcoming = 10
+
+// getUpcomingEvents обрабатывает запрос на получение n ближайших событий пользователя
+func (h *Handler) getUpcomingEvents(w http.ResponseWriter, r *http.Request) {
+	// Проверяем метод запроса на соответствие GET
+	if r.Method != http.MethodGet {
+		responsErrorJSON(w, errors.NewBadMethodError(r.Method, http.MethodGet), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Проверяем наличие необходимых параметров в запросе
+	if err := checkGetRequrst(r, "user_id"); err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем и проверяем целочисленное значение user_id из строки запроса
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil {
+		responsErrorJSON(w, errors.NewBadRequestError("invalid user_id: use only numbers"), http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем и проверяем число событий
+	n := defaultUpcoming
+	if value := r.URL.Query().Get("n"); value != "" {
+		n, err = strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxLimit {
+			responsErrorJSON(w, errors.NewBadRequestError(fmt.Sprintf("invalid n: must be between 1 and %d", maxLimit)), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Извлекаем часовой пояс ответа и момента from
+	location, err := parseQueryLocation(r)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// По умолчанию ближайшие события считаются от текущего момента
+	fromDate := time.Now()
+	if value := r.URL.Query().Get("from"); value != "" {
+		fromDate, err = parseRangeDate("from", value, location)
+		if err != nil {
+			responsErrorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Извлекаем фильтр, параметры страницы не используются
+	query, err := parseEventsQuery(r)
+	if err != nil {
+		responsErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Получаем ближайшие события через service
+	events, err := h.service.Upcoming(userID, fromDate, n, query.Filter)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	// Возвращаем события в формате JSON во времени запрошенного часового пояса
+	responsJSON(w, eventsIn(events, location), http.StatusOK)
+}
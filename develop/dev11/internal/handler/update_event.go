@@ -2,7 +2,9 @@ package handler
 
 import (
 	"develop/dev11/internal/errors"
+	"develop/dev11/internal/models"
 	"net/http"
+	"strconv"
 )
 
 // updateEvent обрабатывает запрос на обновление события
@@ -13,22 +15,43 @@ func (h *Handler) updateEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Проверяем наличие необходимых параметров в теле POST запроса
-	if err := checkPostRequrst(r, "user_id", "id", "date", "title"); err != nil {
+	// Проверяем наличие необходимых параметров в теле POST запроса.
+	// Остальные поля необязательны: изменяются только переданные поля
+	if err := checkPostRequrst(r, "user_id", "id"); err != nil {
 		responsErrorJSON(w, err, http.StatusBadRequest)
 		return
 	}
 
-	// Парсим событие из тела запроса
-	updateEvent, err := parseEventFromRequest(r, true)
+	// Извлекаем и проверяем целочисленные значения user_id и id из тела POST запроса
+	userID, err := strconv.Atoi(r.PostFormValue("user_id"))
 	if err != nil {
-		responsErrorJSON(w, err, http.StatusBadRequest)
+		responsErrorJSON(w, errors.NewBadRequestError("invalid user_id: use only numbers"), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(r.PostFormValue("id"))
+	if err != nil {
+		responsErrorJSON(w, errors.NewBadRequestError("invalid id: use only numbers"), http.StatusBadRequest)
 		return
 	}
 
-	// Проверяем валидность события
-	if err := updateEvent.Validate(); err != nil {
-		responsErrorJSON(w, err, http.StatusBadRequest)
+	// Событие обновляется, только если клиент видел его текущую версию
+	version, err := h.parseVersion(r, userID, id)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	// Применяем переданные поля к текущему событию и проверяем результат целиком
+	updateEvent, err := h.mergeEvent(r, userID, id, version)
+	if err != nil {
+		responsError(w, err)
+		return
+	}
+
+	// Ищем пересекающиеся события пользователя
+	conflicts, err := h.checkConflicts(r, updateEvent, true)
+	if err != nil {
+		responsError(w, err)
 		return
 	}
 
@@ -45,6 +68,33 @@ func (h *Handler) updateEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Отправляем подтверждение об успешном обновлении события
-	responsJSON(w, "OK", http.StatusOK)
+	// Отправляем подтверждение об успешном обновлении события и его новую версию
+	setETag(w, updateEvent)
+	responsJSONWithConflicts(w, "OK", conflicts, http.StatusOK)
+}
+
+// mergeEvent - читает событие, применяет к нему поля из запроса и проверяет результат.
+// Изменения применяются к прочитанной версии события: если событие успело измениться, то обновление
+// будет отклонено с 412, даже при If-Match: *, чтобы не потерять чужие изменения
+func (h *Handler) mergeEvent(r *http.Request, userID, id, version int) (*models.Event, error) {
+	current, err := h.service.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	event, err := patchEvent(r, current)
+	if err != nil {
+		return nil, err
+	}
+	if err := event.ValidateChange(current); err != nil {
+		return nil, err
+	}
+
+	if version == 0 {
+		version = current.Version
+	}
+	event.Version = version
+	// Событие, на которое пользователь приглашен, принадлежит другому пользователю:
+	// изменение идет от имени пользователя запроса, и сервис отклонит его, так как пользователь не владелец
+	event.UserID = userID
+	return event, nil
 }
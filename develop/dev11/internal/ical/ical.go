@@ -0,0 +1,381 @@
+package ical
+
+import (
+	"bufio"
+	"develop/dev11/internal/models"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Форматы дат iCalendar (RFC 5545)
+const (
+	dateTimeUTCLayout = "20060102T150405Z"
+	dateTimeLayout    = "20060102T150405"
+	dateLayout        = "20060102"
+)
+
+// maxLineLength - максимальная длина строки в октетах, после которой строка переносится
+const maxLineLength = 75
+
+// Encode - сериализует события в VCALENDAR. Для каждого часового пояса, на который ссылается TZID,
+// записывается VTIMEZONE, чтобы клиенты без базы часовых поясов IANA правильно читали местное время
+func Encode(w io.Writer, events []*models.Event) error {
+	writer := &lineWriter{w: bufio.NewWriter(w)}
+	now := time.Now()
+	stamp := now.UTC().Format(dateTimeUTCLayout)
+
+	writer.line("BEGIN:VCALENDAR")
+	writer.line("VERSION:2.0")
+	writer.line("PRODID:-//dev11//calendar//EN")
+	writer.line("CALSCALE:GREGORIAN")
+	writeTimezones(writer, events, now)
+	for _, event := range events {
+		writer.line("BEGIN:VEVENT")
+		writer.line("UID:" + UID(event))
+		writer.line("DTSTAMP:" + stamp)
+		writer.line(dateProperty("DTSTART", event, event.Date))
+		if event.End != nil {
+			writer.line(dateProperty("DTEND", event, *event.End))
+		}
+		writer.line("SUMMARY:" + escapeText(event.Title))
+		if event.Description != "" {
+			writer.line("DESCRIPTION:" + escapeText(event.Description))
+		}
+		if event.Recurrence != nil {
+			writer.line("RRULE:" + event.Recurrence.String())
+			if len(event.Recurrence.ExDates) > 0 {
+				exDates := make([]string, 0, len(event.Recurrence.ExDates))
+				for _, exDate := range event.Recurrence.ExDates {
+					exDates = append(exDates, formatDate(event, exDate))
+				}
+				writer.line(dateName("EXDATE", event) + ":" + strings.Join(exDates, ","))
+			}
+		}
+		writer.line("END:VEVENT")
+	}
+	writer.line("END:VCALENDAR")
+
+	if writer.err != nil {
+		return writer.err
+	}
+	return writer.w.Flush()
+}
+
+// dateProperty - возвращает строку свойства с датой события
+func dateProperty(name string, event *models.Event, date time.Time) string {
+	return dateName(name, event) + ":" + formatDate(event, date)
+}
+
+// dateName - возвращает имя свойства с датой, для события с часовым поясом - с параметром TZID
+func dateName(name string, event *models.Event) string {
+	if event.TZ == "" {
+		return name
+	}
+	return name + ";TZID=" + event.TZ
+}
+
+// formatDate - форматирует дату в UTC или, для события с часовым поясом, в местном времени.
+// Местное время с TZID сохраняет смысл правила повторения при переходе на летнее время
+func formatDate(event *models.Event, date time.Time) string {
+	if event.TZ == "" {
+		return date.UTC().Format(dateTimeUTCLayout)
+	}
+	return date.In(event.Location()).Format(dateTimeLayout)
+}
+
+// UID - возвращает уникальный идентификатор события в iCalendar: UID, с которым событие было импортировано,
+// или идентификатор, составленный из владельца и id события
+func UID(event *models.Event) string {
+	if event.UID != "" {
+		return event.UID
+	}
+	return fmt.Sprintf("%d-%d@dev11", event.UserID, event.ID)
+}
+
+// Item - результат разбора одного VEVENT
+type Item struct {
+	UID          string        // UID события, если он указан
+	RecurrenceID *time.Time    // RECURRENCE-ID, если VEVENT изменяет одно повторение серии с тем же UID
+	Event        *models.Event // Разобранное событие (nil, если была ошибка)
+	Err          error         // Ошибка разбора
+}
+
+// Key - возвращает ключ события в календаре: UID, а для измененного повторения серии - UID вместе с RECURRENCE-ID
+func (item Item) Key() string {
+	if item.RecurrenceID == nil {
+		return item.UID
+	}
+	return item.UID + "#" + item.RecurrenceID.UTC().Format(dateTimeUTCLayout)
+}
+
+// Decode - разбирает VCALENDAR и возвращает по одному Item на каждый VEVENT.
+// Ошибка возвращается, только если сам календарь не удалось прочитать
+func Decode(r io.Reader) ([]Item, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, 0)
+	var props []property
+	inCalendar, inEvent := false, false
+	for _, line := range lines {
+		prop, err := parseProperty(line)
+		if err != nil {
+			if inEvent {
+				// Ошибку в строке события относим к этому событию
+				props = append(props, property{name: "X-INVALID", value: err.Error()})
+				continue
+			}
+			return nil, err
+		}
+
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VCALENDAR"):
+			inCalendar = true
+		case prop.name == "END" && strings.EqualFold(prop.value, "VCALENDAR"):
+			inCalendar = false
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT"):
+			if !inCalendar {
+				return nil, fmt.Errorf("VEVENT outside of VCALENDAR")
+			}
+			inEvent, props = true, nil
+		case prop.name == "END" && strings.EqualFold(prop.value, "VEVENT"):
+			inEvent = false
+			items = append(items, decodeEvent(props))
+		case inEvent:
+			props = append(props, prop)
+		}
+	}
+	if inEvent {
+		return nil, fmt.Errorf("unterminated VEVENT")
+	}
+	return items, nil
+}
+
+// property - строка содержимого "NAME;PARAM=VALUE:VALUE"
+type property struct {
+	name   string            // Имя свойства в верхнем регистре
+	params map[string]string // Параметры свойства
+	value  string            // Значение свойства
+}
+
+// parseProperty - разбирает строку содержимого
+func parseProperty(line string) (property, error) {
+	// Двоеточие внутри параметров в кавычках не считается разделителем
+	quoted := false
+	split := -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		}
+		if r == ':' && !quoted {
+			split = i
+			break
+		}
+	}
+	if split < 0 {
+		return property{}, fmt.Errorf("invalid content line: %q", line)
+	}
+
+	parts := strings.Split(line[:split], ";")
+	prop := property{name: strings.ToUpper(parts[0]), params: make(map[string]string), value: line[split+1:]}
+	for _, param := range parts[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+	return prop, nil
+}
+
+// decodeEvent - собирает событие из свойств VEVENT
+func decodeEvent(props []property) Item {
+	item := Item{}
+	event := &models.Event{}
+	var rule string
+	var exDates []time.Time
+	hasStart := false
+
+	for _, prop := range props {
+		switch prop.name {
+		case "X-INVALID":
+			item.Err = fmt.Errorf("%s", prop.value)
+		case "UID":
+			item.UID = prop.value
+		case "RECURRENCE-ID":
+			if prop.params["RANGE"] != "" {
+				item.Err = fmt.Errorf("RECURRENCE-ID with RANGE is not supported")
+				continue
+			}
+			recurrenceID, err := parseDate(prop)
+			if err != nil {
+				item.Err = err
+				continue
+			}
+			item.RecurrenceID = &recurrenceID
+		case "DTSTART":
+			date, err := parseDate(prop)
+			if err != nil {
+				item.Err = err
+				continue
+			}
+			event.Date, hasStart = date, true
+			// Часовой пояс начала становится часовым поясом события
+			if tzid := prop.params["TZID"]; tzid != "" && !strings.HasSuffix(prop.value, "Z") {
+				event.TZ = tzid
+			}
+		case "DTEND":
+			end, err := parseDate(prop)
+			if err != nil {
+				item.Err = err
+				continue
+			}
+			event.End = &end
+		case "SUMMARY":
+			event.Title = strings.TrimSpace(unescapeText(prop.value))
+		case "DESCRIPTION":
+			event.Description = strings.TrimSpace(unescapeText(prop.value))
+		case "RRULE":
+			rule = prop.value
+		case "EXDATE":
+			for _, value := range strings.Split(prop.value, ",") {
+				exDate, err := parseDate(property{name: prop.name, params: prop.params, value: value})
+				if err != nil {
+					item.Err = err
+					continue
+				}
+				exDates = append(exDates, exDate)
+			}
+		}
+	}
+
+	if item.Err != nil {
+		return item
+	}
+	if !hasStart {
+		item.Err = fmt.Errorf("missing DTSTART")
+		return item
+	}
+	if item.RecurrenceID != nil && item.UID == "" {
+		item.Err = fmt.Errorf("RECURRENCE-ID without UID")
+		return item
+	}
+	if item.RecurrenceID != nil && rule != "" {
+		item.Err = fmt.Errorf("RRULE in an overridden occurrence is not supported")
+		return item
+	}
+	if rule != "" {
+		// UNTIL без времени относится к местной дате начала события
+		recurrence, err := models.ParseRecurrence(rule, exDates, event.Date.Location())
+		if err != nil {
+			item.Err = err
+			return item
+		}
+		event.Recurrence = recurrence
+	}
+	item.Event = event
+	return item
+}
+
+// parseDate - разбирает дату DTSTART/EXDATE/RECURRENCE-ID с учетом VALUE=DATE и TZID
+func parseDate(prop property) (time.Time, error) {
+	value := strings.TrimSpace(prop.value)
+	if prop.params["VALUE"] == "DATE" || len(value) == len(dateLayout) {
+		return time.Parse(dateLayout, value)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(dateTimeUTCLayout, value)
+	}
+
+	location := time.UTC
+	if tzid := prop.params["TZID"]; tzid != "" {
+		loaded, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unknown TZID %q", tzid)
+		}
+		location = loaded
+	}
+	date, err := time.ParseInLocation(dateTimeLayout, value, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q", prop.name, value)
+	}
+	return date, nil
+}
+
+// unfold - читает строки содержимого, склеивая перенесенные строки
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	lines := make([]string, 0)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		// Строка, начинающаяся с пробела или табуляции, продолжает предыдущую
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// lineWriter - записывает строки содержимого с переносом длинных строк и CRLF
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line - записывает строку, перенося ее по 75 октетов без разрыва UTF-8 символов
+func (lw *lineWriter) line(text string) {
+	if lw.err != nil {
+		return
+	}
+	limit := maxLineLength
+	for len(text) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(text[cut]) {
+			cut--
+		}
+		if _, lw.err = lw.w.WriteString(text[:cut] + "\r\n "); lw.err != nil {
+			return
+		}
+		text = text[cut:]
+		// Продолжение начинается с пробела, который тоже занимает октет
+		limit = maxLineLength - 1
+	}
+	_, lw.err = lw.w.WriteString(text + "\r\n")
+}
+
+// isRuneStart - проверяет, что байт начинает UTF-8 символ
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// escapeText - экранирует значение типа TEXT
+func escapeText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(text)
+}
+
+// unescapeText - снимает экранирование значения типа TEXT
+func unescapeText(text string) string {
+	var builder strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i+1 == len(text) {
+			builder.WriteByte(text[i])
+			continue
+		}
+		i++
+		switch text[i] {
+		case 'n', 'N':
+			builder.WriteByte('\n')
+		default:
+			builder.WriteByte(text[i])
+		}
+	}
+	return builder.String()
+}
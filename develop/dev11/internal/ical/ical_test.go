@@ -0,0 +1,190 @@
+package ical
+
+import (
+	"bytes"
+	"develop/dev11/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	event := models.NewEvent(5, 3, time.Date(2036, 5, 12, 15, 04, 05, 0, time.UTC), "Стендап, команда", "Line1\nLine2; "+strings.Repeat("long ", 20))
+	end := event.Date.Add(time.Hour)
+	event.End = &end
+	event.Recurrence = &models.Recurrence{
+		Freq:    models.FreqWeekly,
+		ByDay:   []string{"MO", "WE"},
+		Count:   10,
+		ExDates: []time.Time{time.Date(2036, 5, 14, 15, 04, 05, 0, time.UTC)},
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, []*models.Event{event}); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > maxLineLength {
+			t.Errorf("line is longer than %d octets: %q", maxLineLength, line)
+		}
+	}
+
+	items, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Err != nil {
+		t.Fatalf("Decode() got %+v want 1 event", items)
+	}
+	got := items[0].Event
+	if items[0].UID != "5-3@dev11" {
+		t.Errorf("UID: got %s want 5-3@dev11", items[0].UID)
+	}
+	if !got.Date.Equal(event.Date) || got.Title != event.Title || got.Description != strings.TrimSpace(event.Description) {
+		t.Errorf("Decode() got %+v\nwant %+v", got, event)
+	}
+	if got.End == nil || !got.End.Equal(end) {
+		t.Errorf("End: got %v want %v", got.End, end)
+	}
+	if got.Recurrence == nil || got.Recurrence.String() != event.Recurrence.String() || len(got.Recurrence.ExDates) != 1 {
+		t.Errorf("Recurrence: got %+v want %+v", got.Recurrence, event.Recurrence)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		calendar string
+		wantErr  bool
+		want     []string
+	}{
+		{
+			name: "TZID And Date",
+			calendar: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:a\r\nDTSTART;TZID=Europe/Moscow:20360512T150405\r\nSUMMARY:A\r\nEND:VEVENT\r\n" +
+				"BEGIN:VEVENT\r\nUID:b\r\nDTSTART;VALUE=DATE:20360513\r\nSUMMARY:B\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+			want: []string{"2036-05-12T12:04:05Z", "2036-05-13T00:00:00Z"},
+		},
+		{
+			name: "Per Event Errors",
+			calendar: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:a\r\nSUMMARY:A\r\nEND:VEVENT\r\n" +
+				"BEGIN:VEVENT\r\nUID:b\r\nDTSTART:20360513T000000Z\r\nRRULE:FREQ=HOURLY\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+			want: []string{"missing DTSTART", "bad request: invalid rrule FREQ: must be DAILY, WEEKLY, MONTHLY or YEARLY"},
+		},
+		{
+			name: "Recurrence ID",
+			calendar: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:a\r\nRECURRENCE-ID:20360519T150405Z\r\nDTSTART:20360519T170000Z\r\nEND:VEVENT\r\n" +
+				"BEGIN:VEVENT\r\nUID:a\r\nRECURRENCE-ID;RANGE=THISANDFUTURE:20360519T150405Z\r\nDTSTART:20360519T170000Z\r\nEND:VEVENT\r\n" +
+				"BEGIN:VEVENT\r\nRECURRENCE-ID:20360519T150405Z\r\nDTSTART:20360519T170000Z\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+			want: []string{"2036-05-19T17:00:00Z", "RECURRENCE-ID with RANGE is not supported", "RECURRENCE-ID without UID"},
+		},
+		{
+			name:     "Unterminated Event",
+			calendar: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:20360513T000000Z\r\n",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := Decode(strings.NewReader(tt.calendar))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error=%v wantErr=%v", err, tt.wantErr)
+			}
+			if len(items) != len(tt.want) {
+				t.Fatalf("Decode() got %d items want %d", len(items), len(tt.want))
+			}
+			for i, item := range items {
+				got := ""
+				if item.Err != nil {
+					got = item.Err.Error()
+				} else {
+					got = item.Event.Date.UTC().Format(time.RFC3339)
+				}
+				if got != tt.want[i] {
+					t.Errorf("item %d: got %s want %s", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeTimeZone(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	event := models.NewEvent(5, 3, time.Date(2036, 3, 24, 9, 0, 0, 0, location), "Стендап", "")
+	event.TZ = "Europe/Berlin"
+	event.Recurrence = &models.Recurrence{Freq: models.FreqWeekly}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, []*models.Event{event}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "DTSTART;TZID=Europe/Berlin:20360324T090000\r\n") {
+		t.Errorf("Encode() got %s want DTSTART with TZID", buf.String())
+	}
+	// TZID ссылается на VTIMEZONE, а переходы после 2037 года описаны правилами последнего воскресенья
+	for _, want := range []string{
+		"BEGIN:VTIMEZONE\r\nTZID:Europe/Berlin\r\n",
+		"BEGIN:DAYLIGHT\r\nDTSTART:20360330T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\nTZNAME:CEST\r\n",
+		"BEGIN:DAYLIGHT\r\nDTSTART:20370329T020000\r\nRRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\n",
+		"BEGIN:STANDARD\r\nDTSTART:20371025T030000\r\nRRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU\r\nTZOFFSETFROM:+0200\r\nTZOFFSETTO:+0100\r\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Encode() got %s want VTIMEZONE with %q", buf.String(), want)
+		}
+	}
+
+	items, err := Decode(&buf)
+	if err != nil || len(items) != 1 || items[0].Err != nil {
+		t.Fatalf("Decode() got %+v, %v want 1 event", items, err)
+	}
+	got := items[0].Event
+	if got.TZ != event.TZ || !got.Date.Equal(event.Date) {
+		t.Errorf("Decode() got %s %v want %s %v", got.TZ, got.Date, event.TZ, event.Date)
+	}
+}
+
+func TestFormatOffset(t *testing.T) {
+	tests := []struct {
+		offset int
+		want   string
+	}{
+		{offset: 3 * 60 * 60, want: "+0300"},
+		{offset: -(3*60*60 + 30*60), want: "-0330"},
+		{offset: 0, want: "+0000"},
+		{offset: 2*60*60 + 30*60 + 17, want: "+023017"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := formatOffset(tt.offset); got != tt.want {
+				t.Errorf("formatOffset(%d) got %s want %s", tt.offset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUID(t *testing.T) {
+	event := models.NewEvent(5, 3, time.Date(2036, 5, 12, 15, 04, 05, 0, time.UTC), "Test", "")
+	if got := UID(event); got != "5-3@dev11" {
+		t.Errorf("UID() got %s want 5-3@dev11", got)
+	}
+	// Импортированное событие выгружается с исходным UID
+	event.UID = "abc@example.com"
+	if got := UID(event); got != "abc@example.com" {
+		t.Errorf("UID() got %s want abc@example.com", got)
+	}
+}
+
+func TestItemKey(t *testing.T) {
+	item := Item{UID: "abc@example.com"}
+	if got := item.Key(); got != "abc@example.com" {
+		t.Errorf("Key() got %s want abc@example.com", got)
+	}
+	// Измененное повторение не совпадает по ключу с серией
+	recurrenceID := time.Date(2036, 5, 19, 18, 04, 05, 0, time.FixedZone("", 3*60*60))
+	item.RecurrenceID = &recurrenceID
+	if got := item.Key(); got != "abc@example.com#20360519T150405Z" {
+		t.Errorf("Key() got %s want abc@example.com#20360519T150405Z", got)
+	}
+}
@@ -0,0 +1,191 @@
+package ical
+
+import (
+	"develop/dev11/internal/models"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// transitionStep - шаг поиска переходов часового пояса. Переходы одного пояса разделены месяцами,
+// поэтому за шаг смещение меняется не больше одного раза
+const transitionStep = 6 * time.Hour
+
+// weekdays - дни недели в формате RRULE по time.Weekday
+var weekdays = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// transition - смена смещения часового пояса
+type transition struct {
+	at         time.Time // at - момент перехода
+	fromOffset int       // fromOffset - смещение от UTC в секундах до перехода
+	toOffset   int       // toOffset - смещение от UTC в секундах после перехода
+	name       string    // name - сокращенное название пояса после перехода
+	dst        bool      // dst - после перехода действует летнее время
+}
+
+// writeTimezones - записывает VTIMEZONE для каждого часового пояса событий, на который ссылается TZID.
+// Переходы перечисляются с года самого раннего события, а переходы года после текущего (или после
+// самого позднего события) описываются ежегодным правилом, чтобы клиенты считали время повторений и дальше
+func writeTimezones(writer *lineWriter, events []*models.Event, now time.Time) {
+	zones := make([]string, 0)
+	earliest := make(map[string]time.Time)
+	latest := make(map[string]time.Time)
+	for _, event := range events {
+		if event.TZ == "" {
+			continue
+		}
+		if _, ok := earliest[event.TZ]; !ok {
+			zones = append(zones, event.TZ)
+			earliest[event.TZ], latest[event.TZ] = event.Date, event.Date
+		}
+		if event.Date.Before(earliest[event.TZ]) {
+			earliest[event.TZ] = event.Date
+		}
+		if event.Date.After(latest[event.TZ]) {
+			latest[event.TZ] = event.Date
+		}
+	}
+
+	for _, tz := range zones {
+		location, err := models.LoadLocation(tz)
+		if err != nil {
+			continue
+		}
+		ruleYear := max(now.Year(), latest[tz].Year()) + 1
+		writeTimezone(writer, tz, location, earliest[tz].Year(), ruleYear)
+	}
+}
+
+// writeTimezone - записывает VTIMEZONE пояса location с переходами с начала года fromYear.
+// Переходы года ruleYear записываются правилом RRULE, если в следующем году они повторяются по тому же правилу
+func writeTimezone(writer *lineWriter, tz string, location *time.Location, fromYear, ruleYear int) {
+	start := time.Date(fromYear, time.January, 1, 0, 0, 0, 0, location)
+	all := transitions(location, start, time.Date(ruleYear+2, time.January, 1, 0, 0, 0, 0, location))
+
+	var listed, current, next []transition
+	for _, tr := range all {
+		switch localYear(tr) {
+		case ruleYear:
+			current = append(current, tr)
+		case ruleYear + 1:
+			next = append(next, tr)
+		default:
+			listed = append(listed, tr)
+		}
+	}
+	rules := yearlyRules(current, next)
+	if rules == nil {
+		listed = append(listed, current...)
+		listed = append(listed, next...)
+	}
+
+	writer.line("BEGIN:VTIMEZONE")
+	writer.line("TZID:" + tz)
+	// Смещение, действующее в начале периода, чтобы у каждого события было известно его смещение
+	name, offset := start.In(location).Zone()
+	writeObservance(writer, transition{at: start, fromOffset: offset, toOffset: offset, name: name, dst: start.In(location).IsDST()}, "")
+	for _, tr := range listed {
+		writeObservance(writer, tr, "")
+	}
+	for i, rule := range rules {
+		writeObservance(writer, current[i], rule)
+	}
+	writer.line("END:VTIMEZONE")
+}
+
+// writeObservance - записывает компонент STANDARD или DAYLIGHT для перехода tr, с rule - повторяющийся ежегодно
+func writeObservance(writer *lineWriter, tr transition, rule string) {
+	component := "STANDARD"
+	if tr.dst {
+		component = "DAYLIGHT"
+	}
+	writer.line("BEGIN:" + component)
+	// Начало действия записывается в местном времени до перехода
+	writer.line("DTSTART:" + localStart(tr).Format(dateTimeLayout))
+	if rule != "" {
+		writer.line("RRULE:" + rule)
+	}
+	writer.line("TZOFFSETFROM:" + formatOffset(tr.fromOffset))
+	writer.line("TZOFFSETTO:" + formatOffset(tr.toOffset))
+	writer.line("TZNAME:" + escapeText(tr.name))
+	writer.line("END:" + component)
+}
+
+// transitions - возвращает переходы пояса location в периоде [start, end) с точностью до секунды
+func transitions(location *time.Location, start, end time.Time) []transition {
+	result := make([]transition, 0)
+	_, offset := start.In(location).Zone()
+	for from := start; from.Before(end); from = from.Add(transitionStep) {
+		to := from.Add(transitionStep)
+		if _, toOffset := to.In(location).Zone(); toOffset == offset {
+			continue
+		}
+		// Ищем первую секунду с новым смещением
+		low, high := from.Unix(), to.Unix()
+		for high-low > 1 {
+			middle := low + (high-low)/2
+			if _, middleOffset := time.Unix(middle, 0).In(location).Zone(); middleOffset == offset {
+				low = middle
+			} else {
+				high = middle
+			}
+		}
+		at := time.Unix(high, 0).In(location)
+		name, toOffset := at.Zone()
+		result = append(result, transition{at: at, fromOffset: offset, toOffset: toOffset, name: name, dst: at.IsDST()})
+		offset = toOffset
+	}
+	return result
+}
+
+// yearlyRules - возвращает правила RRULE для переходов current, если в следующем году переходы next
+// происходят по тем же правилам. Иначе возвращает nil
+func yearlyRules(current, next []transition) []string {
+	if len(current) == 0 || len(current) != len(next) {
+		return nil
+	}
+	rules := make([]string, 0, len(current))
+	for i := range current {
+		rule := yearlyRule(current[i])
+		same := current[i].fromOffset == next[i].fromOffset && current[i].toOffset == next[i].toOffset &&
+			localStart(current[i]).Format("150405") == localStart(next[i]).Format("150405")
+		if !same || rule != yearlyRule(next[i]) {
+			return nil
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// yearlyRule - описывает день перехода как n-й или последний день недели месяца, например BYDAY=-1SU
+func yearlyRule(tr transition) string {
+	local := localStart(tr)
+	n := strconv.Itoa((local.Day()-1)/7 + 1)
+	if daysInMonth := time.Date(local.Year(), local.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day(); local.Day()+7 > daysInMonth {
+		n = "-1"
+	}
+	return fmt.Sprintf("FREQ=YEARLY;BYMONTH=%d;BYDAY=%s%s", int(local.Month()), n, weekdays[local.Weekday()])
+}
+
+// localStart - возвращает момент перехода в местном времени до перехода
+func localStart(tr transition) time.Time {
+	return tr.at.In(time.FixedZone("", tr.fromOffset))
+}
+
+// localYear - возвращает год перехода в местном времени
+func localYear(tr transition) int {
+	return localStart(tr).Year()
+}
+
+// formatOffset - форматирует смещение от UTC как +HHMM или +HHMMSS
+func formatOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	formatted := fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset/60%60)
+	if offset%60 != 0 {
+		formatted += fmt.Sprintf("%02d", offset%60)
+	}
+	return formatted
+}
@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+)
+
+// RequestIDKey - имя поля с идентификатором запроса в записях журнала и JSON-ответах
+const RequestIDKey = "request_id"
+
+// New - создает логгер, который пишет записи в формате JSON и добавляет к ним идентификатор запроса из контекста.
+// С *slog.LevelVar уровень журнала можно менять без пересоздания логгера
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(&contextHandler{Handler: slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})})
+}
+
+// requestIDKey - ключ контекста для идентификатора запроса
+type requestIDKey struct{}
+
+// WithRequestID - возвращает контекст с идентификатором запроса
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID - возвращает идентификатор запроса из контекста
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// NewRequestID - генерирует случайный идентификатор запроса
+func NewRequestID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// contextHandler - slog.Handler, добавляющий к записи идентификатор запроса из контекста
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle - добавляет request_id, если он есть в контексте, и передает запись дальше
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestID(ctx); requestID != "" {
+		record.AddAttrs(slog.String(RequestIDKey, requestID))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs - возвращает обработчик с дополнительными полями
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup - возвращает обработчик с группой полей
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
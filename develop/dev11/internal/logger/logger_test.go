@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestLoggerRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{
+			name: "With Request ID",
+			ctx:  WithRequestID(context.Background(), "abc"),
+			want: "abc",
+		},
+		{
+			name: "Without Request ID",
+			ctx:  context.Background(),
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			New(&buf, slog.LevelInfo).With("user_id", 5).InfoContext(tt.ctx, "test")
+
+			var record map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("record is not JSON: %v %s", err, buf.String())
+			}
+			if record["msg"] != "test" || record["user_id"] != float64(5) {
+				t.Errorf("record: got %v", record)
+			}
+			got, _ := record[RequestIDKey].(string)
+			if got != tt.want {
+				t.Errorf("request_id: got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRequestID(t *testing.T) {
+	first, second := NewRequestID(), NewRequestID()
+	if len(first) != 32 || first == second {
+		t.Errorf("NewRequestID() got %q and %q", first, second)
+	}
+}
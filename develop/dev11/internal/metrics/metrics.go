@@ -0,0 +1,283 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType - тип содержимого текстового формата Prometheus
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets - границы корзин гистограммы длительности запросов в секундах
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// collector - метрика, которую умеет выводить Registry
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry - набор метрик, выводимых в текстовом формате Prometheus
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry - конструктор Registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// NewCounter - регистрирует счетчик с метками labels
+func (registry *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	counter := &Counter{vec: newVec(name, help, labels)}
+	registry.register(counter)
+	return counter
+}
+
+// NewGauge - регистрирует измеритель с метками labels
+func (registry *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	gauge := &Gauge{vec: newVec(name, help, labels)}
+	registry.register(gauge)
+	return gauge
+}
+
+// NewGaugeFunc - регистрирует измеритель, значение которого вычисляется при каждом выводе
+func (registry *Registry) NewGaugeFunc(name, help string, value func() float64) {
+	registry.register(&gaugeFunc{name: name, help: help, value: value})
+}
+
+// NewHistogram - регистрирует гистограмму с границами корзин buckets и метками labels
+func (registry *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	histogram := &Histogram{vec: newVec(name, help, labels), buckets: buckets}
+	registry.register(histogram)
+	return histogram
+}
+
+// Write - выводит все метрики в текстовом формате Prometheus
+func (registry *Registry) Write(w io.Writer) error {
+	registry.mu.Lock()
+	collectors := append([]collector(nil), registry.collectors...)
+	registry.mu.Unlock()
+
+	writer := bufio.NewWriter(w)
+	for _, collector := range collectors {
+		collector.write(writer)
+	}
+	return writer.Flush()
+}
+
+// register - добавляет метрику в Registry
+func (registry *Registry) register(collector collector) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.collectors = append(registry.collectors, collector)
+}
+
+// vec - семейство рядов одной метрики, различающихся значениями меток
+type vec struct {
+	mu     sync.Mutex
+	name   string
+	help   string
+	labels []string
+	series map[string]*series
+}
+
+// series - один ряд метрики
+type series struct {
+	labelValues []string
+	value       float64  // Значение счетчика или измерителя, сумма наблюдений гистограммы
+	count       uint64   // Число наблюдений гистограммы
+	buckets     []uint64 // Число наблюдений в каждой корзине гистограммы (не накопительно)
+}
+
+// newVec - конструктор vec
+func newVec(name, help string, labels []string) *vec {
+	return &vec{name: name, help: help, labels: labels, series: make(map[string]*series)}
+}
+
+// get - возвращает ряд для значений меток, создавая его при необходимости. Вызывается под mu
+func (v *vec) get(labelValues []string) *series {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		v.series[key] = s
+	}
+	return s
+}
+
+// sorted - возвращает ряды, упорядоченные по значениям меток. Вызывается под mu
+func (v *vec) sorted() []*series {
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]*series, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, v.series[key])
+	}
+	return result
+}
+
+// header - выводит строки HELP и TYPE
+func (v *vec) header(w *bufio.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, escapeHelp(v.help), v.name, kind)
+}
+
+// Counter - монотонно растущий счетчик
+type Counter struct {
+	*vec
+}
+
+// Inc - увеличивает счетчик на 1
+func (counter *Counter) Inc(labelValues ...string) {
+	counter.Add(1, labelValues...)
+}
+
+// Add - увеличивает счетчик на value
+func (counter *Counter) Add(value float64, labelValues ...string) {
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+	counter.get(labelValues).value += value
+}
+
+func (counter *Counter) write(w *bufio.Writer) {
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+
+	counter.header(w, "counter")
+	for _, s := range counter.sorted() {
+		fmt.Fprintf(w, "%s%s %s\n", counter.name, formatLabels(counter.labels, s.labelValues, "", ""), formatValue(s.value))
+	}
+}
+
+// Gauge - измеритель, который может расти и уменьшаться
+type Gauge struct {
+	*vec
+}
+
+// Set - устанавливает значение измерителя
+func (gauge *Gauge) Set(value float64, labelValues ...string) {
+	gauge.mu.Lock()
+	defer gauge.mu.Unlock()
+	gauge.get(labelValues).value = value
+}
+
+// Add - изменяет значение измерителя на value
+func (gauge *Gauge) Add(value float64, labelValues ...string) {
+	gauge.mu.Lock()
+	defer gauge.mu.Unlock()
+	gauge.get(labelValues).value += value
+}
+
+func (gauge *Gauge) write(w *bufio.Writer) {
+	gauge.mu.Lock()
+	defer gauge.mu.Unlock()
+
+	gauge.header(w, "gauge")
+	for _, s := range gauge.sorted() {
+		fmt.Fprintf(w, "%s%s %s\n", gauge.name, formatLabels(gauge.labels, s.labelValues, "", ""), formatValue(s.value))
+	}
+}
+
+// gaugeFunc - измеритель без меток, вычисляемый при выводе
+type gaugeFunc struct {
+	name  string
+	help  string
+	value func() float64
+}
+
+func (gauge *gaugeFunc) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", gauge.name, escapeHelp(gauge.help), gauge.name, gauge.name, formatValue(gauge.value()))
+}
+
+// Histogram - гистограмма наблюдений
+type Histogram struct {
+	*vec
+	buckets []float64
+}
+
+// Observe - добавляет наблюдение value
+func (histogram *Histogram) Observe(value float64, labelValues ...string) {
+	histogram.mu.Lock()
+	defer histogram.mu.Unlock()
+
+	s := histogram.get(labelValues)
+	if s.buckets == nil {
+		s.buckets = make([]uint64, len(histogram.buckets))
+	}
+	s.value += value
+	s.count++
+	for i, bound := range histogram.buckets {
+		if value <= bound {
+			s.buckets[i]++
+			break
+		}
+	}
+}
+
+func (histogram *Histogram) write(w *bufio.Writer) {
+	histogram.mu.Lock()
+	defer histogram.mu.Unlock()
+
+	histogram.header(w, "histogram")
+	for _, s := range histogram.sorted() {
+		// В формате Prometheus корзины накопительные
+		var cumulative uint64
+		for i, bound := range histogram.buckets {
+			cumulative += s.buckets[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", histogram.name, formatLabels(histogram.labels, s.labelValues, "le", formatValue(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", histogram.name, formatLabels(histogram.labels, s.labelValues, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", histogram.name, formatLabels(histogram.labels, s.labelValues, "", ""), formatValue(s.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", histogram.name, formatLabels(histogram.labels, s.labelValues, "", ""), s.count)
+	}
+}
+
+// formatLabels - выводит метки в виде {name="value",...}, extraName добавляется последней (le для корзин)
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	parts := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		parts = append(parts, name+`="`+escapeLabel(values[i])+`"`)
+	}
+	if extraName != "" {
+		parts = append(parts, extraName+`="`+extraValue+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// formatValue - выводит число так, как его ожидает Prometheus
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// escapeLabel - экранирует значение метки
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// escapeHelp - экранирует текст HELP
+func escapeHelp(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(value)
+}
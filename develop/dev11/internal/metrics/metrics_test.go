@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.NewCounter("requests_total", "Total requests.", "route", "status")
+	duration := registry.NewHistogram("duration_seconds", "Latency.", []float64{0.1, 1}, "route")
+	inFlight := registry.NewGauge("in_flight", "In flight.")
+	registry.NewGaugeFunc("events", "Events.", func() float64 { return 3 })
+
+	requests.Inc("/b", "200")
+	requests.Inc("/a", "200")
+	requests.Add(2, "/a", "200")
+	requests.Inc(`/"quoted"`, "500")
+	duration.Observe(0.05, "/a")
+	duration.Observe(0.5, "/a")
+	duration.Observe(5, "/a")
+	inFlight.Add(1)
+	inFlight.Add(1)
+	inFlight.Add(-1)
+
+	var buf bytes.Buffer
+	if err := registry.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{route="/\"quoted\"",status="500"} 1
+requests_total{route="/a",status="200"} 3
+requests_total{route="/b",status="200"} 1
+# HELP duration_seconds Latency.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{route="/a",le="0.1"} 1
+duration_seconds_bucket{route="/a",le="1"} 2
+duration_seconds_bucket{route="/a",le="+Inf"} 3
+duration_seconds_sum{route="/a"} 5.55
+duration_seconds_count{route="/a"} 3
+# HELP in_flight In flight.
+# TYPE in_flight gauge
+in_flight 1
+# HELP events Events.
+# TYPE events gauge
+events 3
+`
+	if buf.String() != want {
+		t.Errorf("Registry.Write() got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"develop/dev11/internal/errors"
+	"fmt"
+	"slices"
+)
+
+// MaxAttendees - максимальное число участников события
+const MaxAttendees = 100
+
+// Ответы участника на приглашение
+const (
+	RSVPNeedsAction = "needs-action" // Участник еще не ответил
+	RSVPAccepted    = "accepted"     // Участник придет
+	RSVPDeclined    = "declined"     // Участник отказался, событие не показывается в его календаре
+	RSVPTentative   = "tentative"    // Участник, возможно, придет
+)
+
+// Attendee - приглашенный на событие пользователь и его ответ
+type Attendee struct {
+	UserID int    `json:"user_id"` // ID приглашенного пользователя
+	Status string `json:"status"`  // Ответ на приглашение: needs-action, accepted, declined или tentative
+}
+
+// ValidRSVP - проверяет, что status - допустимый ответ на приглашение
+func ValidRSVP(status string) bool {
+	switch status {
+	case RSVPNeedsAction, RSVPAccepted, RSVPDeclined, RSVPTentative:
+		return true
+	}
+	return false
+}
+
+// Attendee - возвращает участника события с ID userID
+func (event *Event) Attendee(userID int) (Attendee, bool) {
+	for _, attendee := range event.Attendees {
+		if attendee.UserID == userID {
+			return attendee, true
+		}
+	}
+	return Attendee{}, false
+}
+
+// InvitedTo - проверяет, приглашен ли пользователь на событие и не отказался ли от него
+func (event *Event) InvitedTo(userID int) bool {
+	attendee, ok := event.Attendee(userID)
+	return ok && attendee.Status != RSVPDeclined
+}
+
+// KeepResponses - переносит ответы участников из previous: ответ дает только сам участник,
+// поэтому владелец, изменяя список участников, не может изменить их ответы. Новые участники еще не ответили
+func (event *Event) KeepResponses(previous *Event) {
+	for i := range event.Attendees {
+		event.Attendees[i].Status = RSVPNeedsAction
+		if previous == nil {
+			continue
+		}
+		if attendee, ok := previous.Attendee(event.Attendees[i].UserID); ok {
+			event.Attendees[i].Status = attendee.Status
+		}
+	}
+}
+
+// validateAttendees выполняет валидацию участников события
+func validateAttendees(ownerID int, attendees []Attendee) error {
+	if len(attendees) > MaxAttendees {
+		return errors.NewBadRequestError(fmt.Sprintf("too many attendees, maximum %d", MaxAttendees))
+	}
+	seen := make([]int, 0, len(attendees))
+	for _, attendee := range attendees {
+		if attendee.UserID < 0 {
+			return errors.NewBadRequestError("attendee user_id must be positive")
+		}
+		if attendee.UserID == ownerID {
+			return errors.NewBadRequestError("event owner cannot be an attendee")
+		}
+		if slices.Contains(seen, attendee.UserID) {
+			return errors.NewBadRequestError(fmt.Sprintf("duplicate attendee user_id %d", attendee.UserID))
+		}
+		if !ValidRSVP(attendee.Status) {
+			return errors.NewBadRequestError("invalid attendee status: must be needs-action, accepted, declined or tentative")
+		}
+		seen = append(seen, attendee.UserID)
+	}
+	return nil
+}
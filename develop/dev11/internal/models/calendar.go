@@ -0,0 +1,63 @@
+package models
+
+import (
+	"develop/dev11/internal/errors"
+	"regexp"
+)
+
+// DefaultCalendarColor - цвет календаря, если он не задан
+const DefaultCalendarColor = "#4285f4"
+
+// Видимость календаря в выборках событий
+const (
+	CalendarVisible = "visible" // События календаря показываются в выборках по умолчанию
+	CalendarHidden  = "hidden"  // События календаря показываются, только если календарь указан в фильтре
+)
+
+// calendarColor - формат цвета календаря #RRGGBB
+var calendarColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Calendar - именованный календарь пользователя (например, work, personal, on-call), в котором лежат его события.
+// События без календаря (CalendarID 0) относятся к календарю пользователя по умолчанию
+type Calendar struct {
+	UserID     int    `json:"user_id"`    // ID пользователя, владельца календаря
+	ID         int    `json:"id"`         // Уникальный идентификатор календаря, начиная с 1
+	Name       string `json:"name"`       // Название календаря
+	Color      string `json:"color"`      // Цвет календаря в формате #RRGGBB
+	Visibility string `json:"visibility"` // Видимость календаря: visible или hidden
+}
+
+// NewCalendar - конструктор для Calendar с цветом по умолчанию, видимый в выборках
+func NewCalendar(userID, id int, name string) *Calendar {
+	return &Calendar{
+		UserID:     userID,
+		ID:         id,
+		Name:       name,
+		Color:      DefaultCalendarColor,
+		Visibility: CalendarVisible,
+	}
+}
+
+// Validate выполняет валидацию полей календаря
+func (calendar *Calendar) Validate() error {
+	if calendar.UserID < 0 {
+		return errors.NewBadRequestError("user_id must be positive")
+	}
+
+	if calendar.ID < 0 {
+		return errors.NewBadRequestError("id must be positive")
+	}
+
+	if err := validateText("name", calendar.Name, 20, true); err != nil {
+		return err
+	}
+
+	if !calendarColor.MatchString(calendar.Color) {
+		return errors.NewBadRequestError("invalid color: correct format #RRGGBB")
+	}
+
+	if calendar.Visibility != CalendarVisible && calendar.Visibility != CalendarHidden {
+		return errors.NewBadRequestError("invalid visibility: must be visible or hidden")
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"develop/dev11/internal/errors"
+	"testing"
+	"time"
+)
+
+func TestCalendarValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		calendar *Calendar
+		wantErr  error
+	}{
+		{
+			name:     "OK",
+			calendar: NewCalendar(5, 1, "work"),
+			wantErr:  nil,
+		},
+		{
+			name:     "UserID must be positive",
+			calendar: NewCalendar(-5, 1, "work"),
+			wantErr:  errors.NewBadRequestError("user_id must be positive"),
+		},
+		{
+			name:     "Empty Name",
+			calendar: NewCalendar(5, 1, ""),
+			wantErr:  errors.NewBadRequestError("empty parameter: name"),
+		},
+		{
+			name:     "Long Name",
+			calendar: NewCalendar(5, 1, "very long calendar name"),
+			wantErr:  errors.NewBadRequestError("name parameter is too long, maximum length 20 symbols"),
+		},
+		{
+			name:     "Invalid Color",
+			calendar: &Calendar{UserID: 5, ID: 1, Name: "work", Color: "red", Visibility: CalendarVisible},
+			wantErr:  errors.NewBadRequestError("invalid color: correct format #RRGGBB"),
+		},
+		{
+			name:     "Invalid Visibility",
+			calendar: &Calendar{UserID: 5, ID: 1, Name: "work", Color: "#00ff00", Visibility: "private"},
+			wantErr:  errors.NewBadRequestError("invalid visibility: must be visible or hidden"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.calendar.Validate()
+			if (err == nil) != (tt.wantErr == nil) || err != nil && err.Error() != tt.wantErr.Error() {
+				t.Errorf("Calendar.Validate() error=%v\nwantErr=%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFilterCalendars(t *testing.T) {
+	date := time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC)
+	inDefault := NewEvent(5, 0, date, "Test", "Test")
+	inWork := NewEvent(5, 1, date, "Test", "Test")
+	inWork.CalendarID = 1
+	inOnCall := NewEvent(5, 2, date, "Test", "Test")
+	inOnCall.CalendarID = 2
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []bool
+	}{
+		{name: "No Calendars", filter: Filter{}, want: []bool{true, true, true}},
+		{name: "Hidden Calendar", filter: Filter{Hidden: []int{2}}, want: []bool{true, true, false}},
+		{name: "Selected Calendars", filter: Filter{Calendars: []int{0, 1}}, want: []bool{true, true, false}},
+		{name: "Selected Hidden Calendar", filter: Filter{Calendars: []int{2}, Hidden: []int{2}}, want: []bool{false, false, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, event := range []*Event{inDefault, inWork, inOnCall} {
+				if got := tt.filter.Match(event); got != tt.want[i] {
+					t.Errorf("Filter.Match(calendar %d) got %v want %v", event.CalendarID, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Операции, которые записываются в журнал изменений
+const (
+	ChangeCreate  = "create"  // Событие создано
+	ChangeUpdate  = "update"  // Событие изменено
+	ChangeDelete  = "delete"  // Событие перемещено в корзину
+	ChangeRestore = "restore" // Событие восстановлено из корзины
+	ChangeRespond = "respond" // Участник ответил на приглашение
+)
+
+// Change - запись журнала изменений событий
+type Change struct {
+	Seq     int64     `json:"seq"`              // Порядковый номер изменения, по нему читается лента изменений
+	Op      string    `json:"op"`               // Операция: create, update, delete, restore или respond
+	UserID  int       `json:"user_id"`          // Пользователь, в календаре которого изменено событие: владелец или участник
+	EventID int       `json:"event_id"`         // ID измененного события
+	Version int       `json:"version"`          // Версия события после изменения
+	At      time.Time `json:"at"`               // Время изменения
+	Before  *Event    `json:"before,omitempty"` // Событие до изменения, nil для create
+	After   *Event    `json:"after"`            // Событие после изменения (для delete - событие в корзине)
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"develop/dev11/internal/errors"
 	"fmt"
+	"slices"
 	"time"
 	"unicode/utf8"
 )
@@ -12,9 +13,25 @@ import (
 type Event struct {
 	UserID      int       `json:"user_id"`     // ID пользователя, владельца события
 	ID          int       `json:"id"`          // Уникальный идентификатор события
+	Version     int       `json:"version"`     // Версия события, увеличивается при каждом изменении
 	Date        time.Time `json:"date"`        // Дата и время проведения события
 	Title       string    `json:"title"`       // Заголовок события
 	Description string    `json:"description"` // Описание события
+
+	CalendarID int         `json:"calendar_id,omitempty"` // ID календаря владельца, 0 - календарь по умолчанию
+	TZ         string      `json:"tz,omitempty"`          // Часовой пояс события (IANA), в нем разворачиваются повторения
+	End        *time.Time  `json:"end,omitempty"`         // Время окончания события, nil - событие без длительности
+	Recurrence *Recurrence `json:"recurrence,omitempty"`  // Правило повторения события
+	Reminders  []Reminder  `json:"reminders,omitempty"`   // Напоминания до начала события
+	Attendees  []Attendee  `json:"attendees,omitempty"`   // Приглашенные пользователи и их ответы
+	DeletedAt  *time.Time  `json:"deleted_at,omitempty"`  // Время перемещения в корзину, nil - событие не удалено
+	UID        string      `json:"uid,omitempty"`         // UID события, импортированного из iCalendar, по нему повторный импорт обновляет событие
+}
+
+// Interval - промежуток времени [Start, End)
+type Interval struct {
+	Start time.Time `json:"start"` // Начало промежутка
+	End   time.Time `json:"end"`   // Конец промежутка
 }
 
 // NewEvent - конструктор для Event
@@ -28,8 +45,19 @@ func NewEvent(userID, id int, date time.Time, title, description string) *Event
 	}
 }
 
-// Validate выполняет валидацию полей события
+// Validate выполняет валидацию полей события. Дата нового события не может быть в прошлом
 func (event *Event) Validate() error {
+	return event.validate(true)
+}
+
+// ValidateChange выполняет валидацию полей измененного события current. Дата проверяется на прошлое,
+// только если начало события изменено: прошедшее событие или серию можно изменить, не перенося их
+func (event *Event) ValidateChange(current *Event) error {
+	return event.validate(!event.Date.Equal(current.Date))
+}
+
+// validate выполняет валидацию полей события, с checkPast дата не может быть в прошлом
+func (event *Event) validate(checkPast bool) error {
 	if event.UserID < 0 {
 		return errors.NewBadRequestError("user_id must be positive")
 	}
@@ -38,10 +66,22 @@ func (event *Event) Validate() error {
 		return errors.NewBadRequestError("id must be positive")
 	}
 
-	if time.Now().After(event.Date) {
+	if event.CalendarID < 0 {
+		return errors.NewBadRequestError("calendar_id must be positive")
+	}
+
+	if checkPast && time.Now().After(event.Date) {
 		return errors.NewBadRequestError("event date cannot be in the past")
 	}
 
+	if _, err := LoadLocation(event.TZ); err != nil {
+		return err
+	}
+
+	if event.End != nil && !event.End.After(event.Date) {
+		return errors.NewBadRequestError("event end must be after event date")
+	}
+
 	if err := validateText("title", event.Title, 20, true); err != nil {
 		return err
 	}
@@ -49,9 +89,130 @@ func (event *Event) Validate() error {
 	if err := validateText("description", event.Description, 50, false); err != nil {
 		return err
 	}
+
+	if err := validateReminders(event.Reminders); err != nil {
+		return err
+	}
+
+	if err := validateAttendees(event.UserID, event.Attendees); err != nil {
+		return err
+	}
+
+	if event.Recurrence != nil {
+		return event.Recurrence.Validate()
+	}
 	return nil
 }
 
+// Location возвращает часовой пояс события (UTC, если пояс не задан или неизвестен)
+func (event *Event) Location() *time.Location {
+	location, err := LoadLocation(event.TZ)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// In возвращает копию события, время которого представлено в часовом поясе location
+func (event *Event) In(location *time.Location) *Event {
+	converted := *event
+	converted.Date = event.Date.In(location)
+	if event.End != nil {
+		end := event.End.In(location)
+		converted.End = &end
+	}
+	return &converted
+}
+
+// Clone возвращает полную копию события, изменение которой не затрагивает исходное событие
+func (event *Event) Clone() *Event {
+	clone := *event
+	if event.End != nil {
+		end := *event.End
+		clone.End = &end
+	}
+	if event.Recurrence != nil {
+		recurrence := *event.Recurrence
+		recurrence.ByDay = slices.Clone(event.Recurrence.ByDay)
+		recurrence.ExDates = slices.Clone(event.Recurrence.ExDates)
+		if event.Recurrence.Until != nil {
+			until := *event.Recurrence.Until
+			recurrence.Until = &until
+		}
+		clone.Recurrence = &recurrence
+	}
+	clone.Reminders = slices.Clone(event.Reminders)
+	clone.Attendees = slices.Clone(event.Attendees)
+	if event.DeletedAt != nil {
+		deletedAt := *event.DeletedAt
+		clone.DeletedAt = &deletedAt
+	}
+	return &clone
+}
+
+// Deleted проверяет, находится ли событие в корзине
+func (event *Event) Deleted() bool {
+	return event.DeletedAt != nil
+}
+
+// EndTime возвращает время окончания события (для события без длительности - время начала)
+func (event *Event) EndTime() time.Time {
+	if event.End == nil {
+		return event.Date
+	}
+	return *event.End
+}
+
+// Duration возвращает длительность события
+func (event *Event) Duration() time.Duration {
+	return event.EndTime().Sub(event.Date)
+}
+
+// Overlaps проверяет, пересекается ли событие с промежутком [fromDate, toDate).
+// Событие без длительности пересекается с промежутком, если начинается внутри него
+func (event *Event) Overlaps(fromDate, toDate time.Time) bool {
+	return event.Date.Before(toDate) && (event.EndTime().After(fromDate) || !event.Date.Before(fromDate))
+}
+
+// Conflicts проверяет, пересекаются ли два события по времени.
+// События без длительности конфликтуют, если начинаются одновременно
+func (event *Event) Conflicts(other *Event) bool {
+	if event.Date.Equal(other.Date) {
+		return true
+	}
+	return event.Date.Before(other.EndTime()) && other.Date.Before(event.EndTime())
+}
+
+// Occurrences возвращает экземпляры события, пересекающиеся с периодом [fromDate, toDate).
+// Для повторяющегося события каждый экземпляр - копия события с датой повторения
+func (event *Event) Occurrences(fromDate, toDate time.Time) []*Event {
+	if event.Recurrence == nil {
+		if event.Overlaps(fromDate, toDate) {
+			return []*Event{event}
+		}
+		return nil
+	}
+
+	// Повторения разворачиваются в часовом поясе события: 09:00 остается 09:00 и после перехода на летнее время
+	start := event.Date.In(event.Location())
+	// Повторение, начавшееся до периода, может еще продолжаться в его начале
+	duration := event.Duration()
+	dates := event.Recurrence.Occurrences(start, fromDate.Add(-duration), toDate)
+	occurrences := make([]*Event, 0, len(dates))
+	for _, date := range dates {
+		occurrence := *event
+		occurrence.Date = date
+		if event.End != nil {
+			end := date.Add(duration)
+			occurrence.End = &end
+		}
+		if occurrence.Overlaps(fromDate, toDate) {
+			occurrences = append(occurrences, &occurrence)
+		}
+	}
+	return occurrences
+}
+
 // validateText выполняет валидацию текстовых полей 
 func validateText(parameter, text string, textLength int, noEmpty bool) error {
 	if noEmpty && text == "" {
@@ -15,17 +15,17 @@ func TestEventValidate(t *testing.T) {
 	}{
 		{
 			name:    "OK",
-			event:   NewEvent(5, 0, time.Date(2026, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test"),
+			event:   NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test"),
 			wantErr: nil,
 		},
 		{
 			name:    "UserID must be positive",
-			event:   NewEvent(-5, 0, time.Date(2026, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test"),
+			event:   NewEvent(-5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test"),
 			wantErr: errors.NewBadRequestError("user_id must be positive"),
 		},
 		{
 			name:    "EventID must be positive",
-			event:   NewEvent(5, -5, time.Date(2026, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test"),
+			event:   NewEvent(5, -5, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test"),
 			wantErr: errors.NewBadRequestError("id must be positive"),
 		},
 		{
@@ -33,21 +33,76 @@ func TestEventValidate(t *testing.T) {
 			event:   NewEvent(5, 5, time.Date(2007, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test"),
 			wantErr: errors.NewBadRequestError("event date cannot be in the past"),
 		},
+		{
+			name: "End Before Date",
+			event: func() *Event {
+				event := NewEvent(5, 5, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")
+				end := event.Date.Add(-time.Hour)
+				event.End = &end
+				return event
+			}(),
+			wantErr: errors.NewBadRequestError("event end must be after event date"),
+		},
+		{
+			name: "Invalid Time Zone",
+			event: func() *Event {
+				event := NewEvent(5, 5, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")
+				event.TZ = "Mars/Olympus"
+				return event
+			}(),
+			wantErr: errors.NewBadRequestError("invalid tz: unknown time zone Mars/Olympus"),
+		},
 		{
 			name:    "Empty Title",
-			event:   NewEvent(5, 5, time.Date(2026, 5, 12, 15, 04, 04, 0, time.UTC), "", "Test"),
+			event:   NewEvent(5, 5, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "", "Test"),
 			wantErr: errors.NewBadRequestError("empty parameter: title"),
 		},
 		{
 			name:    "Title Too Long",
-			event:   NewEvent(5, 5, time.Date(2026, 5, 12, 15, 04, 04, 0, time.UTC), string(make([]rune, 21)), "Test"),
+			event:   NewEvent(5, 5, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), string(make([]rune, 21)), "Test"),
 			wantErr: errors.NewBadRequestError("title parameter is too long, maximum length 20 symbols"),
 		},
 		{
 			name:    "Description Too Long",
-			event:   NewEvent(5, 5, time.Date(2026, 5, 12, 15, 04, 04, 0, time.UTC), "Test", string(make([]rune, 51))),
+			event:   NewEvent(5, 5, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", string(make([]rune, 51))),
 			wantErr: errors.NewBadRequestError("description parameter is too long, maximum length 50 symbols"),
 		},
+		{
+			name: "OK Attendees",
+			event: func() *Event {
+				event := NewEvent(5, 5, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")
+				event.Attendees = []Attendee{{UserID: 6, Status: RSVPNeedsAction}, {UserID: 7, Status: RSVPDeclined}}
+				return event
+			}(),
+			wantErr: nil,
+		},
+		{
+			name: "Owner Attendee",
+			event: func() *Event {
+				event := NewEvent(5, 5, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")
+				event.Attendees = []Attendee{{UserID: 5, Status: RSVPNeedsAction}}
+				return event
+			}(),
+			wantErr: errors.NewBadRequestError("event owner cannot be an attendee"),
+		},
+		{
+			name: "Duplicate Attendee",
+			event: func() *Event {
+				event := NewEvent(5, 5, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")
+				event.Attendees = []Attendee{{UserID: 6, Status: RSVPNeedsAction}, {UserID: 6, Status: RSVPAccepted}}
+				return event
+			}(),
+			wantErr: errors.NewBadRequestError("duplicate attendee user_id 6"),
+		},
+		{
+			name: "Invalid Attendee Status",
+			event: func() *Event {
+				event := NewEvent(5, 5, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")
+				event.Attendees = []Attendee{{UserID: 6, Status: "maybe"}}
+				return event
+			}(),
+			wantErr: errors.NewBadRequestError("invalid attendee status: must be needs-action, accepted, declined or tentative"),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -59,3 +114,81 @@ func TestEventValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestEventConflicts(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2036, 5, 12, hour, minute, 0, 0, time.UTC)
+	}
+	event := func(start, end time.Time) *Event {
+		event := NewEvent(5, 0, start, "Test", "")
+		if !end.IsZero() {
+			event.End = &end
+		}
+		return event
+	}
+
+	tests := []struct {
+		name  string
+		a, b  *Event
+		wantA bool
+	}{
+		{name: "Overlap", a: event(at(10, 0), at(11, 0)), b: event(at(10, 30), at(11, 30)), wantA: true},
+		{name: "Inside", a: event(at(10, 0), at(12, 0)), b: event(at(10, 30), at(11, 0)), wantA: true},
+		{name: "Adjacent", a: event(at(10, 0), at(11, 0)), b: event(at(11, 0), at(12, 0)), wantA: false},
+		{name: "Point Inside", a: event(at(10, 0), at(11, 0)), b: event(at(10, 30), time.Time{}), wantA: true},
+		{name: "Same Point", a: event(at(10, 0), time.Time{}), b: event(at(10, 0), time.Time{}), wantA: true},
+		{name: "Different Points", a: event(at(10, 0), time.Time{}), b: event(at(10, 1), time.Time{}), wantA: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Conflicts(tt.b); got != tt.wantA {
+				t.Errorf("Event.Conflicts() got %v want %v", got, tt.wantA)
+			}
+			if got := tt.b.Conflicts(tt.a); got != tt.wantA {
+				t.Errorf("Event.Conflicts() reversed got %v want %v", got, tt.wantA)
+			}
+		})
+	}
+}
+
+func TestEventOccurrencesTimeZone(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	// Еженедельное событие в 09:00 по Берлину до и после перехода на летнее время 29 марта 2036
+	event := NewEvent(5, 0, time.Date(2036, 3, 24, 8, 0, 0, 0, time.UTC), "Test", "")
+	event.TZ = "Europe/Berlin"
+	event.Recurrence = &Recurrence{Freq: FreqWeekly, Count: 2}
+
+	occurrences := event.Occurrences(time.Date(2036, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2036, 5, 1, 0, 0, 0, 0, time.UTC))
+	if len(occurrences) != 2 {
+		t.Fatalf("Event.Occurrences() got %d want 2", len(occurrences))
+	}
+	for _, occurrence := range occurrences {
+		if local := occurrence.Date.In(location); local.Hour() != 9 {
+			t.Errorf("Event.Occurrences() got %v want 09:00 local time", local)
+		}
+	}
+	if want := time.Date(2036, 3, 31, 7, 0, 0, 0, time.UTC); !occurrences[1].Date.Equal(want) {
+		t.Errorf("Event.Occurrences() got %v want %v", occurrences[1].Date, want)
+	}
+}
+
+func TestEventKeepResponses(t *testing.T) {
+	previous := NewEvent(5, 0, time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC), "Test", "Test")
+	previous.Attendees = []Attendee{{UserID: 6, Status: RSVPAccepted}, {UserID: 7, Status: RSVPDeclined}}
+
+	// Владелец пытается изменить ответ участника 6, убирает участника 7 и приглашает участника 8
+	event := previous.Clone()
+	event.Attendees = []Attendee{{UserID: 6, Status: RSVPDeclined}, {UserID: 8, Status: RSVPAccepted}}
+	event.KeepResponses(previous)
+
+	want := []Attendee{{UserID: 6, Status: RSVPAccepted}, {UserID: 8, Status: RSVPNeedsAction}}
+	if len(event.Attendees) != len(want) || event.Attendees[0] != want[0] || event.Attendees[1] != want[1] {
+		t.Errorf("Event.KeepResponses() got %v want %v", event.Attendees, want)
+	}
+	if !event.InvitedTo(6) || event.InvitedTo(7) || previous.InvitedTo(7) {
+		t.Errorf("Event.InvitedTo() got wrong invitations for %v and %v", event.Attendees, previous.Attendees)
+	}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Filter - фильтр событий по тексту и календарям. Пустые поля не ограничивают выборку, текст сравнивается без учета регистра
+type Filter struct {
+	Title       string // Подстрока заголовка
+	Description string // Подстрока описания
+	Text        string // Подстрока заголовка или описания
+	Calendars   []int  // Календари событий (0 - календарь по умолчанию)
+	Hidden      []int  // Скрытые календари пользователя, события которых не показываются, если Calendars пуст
+}
+
+// Match проверяет, подходит ли событие пользователя под фильтр
+func (filter Filter) Match(event *Event) bool {
+	return filter.match(event, event.CalendarID, slices.Contains(filter.Hidden, event.CalendarID))
+}
+
+// MatchInvited проверяет, подходит ли под фильтр событие другого пользователя, на которое пользователь приглашен.
+// Календарь владельца не относится к календарям пользователя, поэтому приглашение считается событием календаря по умолчанию,
+// а скрыто оно, если владелец скрыл свой календарь события (ownerHidden)
+func (filter Filter) MatchInvited(event *Event, ownerHidden bool) bool {
+	return filter.match(event, 0, ownerHidden)
+}
+
+// match проверяет, подходит ли под фильтр событие, отнесенное к календарю calendarID.
+// Событие скрытого календаря (hidden) подходит, только если календари указаны в фильтре
+func (filter Filter) match(event *Event, calendarID int, hidden bool) bool {
+	if len(filter.Calendars) > 0 && !slices.Contains(filter.Calendars, calendarID) {
+		return false
+	}
+	if len(filter.Calendars) == 0 && hidden {
+		return false
+	}
+	if !containsFold(event.Title, filter.Title) {
+		return false
+	}
+	if !containsFold(event.Description, filter.Description) {
+		return false
+	}
+	return containsFold(event.Title, filter.Text) || containsFold(event.Description, filter.Text)
+}
+
+// SortEvents - сортирует события по дате начала, затем по id
+func SortEvents(events []*Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return Less(events[i], events[j])
+	})
+}
+
+// Less - порядок событий в выдаче: по дате начала, затем по id
+func Less(a, b *Event) bool {
+	if !a.Date.Equal(b.Date) {
+		return a.Date.Before(b.Date)
+	}
+	return a.ID < b.ID
+}
+
+// containsFold - проверяет вхождение подстроки без учета регистра
+func containsFold(text, substring string) bool {
+	return substring == "" || strings.Contains(strings.ToLower(text), strings.ToLower(substring))
+}
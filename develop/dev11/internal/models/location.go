@@ -0,0 +1,27 @@
+package models
+
+import (
+	"develop/dev11/internal/errors"
+	"sync"
+	"time"
+)
+
+// locations - кэш загруженных часовых поясов, чтобы не читать базу tzdata на каждый запрос
+var locations sync.Map
+
+// LoadLocation - возвращает часовой пояс по имени IANA (Europe/Moscow). Пустое имя - UTC
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if location, ok := locations.Load(name); ok {
+		return location.(*time.Location), nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.NewBadRequestError("invalid tz: unknown time zone " + name)
+	}
+	locations.Store(name, location)
+	return location, nil
+}
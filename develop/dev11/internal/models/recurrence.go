@@ -0,0 +1,325 @@
+package models
+
+import (
+	"develop/dev11/internal/errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Частота повторения события (FREQ из RFC 5545)
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+// untilLayout - формат UNTIL в строке правила
+const untilLayout = "20060102T150405Z"
+
+// maxPeriods - ограничение на число перебираемых периодов, чтобы правило не могло зациклить запрос
+const maxPeriods = 100000
+
+// weekdays - соответствие кодов дней недели из RFC 5545 и time.Weekday
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Recurrence - правило повторения события (подмножество RRULE из RFC 5545)
+type Recurrence struct {
+	Freq     string      `json:"freq"`               // Частота: DAILY, WEEKLY, MONTHLY или YEARLY
+	Interval int         `json:"interval,omitempty"` // Интервал между периодами, по умолчанию 1
+	ByDay    []string    `json:"by_day,omitempty"`   // Дни недели: MO, TU... Для MONTHLY допускается номер: 1MO, -1FR
+	Count    int         `json:"count,omitempty"`    // Количество повторений
+	Until    *time.Time  `json:"until,omitempty"`    // Дата, после которой повторений нет
+	ExDates  []time.Time `json:"exdates,omitempty"`  // Исключенные повторения
+}
+
+// byDay - разобранный элемент BYDAY
+type byDay struct {
+	ordinal int          // Номер дня недели в месяце (0 - все такие дни)
+	weekday time.Weekday // День недели
+}
+
+// ParseRecurrence - разбирает правило в формате RRULE ("FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10").
+// UNTIL без времени считается концом дня в часовом поясе события location (nil - UTC)
+func ParseRecurrence(rule string, exDates []time.Time, location *time.Location) (*Recurrence, error) {
+	recurrence := &Recurrence{ExDates: exDates}
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, errors.NewBadRequestError("invalid rrule: " + part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			recurrence.Freq = strings.ToUpper(value)
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, errors.NewBadRequestError("invalid rrule INTERVAL: use only numbers")
+			}
+			recurrence.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, errors.NewBadRequestError("invalid rrule COUNT: use only numbers")
+			}
+			recurrence.Count = count
+		case "UNTIL":
+			until, err := parseUntil(value, location)
+			if err != nil {
+				return nil, err
+			}
+			recurrence.Until = &until
+		case "BYDAY":
+			recurrence.ByDay = strings.Split(strings.ToUpper(value), ",")
+		default:
+			return nil, errors.NewBadRequestError("unsupported rrule part: " + key)
+		}
+	}
+
+	if err := recurrence.Validate(); err != nil {
+		return nil, err
+	}
+	return recurrence, nil
+}
+
+// parseUntil - разбирает UNTIL в форматах 20060102T150405Z и 20060102. Дата без времени включает весь день
+// в часовом поясе location (nil - UTC), поэтому последнее повторение определяется по местной дате события
+func parseUntil(value string, location *time.Location) (time.Time, error) {
+	if until, err := time.Parse(untilLayout, value); err == nil {
+		return until, nil
+	}
+	if location == nil {
+		location = time.UTC
+	}
+	if day, err := time.ParseInLocation("20060102", value, location); err == nil {
+		// День может длиться 23 или 25 часов, поэтому конец дня считаем от начала следующего
+		return day.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, errors.NewBadRequestError("invalid rrule UNTIL: correct format 20060102T150405Z")
+}
+
+// String - возвращает правило в формате RRULE (без EXDATE)
+func (recurrence *Recurrence) String() string {
+	parts := []string{"FREQ=" + recurrence.Freq}
+	if recurrence.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(recurrence.Interval))
+	}
+	if len(recurrence.ByDay) > 0 {
+		parts = append(parts, "BYDAY="+strings.Join(recurrence.ByDay, ","))
+	}
+	if recurrence.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(recurrence.Count))
+	}
+	if recurrence.Until != nil {
+		parts = append(parts, "UNTIL="+recurrence.Until.UTC().Format(untilLayout))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Validate выполняет валидацию правила повторения
+func (recurrence *Recurrence) Validate() error {
+	switch recurrence.Freq {
+	case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+	case "":
+		return errors.NewBadRequestError("empty rrule FREQ")
+	default:
+		return errors.NewBadRequestError("invalid rrule FREQ: must be DAILY, WEEKLY, MONTHLY or YEARLY")
+	}
+
+	if recurrence.Interval < 0 {
+		return errors.NewBadRequestError("rrule INTERVAL must be positive")
+	}
+	if recurrence.Count < 0 {
+		return errors.NewBadRequestError("rrule COUNT must be positive")
+	}
+	if recurrence.Count > 0 && recurrence.Until != nil {
+		return errors.NewBadRequestError("rrule COUNT and UNTIL cannot be used together")
+	}
+
+	if len(recurrence.ByDay) > 0 && recurrence.Freq == FreqYearly {
+		return errors.NewBadRequestError("rrule BYDAY is not supported for YEARLY")
+	}
+	days, err := recurrence.parseByDay()
+	if err != nil {
+		return err
+	}
+	for _, day := range days {
+		if day.ordinal != 0 && recurrence.Freq != FreqMonthly {
+			return errors.NewBadRequestError("rrule BYDAY with number is supported only for MONTHLY")
+		}
+	}
+	return nil
+}
+
+// parseByDay - разбирает элементы BYDAY
+func (recurrence *Recurrence) parseByDay() ([]byDay, error) {
+	days := make([]byDay, 0, len(recurrence.ByDay))
+	for _, value := range recurrence.ByDay {
+		if len(value) < 2 {
+			return nil, errors.NewBadRequestError("invalid rrule BYDAY: " + value)
+		}
+		weekday, ok := weekdays[value[len(value)-2:]]
+		if !ok {
+			return nil, errors.NewBadRequestError("invalid rrule BYDAY: " + value)
+		}
+		ordinal := 0
+		if prefix := value[:len(value)-2]; prefix != "" {
+			number, err := strconv.Atoi(prefix)
+			if err != nil || number == 0 || number < -5 || number > 5 {
+				return nil, errors.NewBadRequestError("invalid rrule BYDAY: " + value)
+			}
+			ordinal = number
+		}
+		days = append(days, byDay{ordinal: ordinal, weekday: weekday})
+	}
+	return days, nil
+}
+
+// Occurrences - возвращает повторения события с началом start, попадающие в период [fromDate, toDate)
+func (recurrence *Recurrence) Occurrences(start, fromDate, toDate time.Time) []time.Time {
+	days, err := recurrence.parseByDay()
+	if err != nil {
+		return nil
+	}
+	interval := max(recurrence.Interval, 1)
+
+	occurrences := make([]time.Time, 0)
+	count := 0
+	for period := 0; period < maxPeriods; period++ {
+		for _, candidate := range recurrence.candidates(start, period*interval, days) {
+			// Повторения раньше начала события не считаются
+			if candidate.Before(start) {
+				continue
+			}
+			if recurrence.Until != nil && candidate.After(*recurrence.Until) {
+				return occurrences
+			}
+			// Исключенные даты учитываются в COUNT (RFC 5545)
+			count++
+			if recurrence.Count > 0 && count > recurrence.Count {
+				return occurrences
+			}
+			if !candidate.Before(toDate) {
+				return occurrences
+			}
+			if !candidate.Before(fromDate) && !recurrence.excluded(candidate) {
+				occurrences = append(occurrences, candidate)
+			}
+		}
+	}
+	return occurrences
+}
+
+// candidates - возвращает упорядоченные кандидаты в повторения для периода с номером offset
+func (recurrence *Recurrence) candidates(start time.Time, offset int, days []byDay) []time.Time {
+	year, month, day := start.Date()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+	}
+
+	switch recurrence.Freq {
+	case FreqDaily:
+		candidate := at(year, month, day+offset)
+		if len(days) > 0 && !containsWeekday(days, candidate.Weekday()) {
+			return nil
+		}
+		return []time.Time{candidate}
+
+	case FreqWeekly:
+		if len(days) == 0 {
+			return []time.Time{at(year, month, day+7*offset)}
+		}
+		// Неделя начинается с понедельника (WKST=MO по умолчанию)
+		monday := day - (int(start.Weekday())+6)%7 + 7*offset
+		candidates := make([]time.Time, 0, len(days))
+		for _, d := range days {
+			candidates = append(candidates, at(year, month, monday+(int(d.weekday)+6)%7))
+		}
+		sortTimes(candidates)
+		return candidates
+
+	case FreqMonthly:
+		if len(days) == 0 {
+			candidate := at(year, month+time.Month(offset), day)
+			// Месяцы без такого числа пропускаются (например, 31-е число)
+			if candidate.Day() != day {
+				return nil
+			}
+			return []time.Time{candidate}
+		}
+		first := at(year, month+time.Month(offset), 1)
+		candidates := make([]time.Time, 0)
+		for _, d := range days {
+			candidates = append(candidates, weekdaysInMonth(first, d)...)
+		}
+		sortTimes(candidates)
+		return candidates
+
+	case FreqYearly:
+		candidate := at(year+offset, month, day)
+		// Годы без такой даты пропускаются (например, 29 февраля)
+		if candidate.Day() != day {
+			return nil
+		}
+		return []time.Time{candidate}
+	}
+	return nil
+}
+
+// excluded - проверяет, исключено ли повторение
+func (recurrence *Recurrence) excluded(date time.Time) bool {
+	for _, exDate := range recurrence.ExDates {
+		if exDate.Equal(date) {
+			return true
+		}
+	}
+	return false
+}
+
+// weekdaysInMonth - возвращает дни месяца first, подходящие под элемент BYDAY
+func weekdaysInMonth(first time.Time, day byDay) []time.Time {
+	matches := make([]time.Time, 0, 5)
+	for date := first; date.Month() == first.Month(); date = date.AddDate(0, 0, 1) {
+		if date.Weekday() == day.weekday {
+			matches = append(matches, date)
+		}
+	}
+
+	switch {
+	case day.ordinal == 0:
+		return matches
+	case day.ordinal > 0 && day.ordinal <= len(matches):
+		return matches[day.ordinal-1 : day.ordinal]
+	case day.ordinal < 0 && -day.ordinal <= len(matches):
+		index := len(matches) + day.ordinal
+		return matches[index : index+1]
+	}
+	return nil
+}
+
+// containsWeekday - проверяет наличие дня недели в BYDAY
+func containsWeekday(days []byDay, weekday time.Weekday) bool {
+	for _, day := range days {
+		if day.weekday == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// sortTimes - сортирует даты по возрастанию
+func sortTimes(times []time.Time) {
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+}
@@ -0,0 +1,208 @@
+package models
+
+import (
+	"develop/dev11/internal/errors"
+	"testing"
+	"time"
+)
+
+func TestRecurrenceOccurrences(t *testing.T) {
+	// Понедельник
+	start := time.Date(2036, 5, 12, 10, 0, 0, 0, time.UTC)
+	day := func(month time.Month, day int) time.Time {
+		return time.Date(2036, month, day, 10, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		rule     string
+		exDates  []time.Time
+		fromDate time.Time
+		toDate   time.Time
+		want     []time.Time
+	}{
+		{
+			name:     "Daily",
+			rule:     "FREQ=DAILY",
+			fromDate: day(5, 13),
+			toDate:   day(5, 16),
+			want:     []time.Time{day(5, 13), day(5, 14), day(5, 15)},
+		},
+		{
+			name:     "Daily Interval Count",
+			rule:     "FREQ=DAILY;INTERVAL=2;COUNT=3",
+			fromDate: day(5, 1),
+			toDate:   day(6, 1),
+			want:     []time.Time{day(5, 12), day(5, 14), day(5, 16)},
+		},
+		{
+			name:     "Weekly By Day",
+			rule:     "FREQ=WEEKLY;BYDAY=MO,WE",
+			fromDate: day(5, 12),
+			toDate:   day(5, 26),
+			want:     []time.Time{day(5, 12), day(5, 14), day(5, 19), day(5, 21)},
+		},
+		{
+			name:     "Weekly Until",
+			rule:     "FREQ=WEEKLY;UNTIL=20360526T100000Z",
+			fromDate: day(5, 1),
+			toDate:   day(7, 1),
+			want:     []time.Time{day(5, 12), day(5, 19), day(5, 26)},
+		},
+		{
+			name:     "Weekly ExDate",
+			rule:     "FREQ=WEEKLY;COUNT=3",
+			exDates:  []time.Time{day(5, 19)},
+			fromDate: day(5, 1),
+			toDate:   day(7, 1),
+			want:     []time.Time{day(5, 12), day(5, 26)},
+		},
+		{
+			name:     "Monthly Skips Short Months",
+			rule:     "FREQ=MONTHLY;COUNT=3",
+			fromDate: time.Date(2036, 1, 1, 0, 0, 0, 0, time.UTC),
+			toDate:   time.Date(2037, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2036, 5, 12, 10, 0, 0, 0, time.UTC),
+				time.Date(2036, 6, 12, 10, 0, 0, 0, time.UTC),
+				time.Date(2036, 7, 12, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:     "Monthly Last Friday",
+			rule:     "FREQ=MONTHLY;BYDAY=-1FR;COUNT=2",
+			fromDate: day(5, 1),
+			toDate:   day(12, 1),
+			want:     []time.Time{day(5, 30), day(6, 27)},
+		},
+		{
+			name:     "Yearly",
+			rule:     "FREQ=YEARLY;INTERVAL=2",
+			fromDate: day(1, 1),
+			toDate:   time.Date(2041, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: []time.Time{
+				time.Date(2036, 5, 12, 10, 0, 0, 0, time.UTC),
+				time.Date(2038, 5, 12, 10, 0, 0, 0, time.UTC),
+				time.Date(2040, 5, 12, 10, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recurrence, err := ParseRecurrence(tt.rule, tt.exDates, time.UTC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := recurrence.Occurrences(start, tt.fromDate, tt.toDate)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Recurrence.Occurrences() got %v\nwant %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("Recurrence.Occurrences()[%d] got %v want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRecurrenceUntilDate(t *testing.T) {
+	tests := []struct {
+		name string
+		tz   string
+		hour int
+		want int
+	}{
+		{
+			// 01:00 по Москве - 22:00 UTC предыдущего дня: повторение 15 мая не входит в UNTIL=20360514
+			name: "East Of UTC",
+			tz:   "Europe/Moscow",
+			hour: 1,
+			want: 3,
+		},
+		{
+			// 21:00 в Нью-Йорке - 01:00 UTC следующего дня: повторение 14 мая входит в UNTIL=20360514
+			name: "West Of UTC",
+			tz:   "America/New_York",
+			hour: 21,
+			want: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location, err := LoadLocation(tt.tz)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := time.Date(2036, 5, 12, tt.hour, 0, 0, 0, location)
+			recurrence, err := ParseRecurrence("FREQ=DAILY;UNTIL=20360514", nil, location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := recurrence.Occurrences(start, start.AddDate(0, 0, -1), start.AddDate(0, 0, 7))
+			if len(got) != tt.want {
+				t.Fatalf("Recurrence.Occurrences() got %v want %d occurrences", got, tt.want)
+			}
+			if last := got[len(got)-1].In(location); last.Day() != 14 {
+				t.Errorf("last occurrence: got %v want May 14 in %s", last, tt.tz)
+			}
+		})
+	}
+}
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "OK",
+			rule: "RRULE:FREQ=weekly;INTERVAL=2;BYDAY=mo,fr;COUNT=10",
+			want: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;COUNT=10",
+		},
+		{
+			name:    "Empty Freq",
+			rule:    "INTERVAL=2",
+			wantErr: errors.NewBadRequestError("empty rrule FREQ"),
+		},
+		{
+			name:    "Invalid Freq",
+			rule:    "FREQ=HOURLY",
+			wantErr: errors.NewBadRequestError("invalid rrule FREQ: must be DAILY, WEEKLY, MONTHLY or YEARLY"),
+		},
+		{
+			name:    "Count And Until",
+			rule:    "FREQ=DAILY;COUNT=2;UNTIL=20360526T100000Z",
+			wantErr: errors.NewBadRequestError("rrule COUNT and UNTIL cannot be used together"),
+		},
+		{
+			name:    "Invalid By Day",
+			rule:    "FREQ=WEEKLY;BYDAY=XX",
+			wantErr: errors.NewBadRequestError("invalid rrule BYDAY: XX"),
+		},
+		{
+			name:    "Weekly By Day With Number",
+			rule:    "FREQ=WEEKLY;BYDAY=1MO",
+			wantErr: errors.NewBadRequestError("rrule BYDAY with number is supported only for MONTHLY"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recurrence, err := ParseRecurrence(tt.rule, nil, nil)
+			if err != nil {
+				if tt.wantErr == nil || err.Error() != tt.wantErr.Error() {
+					t.Errorf("ParseRecurrence() error=%v\nwantErr=%v", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != nil {
+				t.Fatalf("ParseRecurrence() error=nil\nwantErr=%v", tt.wantErr)
+			}
+			if recurrence.String() != tt.want {
+				t.Errorf("Recurrence.String() got %s want %s", recurrence.String(), tt.want)
+			}
+		})
+	}
+}
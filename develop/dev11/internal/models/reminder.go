@@ -0,0 +1,109 @@
+package models
+
+import (
+	"develop/dev11/internal/errors"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Ограничения напоминаний события
+const (
+	MaxReminders = 5                   // Максимальное число напоминаний у события
+	MaxReminder  = 30 * 24 * time.Hour // Максимальное время напоминания до начала события
+)
+
+// Reminder - напоминание о событии: за сколько до начала оно срабатывает.
+// В JSON записывается строкой в формате time.Duration ("15m0s")
+type Reminder time.Duration
+
+// ParseReminder - разбирает напоминание в формате 15m, 1h30m
+func ParseReminder(value string) (Reminder, error) {
+	before, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.NewBadRequestError("invalid remind format: correct format 15m,1h")
+	}
+	return Reminder(before), nil
+}
+
+// String - возвращает напоминание в формате time.Duration
+func (reminder Reminder) String() string {
+	return time.Duration(reminder).String()
+}
+
+// MarshalJSON - записывает напоминание строкой
+func (reminder Reminder) MarshalJSON() ([]byte, error) {
+	return json.Marshal(reminder.String())
+}
+
+// UnmarshalJSON - читает напоминание из строки
+func (reminder *Reminder) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*reminder = Reminder(parsed)
+	return nil
+}
+
+// validateReminders выполняет валидацию напоминаний события
+func validateReminders(reminders []Reminder) error {
+	if len(reminders) > MaxReminders {
+		return errors.NewBadRequestError(fmt.Sprintf("too many reminders, maximum %d", MaxReminders))
+	}
+	for _, reminder := range reminders {
+		if reminder <= 0 || time.Duration(reminder) > MaxReminder {
+			return errors.NewBadRequestError("reminder must be between 0 and 720h")
+		}
+	}
+	return nil
+}
+
+// Notification - сработавшее напоминание об экземпляре события
+type Notification struct {
+	UserID      int       `json:"user_id"`     // ID пользователя, владельца события
+	EventID     int       `json:"event_id"`    // ID события
+	Title       string    `json:"title"`       // Заголовок события
+	Description string    `json:"description"` // Описание события
+	Date        time.Time `json:"date"`        // Начало экземпляра события
+	Before      Reminder  `json:"before"`      // За сколько до начала срабатывает напоминание
+	FireAt      time.Time `json:"fire_at"`     // Время срабатывания напоминания
+}
+
+// Key - возвращает ключ напоминания, одинаковый при повторном вычислении.
+// Для повторяющегося события экземпляры различаются датой
+func (notification Notification) Key() string {
+	return fmt.Sprintf("%d-%d-%d-%d", notification.UserID, notification.EventID, notification.Date.Unix(), time.Duration(notification.Before)/time.Second)
+}
+
+// Notifications - возвращает напоминания экземпляров события, срабатывающие в периоде [fromDate, toDate)
+func (event *Event) Notifications(fromDate, toDate time.Time) []Notification {
+	if len(event.Reminders) == 0 {
+		return nil
+	}
+
+	notifications := make([]Notification, 0)
+	// Напоминание срабатывает до начала, поэтому экземпляры ищем с запасом MaxReminder после периода
+	for _, occurrence := range event.Occurrences(fromDate, toDate.Add(MaxReminder)) {
+		for _, reminder := range occurrence.Reminders {
+			fireAt := occurrence.Date.Add(-time.Duration(reminder))
+			if fireAt.Before(fromDate) || !fireAt.Before(toDate) {
+				continue
+			}
+			notifications = append(notifications, Notification{
+				UserID:      occurrence.UserID,
+				EventID:     occurrence.ID,
+				Title:       occurrence.Title,
+				Description: occurrence.Description,
+				Date:        occurrence.Date,
+				Before:      reminder,
+				FireAt:      fireAt,
+			})
+		}
+	}
+	return notifications
+}
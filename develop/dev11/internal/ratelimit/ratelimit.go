@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepInterval - как часто удаляются корзины ключей, которые давно не присылали запросов
+const sweepInterval = time.Minute
+
+// Limiter - ограничитель частоты запросов по алгоритму token bucket с отдельной корзиной на каждый ключ
+type Limiter struct {
+	rate  float64 // rate - сколько токенов в секунду добавляется в корзину
+	burst float64 // burst - емкость корзины, т.е. сколько запросов можно сделать подряд
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket // buckets - корзины по ключам
+	lastSweep time.Time          // lastSweep - время последней очистки корзин
+	now       func() time.Time   // now - источник времени, подменяется в тестах
+}
+
+// bucket - корзина токенов одного ключа
+type bucket struct {
+	tokens  float64   // tokens - число токенов на момент updated
+	updated time.Time // updated - время последнего пересчета токенов
+}
+
+// New - конструктор Limiter. Допускается в среднем rate запросов в секунду и до burst запросов подряд.
+// Нулевой rate снимает ограничение
+func New(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(max(burst, 1)),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// SetLimit - меняет ограничение без сброса корзин: накопленные токены сохраняются, но не больше нового burst.
+// Нулевой rate снимает ограничение
+func (l *Limiter) SetLimit(rate float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Пересчитываем токены по старой скорости, чтобы новая применялась только к будущему времени
+	now := l.now()
+	for _, b := range l.buckets {
+		b.tokens = l.refill(b, now)
+		b.updated = now
+	}
+	l.rate = rate
+	l.burst = float64(max(burst, 1))
+	for _, b := range l.buckets {
+		b.tokens = min(b.tokens, l.burst)
+	}
+}
+
+// Allow - расходует токен ключа key. Если токенов нет, то возвращает false и время до появления токена
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate <= 0 {
+		return true, 0
+	}
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.updated = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// refill - возвращает число токенов корзины на момент now
+func (l *Limiter) refill(b *bucket, now time.Time) float64 {
+	return min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+}
+
+// sweep - удаляет полные корзины, чтобы память не росла с числом клиентов.
+// Полная корзина ничем не отличается от новой, поэтому ее удаление не меняет поведения
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	start := time.Date(2036, 5, 12, 10, 0, 0, 0, time.UTC)
+
+	type step struct {
+		at         time.Duration // время запроса от start
+		key        string
+		want       bool
+		retryAfter time.Duration
+	}
+	tests := []struct {
+		name  string
+		rate  float64
+		burst int
+		steps []step
+	}{
+		{
+			name:  "Burst Then Limited",
+			rate:  1,
+			burst: 2,
+			steps: []step{
+				{at: 0, key: "a", want: true},
+				{at: 0, key: "a", want: true},
+				{at: 0, key: "a", want: false, retryAfter: time.Second},
+				{at: 500 * time.Millisecond, key: "a", want: false, retryAfter: 500 * time.Millisecond},
+				{at: time.Second, key: "a", want: true},
+			},
+		},
+		{
+			name:  "Keys Are Independent",
+			rate:  1,
+			burst: 1,
+			steps: []step{
+				{at: 0, key: "a", want: true},
+				{at: 0, key: "a", want: false, retryAfter: time.Second},
+				{at: 0, key: "b", want: true},
+			},
+		},
+		{
+			name:  "Refill Is Capped By Burst",
+			rate:  10,
+			burst: 2,
+			steps: []step{
+				{at: 0, key: "a", want: true},
+				{at: time.Hour, key: "a", want: true},
+				{at: time.Hour, key: "a", want: true},
+				{at: time.Hour, key: "a", want: false, retryAfter: 100 * time.Millisecond},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := New(tt.rate, tt.burst)
+			var now time.Time
+			limiter.now = func() time.Time { return now }
+
+			for i, step := range tt.steps {
+				now = start.Add(step.at)
+				got, retryAfter := limiter.Allow(step.key)
+				if got != step.want || retryAfter != step.retryAfter {
+					t.Errorf("step %d: Allow(%s) got %v, %v want %v, %v", i, step.key, got, retryAfter, step.want, step.retryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiterSweep(t *testing.T) {
+	start := time.Date(2036, 5, 12, 10, 0, 0, 0, time.UTC)
+	now := start
+	limiter := New(1, 1)
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("a")
+	now = start.Add(2 * sweepInterval)
+	limiter.Allow("b")
+	if _, ok := limiter.buckets["a"]; ok || len(limiter.buckets) != 1 {
+		t.Errorf("buckets after sweep: got %v want only b", limiter.buckets)
+	}
+}
+
+func TestLimiterSetLimit(t *testing.T) {
+	start := time.Date(2036, 5, 12, 10, 0, 0, 0, time.UTC)
+	now := start
+	limiter := New(1, 3)
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("a")
+	if ok, _ := limiter.Allow("a"); !ok {
+		t.Fatalf("second request within burst: got false want true")
+	}
+
+	// Нулевая скорость снимает ограничение
+	limiter.SetLimit(0, 1)
+	for i := 0; i < 10; i++ {
+		if ok, _ := limiter.Allow("a"); !ok {
+			t.Fatalf("unlimited request %d: got false want true", i)
+		}
+	}
+
+	// Накопленные токены не превышают новый burst
+	limiter.SetLimit(2, 1)
+	if ok, _ := limiter.Allow("a"); !ok {
+		t.Errorf("first request after SetLimit: got false want true")
+	}
+	ok, retryAfter := limiter.Allow("a")
+	if ok || retryAfter != 500*time.Millisecond {
+		t.Errorf("second request after SetLimit: got %v, %v want false, 500ms", ok, retryAfter)
+	}
+}
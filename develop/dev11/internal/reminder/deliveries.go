@@ -0,0 +1,175 @@
+package reminder
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// delivery - запись о доставке напоминания получателю
+type delivery struct {
+	Key    string    `json:"key"`     // Получатель и ключ напоминания
+	FireAt time.Time `json:"fire_at"` // Время срабатывания напоминания, по нему устаревшие записи удаляются
+}
+
+// Deliveries - учет доставленных напоминаний.
+// Доставка записывается в файл после успешной отправки, поэтому после перезапуска напоминание
+// не отправляется повторно, а неудачная или прерванная отправка повторяется (at-least-once)
+type Deliveries struct {
+	mu        sync.Mutex           // mu - мьютекс для безопасного доступа к данным
+	path      string               // path - путь к журналу доставок
+	file      *os.File             // file - журнал доставок, nil - учет только в памяти
+	delivered map[string]time.Time // delivered - доставленные ключи и время их срабатывания
+}
+
+// NewDeliveries - конструктор Deliveries без сохранения на диск
+func NewDeliveries() *Deliveries {
+	return &Deliveries{delivered: make(map[string]time.Time)}
+}
+
+// OpenDeliveries - загружает журнал доставок из файла path, отбрасывая записи старше keepAfter.
+// Журнал переписывается без устаревших записей и открывается на дозапись
+func OpenDeliveries(path string, keepAfter time.Time) (*Deliveries, error) {
+	deliveries := NewDeliveries()
+	if err := deliveries.load(path, keepAfter); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	deliveries.path = path
+	if err := deliveries.rewrite(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// Delivered - проверяет, доставлено ли напоминание
+func (deliveries *Deliveries) Delivered(key string) bool {
+	deliveries.mu.Lock()
+	defer deliveries.mu.Unlock()
+
+	_, ok := deliveries.delivered[key]
+	return ok
+}
+
+// Mark - записывает доставку напоминания и сбрасывает журнал на диск
+func (deliveries *Deliveries) Mark(key string, fireAt time.Time) error {
+	deliveries.mu.Lock()
+	defer deliveries.mu.Unlock()
+
+	if deliveries.file != nil {
+		if err := write(deliveries.file, delivery{Key: key, FireAt: fireAt}); err != nil {
+			return err
+		}
+		if err := deliveries.file.Sync(); err != nil {
+			return err
+		}
+	}
+	deliveries.delivered[key] = fireAt
+	return nil
+}
+
+// Forget - удаляет записи о напоминаниях, сработавших раньше before. Если записи удалены,
+// журнал переписывается без них, чтобы он не рос, пока сервер работает
+func (deliveries *Deliveries) Forget(before time.Time) error {
+	deliveries.mu.Lock()
+	defer deliveries.mu.Unlock()
+
+	forgotten := 0
+	for key, fireAt := range deliveries.delivered {
+		if fireAt.Before(before) {
+			delete(deliveries.delivered, key)
+			forgotten++
+		}
+	}
+	if forgotten == 0 || deliveries.file == nil {
+		return nil
+	}
+	return deliveries.rewrite()
+}
+
+// Close - закрывает журнал доставок
+func (deliveries *Deliveries) Close() error {
+	deliveries.mu.Lock()
+	defer deliveries.mu.Unlock()
+
+	if deliveries.file == nil {
+		return nil
+	}
+	err := deliveries.file.Close()
+	deliveries.file = nil
+	return err
+}
+
+// load - читает журнал доставок. Оборванная последняя строка пропускается:
+// такая доставка считается неподтвержденной и будет повторена
+func (deliveries *Deliveries) load(path string, keepAfter time.Time) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record delivery
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if record.FireAt.Before(keepAfter) {
+			continue
+		}
+		deliveries.delivered[record.Key] = record.FireAt
+	}
+	return scanner.Err()
+}
+
+// rewrite - переписывает журнал через временный файл только с записями из памяти и открывает его на дозапись.
+// При ошибке продолжает работать прежний журнал. Вызывается под mu или до начала работы
+func (deliveries *Deliveries) rewrite() error {
+	tmpPath := deliveries.path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	for key, fireAt := range deliveries.delivered {
+		if err = write(file, delivery{Key: key, FireAt: fireAt}); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, deliveries.path)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if deliveries.file != nil {
+		deliveries.file.Close()
+	}
+	deliveries.file = file
+	return nil
+}
+
+// write - дописывает запись в журнал file
+func write(file *os.File, record delivery) error {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(payload, '\n'))
+	return err
+}
@@ -0,0 +1,127 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"develop/dev11/internal/models"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Notifier - получатель сработавших напоминаний
+type Notifier interface {
+	// Name возвращает имя получателя, под которым учитывается доставка
+	Name() string
+	// Notify доставляет напоминание, ошибка означает, что доставку нужно повторить
+	Notify(ctx context.Context, notification models.Notification) error
+}
+
+// LogNotifier - записывает напоминания в лог сервера
+type LogNotifier struct{}
+
+// Name - имя получателя
+func (LogNotifier) Name() string {
+	return "log"
+}
+
+// Notify - записывает напоминание в лог
+func (LogNotifier) Notify(_ context.Context, notification models.Notification) error {
+	slog.Info("reminder", "user_id", notification.UserID, "event_id", notification.EventID, "title", notification.Title,
+		"date", notification.Date.Format(time.RFC3339), "before", notification.Before.String())
+	return nil
+}
+
+// WebhookNotifier - отправляет напоминания POST-запросом с JSON-телом
+type WebhookNotifier struct {
+	url    string       // url - адрес, на который отправляются напоминания
+	client *http.Client // client - HTTP-клиент с таймаутом
+}
+
+// NewWebhookNotifier - конструктор WebhookNotifier. Перенаправления не выполняются, чтобы напоминания
+// не ушли с локального адреса на другой хост: ответ 3xx считается ошибкой
+func NewWebhookNotifier(url string, timeout time.Duration) *WebhookNotifier {
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return &WebhookNotifier{url: url, client: client}
+}
+
+// Name - имя получателя
+func (notifier *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+// Notify - отправляет напоминание, ответ не из диапазона 2xx считается ошибкой
+func (notifier *WebhookNotifier) Notify(ctx context.Context, notification models.Notification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, notifier.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	// Получатель может отбросить повторную доставку по этому ключу
+	request.Header.Set("Idempotency-Key", notification.Key())
+
+	response, err := notifier.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+	return nil
+}
+
+// FileNotifier - дописывает напоминания в файл, по одному JSON-объекту на строку
+type FileNotifier struct {
+	mu   sync.Mutex // mu - сериализует запись в файл
+	file *os.File   // file - файл, открытый на дозапись
+}
+
+// NewFileNotifier - конструктор FileNotifier
+func NewFileNotifier(path string) (*FileNotifier, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileNotifier{file: file}, nil
+}
+
+// Name - имя получателя
+func (notifier *FileNotifier) Name() string {
+	return "file"
+}
+
+// Notify - дописывает напоминание в файл и сбрасывает его на диск
+func (notifier *FileNotifier) Notify(_ context.Context, notification models.Notification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+
+	if _, err := notifier.file.Write(append(payload, '\n')); err != nil {
+		return err
+	}
+	return notifier.file.Sync()
+}
+
+// Close - закрывает файл
+func (notifier *FileNotifier) Close() error {
+	return notifier.file.Close()
+}
@@ -0,0 +1,143 @@
+package reminder
+
+import (
+	"context"
+	"develop/dev11/internal/data"
+	"develop/dev11/internal/models"
+	"develop/dev11/internal/service"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordNotifier - получатель, запоминающий доставленные напоминания
+type recordNotifier struct {
+	mu       sync.Mutex
+	fail     bool
+	received []string
+}
+
+func (notifier *recordNotifier) Name() string {
+	return "record"
+}
+
+func (notifier *recordNotifier) Notify(_ context.Context, notification models.Notification) error {
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+
+	if notifier.fail {
+		return fmt.Errorf("unavailable")
+	}
+	notifier.received = append(notifier.received, notification.Title+"@"+notification.FireAt.Format("15:04"))
+	return nil
+}
+
+func TestSchedulerDispatch(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2036, 5, day, hour, minute, 0, 0, time.UTC)
+	}
+	events := service.New(data.New())
+	event := models.NewEvent(5, 0, at(12, 10, 0), "Standup", "")
+	event.Reminders = []models.Reminder{models.Reminder(15 * time.Minute), models.Reminder(time.Hour)}
+	event.Recurrence = &models.Recurrence{Freq: models.FreqDaily}
+	events.Create(event)
+	events.Create(models.NewEvent(7, 0, at(12, 9, 50), "No Reminders", ""))
+
+	statePath := filepath.Join(t.TempDir(), "reminders.state")
+	notifier := &recordNotifier{}
+	newScheduler := func(now time.Time) *Scheduler {
+		deliveries, err := OpenDeliveries(statePath, now.Add(-time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		scheduler := New(events, deliveries, time.Minute, time.Hour, notifier)
+		scheduler.now = func() time.Time { return now }
+		return scheduler
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		fail bool
+		want []string
+	}{
+		{name: "Before Reminders", now: at(12, 8, 59), want: nil},
+		{name: "First Reminder", now: at(12, 9, 0), want: []string{"Standup@09:00"}},
+		{name: "Not Repeated", now: at(12, 9, 30), want: nil},
+		{name: "Failed Delivery", now: at(12, 9, 45), fail: true, want: nil},
+		{name: "Retried Delivery", now: at(12, 9, 50), want: []string{"Standup@09:45"}},
+		{name: "Next Occurrence", now: at(13, 9, 0), want: []string{"Standup@09:00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Каждый шаг - новый запуск сервера: доставки восстанавливаются из файла
+			scheduler := newScheduler(tt.now)
+			notifier.fail, notifier.received = tt.fail, nil
+			scheduler.dispatch()
+			if err := scheduler.Stop(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			if fmt.Sprint(notifier.received) != fmt.Sprint(tt.want) {
+				t.Errorf("delivered: got %v want %v", notifier.received, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliveriesCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reminders.state")
+	now := time.Date(2036, 5, 12, 10, 0, 0, 0, time.UTC)
+
+	deliveries, err := OpenDeliveries(path, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliveries.Mark("old", now.Add(-2*time.Hour))
+	deliveries.Mark("new", now.Add(-time.Minute))
+	deliveries.Close()
+
+	reopened, err := OpenDeliveries(path, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if reopened.Delivered("old") {
+		t.Error("old delivery must be dropped")
+	}
+	if !reopened.Delivered("new") {
+		t.Error("new delivery must survive restart")
+	}
+
+	// Forget переписывает журнал без сработавших записей, не дожидаясь перезапуска
+	if err := reopened.Forget(now); err != nil {
+		t.Fatal(err)
+	}
+	reopened.Mark("next", now.Add(time.Minute))
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(payload), `"new"`) || !strings.Contains(string(payload), `"next"`) {
+		t.Errorf("journal after forget: got %s want only next", payload)
+	}
+}
+
+func TestSchedulerStop(t *testing.T) {
+	scheduler := New(service.New(data.New()), NewDeliveries(), time.Hour, time.Hour, &recordNotifier{})
+	scheduler.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := scheduler.Stop(ctx); err != nil {
+		t.Errorf("Stop() error=%v", err)
+	}
+	// Повторная остановка не должна паниковать
+	if err := scheduler.Stop(ctx); err != nil {
+		t.Errorf("second Stop() error=%v", err)
+	}
+}
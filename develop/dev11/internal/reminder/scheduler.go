@@ -0,0 +1,193 @@
+package reminder
+
+import (
+	"context"
+	"develop/dev11/config"
+	"develop/dev11/internal/models"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// webhookTimeout - таймаут одной отправки напоминания на webhook
+const webhookTimeout = 10 * time.Second
+
+// Source - источник напоминаний
+type Source interface {
+	// DueReminders возвращает напоминания, срабатывающие в периоде [fromDate, toDate)
+	DueReminders(fromDate, toDate time.Time) ([]models.Notification, error)
+}
+
+// Scheduler - фоновый планировщик напоминаний.
+// Раз в interval отправляет получателям напоминания, сработавшие за последние catchUp,
+// и повторяет недоставленные, пока они не выйдут из этого окна
+type Scheduler struct {
+	source     Source           // source - источник напоминаний
+	deliveries *Deliveries      // deliveries - учет доставленных напоминаний
+	notifiers  []Notifier       // notifiers - получатели напоминаний
+	interval   time.Duration    // interval - период проверки напоминаний
+	catchUp    time.Duration    // catchUp - за какой период в прошлом отправляются пропущенные напоминания
+	now        func() time.Time // now - текущее время, подменяется в тестах
+
+	ctx     context.Context    // ctx - контекст отправки, отменяется при принудительной остановке
+	cancel  context.CancelFunc // cancel - отменяет ctx
+	stop    chan struct{}      // stop - сигнал остановки
+	done    chan struct{}      // done - закрывается после остановки цикла
+	started atomic.Bool        // started - цикл запущен, Stop должен дождаться его окончания
+	once    sync.Once          // once - защищает от повторной остановки
+	closers []func() error     // closers - освобождают ресурсы получателей и журнала доставок
+}
+
+// New - конструктор Scheduler
+func New(source Source, deliveries *Deliveries, interval, catchUp time.Duration, notifiers ...Notifier) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		source:     source,
+		deliveries: deliveries,
+		notifiers:  notifiers,
+		interval:   interval,
+		catchUp:    catchUp,
+		now:        time.Now,
+		ctx:        ctx,
+		cancel:     cancel,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+		closers:    []func() error{deliveries.Close},
+	}
+}
+
+// NewFromConfig - создает планировщик с получателями из конфигурации.
+// Возвращает nil, если получатели не заданы
+func NewFromConfig(cfg config.Config, source Source) (*Scheduler, error) {
+	if len(cfg.ReminderNotifiers) == 0 {
+		return nil, nil
+	}
+
+	deliveries, err := OpenDeliveries(cfg.ReminderStatePath, time.Now().Add(-cfg.ReminderCatchUp))
+	if err != nil {
+		return nil, err
+	}
+
+	notifiers := make([]Notifier, 0, len(cfg.ReminderNotifiers))
+	closers := make([]func() error, 0)
+	for _, name := range cfg.ReminderNotifiers {
+		switch name {
+		case config.NotifierLog:
+			notifiers = append(notifiers, LogNotifier{})
+		case config.NotifierWebhook:
+			notifiers = append(notifiers, NewWebhookNotifier(cfg.ReminderWebhookURL, webhookTimeout))
+		case config.NotifierFile:
+			notifier, err := NewFileNotifier(cfg.ReminderFilePath)
+			if err != nil {
+				deliveries.Close()
+				return nil, err
+			}
+			notifiers = append(notifiers, notifier)
+			closers = append(closers, notifier.Close)
+		default:
+			deliveries.Close()
+			return nil, fmt.Errorf("unknown reminder notifier %q", name)
+		}
+	}
+
+	scheduler := New(source, deliveries, cfg.ReminderInterval, cfg.ReminderCatchUp, notifiers...)
+	scheduler.closers = append(scheduler.closers, closers...)
+	return scheduler, nil
+}
+
+// Start - запускает цикл проверки напоминаний в горутине
+func (scheduler *Scheduler) Start() {
+	if scheduler.started.CompareAndSwap(false, true) {
+		go scheduler.run()
+	}
+}
+
+// Stop - останавливает планировщик, дожидаясь окончания текущей отправки, и освобождает ресурсы.
+// Если ctx завершится раньше, отправка прерывается; прерванные напоминания будут отправлены после перезапуска
+func (scheduler *Scheduler) Stop(ctx context.Context) error {
+	var err error
+	scheduler.once.Do(func() {
+		close(scheduler.stop)
+		// Цикл, который не запускали, ждать не нужно
+		if !scheduler.started.Swap(true) {
+			close(scheduler.done)
+		}
+
+		select {
+		case <-scheduler.done:
+		case <-ctx.Done():
+			scheduler.cancel()
+			<-scheduler.done
+			err = ctx.Err()
+		}
+		scheduler.cancel()
+
+		for _, closer := range scheduler.closers {
+			if closeErr := closer(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}
+	})
+	return err
+}
+
+// run - цикл проверки напоминаний до вызова Stop
+func (scheduler *Scheduler) run() {
+	defer close(scheduler.done)
+
+	ticker := time.NewTicker(scheduler.interval)
+	defer ticker.Stop()
+
+	// Сразу после запуска отправляем напоминания, пропущенные за время простоя
+	scheduler.dispatch()
+	for {
+		select {
+		case <-scheduler.stop:
+			return
+		case <-ticker.C:
+			scheduler.dispatch()
+		}
+	}
+}
+
+// dispatch - отправляет сработавшие и еще не доставленные напоминания
+func (scheduler *Scheduler) dispatch() {
+	now := scheduler.now()
+	fromDate := now.Add(-scheduler.catchUp)
+	// Напоминания, сработавшие в эту секунду, тоже отправляются
+	notifications, err := scheduler.source.DueReminders(fromDate, now.Add(time.Nanosecond))
+	if err != nil {
+		slog.Error("reminders", "error", err.Error())
+		return
+	}
+
+	for _, notification := range notifications {
+		for _, notifier := range scheduler.notifiers {
+			// Прекращаем отправку при остановке, оставшиеся напоминания будут отправлены после перезапуска
+			select {
+			case <-scheduler.stop:
+				return
+			default:
+			}
+
+			key := notifier.Name() + "/" + notification.Key()
+			if scheduler.deliveries.Delivered(key) {
+				continue
+			}
+			if err := notifier.Notify(scheduler.ctx, notification); err != nil {
+				slog.Warn("reminder", "key", notification.Key(), "notifier", notifier.Name(), "error", err.Error())
+				continue
+			}
+			if err := scheduler.deliveries.Mark(key, notification.FireAt); err != nil {
+				slog.Error("reminder mark delivered", "key", notification.Key(), "notifier", notifier.Name(), "error", err.Error())
+			}
+		}
+	}
+
+	// Напоминания вне окна больше не отправляются, учитывать их доставку не нужно
+	if err := scheduler.deliveries.Forget(fromDate); err != nil {
+		slog.Error("reminder forget deliveries", "error", err.Error())
+	}
+}
@@ -3,25 +3,116 @@ package server
 import (
 	"context"
 	"develop/dev11/config"
+	"develop/dev11/internal/certs"
+	"errors"
+	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// Server - HTTP сервер, таймауты которого можно менять без перезапуска.
+// Поля таймаутов http.Server читаются без синхронизации, поэтому текущие значения применяются к каждому
+// запросу (чтение тела и запись ответа) и к каждому соединению HTTP/1 (ожидание запроса).
+// ReadHeaderTimeout и IdleTimeout http.Server задаются значениями при запуске и остаются нижней границей:
+// соединения HTTP/2 не переходят в StateIdle, и простаивающие соединения закрывает только IdleTimeout
+// http.Server, поэтому для HTTP/2 новое время простоя действует после перезапуска
 type Server struct {
 	httpServer *http.Server
+
+	readTimeout  atomic.Int64 // readTimeout - время чтения запроса вместе с телом
+	writeTimeout atomic.Int64 // writeTimeout - время записи ответа
+	idleTimeout  atomic.Int64 // idleTimeout - время ожидания следующего запроса
+
+	mu     sync.Mutex
+	timers map[net.Conn]*time.Timer // timers - закрывают соединения, слишком долго ожидающие запроса
 }
 
-func (s *Server) Run(cfg config.Config, handler http.Handler) error {
+// New - конструктор Server на адресе из конфигурации. Если переданы сертификаты, сервер работает по HTTPS
+// с поддержкой HTTP/2, а сертификаты берутся из certificates при каждом новом соединении
+func New(cfg config.Config, handler http.Handler, certificates *certs.Reloader) *Server {
+	s := &Server{timers: make(map[net.Conn]*time.Timer)}
+	s.SetTimeouts(cfg.ReadTimeout, cfg.WriteTimeout, cfg.IdleTimeout)
 	s.httpServer = &http.Server{
-		Addr:           ":" + cfg.Port,
-		Handler:        handler,
-		MaxHeaderBytes: 1 << 20,
-		IdleTimeout:    cfg.IdleTimeout,
-		ReadTimeout:    cfg.Timeout,
-		WriteTimeout:   cfg.IdleTimeout,
+		Addr:              cfg.Addr,
+		Handler:           s.deadlines(handler),
+		ReadHeaderTimeout: time.Duration(s.readTimeout.Load()),
+		IdleTimeout:       time.Duration(s.idleTimeout.Load()),
+		MaxHeaderBytes:    1 << 20,
+		ConnState:         s.trackConn,
+	}
+	if certificates != nil {
+		s.httpServer.TLSConfig = certificates.TLSConfig()
 	}
-	return s.httpServer.ListenAndServe()
+	return s
+}
+
+// Run - запускает сервер и блокируется до его остановки. После Shutdown возвращает nil
+func (s *Server) Run() error {
+	var err error
+	if s.httpServer.TLSConfig != nil {
+		err = s.httpServer.ListenAndServeTLS("", "")
+	} else {
+		err = s.httpServer.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// SetTimeouts - меняет таймауты сервера. Новые значения применяются к следующим запросам и периодам ожидания
+func (s *Server) SetTimeouts(read, write, idle time.Duration) {
+	s.readTimeout.Store(int64(read))
+	s.writeTimeout.Store(int64(write))
+	s.idleTimeout.Store(int64(idle))
 }
 
+// Shutdown - перестает принимать соединения и дожидается завершения запросов.
+// Если ctx истекает раньше, оставшиеся соединения закрываются принудительно
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
+	err := s.httpServer.Shutdown(ctx)
+	if err != nil {
+		s.httpServer.Close()
+	}
+	return err
+}
+
+// deadlines - middleware, задающее сроки чтения тела запроса и записи ответа по текущим таймаутам.
+// Обработчик может продлить или снять срок записи, как поток изменений
+func (s *Server) deadlines(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		controller := http.NewResponseController(w)
+		// ResponseWriter без поддержки сроков (например, в тестах) обслуживается без них
+		controller.SetReadDeadline(now.Add(time.Duration(s.readTimeout.Load())))
+		controller.SetWriteDeadline(now.Add(time.Duration(s.writeTimeout.Load())))
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// trackConn - закрывает соединение, если запрос не пришел вовремя: новое соединение должно прислать
+// заголовки за время чтения, простаивающее - за время простоя. Активные соединения не ограничиваются
+func (s *Server) trackConn(conn net.Conn, state http.ConnState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if timer, ok := s.timers[conn]; ok {
+		timer.Stop()
+		delete(s.timers, conn)
+	}
+
+	var timeout time.Duration
+	switch state {
+	case http.StateNew:
+		timeout = time.Duration(s.readTimeout.Load())
+	case http.StateIdle:
+		timeout = time.Duration(s.idleTimeout.Load())
+	default:
+		return
+	}
+	s.timers[conn] = time.AfterFunc(timeout, func() {
+		conn.Close()
+	})
 }
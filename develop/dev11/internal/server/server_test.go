@@ -0,0 +1,116 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"develop/dev11/config"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// start - запускает Server на свободном локальном порту
+func start(t *testing.T, handler http.Handler) (*Server, string) {
+	t.Helper()
+	s := New(config.Config{ReadTimeout: time.Second, WriteTimeout: time.Second, IdleTimeout: time.Second}, handler, nil)
+	ts := httptest.NewUnstartedServer(nil)
+	ts.Config = s.httpServer
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return s, ts.Listener.Addr().String()
+}
+
+// request - отправляет запрос keep-alive в соединение и читает ответ
+func request(t *testing.T, conn net.Conn, reader *bufio.Reader) {
+	t.Helper()
+	if _, err := io.WriteString(conn, "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+}
+
+// closed - проверяет, закрыл ли сервер соединение в течение wait
+func closed(conn net.Conn, reader *bufio.Reader, wait time.Duration) bool {
+	conn.SetReadDeadline(time.Now().Add(wait))
+	_, err := reader.ReadByte()
+	return errors.Is(err, io.EOF)
+}
+
+func TestServerIdleTimeout(t *testing.T) {
+	s, addr := start(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name       string
+		idle       time.Duration
+		wantClosed bool
+	}{
+		{name: "Long Idle Timeout", idle: time.Minute, wantClosed: false},
+		{name: "Reloaded Short Idle Timeout", idle: 50 * time.Millisecond, wantClosed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.SetTimeouts(time.Second, time.Second, tt.idle)
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+
+			request(t, conn, reader)
+			if got := closed(conn, reader, 300*time.Millisecond); got != tt.wantClosed {
+				t.Errorf("idle connection closed: got %v want %v", got, tt.wantClosed)
+			}
+		})
+	}
+}
+
+func TestServerTimeoutFloor(t *testing.T) {
+	s := New(config.Config{ReadTimeout: 2 * time.Second, WriteTimeout: time.Second, IdleTimeout: time.Minute}, http.NotFoundHandler(), nil)
+	// Соединения HTTP/2 остаются активными, поэтому их простой ограничивают только поля http.Server
+	if s.httpServer.ReadHeaderTimeout != 2*time.Second || s.httpServer.IdleTimeout != time.Minute {
+		t.Errorf("server timeouts: got header %v idle %v want 2s 1m", s.httpServer.ReadHeaderTimeout, s.httpServer.IdleTimeout)
+	}
+}
+
+func TestServerShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	s, addr := start(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	}))
+
+	done := make(chan error, 1)
+	go func() {
+		response, err := http.Get("http://" + addr)
+		if err == nil {
+			response.Body.Close()
+		}
+		done <- err
+	}()
+	<-started
+
+	// Зависший запрос не задерживает остановку дольше срока, соединение закрывается принудительно
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("shutdown: got %v want %v", err, context.DeadlineExceeded)
+	}
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("request after forced close: got nil want error")
+		}
+	case <-time.After(time.Second):
+		t.Error("connection is not closed after shutdown timeout")
+	}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"develop/dev11/internal/models"
+	"sync"
+)
+
+// subscriberBuffer - сколько изменений может ждать отправки одному подписчику
+const subscriberBuffer = 64
+
+// broker - рассылка изменений событий подписчикам по пользователям
+type broker struct {
+	mu          sync.Mutex
+	subscribers map[int]map[chan models.Change]struct{} // subscribers - каналы подписчиков каждого пользователя
+}
+
+// newBroker - конструктор broker
+func newBroker() *broker {
+	return &broker{subscribers: make(map[int]map[chan models.Change]struct{})}
+}
+
+// subscribe - подписывает на изменения событий пользователя.
+// Возвращает канал изменений и функцию отписки, которая закрывает канал
+func (b *broker) subscribe(userID int) (<-chan models.Change, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	changes := make(chan models.Change, subscriberBuffer)
+	if _, ok := b.subscribers[userID]; !ok {
+		b.subscribers[userID] = make(map[chan models.Change]struct{})
+	}
+	b.subscribers[userID][changes] = struct{}{}
+
+	return changes, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(userID, changes)
+	}
+}
+
+// publish - рассылает изменение подписчикам пользователя без ожидания.
+// Канал подписчика, который не успевает читать, закрывается: клиент переподключится и дочитает пропущенное из журнала
+func (b *broker) publish(change models.Change) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for changes := range b.subscribers[change.UserID] {
+		select {
+		case changes <- change:
+		default:
+			b.remove(change.UserID, changes)
+		}
+	}
+}
+
+// remove - удаляет подписчика и закрывает его канал, если он еще подписан. Вызывается под mu
+func (b *broker) remove(userID int, changes chan models.Change) {
+	if _, ok := b.subscribers[userID][changes]; !ok {
+		return
+	}
+	delete(b.subscribers[userID], changes)
+	close(changes)
+	if len(b.subscribers[userID]) == 0 {
+		delete(b.subscribers, userID)
+	}
+}
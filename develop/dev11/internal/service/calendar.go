@@ -0,0 +1,41 @@
+package service
+
+import (
+	"develop/dev11/internal/data"
+	"develop/dev11/internal/models"
+)
+
+// CalendarService - структура сервиса календарей
+type CalendarService struct {
+	data data.Calendarer
+}
+
+// NewCalendarService - конструктор для CalendarService
+func NewCalendarService(data data.Calendarer) *CalendarService {
+	return &CalendarService{data: data}
+}
+
+// CreateCalendar - метод для создания нового календаря
+func (calendarService *CalendarService) CreateCalendar(createCalendar *models.Calendar) (int, error) {
+	return calendarService.data.CreateCalendar(createCalendar)
+}
+
+// UpdateCalendar - метод для изменения названия, цвета и видимости календаря
+func (calendarService *CalendarService) UpdateCalendar(updateCalendar *models.Calendar) error {
+	return calendarService.data.UpdateCalendar(updateCalendar)
+}
+
+// DeleteCalendar - метод для удаления календаря. Календарь с событиями не удаляется
+func (calendarService *CalendarService) DeleteCalendar(userID, id int) error {
+	return calendarService.data.DeleteCalendar(userID, id)
+}
+
+// Calendar - метод для получения календаря по id
+func (calendarService *CalendarService) Calendar(userID, id int) (*models.Calendar, error) {
+	return calendarService.data.Calendar(userID, id)
+}
+
+// Calendars - метод для получения календарей пользователя
+func (calendarService *CalendarService) Calendars(userID int) ([]*models.Calendar, error) {
+	return calendarService.data.Calendars(userID)
+}
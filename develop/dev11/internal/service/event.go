@@ -1,38 +1,276 @@
 package service
 
 import (
+	"develop/dev11/internal/audit"
 	"develop/dev11/internal/data"
+	"develop/dev11/internal/errors"
 	"develop/dev11/internal/models"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
 	"time"
 )
 
+// maxDate - верхняя граница периода при выборке всех событий
+var maxDate = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// conflictHorizon - на сколько вперед проверяются конфликты повторяющегося события
+const conflictHorizon = MaxRange
+
 // EventService - структура сервиса событий
 type EventService struct {
-	data data.Eventer
+	data    data.Eventer
+	changes ChangeLog  // changes - журнал изменений событий
+	broker  *broker    // broker - рассылка записанных изменений подписчикам
+	writeMu sync.Mutex // writeMu - сериализует изменения, чтобы порядок в журнале совпадал с порядком в хранилище
+}
+
+// Option - необязательная настройка EventService
+type Option func(*EventService)
+
+// WithChangeLog - задает журнал изменений событий. По умолчанию журнал хранится только в памяти
+func WithChangeLog(changes ChangeLog) Option {
+	return func(eventService *EventService) {
+		eventService.changes = changes
+	}
 }
 
 // NewEventService - конструктор для EventService
-func NewEventService(data data.Eventer) *EventService {
-	return &EventService{data: data}
+func NewEventService(data data.Eventer, options ...Option) *EventService {
+	eventService := &EventService{data: data, changes: audit.NewLog(), broker: newBroker()}
+	for _, option := range options {
+		option(eventService)
+	}
+	return eventService
 }
 
-// Create - метод для создания нового события
+// Create - метод для создания нового события. Приглашенные участники еще не ответили на приглашение
 func (eventService *EventService) Create(createEvent *models.Event) (int, error) {
-	return eventService.data.Create(createEvent)
+	eventService.writeMu.Lock()
+	defer eventService.writeMu.Unlock()
+
+	createEvent.KeepResponses(nil)
+	id, err := eventService.data.Create(createEvent)
+	if err != nil {
+		return 0, err
+	}
+	eventService.record(models.ChangeCreate, nil, createEvent)
+	return id, nil
 }
 
-// Update - метод для обновления существующего события
+// Update - метод для обновления существующего события. Изменить событие может только его владелец,
+// ответы участников при этом сохраняются.
+// Если updateEvent.Version не 0, то событие обновляется, только если его версия не изменилась
 func (eventService *EventService) Update(updateEvent *models.Event) error {
-	return eventService.data.Update(updateEvent)
+	eventService.writeMu.Lock()
+	defer eventService.writeMu.Unlock()
+
+	// Пока держим writeMu, событие не может измениться через сервис, поэтому before - именно обновляемая версия
+	before, err := eventService.owned(updateEvent.UserID, updateEvent.ID)
+	if err != nil {
+		return err
+	}
+	updateEvent.KeepResponses(before)
+	// UID импортированного события не передается при изменении, но должен сохраниться для повторного импорта
+	if updateEvent.UID == "" {
+		updateEvent.UID = before.UID
+	}
+	if err := eventService.data.Update(updateEvent); err != nil {
+		return err
+	}
+	eventService.record(models.ChangeUpdate, before, updateEvent)
+	return nil
+}
+
+// Delete - метод для перемещения события в корзину. Удалить событие может только его владелец.
+// Если version не 0, то событие удаляется, только если его версия не изменилась
+func (eventService *EventService) Delete(userID, id, version int) error {
+	eventService.writeMu.Lock()
+	defer eventService.writeMu.Unlock()
+
+	before, err := eventService.owned(userID, id)
+	if err != nil {
+		return err
+	}
+	if err := eventService.data.Delete(userID, id, version); err != nil {
+		return err
+	}
+	if after, err := eventService.trashed(userID, id); err == nil {
+		eventService.record(models.ChangeDelete, before, after)
+	}
+	return nil
+}
+
+// Restore - метод для восстановления события из корзины
+func (eventService *EventService) Restore(userID, id int) (*models.Event, error) {
+	eventService.writeMu.Lock()
+	defer eventService.writeMu.Unlock()
+
+	before, err := eventService.trashed(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	event, err := eventService.data.Restore(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	eventService.record(models.ChangeRestore, before, event)
+	return event, nil
+}
+
+// Respond - метод для ответа приглашенного пользователя на событие: accepted, declined или tentative.
+// Ответить может только участник события, владелец отвечать на свое событие не может
+func (eventService *EventService) Respond(userID, id int, status string) (*models.Event, error) {
+	if status != models.RSVPAccepted && status != models.RSVPDeclined && status != models.RSVPTentative {
+		return nil, errors.NewBadRequestError("invalid status: must be accepted, declined or tentative")
+	}
+
+	eventService.writeMu.Lock()
+	defer eventService.writeMu.Unlock()
+
+	before, err := eventService.data.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if before.UserID == userID {
+		return nil, errors.NewForbiddenError(fmt.Sprintf("owner cannot respond to event id %d", id))
+	}
+
+	// Меняем только ответ участника, остальное событие остается прежним
+	event := before.Clone()
+	for i := range event.Attendees {
+		if event.Attendees[i].UserID == userID {
+			event.Attendees[i].Status = status
+		}
+	}
+	if err := eventService.data.Update(event); err != nil {
+		return nil, err
+	}
+	eventService.record(models.ChangeRespond, before, event)
+	return event, nil
 }
 
-// Delete - метод для удаления события
-func (eventService *EventService) Delete(userID, id int) error {
-	return eventService.data.Delete(userID, id)
+// History - метод для получения истории изменений события, в том числе удаленного
+func (eventService *EventService) History(userID, id int) ([]models.Change, error) {
+	history, err := eventService.changes.History(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("history of event id %d", id))
+	}
+	return history, nil
+}
+
+// Changes - метод для получения ленты изменений событий пользователя после изменения с номером since
+func (eventService *EventService) Changes(userID int, since int64, limit int) ([]models.Change, error) {
+	return eventService.changes.Since(userID, since, limit)
 }
 
-// GetFor - метод для получения событий для указанного пользователя в определенном периоде времени
-func (eventService *EventService) GetFor(userID int, fromDate time.Time, mode string) ([]*models.Event, error) {
+// Subscribe - метод для подписки на изменения событий пользователя
+func (eventService *EventService) Subscribe(userID int) (<-chan models.Change, func()) {
+	return eventService.broker.subscribe(userID)
+}
+
+// record - записывает изменение события в журнал и рассылает его подписчикам: владельцу события
+// и каждому участнику, в том числе исключенному из участников этим изменением.
+// Изменение уже применено к хранилищу, поэтому ошибка журнала только пишется в лог
+func (eventService *EventService) record(op string, before, after *models.Event) {
+	users := []int{after.UserID}
+	for _, event := range []*models.Event{before, after} {
+		if event == nil {
+			continue
+		}
+		for _, attendee := range event.Attendees {
+			if !slices.Contains(users, attendee.UserID) {
+				users = append(users, attendee.UserID)
+			}
+		}
+	}
+
+	for _, userID := range users {
+		change := models.Change{
+			Op:      op,
+			UserID:  userID,
+			EventID: after.ID,
+			Version: after.Version,
+			After:   after.Clone(),
+		}
+		if before != nil {
+			change.Before = before.Clone()
+		}
+		change, err := eventService.changes.Append(change)
+		if err != nil {
+			slog.Error("change log", "op", op, "user_id", userID, "event_id", after.ID, "error", err.Error())
+			continue
+		}
+		eventService.broker.publish(change)
+	}
+}
+
+// owned - возвращает событие, проверяя, что пользователь - его владелец.
+// Участники видят событие, но изменять и удалять его не могут
+func (eventService *EventService) owned(userID, id int) (*models.Event, error) {
+	event, err := eventService.data.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if event.UserID != userID {
+		return nil, errors.NewForbiddenError(fmt.Sprintf("only the owner can change event id %d", id))
+	}
+	return event, nil
+}
+
+// trashed - возвращает событие пользователя из корзины
+func (eventService *EventService) trashed(userID, id int) (*models.Event, error) {
+	events, err := eventService.data.Trash(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		if event.ID == id {
+			return event, nil
+		}
+	}
+	return nil, errors.NewNotFoundError(fmt.Sprintf("deleted event id %d", id))
+}
+
+// Trash - метод для получения событий пользователя в корзине, начиная с удаленных последними
+func (eventService *EventService) Trash(userID int) ([]*models.Event, error) {
+	return eventService.data.Trash(userID)
+}
+
+// Purge - метод для окончательного удаления событий, находящихся в корзине дольше retention.
+// Вместе с событиями из журнала удаляется их история, чтобы удаленные данные нельзя было прочитать
+func (eventService *EventService) Purge(retention time.Duration) (int, error) {
+	// Если очистка прервалась, история уже удаленных событий тоже удаляется
+	purged, err := eventService.data.Purge(time.Now().Add(-retention))
+	if forgetErr := eventService.changes.Forget(purged); forgetErr != nil {
+		slog.Error("change log", "op", "forget", "purged", len(purged), "error", forgetErr.Error())
+	}
+	return len(purged), err
+}
+
+// Stats - метод для получения размера хранилища событий
+func (eventService *EventService) Stats() (data.Stats, error) {
+	return eventService.data.Stats()
+}
+
+// Ready - метод для проверки готовности хранилища событий
+func (eventService *EventService) Ready() error {
+	return eventService.data.Ready()
+}
+
+// Get - метод для получения события по id
+func (eventService *EventService) Get(userID, id int) (*models.Event, error) {
+	return eventService.data.Get(userID, id)
+}
+
+// GetFor - метод для получения событий для указанного пользователя в определенном периоде времени.
+// Границы периода считаются в часовом поясе fromDate, поэтому день может длиться 23 или 25 часов
+func (eventService *EventService) GetFor(userID int, fromDate time.Time, mode string, query Query) (Page, error) {
 	// toDate максимальная дата
 	toDate := fromDate
 	switch mode {
@@ -47,5 +285,206 @@ func (eventService *EventService) GetFor(userID int, fromDate time.Time, mode st
 		toDate = toDate.AddDate(0, 1, 0)
 	}
 
-	return eventService.data.GetFor(userID, fromDate, toDate)
+	return eventService.GetRange(userID, fromDate, toDate, query)
+}
+
+// GetRange - метод для получения страницы экземпляров событий, пересекающихся с периодом [fromDate, toDate)
+func (eventService *EventService) GetRange(userID int, fromDate, toDate time.Time, query Query) (Page, error) {
+	occurrences, err := eventService.occurrences(userID, fromDate, toDate, query.Filter)
+	if err != nil {
+		return Page{}, err
+	}
+	return paginate(occurrences, query)
+}
+
+// Upcoming - метод для получения не более n ближайших экземпляров событий, начинающихся не раньше fromDate.
+// События ищутся на MaxRange вперед
+func (eventService *EventService) Upcoming(userID int, fromDate time.Time, n int, filter models.Filter) ([]*models.Event, error) {
+	occurrences, err := eventService.occurrences(userID, fromDate, fromDate.Add(MaxRange), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	upcoming := make([]*models.Event, 0, n)
+	for _, occurrence := range occurrences {
+		// Уже идущие события не считаются предстоящими
+		if occurrence.Date.Before(fromDate) {
+			continue
+		}
+		if len(upcoming) == n {
+			break
+		}
+		upcoming = append(upcoming, occurrence)
+	}
+	return upcoming, nil
+}
+
+// DueReminders - метод для получения напоминаний всех пользователей, срабатывающих в периоде [fromDate, toDate)
+func (eventService *EventService) DueReminders(fromDate, toDate time.Time) ([]models.Notification, error) {
+	users, err := eventService.data.Users()
+	if err != nil {
+		return nil, err
+	}
+
+	notifications := make([]models.Notification, 0)
+	for _, userID := range users {
+		// Напоминание срабатывает до начала события, поэтому события ищем с запасом MaxReminder
+		events, err := eventService.data.GetFor(userID, fromDate, toDate.Add(models.MaxReminder), models.Filter{})
+		if err != nil {
+			// Пользователь мог пропасть между Users и GetFor
+			if _, ok := err.(*errors.NotFoundError); ok {
+				continue
+			}
+			return nil, err
+		}
+		for _, event := range events {
+			// Напоминания о событии получает только владелец, иначе они дублировались бы для каждого участника
+			if event.UserID != userID {
+				continue
+			}
+			notifications = append(notifications, event.Notifications(fromDate, toDate)...)
+		}
+	}
+	sort.SliceStable(notifications, func(i, j int) bool { return notifications[i].FireAt.Before(notifications[j].FireAt) })
+	return notifications, nil
+}
+
+// occurrences - возвращает упорядоченные экземпляры событий пользователя, пересекающиеся с периодом [fromDate, toDate)
+func (eventService *EventService) occurrences(userID int, fromDate, toDate time.Time, filter models.Filter) ([]*models.Event, error) {
+	filter, err := eventService.calendarFilter(userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	events, err := eventService.data.GetFor(userID, fromDate, toDate, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Разворачиваем повторяющиеся события в экземпляры внутри периода
+	occurrences := make([]*models.Event, 0, len(events))
+	for _, event := range events {
+		occurrences = append(occurrences, event.Occurrences(fromDate, toDate)...)
+	}
+	// Экземпляры повторяющихся событий перемешаны с остальными, поэтому сортируем еще раз
+	models.SortEvents(occurrences)
+	return occurrences, nil
+}
+
+// calendarFilter - проверяет, что календари фильтра принадлежат пользователю (0 - календарь по умолчанию),
+// и, если календари не заданы, добавляет в фильтр скрытые календари пользователя
+func (eventService *EventService) calendarFilter(userID int, filter models.Filter) (models.Filter, error) {
+	calendars, err := eventService.data.Calendars(userID)
+	if err != nil {
+		return filter, err
+	}
+	for _, id := range filter.Calendars {
+		if id == 0 {
+			continue
+		}
+		if !slices.ContainsFunc(calendars, func(calendar *models.Calendar) bool { return calendar.ID == id }) {
+			return filter, errors.NewNotFoundError(fmt.Sprintf("calendar id %d", id))
+		}
+	}
+	if len(filter.Calendars) == 0 {
+		for _, calendar := range calendars {
+			if calendar.Visibility == models.CalendarHidden {
+				filter.Hidden = append(filter.Hidden, calendar.ID)
+			}
+		}
+	}
+	return filter, nil
+}
+
+// GetAll - метод для получения всех событий пользователя (повторяющиеся события не разворачиваются)
+func (eventService *EventService) GetAll(userID int, query Query) (Page, error) {
+	filter, err := eventService.calendarFilter(userID, query.Filter)
+	if err != nil {
+		return Page{}, err
+	}
+	events, err := eventService.data.GetFor(userID, time.Time{}, maxDate, filter)
+	if err != nil {
+		return Page{}, err
+	}
+	return paginate(events, query)
+}
+
+// Conflicts - метод для поиска экземпляров событий пользователя, пересекающихся с событием.
+// Для уже сохраненного события (existing) его собственные экземпляры не считаются конфликтами
+func (eventService *EventService) Conflicts(event *models.Event, existing bool) ([]*models.Event, error) {
+	fromDate, toDate := event.Date, event.EndTime()
+	if event.Recurrence != nil {
+		toDate = event.Date.Add(conflictHorizon)
+	}
+	// Для события без длительности проверяем момент его начала
+	if !toDate.After(fromDate) {
+		toDate = fromDate.Add(time.Nanosecond)
+	}
+
+	events, err := eventService.data.GetFor(event.UserID, fromDate, toDate, models.Filter{})
+	if err != nil {
+		// У нового пользователя еще нет событий, значит, нет и конфликтов
+		if _, ok := err.(*errors.NotFoundError); ok {
+			return []*models.Event{}, nil
+		}
+		return nil, err
+	}
+
+	candidates := event.Occurrences(fromDate, toDate)
+	conflicts := make([]*models.Event, 0)
+	for _, other := range events {
+		if existing && other.ID == event.ID {
+			continue
+		}
+		for _, occurrence := range other.Occurrences(fromDate, toDate) {
+			for _, candidate := range candidates {
+				if candidate.Conflicts(occurrence) {
+					conflicts = append(conflicts, occurrence)
+					break
+				}
+			}
+		}
+	}
+	models.SortEvents(conflicts)
+	return conflicts, nil
+}
+
+// FreeBusy - метод для получения занятых промежутков пользователя в периоде [fromDate, toDate).
+// Пересекающиеся события объединяются в один промежуток, события без длительности не занимают время
+func (eventService *EventService) FreeBusy(userID int, fromDate, toDate time.Time) ([]models.Interval, error) {
+	events, err := eventService.data.GetFor(userID, fromDate, toDate, models.Filter{})
+	if err != nil {
+		return nil, err
+	}
+
+	intervals := make([]models.Interval, 0)
+	for _, event := range events {
+		for _, occurrence := range event.Occurrences(fromDate, toDate) {
+			if occurrence.Duration() <= 0 {
+				continue
+			}
+			// Обрезаем промежуток по границам периода
+			start, end := occurrence.Date, occurrence.EndTime()
+			if start.Before(fromDate) {
+				start = fromDate
+			}
+			if end.After(toDate) {
+				end = toDate
+			}
+			intervals = append(intervals, models.Interval{Start: start, End: end})
+		}
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].Start.Before(intervals[j].Start) })
+
+	// Объединяем пересекающиеся и смежные промежутки
+	busy := make([]models.Interval, 0, len(intervals))
+	for _, interval := range intervals {
+		if last := len(busy) - 1; last >= 0 && !interval.Start.After(busy[last].End) {
+			if interval.End.After(busy[last].End) {
+				busy[last].End = interval.End
+			}
+			continue
+		}
+		busy = append(busy, interval)
+	}
+	return busy, nil
 }
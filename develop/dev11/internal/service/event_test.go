@@ -0,0 +1,94 @@
+package service
+
+import (
+	"develop/dev11/internal/data"
+	"develop/dev11/internal/models"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestEventServicePurge(t *testing.T) {
+	date := time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC)
+	eventService := NewEventService(data.New())
+
+	// Событие 0 с участником удаляется и попадает в корзину, событие 1 остается
+	event := models.NewEvent(5, 0, date, "Test1", "Test1")
+	event.Attendees = []models.Attendee{{UserID: 6}}
+	if _, err := eventService.Create(event); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := eventService.Create(models.NewEvent(5, 0, date, "Test2", "Test2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := eventService.Delete(5, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Срок хранения еще не истек: ни события, ни история не удаляются
+	if purged, err := eventService.Purge(time.Hour); err != nil || purged != 0 {
+		t.Fatalf("Purge() got %d, %v want 0, nil", purged, err)
+	}
+	if history, err := eventService.History(5, 0); err != nil || len(history) != 2 {
+		t.Fatalf("history before purge: got %v, %v want create and delete", history, err)
+	}
+	if changes, err := eventService.Changes(6, 0, 10); err != nil || len(changes) != 2 {
+		t.Fatalf("attendee changes before purge: got %v, %v want create and delete", changes, err)
+	}
+
+	if purged, err := eventService.Purge(-time.Hour); err != nil || purged != 1 {
+		t.Fatalf("Purge() got %d, %v want 1, nil", purged, err)
+	}
+	// История удаленного события пропадает у владельца и у участника
+	if history, err := eventService.History(5, 0); err == nil {
+		t.Errorf("history of purged event: got %v want error", history)
+	}
+	changes, err := eventService.Changes(6, 0, 10)
+	if err != nil || len(changes) != 0 {
+		t.Errorf("attendee changes: got %v, %v want []", changes, err)
+	}
+	// История оставшегося события сохраняется
+	if history, err := eventService.History(5, 1); err != nil || len(history) != 1 || history[0].Op != models.ChangeCreate {
+		t.Errorf("history of kept event: got %v, %v want create", history, err)
+	}
+}
+
+func TestEventServiceRecordAttendees(t *testing.T) {
+	date := time.Date(2036, 5, 12, 15, 04, 04, 0, time.UTC)
+	eventService := NewEventService(data.New())
+
+	event := models.NewEvent(5, 0, date, "Test", "Test")
+	event.Attendees = []models.Attendee{{UserID: 6}, {UserID: 7}}
+	if _, err := eventService.Create(event); err != nil {
+		t.Fatal(err)
+	}
+	// Участник 7 исключен: изменение попадает и в его ленту
+	updated := models.NewEvent(5, 0, date, "Updated", "Test")
+	updated.Attendees = []models.Attendee{{UserID: 6}}
+	if err := eventService.Update(updated); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		userID int
+		want   []string
+	}{
+		{userID: 5, want: []string{models.ChangeCreate, models.ChangeUpdate}},
+		{userID: 6, want: []string{models.ChangeCreate, models.ChangeUpdate}},
+		{userID: 7, want: []string{models.ChangeCreate, models.ChangeUpdate}},
+		{userID: 8, want: []string{}},
+	}
+	for _, tt := range tests {
+		changes, err := eventService.Changes(tt.userID, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]string, 0, len(changes))
+		for _, change := range changes {
+			got = append(got, change.Op)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("user %d changes: got %v want %v", tt.userID, got, tt.want)
+		}
+	}
+}
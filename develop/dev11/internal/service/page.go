@@ -0,0 +1,72 @@
+package service
+
+import (
+	"develop/dev11/internal/errors"
+	"develop/dev11/internal/models"
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+// Query - параметры выборки событий: фильтр и страница
+type Query struct {
+	Filter models.Filter // Фильтр по тексту
+	Limit  int           // Максимальное число событий на странице, 0 - без ограничения
+	Cursor string        // Курсор, полученный с предыдущей страницей
+}
+
+// Page - страница событий
+type Page struct {
+	Events     []*models.Event // События страницы по дате и id
+	NextCursor string          // Курсор следующей страницы, пустой для последней страницы
+}
+
+// cursor - позиция последнего события страницы в порядке выдачи
+type cursor struct {
+	date time.Time
+	id   int
+}
+
+// encodeCursor - кодирует позицию события в непрозрачную строку.
+// Секунды и наносекунды хранятся отдельно: UnixNano переполняется для дат после 2262 года
+func encodeCursor(event *models.Event) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d:%d", event.Date.Unix(), event.Date.Nanosecond(), event.ID)))
+}
+
+// decodeCursor - разбирает курсор, полученный от клиента
+func decodeCursor(value string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor{}, errors.NewBadRequestError("invalid cursor")
+	}
+	var seconds, nanos int64
+	var id int
+	if _, err := fmt.Sscanf(string(raw), "%d:%d:%d", &seconds, &nanos, &id); err != nil || nanos < 0 || nanos >= int64(time.Second) {
+		return cursor{}, errors.NewBadRequestError("invalid cursor")
+	}
+	return cursor{date: time.Unix(seconds, nanos), id: id}, nil
+}
+
+// paginate - возвращает страницу упорядоченных событий после курсора.
+// Курсор хранит дату и id, поэтому экземпляры одного повторяющегося события различаются
+func paginate(events []*models.Event, query Query) (Page, error) {
+	if query.Cursor != "" {
+		after, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return Page{}, err
+		}
+		position := &models.Event{Date: after.date, ID: after.id}
+		start := 0
+		for start < len(events) && !models.Less(position, events[start]) {
+			start++
+		}
+		events = events[start:]
+	}
+
+	page := Page{Events: events}
+	if query.Limit > 0 && len(events) > query.Limit {
+		page.Events = events[:query.Limit]
+		page.NextCursor = encodeCursor(page.Events[query.Limit-1])
+	}
+	return page, nil
+}
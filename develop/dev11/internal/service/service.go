@@ -13,24 +13,87 @@ const (
 	ModeForMonth = "month"
 )
 
+// MaxRange - максимальная длина произвольного периода выборки
+const MaxRange = 366 * 24 * time.Hour
+
 // Eventer - интерфейс для работы с событиями
 type Eventer interface {
 	// Create создает новое событие
 	Create(createEvent *models.Event) (int, error)
-	// Update обновляет существующее событие
+	// Update обновляет существующее событие, если его версия совпадает с updateEvent.Version (0 - без проверки)
 	Update(updateEvent *models.Event) error
-	// Delete удаляет событие
-	Delete(userID, id int) error
-	// GetFor возвращает события для указанного пользователя
-	GetFor(userID int, date time.Time, mode string) ([]*models.Event, error)
+	// Delete перемещает событие в корзину, если его версия совпадает с version (0 - без проверки)
+	Delete(userID, id, version int) error
+	// Restore возвращает событие из корзины
+	Restore(userID, id int) (*models.Event, error)
+	// Respond записывает ответ приглашенного пользователя на событие
+	Respond(userID, id int, status string) (*models.Event, error)
+	// Trash возвращает события пользователя в корзине
+	Trash(userID int) ([]*models.Event, error)
+	// Purge окончательно удаляет события, находящиеся в корзине дольше retention
+	Purge(retention time.Duration) (int, error)
+	// Get возвращает событие по id
+	Get(userID, id int) (*models.Event, error)
+	// GetFor возвращает страницу событий для указанного пользователя
+	GetFor(userID int, date time.Time, mode string, query Query) (Page, error)
+	// GetRange возвращает страницу экземпляров событий пользователя в периоде [fromDate, toDate)
+	GetRange(userID int, fromDate, toDate time.Time, query Query) (Page, error)
+	// Upcoming возвращает не более n ближайших экземпляров событий пользователя
+	Upcoming(userID int, fromDate time.Time, n int, filter models.Filter) ([]*models.Event, error)
+	// GetAll возвращает страницу всех событий пользователя без разворачивания повторений
+	GetAll(userID int, query Query) (Page, error)
+	// Conflicts возвращает экземпляры событий пользователя, пересекающиеся с событием
+	Conflicts(event *models.Event, existing bool) ([]*models.Event, error)
+	// DueReminders возвращает напоминания всех пользователей, срабатывающие в периоде
+	DueReminders(fromDate, toDate time.Time) ([]models.Notification, error)
+	// FreeBusy возвращает занятые промежутки пользователя в периоде
+	FreeBusy(userID int, fromDate, toDate time.Time) ([]models.Interval, error)
+	// History возвращает историю изменений события пользователя
+	History(userID, id int) ([]models.Change, error)
+	// Changes возвращает не более limit изменений событий пользователя после изменения с номером since
+	Changes(userID int, since int64, limit int) ([]models.Change, error)
+	// Subscribe подписывает на изменения событий пользователя по мере их записи в журнал.
+	// Возвращает канал изменений и функцию отписки. Канал закрывается, если подписчик не успевает читать
+	Subscribe(userID int) (<-chan models.Change, func())
+	// Stats возвращает размер хранилища событий
+	Stats() (data.Stats, error)
+	// Ready возвращает ошибку, если хранилище событий не готово
+	Ready() error
+}
+
+// Calendarer - интерфейс для работы с календарями пользователей
+type Calendarer interface {
+	// CreateCalendar создает новый календарь
+	CreateCalendar(createCalendar *models.Calendar) (int, error)
+	// UpdateCalendar изменяет существующий календарь
+	UpdateCalendar(updateCalendar *models.Calendar) error
+	// DeleteCalendar удаляет календарь без событий
+	DeleteCalendar(userID, id int) error
+	// Calendar возвращает календарь пользователя по id
+	Calendar(userID, id int) (*models.Calendar, error)
+	// Calendars возвращает календари пользователя
+	Calendars(userID int) ([]*models.Calendar, error)
+}
+
+// ChangeLog - журнал изменений событий, в который записи дописываются, пока событие не удалено окончательно
+type ChangeLog interface {
+	// Append присваивает изменению номер и время и дописывает его в журнал
+	Append(change models.Change) (models.Change, error)
+	// History возвращает изменения события пользователя по возрастанию номера
+	History(userID, eventID int) ([]models.Change, error)
+	// Since возвращает не более limit изменений пользователя с номером больше since
+	Since(userID int, since int64, limit int) ([]models.Change, error)
+	// Forget удаляет изменения окончательно удаленных событий
+	Forget(eventIDs []int) error
 }
 
 // Service - структура сервиса
 type Service struct {
 	Eventer
+	Calendarer
 }
 
 // New - конструктор для Service
-func New(data data.Eventer) *Service {
-	return &Service{NewEventService(data)}
+func New(data data.Eventer, options ...Option) *Service {
+	return &Service{NewEventService(data, options...), NewCalendarService(data)}
 }
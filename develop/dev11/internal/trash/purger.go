@@ -0,0 +1,97 @@
+package trash
+
+import (
+	"develop/dev11/config"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Source - хранилище с корзиной
+type Source interface {
+	// Purge окончательно удаляет события, находящиеся в корзине дольше retention
+	Purge(retention time.Duration) (int, error)
+}
+
+// Purger - фоновая очистка корзины.
+// Раз в interval окончательно удаляет события, находящиеся в корзине дольше retention
+type Purger struct {
+	source    Source        // source - хранилище с корзиной
+	retention time.Duration // retention - сколько событие хранится в корзине
+	interval  time.Duration // interval - период очистки
+
+	stop chan struct{} // stop - сигнал остановки
+	done chan struct{} // done - закрывается после остановки цикла
+	once sync.Once     // once - защищает от повторного запуска
+	halt sync.Once     // halt - защищает от повторной остановки
+}
+
+// NewPurger - конструктор Purger
+func NewPurger(source Source, retention, interval time.Duration) *Purger {
+	return &Purger{
+		source:    source,
+		retention: retention,
+		interval:  interval,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// NewFromConfig - создает очистку корзины с настройками из конфигурации.
+// Возвращает nil, если очистка отключена
+func NewFromConfig(cfg config.Config, source Source) *Purger {
+	if cfg.TrashRetention <= 0 {
+		return nil
+	}
+	return NewPurger(source, cfg.TrashRetention, cfg.TrashPurgeInterval)
+}
+
+// Start - запускает цикл очистки корзины в горутине
+func (purger *Purger) Start() {
+	purger.once.Do(func() {
+		go purger.run()
+	})
+}
+
+// Stop - останавливает очистку, дожидаясь окончания текущего прохода
+func (purger *Purger) Stop() {
+	purger.halt.Do(func() {
+		close(purger.stop)
+		// Цикл, который не запускали, ждать не нужно
+		purger.once.Do(func() {
+			close(purger.done)
+		})
+		<-purger.done
+	})
+}
+
+// run - цикл очистки корзины до вызова Stop
+func (purger *Purger) run() {
+	defer close(purger.done)
+
+	ticker := time.NewTicker(purger.interval)
+	defer ticker.Stop()
+
+	// Сразу после запуска удаляем события, срок хранения которых истек за время простоя
+	purger.purge()
+	for {
+		select {
+		case <-purger.stop:
+			return
+		case <-ticker.C:
+			purger.purge()
+		}
+	}
+}
+
+// purge - окончательно удаляет события с истекшим сроком хранения в корзине
+func (purger *Purger) purge() {
+	purged, err := purger.source.Purge(purger.retention)
+	if err != nil {
+		slog.Error("trash purge", "error", err.Error())
+		return
+	}
+	if purged > 0 {
+		slog.Info("trash purge", "purged", purged)
+	}
+}
@@ -0,0 +1,110 @@
+package trash
+
+import (
+	"develop/dev11/config"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordSource - хранилище, запоминающее вызовы очистки корзины
+type recordSource struct {
+	mu         sync.Mutex
+	fail       bool
+	retentions []time.Duration
+	calls      chan struct{}
+}
+
+func newRecordSource(fail bool) *recordSource {
+	return &recordSource{fail: fail, calls: make(chan struct{}, 16)}
+}
+
+func (source *recordSource) Purge(retention time.Duration) (int, error) {
+	source.mu.Lock()
+	source.retentions = append(source.retentions, retention)
+	source.mu.Unlock()
+
+	select {
+	case source.calls <- struct{}{}:
+	default:
+	}
+	if source.fail {
+		return 0, fmt.Errorf("unavailable")
+	}
+	return 1, nil
+}
+
+func (source *recordSource) count() int {
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	return len(source.retentions)
+}
+
+func TestPurger(t *testing.T) {
+	tests := []struct {
+		name string
+		fail bool
+	}{
+		{name: "OK"},
+		// Ошибка хранилища не останавливает очистку
+		{name: "Source Error", fail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := newRecordSource(tt.fail)
+			purger := NewPurger(source, 24*time.Hour, 10*time.Millisecond)
+			purger.Start()
+			// Повторный запуск не создает второй цикл
+			purger.Start()
+
+			// Первый проход сразу после запуска, следующие - по таймеру
+			for i := 0; i < 3; i++ {
+				select {
+				case <-source.calls:
+				case <-time.After(time.Second):
+					t.Fatalf("purge %d: not called", i)
+				}
+			}
+			purger.Stop()
+
+			stopped := source.count()
+			time.Sleep(30 * time.Millisecond)
+			if got := source.count(); got != stopped {
+				t.Errorf("purges after Stop: got %d want %d", got, stopped)
+			}
+			source.mu.Lock()
+			defer source.mu.Unlock()
+			for _, retention := range source.retentions {
+				if retention != 24*time.Hour {
+					t.Errorf("retention: got %v want 24h", retention)
+				}
+			}
+		})
+	}
+}
+
+func TestPurgerStop(t *testing.T) {
+	// Остановка незапущенной очистки не ждет цикла, повторная остановка не паникует
+	source := newRecordSource(false)
+	purger := NewPurger(source, time.Hour, time.Hour)
+	purger.Stop()
+	purger.Stop()
+	// Запуск после остановки не начинает очистку
+	purger.Start()
+	time.Sleep(10 * time.Millisecond)
+	if got := source.count(); got != 0 {
+		t.Errorf("purges: got %d want 0", got)
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	source := newRecordSource(false)
+	if purger := NewFromConfig(config.Config{TrashRetention: 0, TrashPurgeInterval: time.Hour}, source); purger != nil {
+		t.Errorf("disabled retention: got %v want nil", purger)
+	}
+	purger := NewFromConfig(config.Config{TrashRetention: 48 * time.Hour, TrashPurgeInterval: time.Hour}, source)
+	if purger == nil || purger.retention != 48*time.Hour || purger.interval != time.Hour {
+		t.Errorf("purger: got %+v want retention 48h and interval 1h", purger)
+	}
+}
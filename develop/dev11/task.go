@@ -3,16 +3,28 @@ package main
 import (
 	"context"
 	"develop/dev11/config"
+	"develop/dev11/internal/audit"
+	"develop/dev11/internal/auth"
+	"develop/dev11/internal/certs"
 	"develop/dev11/internal/data"
 	"develop/dev11/internal/handler"
+	"develop/dev11/internal/logger"
+	"develop/dev11/internal/ratelimit"
+	"develop/dev11/internal/reminder"
 	"develop/dev11/internal/server"
 	"develop/dev11/internal/service"
+	"develop/dev11/internal/trash"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
+	"time"
+
+	// База часовых поясов встраивается в бинарник, чтобы параметр tz работал и без tzdata в системе
+	_ "time/tzdata"
 )
 
 /*
@@ -37,48 +49,247 @@ import (
 	4. Код должен проходить проверки go vet и golint.
 */
 
+// schedulerStopTimeout - сколько ждать окончания отправки напоминаний при остановке сервера
+const schedulerStopTimeout = 10 * time.Second
+
+// devCertValidity - срок действия самоподписанного сертификата для разработки
+const devCertValidity = 365 * 24 * time.Hour
+
 func main() {
-	cfgPath := flag.String("cfg", "./.env", "USAGE -cfg='path_to_config_file")
+	// Настройки читаются из файла -config, файла .env, переменных окружения и флагов, см. config.Loader
+	loader := config.NewLoader(flag.CommandLine)
+	hashPassword := flag.String("hash-password", "", "USAGE -hash-password='password' prints password hash for users file")
+	genCert := flag.String("gen-cert", "", "USAGE -gen-cert=./certs writes self-signed localhost cert.pem and key.pem for development")
 	flag.Parse()
 
-	// Инициализация конфигураций
-	cfg, err := config.InitConfig(*cfgPath)
+	// Вывод хеша пароля для файла пользователей
+	if *hashPassword != "" {
+		hash, err := auth.HashPassword(*hashPassword)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(hash)
+		return
+	}
+
+	// Создание самоподписанного сертификата для локальной разработки
+	if *genCert != "" {
+		certFile, keyFile := filepath.Join(*genCert, "cert.pem"), filepath.Join(*genCert, "key.pem")
+		if err := certs.GenerateSelfSigned(certFile, keyFile, certs.DevHosts, devCertValidity); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("TLS_CERT_FILE=%s\nTLS_KEY_FILE=%s\n", certFile, keyFile)
+		return
+	}
+
+	// Инициализация конфигураций: ошибки выводятся списком по всем неверным ключам
+	cfg, err := loader.Load()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		return
+		os.Exit(1)
+	}
+
+	// Журнал пишется в stderr в формате JSON, стандартный log тоже направляется в него.
+	// Уровень журнала меняется по SIGHUP
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(cfg.LogLevel)
+	slog.SetDefault(logger.New(os.Stderr, logLevel))
+
+	// Ошибка запуска освобождает уже открытые ресурсы в обратном порядке и завершает программу с кодом 1:
+	// хранилище и журнал изменений закрываются, чтобы сохранить записанные данные
+	var cleanup []func()
+	fail := func(err error) {
+		fmt.Fprintln(os.Stderr, err)
+		for i := len(cleanup) - 1; i >= 0; i-- {
+			cleanup[i]()
+		}
+		os.Exit(1)
 	}
 
 	// Инициализация хранилища данных
-	data := data.New()
+	data, err := data.NewFromConfig(cfg)
+	if err != nil {
+		fail(err)
+	}
+	cleanup = append(cleanup, func() {
+		if err := data.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	})
+
+	// Инициализация журнала изменений событий
+	changes, err := audit.NewFromConfig(cfg)
+	if err != nil {
+		fail(err)
+	}
+	cleanup = append(cleanup, func() {
+		if err := changes.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	})
 
 	// Инициализация сервиса
-	service := service.New(data)
+	service := service.New(data, service.WithChangeLog(changes))
+
+	// Инициализация планировщика напоминаний
+	scheduler, err := reminder.NewFromConfig(cfg, service)
+	if err != nil {
+		fail(err)
+	}
+	if scheduler != nil {
+		scheduler.Start()
+		cleanup = append(cleanup, func() {
+			ctx, cancel := context.WithTimeout(context.Background(), schedulerStopTimeout)
+			defer cancel()
+			scheduler.Stop(ctx)
+		})
+	}
+
+	// Инициализация очистки корзины
+	purger := trash.NewFromConfig(cfg, service)
+	if purger != nil {
+		purger.Start()
+		cleanup = append(cleanup, purger.Stop)
+	}
+
+	// Инициализация аутентификации, если задан ключ подписи токенов
+	var options []handler.Option
+	if cfg.AuthSecret != "" {
+		users, err := auth.LoadUsers(cfg.AuthUsersPath)
+		if err != nil {
+			fail(err)
+		}
+		options = append(options, handler.WithAuth(auth.New(cfg.AuthSecret, cfg.TokenTTL, users)))
+	}
+
+	// Ограничения частоты и размера запросов
+	options = append(options, handler.WithBodyLimit(cfg.MaxBodyBytes, cfg.MaxImportBytes))
+	// Ограничители создаются и с нулевым лимитом, чтобы лимит можно было включить по SIGHUP
+	ipLimiter := ratelimit.New(cfg.RateLimitIP, cfg.RateLimitIPBurst)
+	userLimiter := ratelimit.New(cfg.RateLimitUser, cfg.RateLimitUserBurst)
+	options = append(options, handler.WithRateLimit(ipLimiter, userLimiter))
 
 	// Инициализация обработчика запросов
-	handler := handler.New(service)
+	handler := handler.New(service, options...)
+
+	// Загрузка сертификатов TLS, если они заданы. Сертификаты перечитываются при изменении файлов
+	certificates, err := certs.NewFromConfig(cfg)
+	if err != nil {
+		fail(err)
+	}
+	if certificates != nil {
+		certificates.Start()
+	}
 
 	// Создание HTTP сервера
-	httpServer := new(server.Server)
+	httpServer := server.New(cfg, handler.InitRouter(), certificates)
 
-	// Запуск HTTP сервера в горутине
+	// Запуск HTTP сервера в горутине, ошибка запуска останавливает программу
+	serverErr := make(chan error, 1)
 	go func() {
-		if err := httpServer.Run(cfg, handler.InitRouter()); err != nil {
-			log.Fatalf("error occured while running http server: %s", err.Error())
-		}
+		serverErr <- httpServer.Run()
 	}()
 
-	log.Print("api server start")
+	slog.Info("api server start", "addr", cfg.Addr, "tls", certificates != nil, "mtls", cfg.TLSClientCAFile != "")
 
 	// Создание канала для обработки сигналов завершения программы (Ctrl+C)
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-	<-quit
+	// SIGHUP перечитывает конфигурацию и сертификаты TLS без разрыва открытых соединений
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	exitCode := 0
+wait:
+	for {
+		select {
+		case <-quit:
+			break wait
+		case err := <-serverErr:
+			slog.Error("error occured while running http server", "error", err.Error())
+			exitCode = 1
+			break wait
+		case <-hangup:
+			reload(loader, logLevel, ipLimiter, userLimiter, httpServer, certificates)
+		}
+	}
+
+	slog.Info("api server shutting down", "timeout", cfg.ShutdownTimeout)
+
+	// /readyz начинает отвечать 503, чтобы новые запросы направлялись на другие экземпляры,
+	// новые потоки изменений отклоняются, а открытые закрываются, и клиенты переподключаются
+	// к другим экземплярам с Last-Event-ID
+	handler.Shutdown()
+
+	// Остановка HTTP сервера: запросы дорабатывают не дольше ShutdownTimeout, затем соединения закрываются
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	if err := httpServer.Shutdown(ctx); err != nil {
+		slog.Error("error occured on server shutting down, connections are closed", "error", err.Error())
+	}
+	cancel()
+
+	// Остановка планировщика напоминаний: текущая отправка завершается, недоставленные напоминания
+	// будут отправлены после перезапуска
+	if scheduler != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), schedulerStopTimeout)
+		if err := scheduler.Stop(ctx); err != nil {
+			slog.Error("error occured on reminder scheduler stopping", "error", err.Error())
+		}
+		cancel()
+	}
+
+	// Остановка проверки изменения сертификатов
+	if certificates != nil {
+		certificates.Stop()
+	}
+
+	// Остановка очистки корзины до закрытия хранилища
+	if purger != nil {
+		purger.Stop()
+	}
+
+	// Закрытие хранилища: для файлового хранилища сохраняется финальный снимок,
+	// поэтому хранилище закрывается после завершения всех запросов, которые могли его менять
+	if err := data.Close(); err != nil {
+		slog.Error("error occured on storage closing", "error", err.Error())
+		exitCode = 1
+	}
+	if err := changes.Close(); err != nil {
+		slog.Error("error occured on change log closing", "error", err.Error())
+		exitCode = 1
+	}
+
+	slog.Info("api server stopped")
+	os.Exit(exitCode)
+}
+
+// reload - перечитывает конфигурацию по SIGHUP и применяет настройки, которые меняются без перезапуска:
+// уровень журнала, ограничения частоты запросов и таймауты сервера, а также перечитывает сертификаты TLS.
+// Остальные настройки требуют перезапуска. При ошибке в конфигурации продолжают действовать прежние настройки
+func reload(loader *config.Loader, logLevel *slog.LevelVar, ipLimiter, userLimiter *ratelimit.Limiter, httpServer *server.Server, certificates *certs.Reloader) {
+	cfg, err := loader.Load()
+	if err != nil {
+		slog.Error("config reload", "error", err.Error())
+		return
+	}
 
-	log.Print("api server shutting down")
+	logLevel.Set(cfg.LogLevel)
+	ipLimiter.SetLimit(cfg.RateLimitIP, cfg.RateLimitIPBurst)
+	userLimiter.SetLimit(cfg.RateLimitUser, cfg.RateLimitUserBurst)
+	httpServer.SetTimeouts(cfg.ReadTimeout, cfg.WriteTimeout, cfg.IdleTimeout)
+	slog.Info("config reloaded", "log_level", cfg.LogLevel.String(),
+		"rate_limit_ip", cfg.RateLimitIP, "rate_limit_user", cfg.RateLimitUser,
+		"read_timeout", cfg.ReadTimeout, "write_timeout", cfg.WriteTimeout, "idle_timeout", cfg.IdleTimeout)
 
-	// Остановка HTTP сервера
-	if err := httpServer.Shutdown(context.Background()); err != nil {
-		fmt.Fprintf(os.Stderr, "error occured on server shutting down: %s", err.Error())
+	if certificates == nil {
+		return
+	}
+	if err := certificates.Reload(); err != nil {
+		slog.Error("TLS certificate reload", "error", err.Error())
+		return
 	}
+	slog.Info("TLS certificate reloaded", "cert", cfg.TLSCertFile)
 }